@@ -0,0 +1,189 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package backup_test
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/suite"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Dump a cluster into an S3 bucket served by MinIO deployed in the aux namespace.
+
+var unit_ds3 *suite.Unit
+var minio *suite.Minio
+
+const BackupProfileNameS3 = "fulldump-s3"
+const MbkToS3 = "dump-test-s3"
+const S3StoragePrefix = "/e2etest/ote-mysql"
+const S3Credentials = "s3-credentials"
+
+var s3StorageOutput string
+
+func CreateS3(t *testing.T) {
+	var err error
+	minio, err = unit_ds3.DeployMinio(unit_ds3.AuxNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = minio.CreateCredentialsSecret(unit_ds3.Namespace, S3Credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unit_ds3.Client.CreateUserSecrets(unit_ds3.Namespace, "mypwds", common.RootUser, common.DefaultHost, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := k8s.NewInnoDBClusterBuilder(ClusterName).
+		Instances(1).
+		SecretName("mypwds").
+		TlsUseSelfSigned().
+		BackupProfile(k8s.NewDumpInstanceProfile(BackupProfileNameS3, minio.NewStorage(S3StoragePrefix, S3Credentials), nil))
+	err = unit_ds3.CreateInnoDBCluster(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unit_ds3.WaitOnPod("mycluster-0", corev1.PodRunning)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitParams := k8s.WaitOnInnoDBClusterParams{
+		Name:              ClusterName,
+		ExpectedStatus:    []string{"ONLINE"},
+		ExpectedNumOnline: 1,
+	}
+	err = unit_ds3.WaitOnInnoDBCluster(waitParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = suite.LoadSakilaScript(unit_ds3, "mycluster-0", k8s.Mysql)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BackupToS3Bucket(t *testing.T) {
+	const BackupToVolumeTemplate = "task-backup-to-volume.yaml"
+	generateData := GenerateBackupData{
+		BackupName:        MbkToS3,
+		BackupProfileName: BackupProfileNameS3,
+	}
+	err := unit_ds3.GenerateAndApply(BackupToVolumeTemplate, generateData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mbk *k8s.MySQLBackup
+	checker := func(args ...interface{}) (bool, error) {
+		var ok bool
+		ok, mbk, err = suite.CheckMySQLBackup(unit_ds3.Client, unit_ds3.Namespace, generateData.BackupName)
+		return ok, err
+	}
+	_, err = unit_ds3.Wait(checker, 300*time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mbkStatus := mbk.Status.Status
+	if mbkStatus != k8s.MBKStatusCompleted {
+		t.Fatalf("expected mbk status is %s but got %s", k8s.MBKStatusCompleted, mbkStatus)
+	}
+
+	mbkStatusOutput := mbk.Status.Output
+	expectedMbkPrefix := generateData.BackupName + "-"
+	if !strings.HasPrefix(mbkStatusOutput, expectedMbkPrefix) {
+		t.Fatalf("mbk output name should begin with %s but it doesn't (%s)", expectedMbkPrefix, mbkStatusOutput)
+	}
+	s3StorageOutput = path.Join(S3StoragePrefix, mbkStatusOutput)
+
+	mbkMethod := mbk.Status.Method
+	const ExpectedMbkMethodPrefix = "dump-instance/"
+	if !strings.HasPrefix(mbkMethod, ExpectedMbkMethodPrefix) {
+		t.Fatalf("mbk method should begin with %s but it doesn't (%s)", ExpectedMbkMethodPrefix, mbkMethod)
+	}
+
+	// check what the backup actually wrote into the bucket
+	store, err := minio.GetObjectStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := suite.CheckDumpInStore(unit_ds3.Context(), store, s3StorageOutput); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func DestroyS3(t *testing.T) {
+	err := unit_ds3.Client.DeleteInnoDBCluster(unit_ds3.Namespace, ClusterName)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_ds3.WaitOnPodGone("mycluster-0")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_ds3.WaitOnInnoDBClusterGone(ClusterName)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = unit_ds3.Client.DeleteMySQLBackup(unit_ds3.Namespace, MbkToS3); err != nil {
+		t.Error(err)
+	}
+
+	if err = unit_ds3.Client.DeleteSecret(unit_ds3.Namespace, S3Credentials); err != nil {
+		t.Error(err)
+	}
+
+	if err = unit_ds3.Client.DeleteSecret(unit_ds3.Namespace, "mypwds"); err != nil {
+		t.Error(err)
+	}
+
+	// minio itself goes away with the aux namespace
+	if minio != nil && len(s3StorageOutput) > 0 {
+		store, err := minio.GetObjectStore()
+		if err != nil {
+			t.Error(err)
+		} else if _, err = store.DeletePrefix(unit_ds3.Context(), s3StorageOutput); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDumpInstanceS3(t *testing.T) {
+	const Namespace = "backup-s3"
+	const NamespaceMinio = "backup-s3-minio"
+	var err error
+	unit_ds3, err = suit.NewUnitSetupWithAuxNamespace(Namespace, NamespaceMinio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unit_ds3.Cfg.CheckS3Config()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	unit_ds3.Run(t, "CreateS3=0", CreateS3)
+	unit_ds3.Run(t, "BackupToS3Bucket=1", BackupToS3Bucket)
+	unit_ds3.Run(t, "DestroyS3=9", DestroyS3)
+
+	err = unit_ds3.Teardown()
+	if err != nil {
+		t.Error(err)
+	}
+}
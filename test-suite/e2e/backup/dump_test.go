@@ -6,11 +6,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/common"
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
 	"github.com/marinesovitch/ote/test-suite/util/mysql"
-	"github.com/marinesovitch/ote/test-suite/util/oci"
+	"github.com/marinesovitch/ote/test-suite/util/objstore"
 	"github.com/marinesovitch/ote/test-suite/util/suite"
 
 	corev1 "k8s.io/api/core/v1"
@@ -30,14 +31,12 @@ const OciCredentials = "backup-apikey"
 var ociBucket string
 var ociStorageOutput string
 
-type GenerateDumpClusterData struct {
-	BackupProfileNameVolume string
-	BackupProfileNameOci    string
-	BackupVolumeName        string
-	BucketName              string
-	BackupDir               string
-	OciStoragePrefix        string
-	OciCredentials          string
+// nil if OCI tests are skipped
+var ociStorage *suite.OciBucket
+
+type GenerateDumpVolumeData struct {
+	BackupVolumeName string
+	BackupDir        string
 }
 
 func Create(t *testing.T) {
@@ -47,23 +46,30 @@ func Create(t *testing.T) {
 	}
 
 	ociBucket = unit_dmp.Cfg.Oci.BucketName
+	if unit_dmp.Cfg.CheckOCIConfig() == nil {
+		// with oci.fake it is the bucket of the fake
+		ociStorage, err = unit_dmp.PrepareOciBucket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ociBucket = ociStorage.BucketName
+	}
 	if ociBucket == "" {
 		const UnsetBucket = "not-set"
 		ociBucket = UnsetBucket
 	}
 
-	const DumpClusterTemplate = "dump-cluster.yaml"
-	generateData := GenerateDumpClusterData{
-		BackupProfileNameVolume: BackupProfileNameVolume,
-		BackupProfileNameOci:    BackupProfileNameOci,
-		BackupVolumeName:        TestBackupVolumeName,
-		BucketName:              ociBucket,
-		BackupDir:               "/tmp/backups",
-		OciStoragePrefix:        OciStoragePrefix,
-		OciCredentials:          OciCredentials,
-	}
-
-	err = unit_dmp.GenerateAndApply(DumpClusterTemplate, generateData)
+	volumeStorage := k8s.NewPersistentVolumeClaimStorage(TestBackupVolumeName)
+	dumpOptions := map[string]interface{}{"excludeSchemas": []interface{}{"excludeme"}}
+	cluster := k8s.NewInnoDBClusterBuilder(ClusterName).
+		Instances(2).
+		SecretName("mypwds").
+		TlsUseSelfSigned().
+		BackupProfile(k8s.NewDumpInstanceProfile(BackupProfileNameVolume, volumeStorage, dumpOptions)).
+		BackupProfile(k8s.NewDumpInstanceProfile(BackupProfileNameOci,
+			k8s.NewOciObjectStorage(OciStoragePrefix, ociBucket, OciCredentials), nil)).
+		BackupProfile(k8s.NewSnapshotProfile("snapshot", volumeStorage))
+	err = unit_dmp.CreateInnoDBCluster(cluster)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -93,7 +99,7 @@ func Create(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_dmp.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_dmp.Context(), unit_dmp.Client, unit_dmp.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -111,6 +117,10 @@ func Create(t *testing.T) {
 
 	// create a test volume to store backups
 	const DumpVolumeTemplate = "dump-volume.yaml"
+	generateData := GenerateDumpVolumeData{
+		BackupVolumeName: TestBackupVolumeName,
+		BackupDir:        "/tmp/backups",
+	}
 	err = unit_dmp.GenerateAndApply(DumpVolumeTemplate, generateData)
 	if err != nil {
 		t.Fatal(err)
@@ -139,22 +149,22 @@ func BackupToVolume(t *testing.T) {
 		ok, mbk, err = suite.CheckMySQLBackup(unit_dmp.Client, unit_dmp.Namespace, generateData.BackupName)
 		return ok, err
 	}
-	_, err = unit_dmp.Wait(checker, 300, 2)
+	_, err = unit_dmp.Wait(checker, 300*time.Second, 2*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	specClusterName := mbk.GetString("spec", "clusterName")
+	specClusterName := mbk.Spec.ClusterName
 	if specClusterName != ClusterName {
 		t.Fatalf("expected cluster name is %s but got %s", ClusterName, specClusterName)
 	}
 
-	mbkStatus := mbk.GetStatus()
+	mbkStatus := mbk.Status.Status
 	if mbkStatus != k8s.MBKStatusCompleted {
 		t.Fatalf("expected mbk status is %s but got %s", k8s.MBKStatusCompleted, mbkStatus)
 	}
 
-	mbkStatusOutput := mbk.GetString("status", "output")
+	mbkStatusOutput := mbk.Status.Output
 	expectedMbkPrefix := generateData.BackupName + "-"
 	if !strings.HasPrefix(mbkStatusOutput, expectedMbkPrefix) {
 		t.Fatalf("mbk output name should begin with %s but it doesn't (%s)", expectedMbkPrefix, mbkStatusOutput)
@@ -166,36 +176,82 @@ func BackupToVolume(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !mbk.HasField("status", "startTime") {
-		t.Fatal("mbk hasn't got status.startTime field")
+	mbkStartTime, err := mbk.Status.GetStartTime()
+	if err != nil {
+		t.Fatalf("mbk hasn't got a correct status.startTime field: %s", err)
 	}
-	if !mbk.HasField("status", "completionTime") {
-		t.Fatal("mbk hasn't got status.completionTime field")
+	mbkCompletionTime, err := mbk.Status.GetCompletionTime()
+	if err != nil {
+		t.Fatalf("mbk hasn't got a correct status.completionTime field: %s", err)
 	}
 
-	mbkCompletionTime := mbk.GetTimeStamp("status", "completionTime")
-	mbkStartTime := mbk.GetTimeStamp("status", "startTime")
-	if mbkCompletionTime < mbkStartTime {
-		t.Fatalf("mbk %s completion time (%d) should be greater than or equal to the start time (%d)", mbk.GetName(), mbkCompletionTime, mbkStartTime)
+	if mbkCompletionTime.Before(mbkStartTime) {
+		t.Fatalf("mbk %s completion time (%s) should be greater than or equal to the start time (%s)", mbk.GetName(), mbkCompletionTime, mbkStartTime)
 	}
 
-	if !mbk.HasField("status", "elapsedTime") {
+	if len(mbk.Status.ElapsedTime) == 0 {
 		t.Fatal("mbk hasn't got status.elapsedTime field")
 	}
 
-	if !mbk.HasField("status", "spaceAvailable") || mbk.GetString("status", "spaceAvailable") == "" {
-		t.Fatalf("mbk %s should have status.spaceAvailable but got '%s'", mbk.GetName(), mbk.GetString("status", "spaceAvailable"))
+	if mbk.Status.SpaceAvailable == "" {
+		t.Fatalf("mbk %s should have status.spaceAvailable but got '%s'", mbk.GetName(), mbk.Status.SpaceAvailable)
 	}
 
-	if !mbk.HasField("status", "size") || mbk.GetString("status", "size") == "" {
-		t.Fatalf("mbk %s should have status.size but got '%s'", mbk.GetName(), mbk.GetString("status", "size"))
+	if mbk.Status.Size == "" {
+		t.Fatalf("mbk %s should have status.size but got '%s'", mbk.GetName(), mbk.Status.Size)
 	}
 
-	mbkMethod := mbk.GetString("status", "method")
+	mbkMethod := mbk.Status.Method
 	expectedMbkMethod := "dump-instance/volume"
 	if mbkMethod != expectedMbkMethod {
 		t.Fatalf("expected mbk method is %s but got %s", expectedMbkMethod, mbkMethod)
 	}
+
+	checkDumpOnVolume(t, mbk)
+}
+
+// the volume is a hostPath one, so it is read on the node the backup job ran on
+func checkDumpOnVolume(t *testing.T, mbk *k8s.MySQLBackup) {
+	nodeName, err := unit_dmp.GetNodeOfPod(mbk.GetName() + "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := unit_dmp.StartVolumeReader(TestBackupVolumeName, nodeName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := reader.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	podSession, err := mysql.NewSession(unit_dmp.Context(), unit_dmp.Client, unit_dmp.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := podSession.FetchAll("show full tables in sakila where table_type='BASE TABLE'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := suite.DumpExpectations{
+		Schemas:         map[string][]string{"sakila": tables.ToStringsSlice(0)},
+		ExcludedSchemas: []string{"excludeme"},
+		MinChunks:       1,
+	}
+	metadata, err := suite.VerifyDump(unit_dmp.Context(), reader, mbk.Status.Output, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.Done.DataBytes <= 0 {
+		t.Fatalf("dump %s should have some data but %s lists %d bytes", mbk.Status.Output, objstore.DumpDoneFile, metadata.Done.DataBytes)
+	}
+	if metadata.GetChunksSize() <= 0 {
+		t.Fatalf("data chunks of the dump %s are empty", mbk.Status.Output)
+	}
 }
 
 func BackupToOciBucket(t *testing.T) {
@@ -204,7 +260,7 @@ func BackupToOciBucket(t *testing.T) {
 		t.Skip(err)
 	}
 
-	err = unit_dmp.Client.CreateApikeySecret(unit_dmp.Namespace, OciCredentials, unit_dmp.Cfg.Oci.ConfigPath, common.OciProfileBackup)
+	err = unit_dmp.Client.CreateApikeySecret(unit_dmp.Namespace, OciCredentials, ociStorage.ConfigPath, common.OciProfileBackup)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -225,22 +281,22 @@ func BackupToOciBucket(t *testing.T) {
 		ok, mbk, err = suite.CheckMySQLBackup(unit_dmp.Client, unit_dmp.Namespace, generateData.BackupName)
 		return ok, err
 	}
-	_, err = unit_dmp.Wait(checker, 300, 2)
+	_, err = unit_dmp.Wait(checker, 300*time.Second, 2*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	specClusterName := mbk.GetString("spec", "clusterName")
+	specClusterName := mbk.Spec.ClusterName
 	if specClusterName != ClusterName {
 		t.Fatalf("expected cluster name is %s but got %s", ClusterName, specClusterName)
 	}
 
-	mbkStatus := mbk.GetStatus()
+	mbkStatus := mbk.Status.Status
 	if mbkStatus != k8s.MBKStatusCompleted {
 		t.Fatalf("expected mbk status is %s but got %s", k8s.MBKStatusCompleted, mbkStatus)
 	}
 
-	mbkStatusOutput := mbk.GetString("status", "output")
+	mbkStatusOutput := mbk.Status.Output
 	expectedMbkPrefix := generateData.BackupName + "-"
 	if !strings.HasPrefix(mbkStatusOutput, expectedMbkPrefix) {
 		t.Fatalf("mbk output name should begin with %s but it doesn't (%s)", expectedMbkPrefix, mbkStatusOutput)
@@ -250,45 +306,54 @@ func BackupToOciBucket(t *testing.T) {
 		ociStorageOutput = filepath.Join(OciStoragePrefix, mbkStatusOutput)
 	}
 
+	// check what the backup actually wrote into the bucket
+	store, err := ociStorage.GetObjectStore(common.OciProfileRestore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := suite.CheckDumpInStore(unit_dmp.Context(), store, ociStorageOutput); err != nil {
+		t.Fatal(err)
+	}
+
 	// check status in backup object
 	mbk, err = unit_dmp.Client.GetMySQLBackup(unit_dmp.Namespace, generateData.BackupName)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if !mbk.HasField("status", "startTime") {
-		t.Fatal("mbk hasn't got status.startTime field")
+	mbkStartTime, err := mbk.Status.GetStartTime()
+	if err != nil {
+		t.Fatalf("mbk hasn't got a correct status.startTime field: %s", err)
 	}
-	if !mbk.HasField("status", "completionTime") {
-		t.Fatal("mbk hasn't got status.completionTime field")
+	mbkCompletionTime, err := mbk.Status.GetCompletionTime()
+	if err != nil {
+		t.Fatalf("mbk hasn't got a correct status.completionTime field: %s", err)
 	}
-	mbkCompletionTime := mbk.GetTimeStamp("status", "completionTime")
-	mbkStartTime := mbk.GetTimeStamp("status", "startTime")
-	if mbkCompletionTime < mbkStartTime {
-		t.Fatalf("mbk %s completion time (%d) should be greater than or equal to the start time (%d)", mbk.GetName(), mbkCompletionTime, mbkStartTime)
+	if mbkCompletionTime.Before(mbkStartTime) {
+		t.Fatalf("mbk %s completion time (%s) should be greater than or equal to the start time (%s)", mbk.GetName(), mbkCompletionTime, mbkStartTime)
 	}
 
-	if !mbk.HasField("status", "elapsedTime") {
+	if len(mbk.Status.ElapsedTime) == 0 {
 		t.Fatal("mbk hasn't got status.elapsedTime field")
 	}
 
-	mbkMethod := mbk.GetString("status", "method")
+	mbkMethod := mbk.Status.Method
 	expectedMbkMethod := "dump-instance/oci-bucket"
 	if mbkMethod != expectedMbkMethod {
 		t.Fatalf("expected mbk method is %s but got %s", expectedMbkMethod, mbkMethod)
 	}
 
-	mbkBucket := mbk.GetString("status", "bucket")
+	mbkBucket := mbk.Status.Bucket
 	if mbkBucket != ociBucket {
 		t.Fatalf("expected bucket name is %s but got %s", ociBucket, mbkBucket)
 	}
 
-	ociTenancy := mbk.GetString("status", "ociTenancy")
+	ociTenancy := mbk.Status.OciTenancy
 	if !strings.Contains(ociTenancy, "oci") || !strings.Contains(ociTenancy, "tenancy") {
 		t.Fatalf("oci tenancy %s is incorrect", ociTenancy)
 	}
 
-	mbkSource := mbk.GetString("status", "source")
+	mbkSource := mbk.Status.Source
 	if len(mbkSource) == 0 {
 		t.Fatalf("mbk status.source is empty")
 	}
@@ -324,7 +389,7 @@ func Destroy(t *testing.T) {
 		t.Error(err)
 	}
 
-	ociEnabled := unit_dmp.Cfg.Oci.Enable
+	ociEnabled := ociStorage != nil
 	if ociEnabled {
 		if err = unit_dmp.Client.DeleteMySQLBackup(unit_dmp.Namespace, MbkToOci); err != nil {
 			t.Error(err)
@@ -352,9 +417,17 @@ func Destroy(t *testing.T) {
 	}
 
 	if ociEnabled && len(ociStorageOutput) > 0 {
-		err = oci.BulkDelete(unit_dmp.Cfg.Oci.ConfigPath, common.OciProfileDelete, ociBucket, ociStorageOutput)
+		store, err := ociStorage.GetObjectStore(common.OciProfileDelete)
 		if err != nil {
 			t.Error(err)
+		} else if _, err = store.DeletePrefix(unit_dmp.Context(), ociStorageOutput); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if ociEnabled {
+		if err = ociStorage.Release(); err != nil {
+			t.Error(err)
 		}
 	}
 }
@@ -367,10 +440,10 @@ func TestDumpInstance(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("Create=0", Create)
-	t.Run("BackupToVolume=1", BackupToVolume)
-	t.Run("BackupToOciBucket=1", BackupToOciBucket)
-	t.Run("Destroy=9", Destroy)
+	unit_dmp.Run(t, "Create=0", Create)
+	unit_dmp.Run(t, "BackupToVolume=1", BackupToVolume)
+	unit_dmp.Run(t, "BackupToOciBucket=1", BackupToOciBucket)
+	unit_dmp.Run(t, "Destroy=9", Destroy)
 
 	err = unit_dmp.Teardown()
 	if err != nil {
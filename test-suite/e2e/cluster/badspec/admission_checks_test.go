@@ -5,77 +5,89 @@ package badspec_test
 // specification errors verified during admission
 
 import (
-	"strings"
 	"testing"
 
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
 	"github.com/marinesovitch/ote/test-suite/util/suite"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 var unit_ac *suite.Unit
 
-func assertApplyFails(t *testing.T, yamlFilename string, expectedErrMsg string) {
-	msg, err := unit_ac.ApplyGetOutput(yamlFilename)
-	if !strings.Contains(msg, expectedErrMsg) {
-		t.Log(err)
-		t.Fatalf("got: '%s', it doesn't contain: '%s'", msg, expectedErrMsg)
+// objects are created (POST), so the server reports the rejected fields in the causes of its status
+func createRejected(t *testing.T, yamlFilename string) *k8s.AdmissionError {
+	output, err := unit_ac.CreateGetOutput(yamlFilename)
+	if err == nil {
+		t.Fatalf("%s created unexpectedly: %s", yamlFilename, output)
+	}
+	admissionErr, ok := k8s.AsAdmissionError(err)
+	if !ok {
+		t.Fatalf("expected an admission error, got: %s", err)
+	}
+	return admissionErr
+}
+
+// the create must be rejected due to the given field, if causeTypes are given, for one of those reasons
+func assertCreateRejected(t *testing.T, yamlFilename string, field string, causeTypes ...metav1.CauseType) {
+	admissionErr := createRejected(t, yamlFilename)
+
+	cause := admissionErr.GetCause(field)
+	if cause == nil {
+		t.Fatalf("expected %s to be rejected, got causes for %v: %s", field, admissionErr.GetFields(), admissionErr)
 	}
+	t.Logf("%s rejected (%d %s): %s: %s", yamlFilename, admissionErr.Code, cause.Type, cause.Field, cause.Message)
+
+	if len(causeTypes) == 0 {
+		return
+	}
+	for _, causeType := range causeTypes {
+		if cause.Type == causeType {
+			return
+		}
+	}
+	t.Fatalf("expected %s to be rejected as %v, got: %s %s", field, causeTypes, cause.Type, cause.Message)
 }
 
 func InvalidField(t *testing.T) {
-	assertApplyFails(t,
-		"invalid-field.yaml",
-		"ValidationError(InnoDBCluster.spec): unknown field \"bogus\" in com.oracle.mysql.v2.InnoDBCluster.spec")
+	// the strict field validation reports unknown fields only in the message, there are no causes
+	admissionErr := createRejected(t, "invalid-field.yaml")
+	if !admissionErr.HasUnknownField("spec.bogus") {
+		t.Fatalf("expected spec.bogus to be rejected as unknown, got %v: %s", admissionErr.UnknownFields, admissionErr)
+	}
 }
 
 func NameTooLong(t *testing.T) {
-	// a cluster name cannot be longer than allowed in innodb cluster (by default 40 chars)
-	assertApplyFails(t,
-		"name-too-long.yaml",
-		"The InnoDBCluster \"veryveryveryveryveryveryveryverylongnamex\" is invalid: "+
-			"metadata.name: Invalid value: \"veryveryveryveryveryveryveryverylongnamex\": "+
-			"metadata.name in body should be at most 40 chars long")
-	// in newer k8 versions:
-	// "The InnoDBCluster \"veryveryveryveryveryveryveryverylongnamex\" is invalid: "+
-	// 	"metadata.name: Too long: may not be longer than 40")
+	// a cluster name cannot be longer than allowed in innodb cluster (by default 40 chars),
+	// older k8s versions report it as an invalid value, newer ones as too long
+	assertCreateRejected(t, "name-too-long.yaml", "metadata.name",
+		metav1.CauseTypeFieldValueInvalid, metav1.CauseType(field.ErrorTypeTooLong))
 }
 
 func LackOfName(t *testing.T) {
-	// a metadata.name is mandatory (blocked even before the schema validation)
-	assertApplyFails(t, "lack-of-name.yaml", "resource name may not be empty")
+	// a metadata.name (or generateName) is mandatory, blocked even before the schema validation
+	assertCreateRejected(t, "lack-of-name.yaml", "metadata.name", metav1.CauseTypeFieldValueRequired)
 }
 
 func LackOfSpec(t *testing.T) {
-	assertApplyFails(t,
-		"lack-of-spec.yaml",
-		"ValidationError(InnoDBCluster): missing required field \"spec\" in com.oracle.mysql.v2.InnoDBCluste")
+	assertCreateRejected(t, "lack-of-spec.yaml", "spec", metav1.CauseTypeFieldValueRequired)
 }
 
 func LackOfSecret(t *testing.T) {
 	// a spec.secretName is obligatory
-	assertApplyFails(t,
-		"lack-of-secret.yaml",
-		"error validating data: ValidationError(InnoDBCluster.spec): missing required field \"secretName\"")
+	assertCreateRejected(t, "lack-of-secret.yaml", "spec.secretName", metav1.CauseTypeFieldValueRequired)
 }
 
 func WrongInstances(t *testing.T) {
 	// check invalid values for spec.instances (too small, too big, not a number)
-	assertApplyFails(t,
-		"zero-instances.yaml",
-		"spec.instances: Invalid value: 0: spec.instances in body should be greater than or equal to 1")
-
-	assertApplyFails(t,
-		"too-many-instances.yaml",
-		"spec.instances: Invalid value: 14: spec.instances in body should be less than or equal to 9")
-
-	assertApplyFails(t,
-		"wrong-instances.yaml",
-		"ValidationError(InnoDBCluster.spec.instances): invalid type for com.oracle.mysql.v2.InnoDBCluster.spec.instances: got \"string\", expected \"integer\"")
+	assertCreateRejected(t, "zero-instances.yaml", "spec.instances", metav1.CauseTypeFieldValueInvalid)
+	assertCreateRejected(t, "too-many-instances.yaml", "spec.instances", metav1.CauseTypeFieldValueInvalid)
+	assertCreateRejected(t, "wrong-instances.yaml", "spec.instances", metav1.CauseType(field.ErrorTypeTypeInvalid))
 }
 
 func WrongMycnf(t *testing.T) {
-	assertApplyFails(t,
-		"wrong-mycnf.yaml", "spec.mycnf: Invalid value: \"integer\": spec.mycnf in body must be of type string: \"integer\"")
+	assertCreateRejected(t, "wrong-mycnf.yaml", "spec.mycnf", metav1.CauseType(field.ErrorTypeTypeInvalid))
 }
 
 func admissionChecksTeardown(t *testing.T) {
@@ -113,13 +125,13 @@ func TestClusterSpecAdmissionChecks(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("InvalidField=1", InvalidField)
-	t.Run("NameTooLong=1", NameTooLong)
-	t.Run("LackOfName=1", LackOfName)
-	t.Run("LackOfSpec=1", LackOfSpec)
-	t.Run("LackOfSecret=1", LackOfSpec)
-	t.Run("WrongInstances=1", LackOfSpec)
-	t.Run("WrongMycnf=1", LackOfSpec)
+	unit_ac.Run(t, "InvalidField=1", InvalidField)
+	unit_ac.Run(t, "NameTooLong=1", NameTooLong)
+	unit_ac.Run(t, "LackOfName=1", LackOfName)
+	unit_ac.Run(t, "LackOfSpec=1", LackOfSpec)
+	unit_ac.Run(t, "LackOfSecret=1", LackOfSecret)
+	unit_ac.Run(t, "WrongInstances=1", WrongInstances)
+	unit_ac.Run(t, "WrongMycnf=1", WrongMycnf)
 
 	admissionChecksTeardown(t)
 
@@ -4,6 +4,7 @@ package badspec_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/common"
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
@@ -53,20 +54,10 @@ func BadSecretDelete(t *testing.T) {
 	// CreateContainerConfigError because the container is setup to read from
 	// it to set MYSQL_ROOT_PASSWORD, so the operator or sidecars will never
 	// run
-	checker := func(args ...interface{}) (bool, error) {
-		pod, err := unit_rcc.Client.GetPod(unit_rcc.Namespace, PodName)
-		if err != nil {
-			return false, err
-		}
-		return k8s.IsPodInitCreateContainerConfigError(pod)
-	}
-	updateFailed, err := unit_rcc.Wait(checker, 100, 4)
+	err = unit_rcc.WaitOnPodState(PodName, 100*time.Second, k8s.IsPodInitCreateContainerConfigError)
 	if err != nil {
-		t.Fatal(err)
-	}
-	if !updateFailed {
-		t.Fatalf("after update the expected status of pod '%s' is '%s'",
-			PodName, k8s.GetPodStateDescription(k8s.PodInitCreateContainerConfigError))
+		t.Fatalf("after update the expected status of pod '%s' is '%s': %s",
+			PodName, k8s.GetPodStateDescription(k8s.PodInitCreateContainerConfigError), err)
 	}
 
 	err = unit_rcc.Client.DeleteInnoDBCluster(unit_rcc.Namespace, "mycluster")
@@ -102,14 +93,7 @@ func UnsupportedVersionDelete(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	checker := func(args ...interface{}) (bool, error) {
-		icEvents, err := unit_rcc.Client.ListInnoDBClusterEvents(unit_rcc.Namespace, "mycluster", common.AnyResourceVersion)
-		if err != nil {
-			return false, err
-		}
-		return len(icEvents.Items) > 0, nil
-	}
-	_, err = unit_rcc.Wait(checker, 60, 2)
+	err = unit_rcc.WaitOnClusterEvents("mycluster", 60*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -171,14 +155,7 @@ func UnsupportedVersionRecover(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	checker := func(args ...interface{}) (bool, error) {
-		icEvents, err := unit_rcc.Client.ListInnoDBClusterEvents(unit_rcc.Namespace, "mycluster", common.AnyResourceVersion)
-		if err != nil {
-			return false, err
-		}
-		return len(icEvents.Items) > 0, nil
-	}
-	_, err = unit_rcc.Wait(checker, 60, 2)
+	err = unit_rcc.WaitOnClusterEvents("mycluster", 60*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -279,14 +256,7 @@ func BadPodDelete(t *testing.T) {
 	}
 
 	const PodName = "mycluster-0"
-	checkPodError := func(args ...interface{}) (bool, error) {
-		pod, err := unit_rcc.Client.GetPod(unit_rcc.Namespace, PodName)
-		if err != nil {
-			return false, err
-		}
-		return k8s.IsPodInitImagePullIssueError(pod)
-	}
-	_, err = unit_rcc.Wait(checkPodError, 60, 2)
+	err = unit_rcc.WaitOnPodState(PodName, 60*time.Second, k8s.IsPodInitImagePullIssueError)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -356,20 +326,10 @@ func BadPodRecover(t *testing.T) {
 	}
 
 	const PodName = "mycluster-0"
-	checkPodError := func(args ...interface{}) (bool, error) {
-		pod, err := unit_rcc.Client.GetPod(unit_rcc.Namespace, PodName)
-		if err != nil {
-			return false, err
-		}
-		return k8s.IsPodInitImagePullIssueError(pod)
-	}
-	updateFailed, err := unit_rcc.Wait(checkPodError, 60, 2)
+	err = unit_rcc.WaitOnPodState(PodName, 60*time.Second, k8s.IsPodInitImagePullIssueError)
 	if err != nil {
-		t.Fatal(err)
-	}
-	if !updateFailed {
-		t.Fatalf("after update the expected status of pod '%s' is '%s'",
-			PodName, k8s.GetPodStateDescription(k8s.PodInitImagePullIssueError))
+		t.Fatalf("after update the expected status of pod '%s' is '%s': %s",
+			PodName, k8s.GetPodStateDescription(k8s.PodInitImagePullIssueError), err)
 	}
 
 	// fixing the imageRepository should let the cluster resume creation
@@ -451,12 +411,12 @@ func TestClusterSpecRuntimeChecksCreation(t *testing.T) {
 	}
 
 	SetupClusterSpecRuntimeChecksCreation(t)
-	t.Run("BadSecretDelete", BadSecretDelete)
-	t.Run("BadSecretRecover", BadSecretRecover)
-	t.Run("UnsupportedVersionDelete", UnsupportedVersionDelete)
-	t.Run("UnsupportedVersionRecover", UnsupportedVersionRecover)
-	t.Run("BadPodDelete", BadPodDelete)
-	t.Run("BadPodRecover", BadPodRecover)
+	unit_rcc.Run(t, "BadSecretDelete", BadSecretDelete)
+	unit_rcc.Run(t, "BadSecretRecover", BadSecretRecover)
+	unit_rcc.Run(t, "UnsupportedVersionDelete", UnsupportedVersionDelete)
+	unit_rcc.Run(t, "UnsupportedVersionRecover", UnsupportedVersionRecover)
+	unit_rcc.Run(t, "BadPodDelete", BadPodDelete)
+	unit_rcc.Run(t, "BadPodRecover", BadPodRecover)
 	TeardownClusterSpecRuntimeChecksCreation(t)
 
 	err = unit_rcc.Teardown()
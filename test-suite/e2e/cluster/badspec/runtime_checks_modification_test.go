@@ -147,9 +147,9 @@ func TestClusterSpecRuntimeChecksModification(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("SetupBadUpgrade", SetupBadUpgrade)
-	t.Run("BadUpgrade", BadUpgrade)
-	t.Run("TeardownBadUpgrade", TeardownBadUpgrade)
+	unit_rcm.Run(t, "SetupBadUpgrade", SetupBadUpgrade)
+	unit_rcm.Run(t, "BadUpgrade", BadUpgrade)
+	unit_rcm.Run(t, "TeardownBadUpgrade", TeardownBadUpgrade)
 
 	err = unit_rcm.Teardown()
 	if err != nil {
@@ -6,9 +6,8 @@ package config_test
 
 import (
 	"encoding/json"
-	"fmt"
-	"strings"
 	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/auxi"
 	"github.com/marinesovitch/ote/test-suite/util/common"
@@ -29,7 +28,13 @@ func CreateClusterOneInstance(t *testing.T) {
 	}
 
 	// create cluster with mostly default configs
-	err = unit_c1d.Apply("cluster1-defaults.yaml")
+	cluster := k8s.NewInnoDBClusterBuilder("mycluster").
+		Instances(1).
+		Routers(0).
+		SecretName("mypwds").
+		Edition("community").
+		TlsUseSelfSigned()
+	err = unit_c1d.CreateInnoDBCluster(cluster)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -88,7 +93,7 @@ func CreateClusterOneInstance(t *testing.T) {
 
 func CheckAccounts1(t *testing.T) {
 	accounts, err := suite.QuerySet(
-		unit_c1d.Namespace, "mycluster-0", "root", "sakila",
+		unit_c1d.Context(), unit_c1d.Client, unit_c1d.Namespace, "mycluster-0", "root", "sakila",
 		"SELECT concat(user,'@',host) FROM mysql.user", 0)
 	if err != nil {
 		t.Fatal(err)
@@ -178,29 +183,8 @@ func AddRouters(t *testing.T) {
 		t.Error(err)
 	}
 
-	routersReady := func(args ...interface{}) (bool, error) {
-		pods, err := unit_c1d.Client.ListPods(unit_c1d.Namespace)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		const expectedRoutersNum = 3
-		routersCounter := 0
-		for _, pod := range pods.Items {
-			podName := pod.GetName()
-			if strings.HasPrefix(podName, "mycluster-router-") && pod.Status.Phase == corev1.PodRunning {
-				routersCounter++
-			}
-		}
-
-		if routersCounter != expectedRoutersNum {
-			return false, nil
-		}
-
-		return true, nil
-	}
-
-	if _, err := unit_c1d.Wait(routersReady, 240, 3); err != nil {
+	err = unit_c1d.WaitOnRouters("mycluster", 3)
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -351,7 +335,7 @@ func RecoverCrash(t *testing.T) {
 		Name:                 "mycluster",
 		ExpectedStatus:       []string{"ONLINE"},
 		ExpectedNumOnline:    1,
-		Timeout:              600,
+		Timeout:              600 * time.Second,
 		SinceResourceVersion: sinceResourceVersion,
 	}
 	err = unit_c1d.WaitOnInnoDBCluster(waitParams)
@@ -454,25 +438,15 @@ func RecoverSidecarCrash(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	checkIsReady := func(args ...interface{}) (bool, error) {
-		t := args[0].(testing.T)
-		pod, err := unit_c1d.Client.GetPod(unit_c1d.Namespace, "mycluster-0")
-		if err != nil {
-			t.Fatal(err)
-		}
+	sidecarRestarted := func(pod *corev1.Pod) (bool, error) {
 		podSidecarCont, err := k8s.GetContainerStatus(pod, k8s.Sidecar)
 		if err != nil {
-			t.Fatal(err)
+			return false, err
 		}
-		currentRestartCount := podSidecarCont.RestartCount
-		previousRestartCount := sidecarCont.RestartCount + 1
-		if podSidecarCont.RestartCount != previousRestartCount {
-			return false, fmt.Errorf("RestartCount current %d, previous %d, expected equal", currentRestartCount, previousRestartCount)
-		}
-		return true, nil
+		return podSidecarCont.RestartCount == sidecarCont.RestartCount+1, nil
 	}
-
-	if _, err := unit_c1d.Wait(checkIsReady, 60, 2, t); err != nil {
+	err = unit_c1d.WaitOnPodState("mycluster-0", 60*time.Second, sidecarRestarted)
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -536,7 +510,7 @@ func RecoverRestart(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_c1d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_c1d.Context(), unit_c1d.Client, unit_c1d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -633,7 +607,7 @@ func RecoverShutdown(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_c1d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_c1d.Context(), unit_c1d.Client, unit_c1d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -774,7 +748,7 @@ func RecoverDelete(t *testing.T) {
 
 func RecoverStop(t *testing.T) {
 	t.Skip("todo")
-	podSessions0, err := mysql.NewSession(unit_c1d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	podSessions0, err := mysql.NewSession(unit_c1d.Context(), unit_c1d.Client, unit_c1d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -844,20 +818,20 @@ func TestCluster1Defaults(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("CreateClusterOneInstance=0", CreateClusterOneInstance)
-	t.Run("CheckAccounts1=1", CheckAccounts1)
-	// t.Run("BadChanges=2", BadChanges)
-	t.Run("GrowTwoInstances=2", GrowTwoInstances)
-	t.Run("AddRouters=2", AddRouters)
-	t.Run("GrowThreeInstances=2", GrowThreeInstances)
-	t.Run("ShrinkToOneInstance=2", ShrinkToOneInstance)
-	t.Run("RecoverCrash=3", RecoverCrash)
-	//t.Run("RecoverSidecarCrash=3", RecoverSidecarCrash)
-	t.Run("RecoverRestart=3", RecoverRestart)
-	t.Run("RecoverShutdown=3", RecoverShutdown)
-	t.Run("RecoverDelete=3", RecoverDelete)
-	// t.Run("RecoverStop=3", RecoverStop)
-	t.Run("AfterCluster1Defaults=9", AfterCluster1Defaults)
+	unit_c1d.Run(t, "CreateClusterOneInstance=0", CreateClusterOneInstance)
+	unit_c1d.Run(t, "CheckAccounts1=1", CheckAccounts1)
+	// unit_c1d.Run(t, "BadChanges=2", BadChanges)
+	unit_c1d.Run(t, "GrowTwoInstances=2", GrowTwoInstances)
+	unit_c1d.Run(t, "AddRouters=2", AddRouters)
+	unit_c1d.Run(t, "GrowThreeInstances=2", GrowThreeInstances)
+	unit_c1d.Run(t, "ShrinkToOneInstance=2", ShrinkToOneInstance)
+	unit_c1d.Run(t, "RecoverCrash=3", RecoverCrash)
+	//unit_c1d.Run(t, "RecoverSidecarCrash=3", RecoverSidecarCrash)
+	unit_c1d.Run(t, "RecoverRestart=3", RecoverRestart)
+	unit_c1d.Run(t, "RecoverShutdown=3", RecoverShutdown)
+	unit_c1d.Run(t, "RecoverDelete=3", RecoverDelete)
+	// unit_c1d.Run(t, "RecoverStop=3", RecoverStop)
+	unit_c1d.Run(t, "AfterCluster1Defaults=9", AfterCluster1Defaults)
 
 	err = unit_c1d.Teardown()
 	if err != nil {
@@ -8,11 +8,12 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/auxi"
+	"github.com/marinesovitch/ote/test-suite/util/chaos"
 	"github.com/marinesovitch/ote/test-suite/util/common"
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
-	"github.com/marinesovitch/ote/test-suite/util/mysql"
 	"github.com/marinesovitch/ote/test-suite/util/suite"
 
 	corev1 "k8s.io/api/core/v1"
@@ -28,7 +29,12 @@ func CreateClusterThreeInstances(t *testing.T) {
 	}
 
 	// create cluster with mostly default configs
-	err = unit_c3d.Apply("cluster3-defaults.yaml")
+	cluster := k8s.NewInnoDBClusterBuilder("mycluster").
+		Instances(3).
+		Routers(2).
+		SecretName("mypwds").
+		TlsUseSelfSigned()
+	err = unit_c3d.CreateInnoDBCluster(cluster)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -190,7 +196,7 @@ func CheckVersion3(t *testing.T) {
 
 func checkClusterAccounts3(t *testing.T, clusterName string) {
 	accounts, err := suite.QuerySet(
-		unit_c3d.Namespace, clusterName, "root", "sakila",
+		unit_c3d.Context(), unit_c3d.Client, unit_c3d.Namespace, clusterName, "root", "sakila",
 		"SELECT concat(user,'@',host) FROM mysql.user", 0)
 	if err != nil {
 		t.Fatal(err)
@@ -240,7 +246,12 @@ func CheckRouting(t *testing.T) {
 		t.Error(err)
 	}
 
-	// TODO: add interactive session to connect all pods under various ports
+	// RW traffic should land on the primary, RO traffic should be spread over secondaries
+	const Connections = 20
+	err = suite.CheckRouting(unit_c3d.Context(), unit_c3d.Client, unit_c3d.Namespace, "mycluster", common.RootUser, common.RootPassword, Connections)
+	if err != nil {
+		t.Error(err)
+	}
 
 	err = unit_c3d.Client.DeletePod(appNamespace, "testpod")
 	if err != nil {
@@ -259,38 +270,19 @@ func RecoverCrash1of3(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// kill mysqld (pid 1)
-	err = unit_c3d.Client.Kill(unit_c3d.Namespace, "mycluster-0", k8s.Mysql, 11, 1)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to notice it gone
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:                 "mycluster",
-		ExpectedStatus:       []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"},
-		ExpectedNumOnline:    2,
-		SinceResourceVersion: sinceResourceVersion,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sinceResourceVersion, err = unit_c3d.GetInnoDBClusterResourceVersion("mycluster")
-	if err != nil {
-		t.Fatal(err)
-	}
+	// crash mysqld, the operator has to notice it gone, then k8s restarts it
+	namespace := unit_c3d.Namespace
+	agents := unit_c3d.GetChaosAgents()
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewKill(agents, namespace, "mycluster-0", k8s.Mysql, chaos.SIGSEGV)).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"}, 2)).
+		Expect(0, unit_c3d.ExpectContainerRestart(namespace, "mycluster-0", k8s.Mysql))
 
-	// wait for operator to restore it
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:                 "mycluster",
-		ExpectedStatus:       []string{"ONLINE"},
-		ExpectedNumOnline:    3,
-		SinceResourceVersion: sinceResourceVersion,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
+	params := unit_c3d.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Primary = suite.NoPrimary
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 
@@ -304,15 +296,6 @@ func RecoverCrash1of3(t *testing.T) {
 		"StatusChange", "Cluster status changed to ONLINE. 3 member\\(s\\) ONLINE"); err != nil {
 		t.Fatal(err)
 	}
-
-	params := unit_c3d.GetDefaultCheckParams()
-	params.Name = "mycluster"
-	params.Instances = 3
-	params.Primary = suite.NoPrimary
-	params.RestartsExpected = true
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
-		t.Fatal(err)
-	}
 }
 
 func RecoverCrash2of3(t *testing.T) {
@@ -321,36 +304,21 @@ func RecoverCrash2of3(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// kill mysqld (pid 1)
-	err = unit_c3d.Client.Kill(unit_c3d.Namespace, "mycluster-1", k8s.Mysql, 11, 1)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = unit_c3d.Client.Kill(unit_c3d.Namespace, "mycluster-0", k8s.Mysql, 11, 1)
-	if err != nil {
-		t.Fatal(err)
-	}
+	// crash mysqld of two instances, so the quorum is lost
+	namespace := unit_c3d.Namespace
+	agents := unit_c3d.GetChaosAgents()
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewKill(agents, namespace, "mycluster-1", k8s.Mysql, chaos.SIGSEGV)).
+		Add(0, 0, chaos.NewKill(agents, namespace, "mycluster-0", k8s.Mysql, chaos.SIGSEGV)).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"NO_QUORUM"}, -1)).
+		Expect(0, unit_c3d.ExpectContainerRestart(namespace, "mycluster-1", k8s.Mysql)).
+		Expect(0, unit_c3d.ExpectContainerRestart(namespace, "mycluster-0", k8s.Mysql))
 
-	// wait for operator to notice them gone
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"NO_QUORUM"},
-		ExpectedNumOnline: -1,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore it
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
+	params := unit_c3d.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Primary = 2
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 
@@ -383,110 +351,45 @@ func RecoverCrash2of3(t *testing.T) {
 		"StatusChange", "Cluster status changed to ONLINE. 3 member\\(s\\) ONLINE"); err != nil {
 		t.Fatal(err)
 	}
-
-	params := unit_c3d.GetDefaultCheckParams()
-	params.Name = "mycluster"
-	params.Instances = 3
-	params.Primary = 2
-	params.RestartsExpected = true
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
-		t.Fatal(err)
-	}
 }
 
 func RecoverCrash3of3(t *testing.T) {
-	sinceResourceVersion, err := unit_c3d.GetInnoDBClusterResourceVersion("mycluster")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// kill mysqld (pid 1)
-	if err := unit_c3d.Client.Kill(unit_c3d.Namespace, "mycluster-2", k8s.Mysql, 11, 1); err != nil {
-		t.Fatal(err)
-	}
-	if err := unit_c3d.Client.Kill(unit_c3d.Namespace, "mycluster-1", k8s.Mysql, 11, 1); err != nil {
-		t.Fatal(err)
-	}
-	if err := unit_c3d.Client.Kill(unit_c3d.Namespace, "mycluster-0", k8s.Mysql, 11, 1); err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to notice them gone
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:                 "mycluster",
-		ExpectedStatus:       []string{"OFFLINE"},
-		ExpectedNumOnline:    0,
-		SinceResourceVersion: sinceResourceVersion,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sinceResourceVersion, err = unit_c3d.GetInnoDBClusterResourceVersion("mycluster")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore it
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:                 "mycluster",
-		ExpectedStatus:       []string{"ONLINE"},
-		ExpectedNumOnline:    3,
-		SinceResourceVersion: sinceResourceVersion,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err = unit_c3d.WaitOnRouters("mycluster", 2); err != nil {
-		t.Fatal(err)
-	}
+	// crash mysqld of all instances
+	namespace := unit_c3d.Namespace
+	agents := unit_c3d.GetChaosAgents()
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewKill(agents, namespace, "mycluster-2", k8s.Mysql, chaos.SIGSEGV)).
+		Add(0, 0, chaos.NewKill(agents, namespace, "mycluster-1", k8s.Mysql, chaos.SIGSEGV)).
+		Add(0, 0, chaos.NewKill(agents, namespace, "mycluster-0", k8s.Mysql, chaos.SIGSEGV)).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"OFFLINE"}, 0))
 
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Routers = 2
 	params.Primary = suite.NoPrimary
-	params.RestartsExpected = true
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func RecoverDelete1of3(t *testing.T) {
 	// delete the PRIMARY
-	err := unit_c3d.Client.DeletePod(unit_c3d.Namespace, "mycluster-0")
-	if err != nil {
-		t.Error(err)
-	}
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewDeletePod(unit_c3d.Client, namespace, "mycluster-0")).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"}, 2))
 
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"},
-		ExpectedNumOnline: 2,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
+	params := unit_c3d.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Routers = 2
+	params.Primary = suite.NoPrimary
+	all_pods, err := unit_c3d.RunChaos(schedule, params)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if err = unit_c3d.WaitOnRouters("mycluster", 2); err != nil {
-		t.Fatal(err)
-	}
-
 	pod0, err := unit_c3d.Client.GetPod(unit_c3d.Namespace, "mycluster-0")
 	if err != nil {
 		t.Fatal(err)
@@ -498,16 +401,6 @@ func RecoverDelete1of3(t *testing.T) {
 		t.Fatalf("pod0 expected restart count is 0 but got %d", pod0RestartCount)
 	}
 
-	params := unit_c3d.GetDefaultCheckParams()
-	params.Name = "mycluster"
-	params.Instances = 3
-	params.Routers = 2
-	params.Primary = suite.NoPrimary
-	params.RestartsExpected = true
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
-		t.Fatal(err)
-	}
-
 	err = unit_c3d.Client.Execute(unit_c3d.Namespace, "mycluster-0", k8s.Sidecar,
 		"mysqlsh", "root:sakila@localhost", "--",
 		"cluster", "set-primary-instance",
@@ -517,22 +410,12 @@ func RecoverDelete1of3(t *testing.T) {
 	}
 
 	if err := suite.CrossSyncGtids(
-		unit_c3d.Namespace, []string{"mycluster-0", "mycluster-1", "mycluster-2"},
+		unit_c3d.Context(), unit_c3d.Client, unit_c3d.Namespace, []string{"mycluster-0", "mycluster-1", "mycluster-2"},
 		"root", "sakila"); err != nil {
 		t.Fatal(err)
 	}
 
-	params = unit_c3d.GetDefaultCheckParams()
-	params.Name = "mycluster"
-	params.Instances = 3
-	params.Primary = 0
-	params.RestartsExpected = true
-	all_pods, err := suite.CheckAll(unit_c3d, params)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err := suite.CheckData(all_pods, common.RootUser, common.RootPassword, 0); err != nil {
+	if err := suite.CheckData(unit_c3d.Context(), unit_c3d.Client, all_pods, common.RootUser, common.RootPassword, 0); err != nil {
 		t.Fatal(err)
 	}
 }
@@ -555,65 +438,18 @@ func RecoverDelete2of3(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	pod0ResourceVersion := pod0.GetResourceVersion()
-	err = unit_c3d.Client.DeletePodWithTimeout(unit_c3d.Namespace, "mycluster-0", 200)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// extra timeout because the deletion of the 2nd pod will be blocked by
-	// the busy handlers from the 1st deletion
-	pod1ResourceVersion := pod1.GetResourceVersion()
-	err = unit_c3d.Client.DeletePodWithTimeout(unit_c3d.Namespace, "mycluster-1", 200)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = unit_c3d.WaitOnPodSince("mycluster-0", pod0ResourceVersion, corev1.PodPending)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = unit_c3d.WaitOnPodSince("mycluster-1", pod1ResourceVersion, corev1.PodPending)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore everything
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:                 "mycluster",
-		ExpectedStatus:       []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"},
-		ExpectedNumOnline:    -1,
-		SinceResourceVersion: sinceResourceVersion,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewDeletePod(unit_c3d.Client, namespace, "mycluster-0")).
+		Add(0, 0, chaos.NewDeletePod(unit_c3d.Client, namespace, "mycluster-1")).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"}, -1))
 
-	sinceResourceVersion, err = unit_c3d.GetInnoDBClusterResourceVersion("mycluster")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = unit_c3d.WaitOnPod("mycluster-0", corev1.PodRunning)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = unit_c3d.WaitOnPod("mycluster-1", corev1.PodRunning)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:                 "mycluster",
-		ExpectedStatus:       []string{"ONLINE"},
-		ExpectedNumOnline:    3,
-		SinceResourceVersion: sinceResourceVersion,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
+	params := unit_c3d.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Routers = suite.NoRouters
+	params.Primary = 2
+	all_pods, err := unit_c3d.RunChaos(schedule, params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -674,33 +510,22 @@ func RecoverDelete2of3(t *testing.T) {
 	}
 
 	if err := suite.CrossSyncGtids(
-		unit_c3d.Namespace, []string{"mycluster-2", "mycluster-0", "mycluster-1"},
+		unit_c3d.Context(), unit_c3d.Client, unit_c3d.Namespace, []string{"mycluster-2", "mycluster-0", "mycluster-1"},
 		"root", "sakila"); err != nil {
 		t.Error(err)
 	}
 	if err := suite.CrossSyncGtids(
-		unit_c3d.Namespace, []string{"mycluster-1", "mycluster-2", "mycluster-0"},
+		unit_c3d.Context(), unit_c3d.Client, unit_c3d.Namespace, []string{"mycluster-1", "mycluster-2", "mycluster-0"},
 		"root", "sakila"); err != nil {
 		t.Error(err)
 	}
 	if err := suite.CrossSyncGtids(
-		unit_c3d.Namespace, []string{"mycluster-0", "mycluster-2", "mycluster-1"},
+		unit_c3d.Context(), unit_c3d.Client, unit_c3d.Namespace, []string{"mycluster-0", "mycluster-2", "mycluster-1"},
 		"root", "sakila"); err != nil {
 		t.Error(err)
 	}
 
-	params := unit_c3d.GetDefaultCheckParams()
-	params.Name = "mycluster"
-	params.Instances = 3
-	params.Routers = suite.NoRouters
-	params.Primary = 2
-	params.RestartsExpected = true
-	all_pods, err := suite.CheckAll(unit_c3d, params)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err := suite.CheckData(all_pods, common.RootUser, common.RootPassword, params.Primary); err != nil {
+	if err := suite.CheckData(unit_c3d.Context(), unit_c3d.Client, all_pods, common.RootUser, common.RootPassword, params.Primary); err != nil {
 		t.Fatal(err)
 	}
 
@@ -713,38 +538,36 @@ func RecoverDelete2of3(t *testing.T) {
 }
 
 func RecoverDeleteAndWipe1of3(t *testing.T) {
-	// delete the pv and pvc first, which will block because until the pod
-	// is deleted
-
-	// delete a secondary
-	err := unit_c3d.Client.DeletePod(unit_c3d.Namespace, "mycluster-1")
+	const ClaimName = "datadir-mycluster-1"
+	claim, err := unit_c3d.Client.GetPersistentVolumeClaim(unit_c3d.Namespace, ClaimName)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"},
-		ExpectedNumOnline: 2,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
+	// delete the pvc of a secondary first, it stays terminating until the pod is deleted,
+	// which happens on revert, then the instance starts with an empty datadir
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 5*time.Second, chaos.NewDeletePvc(unit_c3d.Client, namespace, ClaimName, "mycluster-1")).
+		Expect(5*time.Second, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"}, 2))
+
+	params := unit_c3d.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Routers = 2
+	params.Primary = 0
+	all_pods, err := unit_c3d.RunChaos(schedule, params)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
+	// make sure the datadir was actually wiped
+	newClaim, err := unit_c3d.Client.GetPersistentVolumeClaim(unit_c3d.Namespace, ClaimName)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if err = unit_c3d.WaitOnRouters("mycluster", 2); err != nil {
-		t.Fatal(err)
+	if newClaim.GetUID() == claim.GetUID() {
+		t.Fatalf("pvc %s should be recreated but it is still the same (uid %s)", ClaimName, claim.GetUID())
 	}
 
 	pod1, err := unit_c3d.Client.GetPod(unit_c3d.Namespace, "mycluster-1")
@@ -758,358 +581,175 @@ func RecoverDeleteAndWipe1of3(t *testing.T) {
 		t.Fatalf("pod1 expected restart count is 0 but got %d", pod1RestartCount)
 	}
 
+	if err := suite.CheckData(unit_c3d.Context(), unit_c3d.Client, all_pods, common.RootUser, common.RootPassword, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func RecoverPause1of3(t *testing.T) {
+	// freeze mysqld of one instance, then kill the sidecar of another one while the former
+	// is still frozen
+	namespace := unit_c3d.Namespace
+	agents := unit_c3d.GetChaosAgents()
+	schedule := chaos.NewSchedule().
+		Add(0, 60*time.Second, chaos.NewPause(agents, namespace, "mycluster-1", k8s.Mysql)).
+		Expect(0, unit_c3d.ExpectMemberState(namespace, "mycluster-0", "mycluster-1", "UNREACHABLE", suite.MemberMissing)).
+		Add(20*time.Second, 0, chaos.NewKill(agents, namespace, "mycluster-2", k8s.Sidecar, chaos.SIGKILL)).
+		Expect(20*time.Second, unit_c3d.ExpectContainerRestart(namespace, "mycluster-2", k8s.Sidecar))
+
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Routers = 2
-	params.Primary = 0
-	params.RestartsExpected = true
-	all_pods, err := suite.CheckAll(unit_c3d, params)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err := suite.CheckData(all_pods, common.RootUser, common.RootPassword, 0); err != nil {
+	params.Primary = suite.NoPrimary
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func RecoverStop1of3(t *testing.T) {
-	// Manually stop GR in 1 instance out of 3.
-	t.Skip("TODO decide what to do, leave alone or restore?")
-	podSessions0, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-1", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer podSessions0.Close()
-	if _, err := podSessions0.Exec("stop group_replication"); err != nil {
-		t.Fatal(err)
-	}
+func RecoverLatency1of3(t *testing.T) {
+	// delay the traffic of a secondary longer than the group waits for a member, so the others
+	// suspect it, then it may be expelled, the operator has to rejoin it
+	namespace := unit_c3d.Namespace
+	agents := unit_c3d.GetChaosAgents()
+	schedule := chaos.NewSchedule().
+		Add(0, 60*time.Second, chaos.NewLatency(agents, namespace, "mycluster-2", 6*time.Second)).
+		Expect(0, unit_c3d.ExpectMemberState(namespace, "mycluster-0", "mycluster-2", "UNREACHABLE", suite.MemberMissing))
 
-	// wait for operator to notice it OFFLINE
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE_PARTIAL"},
-		ExpectedNumOnline: 2,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
+	params := unit_c3d.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Routers = 2
+	params.Primary = suite.NoPrimary
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
+}
 
-	// restart GR and wait until everything is back to normal
-	podSessions1, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-1", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer podSessions1.Close()
-	if _, err := podSessions1.Exec("start group_replication"); err != nil {
-		t.Fatal(err)
-	}
+func RecoverFillDatadir1of3(t *testing.T) {
+	// take space in the datadir of a secondary for a while, the ballast is bounded, as the
+	// local volumes of k3d, kind and minikube share the disk of the node
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 30*time.Second, chaos.NewFillDatadir(unit_c3d.Client, namespace, "mycluster-1", "1G")).
+		Expect(0, unit_c3d.ExpectDatadirFilled(namespace, "mycluster-1"))
 
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
+	params := unit_c3d.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Routers = 2
+	params.Primary = suite.NoPrimary
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
+}
+
+func RecoverStop1of3(t *testing.T) {
+	// stop GR in 1 instance out of 3, then start it again (unless the operator does it first)
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, time.Second, chaos.NewStopGroupReplication(unit_c3d.Client, namespace, "mycluster-1")).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL"}, 2)).
+		Expect(0, unit_c3d.ExpectMemberState(namespace, "mycluster-0", "mycluster-1", suite.MemberMissing))
 
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Primary = 0
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func RecoverStop2of3(t *testing.T) {
-	t.Skip("under construction")
-	s0, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s0.Close()
-
-	s2, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-2", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s2.Close()
-
-	if _, err := s0.Exec("stop group_replication"); err != nil {
-		t.Fatal(err)
-	}
-
-	if _, err := s2.Exec("stop group_replication"); err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to notice it ONLINE_PARTIAL
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE_PARTIAL"},
-		ExpectedNumOnline: 1,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
+	// stop GR in 2 instances out of 3, the operator has to restore them
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.Permanent(chaos.NewStopGroupReplication(unit_c3d.Client, namespace, "mycluster-0"))).
+		Add(0, 0, chaos.Permanent(chaos.NewStopGroupReplication(unit_c3d.Client, namespace, "mycluster-2"))).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL"}, 1)).
+		Expect(0, unit_c3d.ExpectMemberState(namespace, "mycluster-1", "mycluster-0", suite.MemberMissing)).
+		Expect(0, unit_c3d.ExpectMemberState(namespace, "mycluster-1", "mycluster-2", suite.MemberMissing))
 
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Primary = 1
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func RecoverStop3of3(t *testing.T) {
-	t.Skip("under construction")
-	s0, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s0.Close()
-
-	s1, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-1", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s1.Close()
-
-	s2, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-2", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s2.Close()
-
-	if _, err := s0.Exec("stop group_replication"); err != nil {
-		t.Fatal(err)
-	}
-
-	if _, err := s1.Exec("stop group_replication"); err != nil {
-		t.Fatal(err)
-	}
-
-	if _, err := s2.Exec("stop group_replication"); err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to notice it OFFLINE
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"OFFLINE"},
-		ExpectedNumOnline: 0,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
+	// stop GR in all instances, the operator has to restore them
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.Permanent(chaos.NewStopGroupReplication(unit_c3d.Client, namespace, "mycluster-0"))).
+		Add(0, 0, chaos.Permanent(chaos.NewStopGroupReplication(unit_c3d.Client, namespace, "mycluster-1"))).
+		Add(0, 0, chaos.Permanent(chaos.NewStopGroupReplication(unit_c3d.Client, namespace, "mycluster-2"))).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"OFFLINE"}, 0))
 
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Primary = 0
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func RecoverRestart1of3(t *testing.T) {
-	s0, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s0.Close()
-	if _, err := s0.Exec("restart"); err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to notice it OFFLINE
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE_PARTIAL"},
-		ExpectedNumOnline: -1,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err = unit_c3d.WaitOnRouters("mycluster", 2); err != nil {
-		t.Fatal(err)
-	}
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewRestartServer(unit_c3d.Client, namespace, "mycluster-0")).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL"}, -1))
 
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Routers = 2
 	params.Primary = suite.NoPrimary
-	params.RestartsExpected = true
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func RecoverRestart2of3(t *testing.T) {
-	s0, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s0.Close()
-	if _, err := s0.Exec("restart"); err != nil {
-		t.Fatal(err)
-	}
-
-	s2, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-2", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s2.Close()
-	if _, err := s2.Exec("restart"); err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to notice it ONLINE_PARTIAL
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE_PARTIAL"},
-		ExpectedNumOnline: 1,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// check status of each pod
-
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewRestartServer(unit_c3d.Client, namespace, "mycluster-0")).
+		Add(0, 0, chaos.NewRestartServer(unit_c3d.Client, namespace, "mycluster-2")).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL"}, 1))
 
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Routers = suite.NoRouters
 	params.Primary = 1
-	params.RestartsExpected = true
-	if _, err := suite.CheckAll(unit_c3d, params); err != nil {
+	if _, err := unit_c3d.RunChaos(schedule, params); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func RecoverRestart3of3(t *testing.T) {
-	s0, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s0.Close()
-	if _, err := s0.Exec("restart"); err != nil {
-		t.Fatal(err)
-	}
-
-	s1, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-1", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s1.Close()
-	if _, err := s1.Exec("restart"); err != nil {
-		t.Fatal(err)
-	}
-
-	s2, err := mysql.NewSession(unit_c3d.Namespace, "mycluster-2", common.RootUser, common.RootPassword)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer s2.Close()
-	if _, err := s2.Exec("restart"); err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to notice it OFFLINE
-	waitParams := k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"OFFLINE"},
-		ExpectedNumOnline: 0,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// wait for operator to restore everything
-	waitParams = k8s.WaitOnInnoDBClusterParams{
-		Name:              "mycluster",
-		ExpectedStatus:    []string{"ONLINE"},
-		ExpectedNumOnline: 3,
-	}
-	err = unit_c3d.WaitOnInnoDBCluster(waitParams)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err = unit_c3d.WaitOnRouters("mycluster", 2); err != nil {
-		t.Fatal(err)
-	}
+	namespace := unit_c3d.Namespace
+	schedule := chaos.NewSchedule().
+		Add(0, 0, chaos.NewRestartServer(unit_c3d.Client, namespace, "mycluster-0")).
+		Add(0, 0, chaos.NewRestartServer(unit_c3d.Client, namespace, "mycluster-1")).
+		Add(0, 0, chaos.NewRestartServer(unit_c3d.Client, namespace, "mycluster-2")).
+		Expect(0, unit_c3d.ExpectClusterStatus(namespace, "mycluster", []string{"OFFLINE"}, 0))
 
 	params := unit_c3d.GetDefaultCheckParams()
 	params.Name = "mycluster"
 	params.Instances = 3
 	params.Routers = 2
 	params.Primary = suite.NoPrimary
-	params.RestartsExpected = true
-	all_pods, err := suite.CheckAll(unit_c3d, params)
+	all_pods, err := unit_c3d.RunChaos(schedule, params)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if err := suite.CheckData(all_pods, common.RootUser, common.RootPassword, 0); err != nil {
+	if err := suite.CheckData(unit_c3d.Context(), unit_c3d.Client, all_pods, common.RootUser, common.RootPassword, 0); err != nil {
 		t.Fatal(err)
 	}
 }
@@ -1150,23 +790,26 @@ func TestCluster3Defaults(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("CreateClusterThreeInstances=0", CreateClusterThreeInstances)
-	t.Run("CheckVersion3=1", CheckVersion3)
-	t.Run("CheckAccounts3=1", CheckAccounts3)
-	t.Run("CheckRouting=2", CheckRouting)
-	t.Run("RecoverCrash1of3=3", RecoverCrash1of3)
-	t.Run("RecoverCrash2of3=3", RecoverCrash2of3)
-	t.Run("RecoverCrash3of3=3", RecoverCrash3of3)
-	t.Run("RecoverDelete1of3=3", RecoverDelete1of3)
-	t.Run("RecoverDelete2of3=3", RecoverDelete2of3)
-	t.Run("RecoverDeleteAndWipe1of3=3", RecoverDeleteAndWipe1of3)
-	// t.Run("RecoverStop1of3=3", RecoverStop1of3)
-	// t.Run("RecoverStop2of3=3", RecoverStop2of3)
-	// t.Run("RecoverStop3of3=3", RecoverStop3of3)
-	t.Run("RecoverRestart1of3=3", RecoverRestart1of3)
-	t.Run("RecoverRestart2of3=3", RecoverRestart2of3)
-	t.Run("RecoverRestart3of3=3", RecoverRestart3of3)
-	t.Run("AfterCluster3Defaults=9", AfterCluster3Defaults)
+	unit_c3d.Run(t, "CreateClusterThreeInstances=0", CreateClusterThreeInstances)
+	unit_c3d.Run(t, "CheckVersion3=1", CheckVersion3)
+	unit_c3d.Run(t, "CheckAccounts3=1", CheckAccounts3)
+	unit_c3d.Run(t, "CheckRouting=2", CheckRouting)
+	unit_c3d.Run(t, "RecoverCrash1of3=3", RecoverCrash1of3)
+	unit_c3d.Run(t, "RecoverCrash2of3=3", RecoverCrash2of3)
+	unit_c3d.Run(t, "RecoverCrash3of3=3", RecoverCrash3of3)
+	unit_c3d.Run(t, "RecoverDelete1of3=3", RecoverDelete1of3)
+	unit_c3d.Run(t, "RecoverDelete2of3=3", RecoverDelete2of3)
+	unit_c3d.Run(t, "RecoverDeleteAndWipe1of3=3", RecoverDeleteAndWipe1of3)
+	unit_c3d.Run(t, "RecoverPause1of3=3", RecoverPause1of3)
+	unit_c3d.Run(t, "RecoverLatency1of3=3", RecoverLatency1of3)
+	unit_c3d.Run(t, "RecoverFillDatadir1of3=3", RecoverFillDatadir1of3)
+	unit_c3d.Run(t, "RecoverStop1of3=3", RecoverStop1of3)
+	unit_c3d.Run(t, "RecoverStop2of3=3", RecoverStop2of3)
+	unit_c3d.Run(t, "RecoverStop3of3=3", RecoverStop3of3)
+	unit_c3d.Run(t, "RecoverRestart1of3=3", RecoverRestart1of3)
+	unit_c3d.Run(t, "RecoverRestart2of3=3", RecoverRestart2of3)
+	unit_c3d.Run(t, "RecoverRestart3of3=3", RecoverRestart3of3)
+	unit_c3d.Run(t, "AfterCluster3Defaults=9", AfterCluster3Defaults)
 
 	err = unit_c3d.Teardown()
 	if err != nil {
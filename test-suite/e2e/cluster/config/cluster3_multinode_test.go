@@ -0,0 +1,184 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package config_test
+
+// test a three instances cluster spread over nodes with anti-affinity, then the loss of a node,
+// it needs a multi-node k8s cluster (k8s.nodes/k8s.agents) with at least 3 schedulable nodes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/chaos"
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/suite"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var unit_c3m *suite.Unit
+
+func CreateClusterOnDistinctNodes(t *testing.T) {
+	err := unit_c3m.Client.CreateUserSecrets(
+		unit_c3m.Namespace, "mypwds", common.RootUser, common.DefaultHost, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no two instances of the cluster may run on the same node
+	podSpec := map[string]interface{}{
+		"affinity": map[string]interface{}{
+			"podAntiAffinity": map[string]interface{}{
+				"requiredDuringSchedulingIgnoredDuringExecution": []interface{}{
+					map[string]interface{}{
+						"labelSelector": map[string]interface{}{
+							"matchLabels": map[string]interface{}{
+								"component":                "mysqld",
+								"mysql.oracle.com/cluster": "mycluster",
+							},
+						},
+						"topologyKey": "kubernetes.io/hostname",
+					},
+				},
+			},
+		},
+	}
+	cluster := k8s.NewInnoDBClusterBuilder("mycluster").
+		Instances(3).
+		Routers(1).
+		SecretName("mypwds").
+		TlsUseSelfSigned().
+		PodSpec(podSpec)
+	if err := unit_c3m.CreateInnoDBCluster(cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	waitParams := k8s.WaitOnInnoDBClusterParams{
+		Name:              "mycluster",
+		ExpectedStatus:    []string{"ONLINE"},
+		ExpectedNumOnline: 3,
+	}
+	if err := unit_c3m.WaitOnInnoDBCluster(waitParams); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unit_c3m.WaitOnRouters("mycluster", 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getServerPods(t *testing.T) []*corev1.Pod {
+	pods, err := unit_c3m.Client.ListPodsWithFilter(unit_c3m.Namespace, "^mycluster-[0-9]+$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serverPods []*corev1.Pod
+	for i := range pods.Items {
+		serverPods = append(serverPods, &pods.Items[i])
+	}
+	return serverPods
+}
+
+func CheckInstancesSpread(t *testing.T) {
+	serverPods := getServerPods(t)
+	if len(serverPods) != 3 {
+		t.Fatalf("expected 3 server pods, got %d", len(serverPods))
+	}
+	if err := suite.CheckPodsOnDistinctNodes(serverPods); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getNodeOfPod(t *testing.T, podName string) string {
+	pod, err := unit_c3m.Client.GetPod(unit_c3m.Namespace, podName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pod.Spec.NodeName
+}
+
+func RecoverNodeDrain(t *testing.T) {
+	// the evicted instance can't go anywhere else (unless there are spare nodes) until the
+	// node is uncordoned, please note all evictable pods of the node are evicted
+	namespace := unit_c3m.Namespace
+	nodeName := getNodeOfPod(t, "mycluster-1")
+	schedule := chaos.NewSchedule().
+		Add(0, 60*time.Second, chaos.NewDrain(unit_c3m.Client, nodeName)).
+		Expect(0, unit_c3m.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"}, 2)).
+		Expect(0, unit_c3m.ExpectMemberState(namespace, "mycluster-0", "mycluster-1", "UNREACHABLE", suite.MemberMissing))
+
+	params := unit_c3m.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Routers = 1
+	params.Primary = suite.NoPrimary
+	if _, err := unit_c3m.RunChaos(schedule, params); err != nil {
+		t.Fatal(err)
+	}
+
+	CheckInstancesSpread(t)
+}
+
+func RecoverCordonAndDelete(t *testing.T) {
+	// the node of the deleted instance is cordoned meanwhile, so it has to wait for it
+	// (or get rescheduled to a spare node)
+	namespace := unit_c3m.Namespace
+	nodeName := getNodeOfPod(t, "mycluster-2")
+	schedule := chaos.NewSchedule().
+		Add(0, 60*time.Second, chaos.NewCordon(unit_c3m.Client, nodeName)).
+		Add(0, 0, chaos.NewDeletePod(unit_c3m.Client, namespace, "mycluster-2")).
+		Expect(0, unit_c3m.ExpectClusterStatus(namespace, "mycluster", []string{"ONLINE_PARTIAL", "ONLINE_UNCERTAIN"}, 2))
+
+	params := unit_c3m.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Routers = 1
+	params.Primary = suite.NoPrimary
+	if _, err := unit_c3m.RunChaos(schedule, params); err != nil {
+		t.Fatal(err)
+	}
+
+	CheckInstancesSpread(t)
+}
+
+func AfterCluster3MultiNode(t *testing.T) {
+	if err := unit_c3m.Client.DeleteInnoDBCluster(unit_c3m.Namespace, "mycluster"); err != nil {
+		t.Error(err)
+	}
+
+	if err := unit_c3m.WaitOnInnoDBClusterGone("mycluster"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCluster3MultiNode(t *testing.T) {
+	if !suit.Cfg.IsMultiNode() {
+		t.Skip("a multi-node k8s cluster is needed, see k8s.nodes and k8s.agents")
+	}
+	nodes, err := suite.CountSchedulableNodes(suit.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const RequiredNodes = 3
+	if nodes < RequiredNodes {
+		t.Skipf("at least %d schedulable nodes are needed, got %d", RequiredNodes, nodes)
+	}
+
+	// the drain affects all pods of the node, so the unit doesn't run in parallel with others
+	const Namespace = "cluster3-multinode"
+	unit_c3m, err = suit.NewUnitSetup(Namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unit_c3m.Run(t, "CreateClusterOnDistinctNodes=0", CreateClusterOnDistinctNodes)
+	unit_c3m.Run(t, "CheckInstancesSpread=1", CheckInstancesSpread)
+	unit_c3m.Run(t, "RecoverNodeDrain=2", RecoverNodeDrain)
+	unit_c3m.Run(t, "RecoverCordonAndDelete=2", RecoverCordonAndDelete)
+	unit_c3m.Run(t, "AfterCluster3MultiNode=9", AfterCluster3MultiNode)
+
+	if err := unit_c3m.Teardown(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,81 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package config_test
+
+// test a three instances cluster recovers after one of its members was cut off the network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/chaos"
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/suite"
+)
+
+func CreatePartitionCluster(t *testing.T, unit *suite.Unit) {
+	err := unit.Client.CreateUserSecrets(unit.Namespace, "mypwds", common.RootUser, common.DefaultHost, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := k8s.NewInnoDBClusterBuilder("mycluster").
+		Instances(3).
+		SecretName("mypwds").
+		TlsUseSelfSigned()
+	if err := unit.CreateInnoDBCluster(cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	waitParams := k8s.WaitOnInnoDBClusterParams{
+		Name:              "mycluster",
+		ExpectedStatus:    []string{"ONLINE"},
+		ExpectedNumOnline: 3,
+	}
+	if err := unit.WaitOnInnoDBCluster(waitParams); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func RecoverPartition1of3(t *testing.T, unit *suite.Unit) {
+	// the others see the isolated member UNREACHABLE first, then they expel it
+	schedule := chaos.NewSchedule().
+		Add(0, 60*time.Second, chaos.NewPartition(unit.Client, unit.Namespace, "mycluster-1")).
+		Expect(0, unit.ExpectMemberState(unit.Namespace, "mycluster-0", "mycluster-1", "UNREACHABLE", suite.MemberMissing))
+
+	params := unit.GetDefaultCheckParams()
+	params.Name = "mycluster"
+	params.Instances = 3
+	params.Primary = suite.NoPrimary
+	if _, err := unit.RunChaos(schedule, params); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func DeletePartitionCluster(t *testing.T, unit *suite.Unit) {
+	if err := unit.Client.DeleteInnoDBCluster(unit.Namespace, "mycluster"); err != nil {
+		t.Fatal(err)
+	}
+	if err := unit.WaitOnInnoDBClusterGone("mycluster"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCluster3Partition(t *testing.T) {
+	// network policies are enforced out of the box only by k3s, the CNI of other environments
+	// (e.g. kindnet) ignores them, so the partition would have no effect
+	if suit.Cfg.K8s.Environment != common.EnvK3d {
+		t.Skipf("network policies are not enforced in %s", suit.Cfg.K8s.Environment)
+	}
+
+	const Namespace = "cluster3-partition"
+	unit, err := suit.NewParallelUnitSetup(t, Namespace, suite.ClusterDemand{Instances: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unit.RunScenario(t, "CreatePartitionCluster=0", CreatePartitionCluster)
+	unit.RunScenario(t, "RecoverPartition1of3=1", RecoverPartition1of3)
+	unit.RunScenario(t, "DeletePartitionCluster=9", DeletePartitionCluster)
+}
@@ -10,17 +10,15 @@ import (
 	"github.com/marinesovitch/ote/test-suite/util/suite"
 )
 
-var unit_cr *suite.Unit
-
 // Create and delete a cluster immediately, before it becomes ONLINE.
-func CreateAndDelete(t *testing.T) {
-	err := unit_cr.Client.CreateUserSecrets(unit_cr.Namespace, "mypwds", common.RootUser, common.DefaultHost, common.RootPassword)
+func CreateAndDelete(t *testing.T, unit *suite.Unit) {
+	err := unit.Client.CreateUserSecrets(unit.Namespace, "mypwds", common.RootUser, common.DefaultHost, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// create cluster with mostly default configs
-	err = unit_cr.Apply("cluster-races.yaml")
+	err = unit.Apply("cluster-races.yaml")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -30,24 +28,24 @@ func CreateAndDelete(t *testing.T) {
 		ExpectedStatus:    []string{"PENDING"},
 		ExpectedNumOnline: -1,
 	}
-	err = unit_cr.WaitOnInnoDBCluster(waitParams)
+	err = unit.WaitOnInnoDBCluster(waitParams)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// deleting a cluster right after it's created and before it's ONLINE
 	// caused a loop and didn't finish deleting before
-	err = unit_cr.Client.DeleteInnoDBCluster(unit_cr.Namespace, "mycluster")
+	err = unit.Client.DeleteInnoDBCluster(unit.Namespace, "mycluster")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = unit_cr.WaitOnInnoDBClusterGone("mycluster")
+	err = unit.WaitOnInnoDBClusterGone("mycluster")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = unit_cr.WaitOnPodGone("mycluster-0")
+	err = unit.WaitOnPodGone("mycluster-0")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -55,16 +53,10 @@ func CreateAndDelete(t *testing.T) {
 
 func TestClusterRaces(t *testing.T) {
 	const Namespace = "cluster-races"
-	var err error
-	unit_cr, err = suit.NewUnitSetup(Namespace)
+	unit, err := suit.NewParallelUnitSetup(t, Namespace, suite.ClusterDemand{Instances: 1})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Run("CreateAndDelete=0", CreateAndDelete)
-
-	err = unit_cr.Teardown()
-	if err != nil {
-		t.Error(err)
-	}
+	unit.RunScenario(t, "CreateAndDelete=0", CreateAndDelete)
 }
@@ -13,8 +13,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-var unit_cc *suite.Unit
-
 type GenerateCustomConfData struct {
 	ClusterName   string
 	ServerVersion string
@@ -22,14 +20,14 @@ type GenerateCustomConfData struct {
 
 const clusterName = "myvalid-cluster-name-28-char"
 
-func CreateCustomConf(t *testing.T) {
+func CreateCustomConf(t *testing.T, unit *suite.Unit) {
 	// Checks:
 	// - cluster name can be 28chars long
 	// - root user name and host can be customized
 	// - base server id can be changed
 	// - version can be customized
 	// - mycnf can be specified
-	err := unit_cc.Client.CreateUserSecrets(unit_cc.Namespace, "mypwds", common.AdminUser, common.DefaultHost, common.AdminPassword)
+	err := unit.Client.CreateUserSecrets(unit.Namespace, "mypwds", common.AdminUser, common.DefaultHost, common.AdminPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,17 +37,17 @@ func CreateCustomConf(t *testing.T) {
 		ClusterName:   clusterName,
 		ServerVersion: oldVersionTag,
 	}
-	err = unit_cc.GenerateAndApply("custom-conf.yaml", generateData)
+	err = unit.GenerateAndApply("custom-conf.yaml", generateData)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = unit_cc.WaitOnPod(clusterName+"-0", corev1.PodRunning)
+	err = unit.WaitOnPod(clusterName+"-0", corev1.PodRunning)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = unit_cc.WaitOnPod(clusterName+"-1", corev1.PodRunning)
+	err = unit.WaitOnPod(clusterName+"-1", corev1.PodRunning)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -59,27 +57,27 @@ func CreateCustomConf(t *testing.T) {
 		ExpectedStatus:    []string{"ONLINE"},
 		ExpectedNumOnline: 2,
 	}
-	err = unit_cc.WaitOnInnoDBCluster(waitParams)
+	err = unit.WaitOnInnoDBCluster(waitParams)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if err = unit_cc.WaitOnRouters(clusterName, 1); err != nil {
+	if err = unit.WaitOnRouters(clusterName, 1); err != nil {
 		t.Fatal(err)
 	}
 
-	params := unit_cc.GetDefaultCheckParams()
+	params := unit.GetDefaultCheckParams()
 	params.Name = clusterName
 	params.Instances = 2
 	params.Routers = 1
 	params.Primary = 0
 	params.User = common.AdminUser
 	params.Password = "secret"
-	if _, err := suite.CheckAll(unit_cc, params); err != nil {
+	if _, err := suite.CheckAll(unit, params); err != nil {
 		t.Fatal(err)
 	}
 
-	session, err := mysql.NewSession(unit_cc.Namespace, clusterName+"-0", common.AdminUser, common.AdminPassword)
+	session, err := mysql.NewSession(unit.Context(), unit.Client, unit.Namespace, clusterName+"-0", common.AdminUser, common.AdminPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -114,7 +112,7 @@ func CreateCustomConf(t *testing.T) {
 		t.Fatalf("expected no users but got %d", len(users.Rows))
 	}
 
-	session, err = mysql.NewSession(unit_cc.Namespace, clusterName+"-1", common.AdminUser, common.AdminPassword)
+	session, err = mysql.NewSession(unit.Context(), unit.Client, unit.Namespace, clusterName+"-1", common.AdminUser, common.AdminPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -146,66 +144,66 @@ func CreateCustomConf(t *testing.T) {
 		t.Fatalf("expected no users but got %d", len(users.Rows))
 	}
 
-	pod, err := unit_cc.Client.GetPod(unit_cc.Namespace, clusterName+"-0")
+	pod, err := unit.Client.GetPod(unit.Namespace, clusterName+"-0")
 	if err != nil {
 		t.Fatal(err)
 	}
-	mysqlCont, err := suite.CheckPodContainer(unit_cc.Client, pod, k8s.Mysql, suite.NoRestarts, true)
+	mysqlCont, err := suite.CheckPodContainer(unit.Client, pod, k8s.Mysql, suite.NoRestarts, true)
 	if err != nil {
 		t.Fatal(err)
 	}
 	mysqlContImage := mysqlCont.Container.Image
-	expectedMysqlContImage := unit_cc.GetServerImage(oldVersionTag)
+	expectedMysqlContImage := unit.GetServerImage(oldVersionTag)
 	if mysqlContImage != expectedMysqlContImage {
 		t.Fatalf("expected mysql container image in pod %s is %s but got %s", pod.GetName(), expectedMysqlContImage, mysqlContImage)
 	}
 
-	sidecarCont, err := suite.CheckPodContainer(unit_cc.Client, pod, k8s.Sidecar, suite.NoRestarts, true)
+	sidecarCont, err := suite.CheckPodContainer(unit.Client, pod, k8s.Sidecar, suite.NoRestarts, true)
 	if err != nil {
 		t.Fatal(err)
 	}
 	sidecarContImage := sidecarCont.Container.Image
-	expectedSidecarContImage := unit_cc.GetDefaultOperatorImage()
+	expectedSidecarContImage := unit.GetDefaultOperatorImage()
 	if sidecarContImage != expectedSidecarContImage {
 		t.Fatalf("expected sidecar container image in pod %s is %s but got %s", pod.GetName(), expectedSidecarContImage, sidecarContImage)
 	}
 
 	// check version of router images
-	routers, err := unit_cc.Client.ListPodsWithFilter(unit_cc.Namespace, clusterName+"-.*-router")
+	routers, err := unit.Client.ListPodsWithFilter(unit.Namespace, clusterName+"-.*-router")
 	if err != nil {
 		t.Fatal(err)
 	}
 	for _, router := range routers.Items {
-		routerCont, err := suite.CheckPodContainer(unit_cc.Client, &router, k8s.Router, suite.NoRestarts, true)
+		routerCont, err := suite.CheckPodContainer(unit.Client, &router, k8s.Router, suite.NoRestarts, true)
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		routerContainerImage := routerCont.Container.Image
-		expectedRouterContainerImage := unit_cc.GetDefaultRouterImage()
+		expectedRouterContainerImage := unit.GetDefaultRouterImage()
 		if routerContainerImage != expectedRouterContainerImage {
 			t.Fatalf("expected container image for router %s is %s but got %s", router.GetName(), expectedRouterContainerImage, routerContainerImage)
 		}
 	}
 }
 
-func DestroyCustomConf(t *testing.T) {
-	err := unit_cc.Client.DeleteInnoDBCluster(unit_cc.Namespace, clusterName)
+func DestroyCustomConf(t *testing.T, unit *suite.Unit) {
+	err := unit.Client.DeleteInnoDBCluster(unit.Namespace, clusterName)
 	if err != nil {
 		t.Error(err)
 	}
 
-	err = unit_cc.WaitOnPodGone(clusterName + "-1")
+	err = unit.WaitOnPodGone(clusterName + "-1")
 	if err != nil {
 		t.Error(err)
 	}
 
-	err = unit_cc.WaitOnPodGone(clusterName + "-0")
+	err = unit.WaitOnPodGone(clusterName + "-0")
 	if err != nil {
 		t.Error(err)
 	}
 
-	err = unit_cc.WaitOnInnoDBClusterGone(clusterName)
+	err = unit.WaitOnInnoDBClusterGone(clusterName)
 	if err != nil {
 		t.Error(err)
 	}
@@ -213,17 +211,11 @@ func DestroyCustomConf(t *testing.T) {
 
 func TestClusterCustomConf(t *testing.T) {
 	const Namespace = "custom-conf"
-	var err error
-	unit_cc, err = suit.NewUnitSetup(Namespace)
+	unit, err := suit.NewParallelUnitSetup(t, Namespace, suite.ClusterDemand{Instances: 2, Routers: 1})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Run("CreateCustomConf=0", CreateCustomConf)
-	t.Run("DestroyCustomConf=1", DestroyCustomConf)
-
-	err = unit_cc.Teardown()
-	if err != nil {
-		t.Error(err)
-	}
+	unit.RunScenario(t, "CreateCustomConf=0", CreateCustomConf)
+	unit.RunScenario(t, "DestroyCustomConf=1", DestroyCustomConf)
 }
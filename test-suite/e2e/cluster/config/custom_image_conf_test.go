@@ -171,8 +171,8 @@ func TestClusterCustomImageConf(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("CreateCustomImageConf=0", CreateCustomImageConf)
-	t.Run("DestroyCustomImageConf=1", DestroyCustomImageConf)
+	unit_ci.Run(t, "CreateCustomImageConf=0", CreateCustomImageConf)
+	unit_ci.Run(t, "DestroyCustomImageConf=1", DestroyCustomImageConf)
 
 	err = unit_ci.Teardown()
 	if err != nil {
@@ -165,10 +165,10 @@ func TestTwoClustersOneNamespace(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("CreateClusterOne=0", CreateClusterOne)
-	t.Run("CreateClusterTwo=0", CreateClusterTwo)
-	t.Run("DestroyClusterOne=1", DestroyClusterOne)
-	t.Run("DestroyClusterTwo=1", DestroyClusterTwo)
+	unit_tc.Run(t, "CreateClusterOne=0", CreateClusterOne)
+	unit_tc.Run(t, "CreateClusterTwo=0", CreateClusterTwo)
+	unit_tc.Run(t, "DestroyClusterOne=1", DestroyClusterOne)
+	unit_tc.Run(t, "DestroyClusterTwo=1", DestroyClusterTwo)
 
 	err = unit_tc.Teardown()
 	if err != nil {
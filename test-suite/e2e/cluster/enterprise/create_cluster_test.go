@@ -94,7 +94,7 @@ func Create(t *testing.T) {
 }
 
 func CheckAccounts(t *testing.T) {
-	session, err := mysql.NewSession(unit_cct.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	session, err := mysql.NewSession(unit_cct.Context(), unit_cct.Client, unit_cct.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -255,10 +255,10 @@ func TestClusterEnterprise(t *testing.T) {
 		t.Skip(err)
 	}
 
-	t.Run("Create=0", Create)
-	t.Run("CheckAccounts=1", CheckAccounts)
-	t.Run("CheckVersion=1", CheckVersion)
-	t.Run("Destroy=9", Destroy)
+	unit_cct.Run(t, "Create=0", Create)
+	unit_cct.Run(t, "CheckAccounts=1", CheckAccounts)
+	unit_cct.Run(t, "CheckVersion=1", CheckVersion)
+	unit_cct.Run(t, "Destroy=9", Destroy)
 
 	err = unit_cct.Teardown()
 	if err != nil {
@@ -5,6 +5,7 @@ package initdb_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/auxi"
 	"github.com/marinesovitch/ote/test-suite/util/common"
@@ -61,7 +62,7 @@ func BeforeFromClone(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_fc.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_fc.Context(), unit_fc.Client, unit_fc.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -105,7 +106,7 @@ func CreateClone(t *testing.T) {
 		Name:              "copycluster",
 		ExpectedStatus:    []string{"ONLINE"},
 		ExpectedNumOnline: 1,
-		Timeout:           300,
+		Timeout:           300 * time.Second,
 	}
 	err = unit_fc.WaitOnInnoDBCluster(waitParams)
 	if err != nil {
@@ -116,7 +117,7 @@ func CreateClone(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_fc.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_fc.Context(), unit_fc.Client, unit_fc.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -127,7 +128,7 @@ func CreateClone(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	cloneSession, err := mysql.NewSession(NamespaceClone, "copycluster-0", common.RootUser, common.RootPassword)
+	cloneSession, err := mysql.NewSession(unit_fc.Context(), unit_fc.Client, NamespaceClone, "copycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -138,6 +139,17 @@ func CreateClone(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// clone copies accounts too, unlike dumps
+	fingerprintOptions := mysql.FingerprintOptions{Schemas: []string{"sakila"}, Accounts: []string{"clone@%"}}
+	sourceFingerprint, err := suite.GetDataFingerprint(unit_fc.Context(), unit_fc.Client, unit_fc.Namespace, "mycluster-0", fingerprintOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = suite.CheckDataFingerprint(unit_fc.Context(), unit_fc.Client, NamespaceClone, "copycluster-0", sourceFingerprint, fingerprintOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// add some data with binlog disabled to make sure that all members of this
 	// cluster are cloned
 	commands := []string{
@@ -194,7 +206,7 @@ func Grow(t *testing.T) {
 	}
 
 	// check that the new instance was cloned
-	cloneSession, err := mysql.NewSession(NamespaceClone, "copycluster-1", common.RootUser, common.RootPassword)
+	cloneSession, err := mysql.NewSession(unit_fc.Context(), unit_fc.Client, NamespaceClone, "copycluster-1", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -270,10 +282,10 @@ func TestClusterFromClone(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("BeforeFromClone=0", BeforeFromClone)
-	t.Run("CreateClone=1", CreateClone)
-	t.Run("Grow=1", Grow)
-	t.Run("AfterFromClone=9", AfterFromClone)
+	unit_fc.Run(t, "BeforeFromClone=0", BeforeFromClone)
+	unit_fc.Run(t, "CreateClone=1", CreateClone)
+	unit_fc.Run(t, "Grow=1", Grow)
+	unit_fc.Run(t, "AfterFromClone=9", AfterFromClone)
 
 	err = unit_fc.Teardown()
 	if err != nil {
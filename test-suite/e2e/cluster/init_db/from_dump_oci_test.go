@@ -6,12 +6,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/auxi"
 	"github.com/marinesovitch/ote/test-suite/util/common"
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
 	"github.com/marinesovitch/ote/test-suite/util/mysql"
-	"github.com/marinesovitch/ote/test-suite/util/oci"
 	"github.com/marinesovitch/ote/test-suite/util/suite"
 
 	corev1 "k8s.io/api/core/v1"
@@ -29,6 +29,9 @@ const OciStoragePrefix = "/e2etest/ote-mysql"
 const OciCredentialsBackup = "backup-apikey"
 const OciCredentialsRestore = "restore-apikey"
 
+// the dumps are made of the whole instance, but only sakila is there to compare
+var SakilaFingerprintOptions = mysql.FingerprintOptions{Schemas: []string{"sakila"}}
+
 type GenerateDumpOCIData struct {
 	BackupProfileName     string
 	DumpName              string
@@ -39,8 +42,10 @@ type GenerateDumpOCIData struct {
 }
 
 var originalTables *mysql.Records
+var originalFingerprint *mysql.Fingerprint
 var generateData GenerateDumpOCIData
 var ociStorageOutput string
+var ociStorage *suite.OciBucket
 
 func BeforeFromDumpOCI(t *testing.T) {
 	err := unit_fdo.Client.CreateUserSecrets(
@@ -49,9 +54,15 @@ func BeforeFromDumpOCI(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// create a secret with the api key to access the bucket, which should be
-	// stored in the path given in the environment variable
-	configPath := unit_fdo.Cfg.Oci.ConfigPath
+	// the bucket is either the real one or the fake one served by the unit (oci.fake)
+	ociStorage, err = unit_fdo.PrepareOciBucket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create a secret with the api key to access the bucket, the key file is given in the
+	// config of the profiles
+	configPath := ociStorage.ConfigPath
 	err = unit_fdo.Client.CreateApikeySecret(unit_fdo.Namespace, OciCredentialsBackup, configPath, common.OciProfileBackup)
 	if err != nil {
 		t.Fatal(err)
@@ -65,7 +76,7 @@ func BeforeFromDumpOCI(t *testing.T) {
 	generateData = GenerateDumpOCIData{
 		BackupProfileName:     BackupProfileName,
 		DumpName:              DumpName,
-		BucketName:            unit_fdo.Cfg.Oci.BucketName,
+		BucketName:            ociStorage.BucketName,
 		OciStoragePrefix:      OciStoragePrefix,
 		OciCredentialsBackup:  OciCredentialsBackup,
 		OciCredentialsRestore: OciCredentialsRestore,
@@ -96,7 +107,7 @@ func BeforeFromDumpOCI(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_fdo.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_fdo.Context(), unit_fdo.Client, unit_fdo.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -106,6 +117,11 @@ func BeforeFromDumpOCI(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	originalFingerprint, err = suite.GetDataFingerprint(unit_fdo.Context(), unit_fdo.Client, unit_fdo.Namespace, "mycluster-0", SakilaFingerprintOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// create a dump in a bucket
 	if err := unit_fdo.GenerateAndApply("dump-into-bucket.yaml", generateData); err != nil {
 		t.Fatal(err)
@@ -118,23 +134,23 @@ func BeforeFromDumpOCI(t *testing.T) {
 		ok, mbk, err = suite.CheckMySQLBackup(unit_fdo.Client, unit_fdo.Namespace, generateData.DumpName)
 		return ok, err
 	}
-	ok, err := unit_fdo.Wait(checker, 300, 2)
+	ok, err := unit_fdo.Wait(checker, 300*time.Second, 2*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	if ok && mbk.GetName() == generateData.DumpName {
-		specClusterName := mbk.GetString("spec", "clusterName")
+		specClusterName := mbk.Spec.ClusterName
 		if specClusterName != ClusterName {
 			t.Fatalf("expected cluster name is %s but got %s", ClusterName, specClusterName)
 		}
 
-		mbkStatus := mbk.GetStatus()
+		mbkStatus := mbk.Status.Status
 		if mbkStatus != k8s.MBKStatusCompleted {
 			t.Fatalf("expected mbk status is %s but got %s", k8s.MBKStatusCompleted, mbkStatus)
 		}
 
-		mbkStatusOutput := mbk.GetString("status", "output")
+		mbkStatusOutput := mbk.Status.Output
 		expectedMbkPrefix := generateData.DumpName + "-"
 		if !strings.HasPrefix(mbkStatusOutput, expectedMbkPrefix) {
 			t.Fatalf("mbk output name should begin with %s but it doesn't (%s)", expectedMbkPrefix, mbkStatusOutput)
@@ -143,6 +159,14 @@ func BeforeFromDumpOCI(t *testing.T) {
 		if len(mbkStatusOutput) > 0 {
 			ociStorageOutput = filepath.Join(OciStoragePrefix, mbkStatusOutput)
 		}
+
+		store, err := ociStorage.GetObjectStore(common.OciProfileRestore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := suite.CheckDumpInStore(unit_fdo.Context(), store, ociStorageOutput); err != nil {
+			t.Fatal(err)
+		}
 	}
 
 	// destroy the test cluster
@@ -197,7 +221,7 @@ func CreateFromDump(t *testing.T) {
 		Name:              NewClusterName,
 		ExpectedStatus:    []string{"ONLINE"},
 		ExpectedNumOnline: 1,
-		Timeout:           600,
+		Timeout:           600 * time.Second,
 	}
 	err = unit_fdo.WaitOnInnoDBCluster(waitParams)
 	if err != nil {
@@ -208,7 +232,7 @@ func CreateFromDump(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_fdo.Namespace, "newcluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_fdo.Context(), unit_fdo.Client, unit_fdo.Namespace, "newcluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -224,6 +248,11 @@ func CreateFromDump(t *testing.T) {
 		t.Fatalf("expected tables: %v but got: %v", originalTableNames, tableNames)
 	}
 
+	err = suite.CheckDataFingerprint(unit_fdo.Context(), unit_fdo.Client, unit_fdo.Namespace, "newcluster-0", originalFingerprint, SakilaFingerprintOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// add some data with binlog disabled to allow testing that new
 	// members added to this cluster use clone for provisioning
 	commands := []string{
@@ -274,7 +303,7 @@ func GrowClusterFromDump(t *testing.T) {
 	}
 
 	// check that the new instance was provisioned through clone and not incremental
-	// podSession, err := mysql.NewSession(unit_fdo.Namespace, "newcluster-1", common.RootUser, common.RootPassword)
+	// podSession, err := mysql.NewSession(unit_fdo.Context(), unit_fdo.Client, unit_fdo.Namespace, "newcluster-1", common.RootUser, common.RootPassword)
 	// if err != nil {
 	// 	t.Fatal(err)
 	// }
@@ -339,7 +368,7 @@ func CreateFromDumpOptions(t *testing.T) {
 		Name:              NewClusterName,
 		ExpectedStatus:    []string{"ONLINE"},
 		ExpectedNumOnline: 1,
-		Timeout:           600,
+		Timeout:           600 * time.Second,
 	}
 	err = unit_fdo.WaitOnInnoDBCluster(waitParams)
 	if err != nil {
@@ -350,7 +379,7 @@ func CreateFromDumpOptions(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	podSession, err := mysql.NewSession(unit_fdo.Namespace, "newcluster-0", common.RootUser, common.RootPassword)
+	podSession, err := mysql.NewSession(unit_fdo.Context(), unit_fdo.Client, unit_fdo.Namespace, "newcluster-0", common.RootUser, common.RootPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -366,6 +395,11 @@ func CreateFromDumpOptions(t *testing.T) {
 		t.Fatalf("expected tables: %v but got: %v", originalTableNames, tableNames)
 	}
 
+	err = suite.CheckDataFingerprint(unit_fdo.Context(), unit_fdo.Client, unit_fdo.Namespace, "newcluster-0", originalFingerprint, SakilaFingerprintOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	if err := suite.CheckRouterPods(unit_fdo.Client, unit_fdo.Namespace, NewClusterName, 1); err != nil {
 		t.Fatal(err)
 	}
@@ -398,12 +432,22 @@ func AfterFromDumpOCI(t *testing.T) {
 		t.Error(err)
 	}
 
+	if ociStorage == nil {
+		return
+	}
+
 	if len(ociStorageOutput) > 0 {
-		err = oci.BulkDelete(unit_fdo.Cfg.Oci.ConfigPath, common.OciProfileDelete, unit_fdo.Cfg.Oci.BucketName, ociStorageOutput)
+		store, err := ociStorage.GetObjectStore(common.OciProfileDelete)
 		if err != nil {
 			t.Error(err)
+		} else if _, err = store.DeletePrefix(unit_fdo.Context(), ociStorageOutput); err != nil {
+			t.Error(err)
 		}
 	}
+
+	if err = ociStorage.Release(); err != nil {
+		t.Error(err)
+	}
 }
 
 func TestClusterFromDumpOCI(t *testing.T) {
@@ -419,12 +463,12 @@ func TestClusterFromDumpOCI(t *testing.T) {
 		t.Skip(err)
 	}
 
-	t.Run("BeforeFromDumpOCI=0", BeforeFromDumpOCI)
-	t.Run("CreateFromDump=1", CreateFromDump)
-	t.Run("GrowClusterFromDump=2", GrowClusterFromDump)
-	t.Run("DestroyClusterFromDump=3", DestroyClusterFromDump)
-	t.Run("CreateFromDumpOptions=4", CreateFromDumpOptions)
-	t.Run("AfterFromDumpOCI=9", AfterFromDumpOCI)
+	unit_fdo.Run(t, "BeforeFromDumpOCI=0", BeforeFromDumpOCI)
+	unit_fdo.Run(t, "CreateFromDump=1", CreateFromDump)
+	unit_fdo.Run(t, "GrowClusterFromDump=2", GrowClusterFromDump)
+	unit_fdo.Run(t, "DestroyClusterFromDump=3", DestroyClusterFromDump)
+	unit_fdo.Run(t, "CreateFromDumpOptions=4", CreateFromDumpOptions)
+	unit_fdo.Run(t, "AfterFromDumpOCI=9", AfterFromDumpOCI)
 
 	err = unit_fdo.Teardown()
 	if err != nil {
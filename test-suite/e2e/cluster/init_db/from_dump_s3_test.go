@@ -0,0 +1,296 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package initdb_test
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/mysql"
+	"github.com/marinesovitch/ote/test-suite/util/suite"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Create cluster and initialize from a shell dump stored in an S3 bucket, served by MinIO
+// deployed in the aux namespace, so it runs offline.
+
+var unit_fds3 *suite.Unit
+var minio *suite.Minio
+
+const DumpNameS3 = "cluster-from-dump-test-s3"
+const BackupProfileNameS3 = "fulldump-s3"
+const S3StoragePrefix = "/e2etest/ote-mysql"
+const S3Credentials = "s3-credentials"
+
+var originalTablesS3 *mysql.Records
+var originalFingerprintS3 *mysql.Fingerprint
+var s3StorageOutput string
+
+func BeforeFromDumpS3(t *testing.T) {
+	var err error
+	minio, err = unit_fds3.DeployMinio(unit_fds3.AuxNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same secret serves both the backup and the restore
+	err = minio.CreateCredentialsSecret(unit_fds3.Namespace, S3Credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unit_fds3.Client.CreateUserSecrets(
+		unit_fds3.Namespace, "mypwds", common.RootUser, common.DefaultHost, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := k8s.NewInnoDBClusterBuilder(ClusterName).
+		Instances(1).
+		Routers(1).
+		SecretName("mypwds").
+		TlsUseSelfSigned().
+		BackupProfile(k8s.NewDumpInstanceProfile(BackupProfileNameS3, minio.NewStorage(S3StoragePrefix, S3Credentials), nil))
+	err = unit_fds3.CreateInnoDBCluster(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unit_fds3.WaitOnPod("mycluster-0", corev1.PodRunning)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitParams := k8s.WaitOnInnoDBClusterParams{
+		Name:              ClusterName,
+		ExpectedStatus:    []string{"ONLINE"},
+		ExpectedNumOnline: 1,
+	}
+	err = unit_fds3.WaitOnInnoDBCluster(waitParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = suite.LoadSakilaScript(unit_fds3, "mycluster-0", k8s.Mysql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podSession, err := mysql.NewSession(unit_fds3.Context(), unit_fds3.Client, unit_fds3.Namespace, "mycluster-0", common.RootUser, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originalTablesS3, err = podSession.FetchAll("show tables in sakila")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originalFingerprintS3, err = suite.GetDataFingerprint(unit_fds3.Context(), unit_fds3.Client, unit_fds3.Namespace, "mycluster-0", SakilaFingerprintOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create a dump in the bucket
+	generateData := GenerateDumpOCIData{
+		BackupProfileName: BackupProfileNameS3,
+		DumpName:          DumpNameS3,
+	}
+	if err := unit_fds3.GenerateAndApply("dump-into-bucket.yaml", generateData); err != nil {
+		t.Fatal(err)
+	}
+
+	var mbk *k8s.MySQLBackup
+	checker := func(args ...interface{}) (bool, error) {
+		var ok bool
+		ok, mbk, err = suite.CheckMySQLBackup(unit_fds3.Client, unit_fds3.Namespace, DumpNameS3)
+		return ok, err
+	}
+	_, err = unit_fds3.Wait(checker, 300*time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mbkStatusOutput := mbk.Status.Output
+	expectedMbkPrefix := DumpNameS3 + "-"
+	if !strings.HasPrefix(mbkStatusOutput, expectedMbkPrefix) {
+		t.Fatalf("mbk output name should begin with %s but it doesn't (%s)", expectedMbkPrefix, mbkStatusOutput)
+	}
+	s3StorageOutput = path.Join(S3StoragePrefix, mbkStatusOutput)
+
+	store, err := minio.GetObjectStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := suite.CheckDumpInStore(unit_fds3.Context(), store, s3StorageOutput); err != nil {
+		t.Fatal(err)
+	}
+
+	// destroy the test cluster
+	err = unit_fds3.Client.DeleteInnoDBCluster(unit_fds3.Namespace, ClusterName)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.WaitOnPodGone("mycluster-0")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.WaitOnInnoDBClusterGone(ClusterName)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// delete pv and pvc related to mycluster
+	err = unit_fds3.DeleteAllPersistentVolumeClaims()
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.Client.DeleteSecret(unit_fds3.Namespace, "mypwds")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func CreateFromDumpS3(t *testing.T) {
+	err := unit_fds3.Client.CreateUserSecrets(
+		unit_fds3.Namespace, "newpwds", common.RootUser, common.DefaultHost, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := k8s.NewInnoDBClusterBuilder(NewClusterName).
+		Instances(1).
+		Routers(1).
+		SecretName("newpwds").
+		TlsUseSelfSigned().
+		BaseServerId(2000).
+		InitDB(k8s.NewInitDBFromDump(DumpNameS3, minio.NewStorage(s3StorageOutput, S3Credentials), nil))
+	err = unit_fds3.CreateInnoDBCluster(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unit_fds3.WaitOnPod("newcluster-0", corev1.PodRunning)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitParams := k8s.WaitOnInnoDBClusterParams{
+		Name:              NewClusterName,
+		ExpectedStatus:    []string{"ONLINE"},
+		ExpectedNumOnline: 1,
+		Timeout:           600 * time.Second,
+	}
+	err = unit_fds3.WaitOnInnoDBCluster(waitParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = unit_fds3.WaitOnRouters(NewClusterName, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	podSession, err := mysql.NewSession(unit_fds3.Context(), unit_fds3.Client, unit_fds3.Namespace, "newcluster-0", common.RootUser, common.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := podSession.FetchAll("show tables in sakila")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originalTableNames := originalTablesS3.ToStringsSlice(0)
+	tableNames := tables.ToStringsSlice(0)
+	if !auxi.AreStringSlicesEqual(originalTableNames, tableNames) {
+		t.Fatalf("expected tables: %v but got: %v", originalTableNames, tableNames)
+	}
+
+	err = suite.CheckDataFingerprint(unit_fds3.Context(), unit_fds3.Client, unit_fds3.Namespace, "newcluster-0", originalFingerprintS3, SakilaFingerprintOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := suite.CheckRouterPods(unit_fds3.Client, unit_fds3.Namespace, NewClusterName, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func AfterFromDumpS3(t *testing.T) {
+	err := unit_fds3.Client.DeleteInnoDBCluster(unit_fds3.Namespace, NewClusterName)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.WaitOnPodGone("newcluster-0")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.WaitOnInnoDBClusterGone(NewClusterName)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.DeleteAllPersistentVolumeClaims()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = unit_fds3.Client.DeleteMySQLBackup(unit_fds3.Namespace, DumpNameS3); err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.Client.DeleteSecret(unit_fds3.Namespace, S3Credentials)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = unit_fds3.Client.DeleteSecret(unit_fds3.Namespace, "newpwds")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// minio itself goes away with the aux namespace
+	if minio != nil && len(s3StorageOutput) > 0 {
+		store, err := minio.GetObjectStore()
+		if err != nil {
+			t.Error(err)
+		} else if _, err = store.DeletePrefix(unit_fds3.Context(), s3StorageOutput); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestClusterFromDumpS3(t *testing.T) {
+	const Namespace = "from-dump-s3"
+	const NamespaceMinio = "from-dump-s3-minio"
+	var err error
+	unit_fds3, err = suit.NewUnitSetupWithAuxNamespace(Namespace, NamespaceMinio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unit_fds3.Cfg.CheckS3Config()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	unit_fds3.Run(t, "BeforeFromDumpS3=0", BeforeFromDumpS3)
+	unit_fds3.Run(t, "CreateFromDumpS3=1", CreateFromDumpS3)
+	unit_fds3.Run(t, "AfterFromDumpS3=9", AfterFromDumpS3)
+
+	err = unit_fds3.Teardown()
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/common"
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
@@ -121,12 +122,7 @@ func UpgradeToNext(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	checkDone := func(args ...interface{}) (bool, error) {
-		name := args[0].(string)
-		pod, err := unit_utn.Client.GetPod(unit_utn.Namespace, name)
-		if err != nil {
-			return false, err
-		}
+	checkDone := func(pod *corev1.Pod) (bool, error) {
 		annotations := pod.GetAnnotations()
 		const membershipInfoKey = "mysql.oracle.com/membership-info"
 		jsonMembershipInfo, ok := annotations[membershipInfoKey]
@@ -139,7 +135,7 @@ func UpgradeToNext(t *testing.T) {
 		if err != nil {
 			return false, nil
 		}
-		version := membershipInfo["version"].(string)
+		version, _ := membershipInfo["version"].(string)
 		if !strings.HasPrefix(version, defaultVersionTag) {
 			return false, nil
 		}
@@ -147,13 +143,13 @@ func UpgradeToNext(t *testing.T) {
 		return true, nil
 	}
 
-	if _, err := unit_utn.Wait(checkDone, 150, 10, "mycluster-2"); err != nil {
+	if err := unit_utn.WaitOnPodState("mycluster-2", 150*time.Second, checkDone); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := unit_utn.Wait(checkDone, 150, 10, "mycluster-1"); err != nil {
+	if err := unit_utn.WaitOnPodState("mycluster-1", 150*time.Second, checkDone); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := unit_utn.Wait(checkDone, 150, 10, "mycluster-0"); err != nil {
+	if err := unit_utn.WaitOnPodState("mycluster-0", 150*time.Second, checkDone); err != nil {
 		t.Fatal(err)
 	}
 
@@ -246,9 +242,9 @@ func TestUpgradeToNext(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("BeforeUpgradeToNext", BeforeUpgradeToNext)
-	t.Run("UpgradeToNext", UpgradeToNext)
-	t.Run("AfterUpgradeToNext", AfterUpgradeToNext)
+	unit_utn.Run(t, "BeforeUpgradeToNext", BeforeUpgradeToNext)
+	unit_utn.Run(t, "UpgradeToNext", UpgradeToNext)
+	unit_utn.Run(t, "AfterUpgradeToNext", AfterUpgradeToNext)
 
 	err = unit_utn.Teardown()
 	if err != nil {
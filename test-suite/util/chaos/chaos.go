@@ -0,0 +1,217 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/log"
+)
+
+// a failure injected into the k8s cluster, reverting it restores the previous state (as far
+// as possible, e.g. a killed process is restarted by k8s, so there is nothing to revert)
+type Fault interface {
+	// a short description used in logs, e.g. "pause mysql in ns/mycluster-1"
+	String() string
+	Inject(ctx context.Context) error
+	Revert(ctx context.Context) error
+}
+
+// an expectation verified at the given point of a schedule, e.g. the cluster notices a fault,
+// so a test doesn't pass merely because the fault had no effect
+type Check interface {
+	// a short description used in logs, e.g. "mycluster-1 is UNREACHABLE"
+	String() string
+	// called for all checks before the first fault is injected, e.g. to capture the state
+	// changes are watched since
+	Prepare(ctx context.Context) error
+	// blocks until the expectation is met, it fails if it isn't met in time
+	Verify(ctx context.Context) error
+}
+
+// faults may need to set something up before the first one is injected, e.g. to not delay
+// the injection
+type preparer interface {
+	Prepare(ctx context.Context) error
+}
+
+// a fault scheduled at the given time since the start of the schedule, it is reverted after
+// the given duration, or at the end of the schedule if the duration is 0
+type step struct {
+	at       time.Duration
+	duration time.Duration
+	fault    Fault
+}
+
+// a check scheduled at the given time since the start of the schedule
+type expectation struct {
+	at    time.Duration
+	check Check
+}
+
+// a list of faults injected and reverted at the given times with the expectations verified
+// meanwhile, e.g.
+//
+//	schedule := chaos.NewSchedule().
+//		Add(0, 30*time.Second, chaos.NewPause(agents, namespace, "mycluster-1", k8s.Mysql)).
+//		Expect(0, unit.ExpectMemberState(namespace, "mycluster-0", "mycluster-1", "UNREACHABLE")).
+//		Add(10*time.Second, 0, chaos.NewKill(agents, namespace, "mycluster-2", k8s.Mysql, chaos.SIGKILL))
+//	err := schedule.Run(ctx)
+//
+// a check blocks the schedule, i.e. the following events are delayed until it passes
+type Schedule struct {
+	steps        []step
+	expectations []expectation
+}
+
+func NewSchedule() *Schedule {
+	return &Schedule{}
+}
+
+func (s *Schedule) Add(at time.Duration, duration time.Duration, fault Fault) *Schedule {
+	s.steps = append(s.steps, step{at: at, duration: duration, fault: fault})
+	return s
+}
+
+func (s *Schedule) Expect(at time.Duration, check Check) *Schedule {
+	s.expectations = append(s.expectations, expectation{at: at, check: check})
+	return s
+}
+
+// on a tie, reverts go first, then injects, then checks, so a check sees all faults
+// scheduled at its time
+type eventKind int
+
+const (
+	revertEvent eventKind = iota
+	injectEvent
+	checkEvent
+)
+
+type event struct {
+	at          time.Duration
+	kind        eventKind
+	step        *step
+	expectation *expectation
+}
+
+func (s *Schedule) getEvents() []event {
+	var events []event
+	for i := range s.steps {
+		step := &s.steps[i]
+		events = append(events, event{at: step.at, kind: injectEvent, step: step})
+		if step.duration > 0 {
+			events = append(events, event{at: step.at + step.duration, kind: revertEvent, step: step})
+		}
+	}
+	for i := range s.expectations {
+		expectation := &s.expectations[i]
+		events = append(events, event{at: expectation.at, kind: checkEvent, expectation: expectation})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].kind < events[j].kind
+	})
+	return events
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Schedule) prepare(ctx context.Context) error {
+	for _, step := range s.steps {
+		if p, ok := step.fault.(preparer); ok {
+			if err := p.Prepare(ctx); err != nil {
+				return fmt.Errorf("cannot prepare %s: %w", step.fault, err)
+			}
+		}
+	}
+	for _, expectation := range s.expectations {
+		if err := expectation.check.Prepare(ctx); err != nil {
+			return fmt.Errorf("cannot prepare check %s: %w", expectation.check, err)
+		}
+	}
+	return nil
+}
+
+// injects and reverts faults according to the schedule and verifies the checks, all faults
+// still injected at the end (or on failure) are reverted in the reverse order, also if ctx
+// is done
+func (s *Schedule) Run(ctx context.Context) (err error) {
+	if err := s.prepare(ctx); err != nil {
+		return err
+	}
+
+	var injected []*step
+	defer func() {
+		// the reverts have to happen even if the context is done
+		revertCtx := context.Background()
+		for i := len(injected) - 1; i >= 0; i-- {
+			fault := injected[i].fault
+			log.Info.Printf("chaos: revert %s", fault)
+			if revertErr := fault.Revert(revertCtx); revertErr != nil {
+				revertErr = fmt.Errorf("cannot revert %s: %w", fault, revertErr)
+				if err != nil {
+					// the first error is returned, the following ones are only reported
+					log.Error.Print(revertErr)
+				} else {
+					err = revertErr
+				}
+			}
+		}
+	}()
+
+	start := time.Now()
+	for _, event := range s.getEvents() {
+		if err := sleep(ctx, event.at-time.Since(start)); err != nil {
+			return err
+		}
+
+		switch event.kind {
+		case checkEvent:
+			check := event.expectation.check
+			log.Info.Printf("chaos: expect %s", check)
+			if err := check.Verify(ctx); err != nil {
+				return fmt.Errorf("expected %s: %w", check, err)
+			}
+
+		case injectEvent:
+			fault := event.step.fault
+			log.Info.Printf("chaos: inject %s", fault)
+			if err := fault.Inject(ctx); err != nil {
+				return fmt.Errorf("cannot inject %s: %w", fault, err)
+			}
+			injected = append(injected, event.step)
+
+		case revertEvent:
+			for i, step := range injected {
+				if step == event.step {
+					injected = append(injected[:i], injected[i+1:]...)
+					break
+				}
+			}
+			fault := event.step.fault
+			log.Info.Printf("chaos: revert %s", fault)
+			if err := fault.Revert(ctx); err != nil {
+				return fmt.Errorf("cannot revert %s: %w", fault, err)
+			}
+		}
+	}
+	return nil
+}
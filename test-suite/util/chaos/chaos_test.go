@@ -0,0 +1,235 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package chaos
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// records what happened to the faults and checks of a schedule, in order
+type recorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) get() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type fakeFault struct {
+	name      string
+	recorder  *recorder
+	injectErr error
+	// the context the fault was reverted with
+	revertCtx context.Context
+}
+
+func (f *fakeFault) String() string {
+	return f.name
+}
+
+func (f *fakeFault) Prepare(ctx context.Context) error {
+	f.recorder.add("prepare " + f.name)
+	return nil
+}
+
+func (f *fakeFault) Inject(ctx context.Context) error {
+	if f.injectErr != nil {
+		f.recorder.add("fail " + f.name)
+		return f.injectErr
+	}
+	f.recorder.add("inject " + f.name)
+	return nil
+}
+
+func (f *fakeFault) Revert(ctx context.Context) error {
+	f.revertCtx = ctx
+	f.recorder.add("revert " + f.name)
+	return nil
+}
+
+type fakeCheck struct {
+	name      string
+	recorder  *recorder
+	verifyErr error
+}
+
+func (c *fakeCheck) String() string {
+	return c.name
+}
+
+func (c *fakeCheck) Prepare(ctx context.Context) error {
+	c.recorder.add("prepare " + c.name)
+	return nil
+}
+
+func (c *fakeCheck) Verify(ctx context.Context) error {
+	c.recorder.add("verify " + c.name)
+	return c.verifyErr
+}
+
+func assertEvents(t *testing.T, r *recorder, expected []string) {
+	t.Helper()
+	if got := r.get(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected events\n%v\ngot\n%v", expected, got)
+	}
+}
+
+const tick = 10 * time.Millisecond
+
+func TestScheduleInjectionOrder(t *testing.T) {
+	r := &recorder{}
+	schedule := NewSchedule().
+		Add(2*tick, 0, &fakeFault{name: "c", recorder: r}).
+		Add(0, 3*tick, &fakeFault{name: "a", recorder: r}).
+		Add(tick, 0, &fakeFault{name: "b", recorder: r})
+
+	if err := schedule.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, r, []string{
+		"prepare c", "prepare a", "prepare b",
+		"inject a", "inject b", "inject c",
+		// a is reverted at its time, the remaining ones at the end in the reverse order
+		"revert a",
+		"revert c", "revert b",
+	})
+}
+
+func TestScheduleRevertsBeforeInjectsOnTie(t *testing.T) {
+	r := &recorder{}
+	schedule := NewSchedule().
+		Add(tick, 0, &fakeFault{name: "b", recorder: r}).
+		Add(0, tick, &fakeFault{name: "a", recorder: r})
+
+	if err := schedule.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, r, []string{
+		"prepare b", "prepare a",
+		"inject a", "revert a", "inject b",
+		"revert b",
+	})
+}
+
+func TestScheduleChecksRunAfterTheirFaults(t *testing.T) {
+	r := &recorder{}
+	// the checks are added before the faults scheduled at the same time
+	schedule := NewSchedule().
+		Expect(0, &fakeCheck{name: "check0", recorder: r}).
+		Expect(tick, &fakeCheck{name: "check1", recorder: r}).
+		Add(tick, 0, &fakeFault{name: "b", recorder: r}).
+		Add(0, tick, &fakeFault{name: "a", recorder: r})
+
+	if err := schedule.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, r, []string{
+		// all faults and checks are prepared before the first injection
+		"prepare b", "prepare a", "prepare check0", "prepare check1",
+		"inject a", "verify check0",
+		"revert a", "inject b", "verify check1",
+		"revert b",
+	})
+}
+
+func TestScheduleRevertsAllOnFailedCheck(t *testing.T) {
+	r := &recorder{}
+	checkErr := errors.New("not met")
+	schedule := NewSchedule().
+		Add(0, 0, &fakeFault{name: "a", recorder: r}).
+		Add(0, time.Hour, &fakeFault{name: "b", recorder: r}).
+		Expect(0, &fakeCheck{name: "check", recorder: r, verifyErr: checkErr}).
+		Add(tick, 0, &fakeFault{name: "c", recorder: r})
+
+	err := schedule.Run(context.Background())
+	if !errors.Is(err, checkErr) {
+		t.Fatalf("expected the error of the check, got %v", err)
+	}
+	assertEvents(t, r, []string{
+		"prepare a", "prepare b", "prepare c", "prepare check",
+		"inject a", "inject b", "verify check",
+		"revert b", "revert a",
+	})
+}
+
+func TestScheduleRevertsInjectedOnFailedInject(t *testing.T) {
+	r := &recorder{}
+	injectErr := errors.New("cannot inject")
+	schedule := NewSchedule().
+		Add(0, 0, &fakeFault{name: "a", recorder: r}).
+		Add(tick, 0, &fakeFault{name: "b", recorder: r, injectErr: injectErr}).
+		Add(2*tick, 0, &fakeFault{name: "c", recorder: r})
+
+	err := schedule.Run(context.Background())
+	if !errors.Is(err, injectErr) {
+		t.Fatalf("expected the error of the injection, got %v", err)
+	}
+	// the fault which failed to be injected isn't reverted
+	assertEvents(t, r, []string{
+		"prepare a", "prepare b", "prepare c",
+		"inject a", "fail b",
+		"revert a",
+	})
+}
+
+func TestScheduleRevertsAllOnCancel(t *testing.T) {
+	r := &recorder{}
+	a := &fakeFault{name: "a", recorder: r}
+	b := &fakeFault{name: "b", recorder: r}
+	schedule := NewSchedule().
+		Add(0, 0, a).
+		Add(0, time.Hour, b).
+		Add(time.Hour, 0, &fakeFault{name: "c", recorder: r})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(2 * tick)
+		cancel()
+	}()
+
+	err := schedule.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the schedule cancelled, got %v", err)
+	}
+	assertEvents(t, r, []string{
+		"prepare a", "prepare b", "prepare c",
+		"inject a", "inject b",
+		"revert b", "revert a",
+	})
+	// the reverts must not be abandoned with the cancelled context
+	for _, fault := range []*fakeFault{a, b} {
+		if fault.revertCtx == nil || fault.revertCtx.Err() != nil {
+			t.Errorf("%s reverted with a done context", fault.name)
+		}
+	}
+}
+
+func TestSchedulePermanentFaultIsNotReverted(t *testing.T) {
+	r := &recorder{}
+	schedule := NewSchedule().
+		Add(0, 0, Permanent(&fakeFault{name: "a", recorder: r})).
+		Add(0, 0, &fakeFault{name: "b", recorder: r})
+
+	if err := schedule.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertEvents(t, r, []string{
+		"prepare a", "prepare b",
+		"inject a", "inject b",
+		"revert b",
+	})
+}
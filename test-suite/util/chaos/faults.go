@@ -0,0 +1,490 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package chaos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/mysql"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// signal numbers as seen inside containers (linux), regardless of the host
+type Signal int
+
+const (
+	SIGKILL Signal = 9
+	SIGSEGV Signal = 11
+	SIGTERM Signal = 15
+	SIGCONT Signal = 18
+	SIGSTOP Signal = 19
+)
+
+// sends signals to the main process of a container from outside of it - inside of the
+// container it is the init of the pid namespace, the kernel drops SIGKILL and SIGSTOP sent
+// to it from there (and any signal it has no handler for)
+type Signaller interface {
+	// sets up whatever is needed to signal containers of the pod, e.g. an agent on its node
+	Prepare(ctx context.Context, namespace string, podName string) error
+	// fails with ProcessNotFoundError if the container doesn't run at the moment
+	Signal(ctx context.Context, namespace string, podName string, containerId k8s.ContainerId, signal Signal) error
+}
+
+type ProcessNotFoundError struct {
+	error
+}
+
+func NewProcessNotFoundError(namespace string, podName string, containerId k8s.ContainerId) error {
+	return ProcessNotFoundError{error: fmt.Errorf("no running process of %s in %s/%s",
+		k8s.GetContainerName(containerId), namespace, podName)}
+}
+
+func (e ProcessNotFoundError) Unwrap() error {
+	return e.error
+}
+
+func IsProcessNotFoundError(err error) bool {
+	var processNotFoundError ProcessNotFoundError
+	return errors.As(err, &processNotFoundError)
+}
+
+// ---------------------------
+
+// kills the main process of a container, k8s restarts it, so there is nothing to revert
+type killFault struct {
+	signaller   Signaller
+	namespace   string
+	podName     string
+	containerId k8s.ContainerId
+	signal      Signal
+}
+
+func NewKill(signaller Signaller, namespace string, podName string, containerId k8s.ContainerId, signal Signal) Fault {
+	return &killFault{signaller: signaller, namespace: namespace, podName: podName, containerId: containerId, signal: signal}
+}
+
+func (f *killFault) String() string {
+	return fmt.Sprintf("kill -%d %s in %s/%s", f.signal, k8s.GetContainerName(f.containerId), f.namespace, f.podName)
+}
+
+func (f *killFault) Prepare(ctx context.Context) error {
+	return f.signaller.Prepare(ctx, f.namespace, f.podName)
+}
+
+func (f *killFault) Inject(ctx context.Context) error {
+	return f.signaller.Signal(ctx, f.namespace, f.podName, f.containerId, f.signal)
+}
+
+func (f *killFault) Revert(ctx context.Context) error {
+	return nil
+}
+
+// ---------------------------
+
+// freezes the main process of a container, so it neither responds nor dies (unless its
+// liveness probe fails meanwhile, then k8s restarts it)
+type pauseFault struct {
+	signaller   Signaller
+	namespace   string
+	podName     string
+	containerId k8s.ContainerId
+}
+
+func NewPause(signaller Signaller, namespace string, podName string, containerId k8s.ContainerId) Fault {
+	return &pauseFault{signaller: signaller, namespace: namespace, podName: podName, containerId: containerId}
+}
+
+func (f *pauseFault) String() string {
+	return fmt.Sprintf("pause %s in %s/%s", k8s.GetContainerName(f.containerId), f.namespace, f.podName)
+}
+
+func (f *pauseFault) Prepare(ctx context.Context) error {
+	return f.signaller.Prepare(ctx, f.namespace, f.podName)
+}
+
+func (f *pauseFault) Inject(ctx context.Context) error {
+	return f.signaller.Signal(ctx, f.namespace, f.podName, f.containerId, SIGSTOP)
+}
+
+func (f *pauseFault) Revert(ctx context.Context) error {
+	err := f.signaller.Signal(ctx, f.namespace, f.podName, f.containerId, SIGCONT)
+	if IsProcessNotFoundError(err) {
+		// restarted meanwhile, so it isn't frozen anymore
+		return nil
+	}
+	return err
+}
+
+// ---------------------------
+
+// cuts a pod off the network (both ingress and egress) with a network policy
+type partitionFault struct {
+	client    *k8s.Client
+	namespace string
+	podName   string
+}
+
+func NewPartition(client *k8s.Client, namespace string, podName string) Fault {
+	return &partitionFault{client: client, namespace: namespace, podName: podName}
+}
+
+func (f *partitionFault) String() string {
+	return fmt.Sprintf("partition %s/%s", f.namespace, f.podName)
+}
+
+func (f *partitionFault) getPolicyName() string {
+	return "ote-chaos-partition-" + f.podName
+}
+
+func (f *partitionFault) Inject(ctx context.Context) error {
+	podSelector := map[string]string{k8s.StatefulSetPodNameLabel: f.podName}
+	policy := k8s.NewIsolatingNetworkPolicy(f.namespace, f.getPolicyName(), podSelector)
+	return f.client.CreateNetworkPolicy(policy)
+}
+
+func (f *partitionFault) Revert(ctx context.Context) error {
+	err := f.client.DeleteNetworkPolicy(f.namespace, f.getPolicyName())
+	if k8s.IsNotFoundError(err) {
+		return nil
+	}
+	return err
+}
+
+// ---------------------------
+
+// runs commands in the network namespace of a pod from outside of it, as the containers lack
+// both tc and the NET_ADMIN capability
+type NetworkExecutor interface {
+	// sets up whatever is needed to reach the network of the pod, e.g. an agent on its node
+	Prepare(ctx context.Context, namespace string, podName string) error
+	// fails with ProcessNotFoundError if no container of the pod runs at the moment
+	ExecuteInNetwork(ctx context.Context, namespace string, podName string, cmd ...string) error
+}
+
+// delays the outgoing traffic of a pod with netem
+type latencyFault struct {
+	executor  NetworkExecutor
+	namespace string
+	podName   string
+	delay     time.Duration
+}
+
+const networkDevice = "eth0"
+
+func NewLatency(executor NetworkExecutor, namespace string, podName string, delay time.Duration) Fault {
+	return &latencyFault{executor: executor, namespace: namespace, podName: podName, delay: delay}
+}
+
+func (f *latencyFault) String() string {
+	return fmt.Sprintf("latency %s on %s/%s", f.delay, f.namespace, f.podName)
+}
+
+func (f *latencyFault) Prepare(ctx context.Context) error {
+	return f.executor.Prepare(ctx, f.namespace, f.podName)
+}
+
+func (f *latencyFault) Inject(ctx context.Context) error {
+	delay := fmt.Sprintf("%dms", f.delay.Milliseconds())
+	return f.executor.ExecuteInNetwork(ctx, f.namespace, f.podName,
+		"tc", "qdisc", "add", "dev", networkDevice, "root", "netem", "delay", delay)
+}
+
+func (f *latencyFault) Revert(ctx context.Context) error {
+	err := f.executor.ExecuteInNetwork(ctx, f.namespace, f.podName,
+		"tc", "qdisc", "del", "dev", networkDevice, "root", "netem")
+	if IsProcessNotFoundError(err) {
+		// the pod was recreated meanwhile, with a new network namespace
+		return nil
+	}
+	return err
+}
+
+// ---------------------------
+
+// allocates a ballast file in the datadir, so mysqld runs out of space
+type fillDatadirFault struct {
+	client    *k8s.Client
+	namespace string
+	podName   string
+	size      string
+}
+
+const DatadirBallastPath = "/var/lib/mysql/ote-chaos-ballast"
+
+// the size is given as accepted by fallocate, e.g. 10G, if it exceeds the free space,
+// fallocate fails, then dd fills whatever is left
+func NewFillDatadir(client *k8s.Client, namespace string, podName string, size string) Fault {
+	return &fillDatadirFault{client: client, namespace: namespace, podName: podName, size: size}
+}
+
+func (f *fillDatadirFault) String() string {
+	return fmt.Sprintf("fill the datadir with %s in %s/%s", f.size, f.namespace, f.podName)
+}
+
+func (f *fillDatadirFault) Inject(ctx context.Context) error {
+	fillCmd := fmt.Sprintf("fallocate -l %s %s || dd if=/dev/zero of=%s bs=1M; sync; true",
+		f.size, DatadirBallastPath, DatadirBallastPath)
+	return f.client.Execute(f.namespace, f.podName, k8s.Mysql, "sh", "-c", fillCmd)
+}
+
+func (f *fillDatadirFault) Revert(ctx context.Context) error {
+	return f.client.Execute(f.namespace, f.podName, k8s.Mysql, "rm", "-f", DatadirBallastPath)
+}
+
+// ---------------------------
+
+// deletes a claim used by a pod, it stays terminating until the pod is gone, so reverting it
+// deletes the pod, then the statefulset recreates both the pod and its claim
+type deletePvcFault struct {
+	client    *k8s.Client
+	namespace string
+	pvcName   string
+	podName   string
+}
+
+// e.g. NewDeletePvc(client, namespace, "datadir-mycluster-1", "mycluster-1")
+func NewDeletePvc(client *k8s.Client, namespace string, pvcName string, podName string) Fault {
+	return &deletePvcFault{client: client, namespace: namespace, pvcName: pvcName, podName: podName}
+}
+
+func (f *deletePvcFault) String() string {
+	return fmt.Sprintf("delete pvc %s/%s of pod %s", f.namespace, f.pvcName, f.podName)
+}
+
+func (f *deletePvcFault) Inject(ctx context.Context) error {
+	return f.client.DeletePersistentVolumeClaim(f.namespace, f.pvcName)
+}
+
+func (f *deletePvcFault) Revert(ctx context.Context) error {
+	err := f.client.DeletePod(f.namespace, f.podName)
+	if k8s.IsNotFoundError(err) {
+		return nil
+	}
+	return err
+}
+
+// ---------------------------
+
+// marks a node unschedulable, the pods running there are left alone
+type cordonFault struct {
+	client   *k8s.Client
+	nodeName string
+}
+
+func NewCordon(client *k8s.Client, nodeName string) Fault {
+	return &cordonFault{client: client, nodeName: nodeName}
+}
+
+func (f *cordonFault) String() string {
+	return "cordon node " + f.nodeName
+}
+
+func (f *cordonFault) Inject(ctx context.Context) error {
+	return f.client.SetNodeUnschedulable(f.nodeName, true)
+}
+
+func (f *cordonFault) Revert(ctx context.Context) error {
+	return f.client.SetNodeUnschedulable(f.nodeName, false)
+}
+
+// ---------------------------
+
+// cordons a node and evicts its pods (except those of daemonsets and mirror pods), like
+// 'kubectl drain', reverting uncordons it, the evicted pods are rescheduled by their owners
+type drainFault struct {
+	cordonFault
+}
+
+func NewDrain(client *k8s.Client, nodeName string) Fault {
+	return &drainFault{cordonFault{client: client, nodeName: nodeName}}
+}
+
+func (f *drainFault) String() string {
+	return "drain node " + f.nodeName
+}
+
+func isEvictable(pod *corev1.Pod) bool {
+	if _, isMirror := pod.GetAnnotations()[corev1.MirrorPodAnnotationKey]; isMirror {
+		return false
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	controller := metav1.GetControllerOf(pod)
+	return controller == nil || controller.Kind != "DaemonSet"
+}
+
+func (f *drainFault) Inject(ctx context.Context) error {
+	if err := f.cordonFault.Inject(ctx); err != nil {
+		return err
+	}
+
+	pods, err := f.client.ListPodsOnNode(f.nodeName)
+	if err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isEvictable(pod) {
+			continue
+		}
+		err := f.client.EvictPod(pod.GetNamespace(), pod.GetName())
+		if err != nil && !k8s.IsNotFoundError(err) {
+			// e.g. blocked by a disruption budget, the pod keeps running there
+			log.Warning.Printf("cannot evict %s/%s: %v", pod.GetNamespace(), pod.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// ---------------------------
+
+// deletes a pod, its owner (e.g. the statefulset) recreates it, so there is nothing to revert
+type deletePodFault struct {
+	client    *k8s.Client
+	namespace string
+	podName   string
+}
+
+func NewDeletePod(client *k8s.Client, namespace string, podName string) Fault {
+	return &deletePodFault{client: client, namespace: namespace, podName: podName}
+}
+
+func (f *deletePodFault) String() string {
+	return fmt.Sprintf("delete pod %s/%s", f.namespace, f.podName)
+}
+
+func (f *deletePodFault) Inject(ctx context.Context) error {
+	// the deletion of an instance may wait for the operator busy with a previous one
+	const Timeout = 200
+	return f.client.DeletePodWithTimeout(f.namespace, f.podName, Timeout)
+}
+
+func (f *deletePodFault) Revert(ctx context.Context) error {
+	return nil
+}
+
+// ---------------------------
+
+// stops group replication on an instance, reverting starts it again, unless the operator
+// already did it
+type stopGroupReplicationFault struct {
+	client    *k8s.Client
+	namespace string
+	podName   string
+}
+
+func NewStopGroupReplication(client *k8s.Client, namespace string, podName string) Fault {
+	return &stopGroupReplicationFault{client: client, namespace: namespace, podName: podName}
+}
+
+func (f *stopGroupReplicationFault) String() string {
+	return fmt.Sprintf("stop group replication on %s/%s", f.namespace, f.podName)
+}
+
+func (f *stopGroupReplicationFault) exec(ctx context.Context, statement string) error {
+	session, err := mysql.NewSession(ctx, f.client, f.namespace, f.podName, common.RootUser, common.RootPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	_, err = session.Exec(statement)
+	return err
+}
+
+func (f *stopGroupReplicationFault) Inject(ctx context.Context) error {
+	return f.exec(ctx, "stop group_replication")
+}
+
+func (f *stopGroupReplicationFault) Revert(ctx context.Context) error {
+	session, err := mysql.NewSession(ctx, f.client, f.namespace, f.podName, common.RootUser, common.RootPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	// a stopped member sees itself OFFLINE
+	var state string
+	err = session.QueryOne(
+		"SELECT member_state FROM performance_schema.replication_group_members WHERE member_id = @@server_uuid").Scan(&state)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && state != "OFFLINE" {
+		log.Info.Printf("%s/%s is %s already", f.namespace, f.podName, state)
+		return nil
+	}
+	_, err = session.Exec("start group_replication")
+	return err
+}
+
+// ---------------------------
+
+// restarts mysqld with the RESTART statement, it is back on its own
+type restartServerFault struct {
+	client    *k8s.Client
+	namespace string
+	podName   string
+}
+
+func NewRestartServer(client *k8s.Client, namespace string, podName string) Fault {
+	return &restartServerFault{client: client, namespace: namespace, podName: podName}
+}
+
+func (f *restartServerFault) String() string {
+	return fmt.Sprintf("restart mysqld on %s/%s", f.namespace, f.podName)
+}
+
+func (f *restartServerFault) Inject(ctx context.Context) error {
+	session, err := mysql.NewSession(ctx, f.client, f.namespace, f.podName, common.RootUser, common.RootPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	_, err = session.Exec("restart")
+	return err
+}
+
+func (f *restartServerFault) Revert(ctx context.Context) error {
+	return nil
+}
+
+// ---------------------------
+
+// a fault which isn't reverted by the schedule, it is up to the operator to recover from it,
+// e.g. chaos.Permanent(chaos.NewStopGroupReplication(client, namespace, "mycluster-0"))
+type permanentFault struct {
+	fault Fault
+}
+
+func Permanent(fault Fault) Fault {
+	return &permanentFault{fault: fault}
+}
+
+func (f *permanentFault) String() string {
+	return f.fault.String() + " (permanently)"
+}
+
+func (f *permanentFault) Prepare(ctx context.Context) error {
+	if p, ok := f.fault.(preparer); ok {
+		return p.Prepare(ctx)
+	}
+	return nil
+}
+
+func (f *permanentFault) Inject(ctx context.Context) error {
+	return f.fault.Inject(ctx)
+}
+
+func (f *permanentFault) Revert(ctx context.Context) error {
+	return nil
+}
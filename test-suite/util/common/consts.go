@@ -24,5 +24,6 @@ const OciProfileDelete = "DELETE"
 
 const EnvMinikube = "minikube"
 const EnvK3d = "k3d"
+const EnvKind = "kind"
 
 const AnyResourceVersion = ""
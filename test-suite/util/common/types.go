@@ -11,6 +11,9 @@ const (
 	Start
 	Stop
 	Deploy
+	Status
+	Undeploy
+	Images
 )
 
 type StringSet map[string]struct{}
@@ -11,6 +11,8 @@ import (
 
 type docker_podman struct {
 	executable string
+	// docker takes insecure registries from the daemon config, podman needs a flag
+	insecurePushArgs []string
 }
 
 func (dp docker_podman) run(args ...string) error {
@@ -53,10 +55,115 @@ func (dp docker_podman) ConnectNetwork(context string, container string) error {
 	return dp.run("network", "connect", context, container)
 }
 
+func (dp docker_podman) getContainers(all bool) ([]string, error) {
+	args := []string{"ps", "--format", "{{.Names}}"}
+	if all {
+		args = append(args, "--all")
+	}
+	containers, err := dp.runGetOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(containers), nil
+}
+
+func (dp docker_podman) DoesContainerExist(container string) (bool, error) {
+	containers, err := dp.getContainers(true)
+	if err != nil {
+		return false, err
+	}
+
+	return auxi.Contains(containers, container), nil
+}
+
+func (dp docker_podman) IsContainerRunning(container string) (bool, error) {
+	containers, err := dp.getContainers(false)
+	if err != nil {
+		return false, err
+	}
+
+	return auxi.Contains(containers, container), nil
+}
+
+func (dp docker_podman) RunContainer(spec ContainerSpec) error {
+	args := []string{"run", "--detach", "--name", spec.Name}
+	for _, port := range spec.Ports {
+		args = append(args, "--publish", port)
+	}
+	for _, volume := range spec.Volumes {
+		args = append(args, "--volume", volume)
+	}
+	if len(spec.Network) > 0 {
+		args = append(args, "--network", spec.Network)
+	}
+	if len(spec.Restart) > 0 {
+		args = append(args, "--restart", spec.Restart)
+	}
+	args = append(args, spec.Image)
+	return dp.run(args...)
+}
+
+func (dp docker_podman) StartContainer(container string) error {
+	return dp.run("start", container)
+}
+
+func (dp docker_podman) StopContainer(container string) error {
+	return dp.run("stop", container)
+}
+
+func (dp docker_podman) RemoveContainer(container string) error {
+	return dp.run("rm", "--force", container)
+}
+
+func (dp docker_podman) getImageIds(image string) ([]string, error) {
+	ids, err := dp.runGetOutput("images", "--quiet", "--no-trunc", image)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(ids), nil
+}
+
+func (dp docker_podman) DoesImageExist(image string) (bool, error) {
+	ids, err := dp.getImageIds(image)
+	if err != nil {
+		return false, err
+	}
+
+	return len(ids) > 0, nil
+}
+
+func (dp docker_podman) GetImageId(image string) (string, error) {
+	id, err := dp.runGetOutput("image", "inspect", "--format", "{{.Id}}", image)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(id), nil
+}
+
+func (dp docker_podman) PullImage(image string) error {
+	return dp.run("pull", image)
+}
+
+func (dp docker_podman) TagImage(source string, target string) error {
+	return dp.run("tag", source, target)
+}
+
+func (dp docker_podman) PushImage(image string, insecure bool) error {
+	args := []string{"push"}
+	if insecure {
+		args = append(args, dp.insecurePushArgs...)
+	}
+	args = append(args, image)
+	return dp.run(args...)
+}
+
 func GetDocker() Engine {
 	return docker_podman{executable: "docker"}
 }
 
 func GetPodman() Engine {
-	return docker_podman{executable: "podman"}
+	return docker_podman{executable: "podman", insecurePushArgs: []string{"--tls-verify=false"}}
 }
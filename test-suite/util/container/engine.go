@@ -2,26 +2,44 @@
 
 package container
 
-// OBSOLETE - to remove as we intend to use (local) registry instead of loading images
-
 import (
 	"errors"
 	"strings"
 )
 
+type ContainerSpec struct {
+	Name    string
+	Image   string
+	Ports   []string
+	Volumes []string
+	Network string
+	Restart string
+}
+
+// a subset of docker/podman features used by the suite to manage images,
+// the local registry and other helper containers
 type Engine interface {
+	// networks
 	DoesNetworkExist(network string) (bool, error)
 	IsNetworkConnectedTo(network string, container string) (bool, error)
 	ConnectNetwork(context string, container string) error
-}
 
-// type Tool int
+	// containers
+	DoesContainerExist(container string) (bool, error)
+	IsContainerRunning(container string) (bool, error)
+	RunContainer(spec ContainerSpec) error
+	StartContainer(container string) error
+	StopContainer(container string) error
+	RemoveContainer(container string) error
 
-// const (
-// 	Auto Tool = iota
-// 	Docker
-// 	Podman
-// )
+	// images
+	DoesImageExist(image string) (bool, error)
+	GetImageId(image string) (string, error)
+	PullImage(image string) error
+	TagImage(source string, target string) error
+	// insecure - the registry is served over plain http
+	PushImage(image string, insecure bool) error
+}
 
 func GetEngine(name string) (Engine, error) {
 	switch strings.ToLower(name) {
@@ -0,0 +1,285 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package container
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
+)
+
+type fakeContainer struct {
+	spec    ContainerSpec
+	running bool
+}
+
+// in-memory engine for unit tests, it keeps the state of networks, containers,
+// images and registries, and records every call
+type FakeEngine struct {
+	mutex      sync.Mutex
+	networks   map[string][]string
+	containers map[string]*fakeContainer
+	images     map[string]string
+	pushed     map[string]string
+	calls      []string
+	nextId     int
+	// if set for a method name (e.g. "PullImage"), the method fails with it
+	Errors map[string]error
+}
+
+func NewFakeEngine() *FakeEngine {
+	return &FakeEngine{
+		networks:   make(map[string][]string),
+		containers: make(map[string]*fakeContainer),
+		images:     make(map[string]string),
+		pushed:     make(map[string]string),
+		Errors:     make(map[string]error),
+	}
+}
+
+func (f *FakeEngine) record(method string, args ...string) error {
+	f.calls = append(f.calls, strings.TrimSpace(method+" "+strings.Join(args, " ")))
+	return f.Errors[method]
+}
+
+func (f *FakeEngine) findContainer(container string) (*fakeContainer, error) {
+	fc, ok := f.containers[container]
+	if !ok {
+		return nil, fmt.Errorf("no such container: %s", container)
+	}
+	return fc, nil
+}
+
+// ---------------------------
+// setup and inspection of the fake state
+
+func (f *FakeEngine) AddNetwork(network string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, ok := f.networks[network]; !ok {
+		f.networks[network] = nil
+	}
+}
+
+func (f *FakeEngine) AddImage(image string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.addImage(image)
+}
+
+func (f *FakeEngine) addImage(image string) string {
+	f.nextId++
+	id := fmt.Sprintf("sha256:%064x", f.nextId)
+	f.images[image] = id
+	return id
+}
+
+// returns the id of the image pushed under the given name
+func (f *FakeEngine) GetPushedImageId(image string) (string, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id, ok := f.pushed[image]
+	return id, ok
+}
+
+// returns all calls in the form "<method> <args...>"
+func (f *FakeEngine) GetCalls() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// ---------------------------
+// networks
+
+func (f *FakeEngine) DoesNetworkExist(network string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("DoesNetworkExist", network); err != nil {
+		return false, err
+	}
+	_, ok := f.networks[network]
+	return ok, nil
+}
+
+func (f *FakeEngine) IsNetworkConnectedTo(network string, container string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("IsNetworkConnectedTo", network, container); err != nil {
+		return false, err
+	}
+	return auxi.Contains(f.networks[network], container), nil
+}
+
+func (f *FakeEngine) ConnectNetwork(context string, container string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("ConnectNetwork", context, container); err != nil {
+		return err
+	}
+	if _, err := f.findContainer(container); err != nil {
+		return err
+	}
+	f.networks[context] = append(f.networks[context], container)
+	return nil
+}
+
+// ---------------------------
+// containers
+
+func (f *FakeEngine) DoesContainerExist(container string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("DoesContainerExist", container); err != nil {
+		return false, err
+	}
+	_, ok := f.containers[container]
+	return ok, nil
+}
+
+func (f *FakeEngine) IsContainerRunning(container string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("IsContainerRunning", container); err != nil {
+		return false, err
+	}
+	fc, ok := f.containers[container]
+	return ok && fc.running, nil
+}
+
+func (f *FakeEngine) RunContainer(spec ContainerSpec) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("RunContainer", spec.Name, spec.Image); err != nil {
+		return err
+	}
+	if _, ok := f.containers[spec.Name]; ok {
+		return fmt.Errorf("container name %s is already in use", spec.Name)
+	}
+	if _, ok := f.images[spec.Image]; !ok {
+		f.addImage(spec.Image)
+	}
+	f.containers[spec.Name] = &fakeContainer{spec: spec, running: true}
+	if len(spec.Network) > 0 {
+		f.networks[spec.Network] = append(f.networks[spec.Network], spec.Name)
+	}
+	return nil
+}
+
+func (f *FakeEngine) StartContainer(container string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("StartContainer", container); err != nil {
+		return err
+	}
+	fc, err := f.findContainer(container)
+	if err != nil {
+		return err
+	}
+	fc.running = true
+	return nil
+}
+
+func (f *FakeEngine) StopContainer(container string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("StopContainer", container); err != nil {
+		return err
+	}
+	fc, err := f.findContainer(container)
+	if err != nil {
+		return err
+	}
+	fc.running = false
+	return nil
+}
+
+func (f *FakeEngine) RemoveContainer(container string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("RemoveContainer", container); err != nil {
+		return err
+	}
+	if _, err := f.findContainer(container); err != nil {
+		return err
+	}
+	delete(f.containers, container)
+	for network, containers := range f.networks {
+		var connected []string
+		for _, c := range containers {
+			if c != container {
+				connected = append(connected, c)
+			}
+		}
+		f.networks[network] = connected
+	}
+	return nil
+}
+
+// ---------------------------
+// images
+
+func (f *FakeEngine) DoesImageExist(image string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("DoesImageExist", image); err != nil {
+		return false, err
+	}
+	_, ok := f.images[image]
+	return ok, nil
+}
+
+func (f *FakeEngine) GetImageId(image string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("GetImageId", image); err != nil {
+		return "", err
+	}
+	id, ok := f.images[image]
+	if !ok {
+		return "", fmt.Errorf("no such image: %s", image)
+	}
+	return id, nil
+}
+
+func (f *FakeEngine) PullImage(image string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("PullImage", image); err != nil {
+		return err
+	}
+	if _, ok := f.images[image]; !ok {
+		f.addImage(image)
+	}
+	return nil
+}
+
+func (f *FakeEngine) TagImage(source string, target string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("TagImage", source, target); err != nil {
+		return err
+	}
+	id, ok := f.images[source]
+	if !ok {
+		return fmt.Errorf("no such image: %s", source)
+	}
+	f.images[target] = id
+	return nil
+}
+
+func (f *FakeEngine) PushImage(image string, insecure bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.record("PushImage", image); err != nil {
+		return err
+	}
+	id, ok := f.images[image]
+	if !ok {
+		return fmt.Errorf("no such image: %s", image)
+	}
+	f.pushed[image] = id
+	return nil
+}
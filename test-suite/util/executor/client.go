@@ -0,0 +1,17 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+)
+
+func newClient(cfg *setup.Configuration) (*k8s.Client, error) {
+	kubeCfg, err := k8s.BuildConfigForContext(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return k8s.NewClient(cfg, kubeCfg)
+}
@@ -17,6 +17,12 @@ func RunCommand(cmd common.Command, cfg *setup.Configuration) error {
 		return stop(cfg)
 	case common.Deploy:
 		return deploy(cfg)
+	case common.Undeploy:
+		return undeploy(cfg)
+	case common.Status:
+		return status(cfg)
+	case common.Images:
+		return images(cfg)
 	default:
 		return errors.New("internal error: unknown command")
 	}
@@ -12,6 +12,8 @@ import (
 	"github.com/marinesovitch/ote/test-suite/util/system"
 )
 
+const deploymentFileName = "ote-deployment.yaml"
+
 type Deployer struct {
 	cfg              *setup.Configuration
 	kustomizationDir string
@@ -84,15 +86,15 @@ func (d *Deployer) generateCustomOperatorYaml() error {
 
 // ---------------------------
 
-func (d *Deployer) deployOperator() error {
+func (d *Deployer) kustomizeDeployment() (string, error) {
 	kustomizationTestSuiteDir := d.kustomizationTestSuiteSubdir()
-	const deploymentFileName = "ote-deployment.yaml"
 	deploymentPath := d.cfg.GetOutputPath(deploymentFileName)
 	kubectl := k8s.Kubectl{}
-	if err := kubectl.Kustomize(kustomizationTestSuiteDir, deploymentPath); err != nil {
-		return err
-	}
+	return deploymentPath, kubectl.Kustomize(kustomizationTestSuiteDir, deploymentPath)
+}
 
+func (d *Deployer) deployOperator(deploymentPath string) error {
+	kubectl := k8s.Kubectl{}
 	if err := kubectl.Create(deploymentPath); err != nil {
 		// temporary patch
 		return kubectl.Apply(deploymentPath)
@@ -103,24 +105,33 @@ func (d *Deployer) deployOperator() error {
 
 // ---------------------------
 
-func (d *Deployer) run() error {
+func (d *Deployer) prepare() (string, error) {
 	if err := d.copyKustomizationSkeleton(); err != nil {
-		return err
+		return "", err
 	}
 
 	if err := d.copyBaseOperatorYamls(); err != nil {
-		return err
+		return "", err
 	}
 
 	if err := d.generateCustomOperatorYaml(); err != nil {
+		return "", err
+	}
+
+	return d.kustomizeDeployment()
+}
+
+func (d *Deployer) run() error {
+	deploymentPath, err := d.prepare()
+	if err != nil {
 		return err
 	}
 
-	if err := d.deployOperator(); err != nil {
+	if err := d.deployOperator(deploymentPath); err != nil {
 		return err
 	}
 
-	return nil
+	return waitOnOperatorReady(d.cfg)
 }
 
 func deploy(cfg *setup.Configuration) error {
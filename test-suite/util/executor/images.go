@@ -0,0 +1,128 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/container"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+)
+
+type imagesMirror struct {
+	cfg             *setup.Configuration
+	containerEngine container.Engine
+	env             k8s.K8sEnvironment
+}
+
+func isEnterpriseImage(item string) bool {
+	imageItem, err := setup.ParseImageItem(item)
+	if err != nil {
+		return false
+	}
+	return imageItem == setup.EnterpriseRouterImage || imageItem == setup.EnterpriseServerImage
+}
+
+func isS3Image(item string) bool {
+	imageItem, err := setup.ParseImageItem(item)
+	return err == nil && imageItem == setup.MinioImage
+}
+
+// the path of an image without the registry host (if any), e.g.
+// container-registry.oracle.com/mysql/community-server:8.0.31 => mysql/community-server:8.0.31
+func getRepositoryPath(image string) string {
+	host, rest, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return rest
+	}
+	return image
+}
+
+// mysql images are put into the configured repository, where the operator and tests look for
+// them, e.g. mysql/mysql-server:8.0.31 => registry.localhost:5000/qa/mysql-server:8.0.31, other
+// images keep their repository path, e.g. minio/minio:<tag> => registry.localhost:5000/minio/minio:<tag>
+func (i *imagesMirror) getTargetName(imageInfo setup.ImageInfo) string {
+	if isS3Image(imageInfo.Item) {
+		return setup.JoinImagePath(i.cfg.Images.Registry, getRepositoryPath(imageInfo.Name))
+	}
+	return setup.JoinImagePath(i.cfg.GetImageRegistryRepository(), path.Base(imageInfo.Name))
+}
+
+func (i *imagesMirror) mirror(imageInfo setup.ImageInfo) error {
+	image := imageInfo.Name
+	if imageInfo.Pull {
+		log.Info.Printf("pulling %s", image)
+		if err := i.containerEngine.PullImage(image); err != nil {
+			return err
+		}
+	} else {
+		exists, err := i.containerEngine.DoesImageExist(image)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("image %s is not available locally and is not marked to pull", image)
+		}
+	}
+
+	target := i.getTargetName(imageInfo)
+	if target != image {
+		log.Info.Printf("tagging %s as %s", image, target)
+		if err := i.containerEngine.TagImage(image, target); err != nil {
+			return err
+		}
+	}
+
+	if i.cfg.HasRegistry() {
+		log.Info.Printf("pushing %s", target)
+		insecure := strings.HasPrefix(i.cfg.GetRegistryUrl(), "http"+common.SchemaSeparator)
+		return i.containerEngine.PushImage(target, insecure)
+	}
+
+	log.Info.Printf("loading %s into %s", target, i.cfg.K8s.ClusterName)
+	return i.env.LoadImage(target)
+}
+
+func (i *imagesMirror) run(images []setup.ImageInfo) error {
+	for _, imageInfo := range images {
+		if isEnterpriseImage(imageInfo.Item) && !i.cfg.Enterprise.Enable {
+			log.Info.Printf("skipping enterprise image %s", imageInfo.Name)
+			continue
+		}
+
+		if isS3Image(imageInfo.Item) && !i.cfg.S3.Enable {
+			log.Info.Printf("skipping S3 image %s", imageInfo.Name)
+			continue
+		}
+
+		if err := i.mirror(imageInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func images(cfg *setup.Configuration) error {
+	var manifest setup.Images
+	if err := manifest.Load(cfg.Images.Manifest); err != nil {
+		return err
+	}
+
+	containerEngine, err := container.GetEngine(cfg.Images.Engine)
+	if err != nil {
+		return err
+	}
+
+	env, err := k8s.GetEnvironment(cfg)
+	if err != nil {
+		return err
+	}
+
+	mirror := imagesMirror{cfg: cfg, containerEngine: containerEngine, env: env}
+	return mirror.run(manifest.GetAll())
+}
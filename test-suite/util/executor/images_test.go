@@ -0,0 +1,137 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/marinesovitch/ote/test-suite/util/container"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+)
+
+// records images loaded into the cluster, i.e. when there is no registry
+type fakeEnvironment struct {
+	loaded []string
+}
+
+func (e *fakeEnvironment) StartCluster() error  { return nil }
+func (e *fakeEnvironment) StopCluster() error   { return nil }
+func (e *fakeEnvironment) DeleteCluster() error { return nil }
+
+func (e *fakeEnvironment) LoadImage(image string) error {
+	e.loaded = append(e.loaded, image)
+	return nil
+}
+
+func newImagesMirror(registry string, repository string) (*imagesMirror, *container.FakeEngine, *fakeEnvironment) {
+	cfg := &setup.Configuration{}
+	cfg.Images.Registry = registry
+	cfg.Images.Repository = repository
+	engine := container.NewFakeEngine()
+	env := &fakeEnvironment{}
+	return &imagesMirror{cfg: cfg, containerEngine: engine, env: env}, engine, env
+}
+
+func TestGetTargetName(t *testing.T) {
+	tests := []struct {
+		registry   string
+		repository string
+		image      setup.ImageInfo
+		expected   string
+	}{
+		{"registry.localhost:5000", "mysql", setup.ImageInfo{Item: "server", Name: "mysql/mysql-server:8.0.31"},
+			"registry.localhost:5000/mysql/mysql-server:8.0.31"},
+		{"registry.localhost:5000", "qa", setup.ImageInfo{Item: "router", Name: "mysql/mysql-router:8.0.31"},
+			"registry.localhost:5000/qa/mysql-router:8.0.31"},
+		{"", "mysql", setup.ImageInfo{Item: "server", Name: "mysql/mysql-server:8.0.31"}, "mysql/mysql-server:8.0.31"},
+		// no leading slash if there is neither a registry nor a repository
+		{"", "", setup.ImageInfo{Item: "server", Name: "mysql/mysql-server:8.0.31"}, "mysql-server:8.0.31"},
+		// minio keeps its own repository path, it isn't collapsed into the one of mysql images
+		{"registry.localhost:5000", "mysql", setup.ImageInfo{Item: "minio", Name: "minio/minio:RELEASE.2022-11-26T22-43-32Z"},
+			"registry.localhost:5000/minio/minio:RELEASE.2022-11-26T22-43-32Z"},
+		{"", "mysql", setup.ImageInfo{Item: "minio", Name: "minio/minio:RELEASE.2022-11-26T22-43-32Z"},
+			"minio/minio:RELEASE.2022-11-26T22-43-32Z"},
+		{"registry.localhost:5000", "mysql", setup.ImageInfo{Item: "minio", Name: "quay.io/minio/minio:RELEASE.2022-11-26T22-43-32Z"},
+			"registry.localhost:5000/minio/minio:RELEASE.2022-11-26T22-43-32Z"},
+	}
+	for _, test := range tests {
+		mirror, _, _ := newImagesMirror(test.registry, test.repository)
+		if target := mirror.getTargetName(test.image); target != test.expected {
+			t.Errorf("%s (registry '%s', repository '%s'): expected %s, got %s",
+				test.image.Name, test.registry, test.repository, test.expected, target)
+		}
+	}
+}
+
+func TestMirrorPushToRegistry(t *testing.T) {
+	mirror, engine, env := newImagesMirror("registry.localhost:5000", "mysql")
+
+	const Image = "mysql/mysql-server:8.0.31"
+	if err := mirror.mirror(setup.ImageInfo{Name: Image, Pull: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	const Target = "registry.localhost:5000/mysql/mysql-server:8.0.31"
+	expectedCalls := []string{
+		"PullImage " + Image,
+		"TagImage " + Image + " " + Target,
+		"PushImage " + Target,
+	}
+	if calls := engine.GetCalls(); !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, calls)
+	}
+	if _, ok := engine.GetPushedImageId(Target); !ok {
+		t.Errorf("%s not pushed", Target)
+	}
+	if len(env.loaded) > 0 {
+		t.Errorf("unexpected images loaded into the cluster %v", env.loaded)
+	}
+}
+
+func TestMirrorLoadIntoCluster(t *testing.T) {
+	mirror, engine, env := newImagesMirror("", "mysql")
+
+	// a local image, already named as expected, is neither pulled nor tagged
+	const Image = "mysql/mysql-router:8.0.31"
+	engine.AddImage(Image)
+	if err := mirror.mirror(setup.ImageInfo{Name: Image}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCalls := []string{"DoesImageExist " + Image}
+	if calls := engine.GetCalls(); !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, calls)
+	}
+	if !reflect.DeepEqual(env.loaded, []string{Image}) {
+		t.Errorf("expected %s loaded into the cluster, got %v", Image, env.loaded)
+	}
+}
+
+func TestMirrorMissingLocalImage(t *testing.T) {
+	mirror, engine, env := newImagesMirror("registry.localhost:5000", "mysql")
+
+	if err := mirror.mirror(setup.ImageInfo{Name: "mysql/mysql-server:8.0.31"}); err == nil {
+		t.Fatal("expected an error for an image neither available locally nor marked to pull")
+	}
+	if calls := engine.GetCalls(); len(calls) != 1 {
+		t.Errorf("expected only the check of the image, got %v", calls)
+	}
+	if len(env.loaded) > 0 {
+		t.Errorf("unexpected images loaded into the cluster %v", env.loaded)
+	}
+}
+
+func TestMirrorPullFailure(t *testing.T) {
+	mirror, engine, _ := newImagesMirror("registry.localhost:5000", "mysql")
+	pullErr := errors.New("pull access denied")
+	engine.Errors["PullImage"] = pullErr
+
+	if err := mirror.mirror(setup.ImageInfo{Name: "mysql/mysql-server:8.0.31", Pull: true}); !errors.Is(err, pullErr) {
+		t.Fatalf("expected %v, got %v", pullErr, err)
+	}
+	if calls := engine.GetCalls(); len(calls) != 1 {
+		t.Errorf("expected nothing after the failed pull, got %v", calls)
+	}
+}
@@ -0,0 +1,291 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type operatorReadiness struct {
+	cfg    *setup.Configuration
+	client *k8s.Client
+	// the operator deployment as observed when rolled out
+	deployment *appsv1.Deployment
+}
+
+// ---------------------------
+
+func checkCRDsEstablished(crds []*unstructured.Unstructured) (bool, string) {
+	established := make(map[string]bool)
+	for _, crd := range crds {
+		established[crd.GetName()] = k8s.IsCRDEstablished(crd)
+	}
+
+	for _, crdName := range k8s.GetOperatorCRDNames() {
+		isEstablished, found := established[crdName]
+		if !found {
+			return false, crdName + " not found"
+		}
+		if !isEstablished {
+			return false, crdName + " not established"
+		}
+	}
+	return true, ""
+}
+
+func (o *operatorReadiness) waitOnCRDsEstablished(ctx context.Context) error {
+	log.Info.Print("Waiting for CRDs to be established")
+	return o.client.WaitOnCustomResourceDefinitions(ctx, checkCRDsEstablished)
+}
+
+func (o *operatorReadiness) waitOnDeploymentRolledOut(ctx context.Context) error {
+	log.Info.Print("Waiting for operator deployment to roll out")
+	params := k8s.WatchParams{
+		Namespace:            common.OperatorNamespace,
+		Name:                 k8s.OperatorDeployment,
+		SinceResourceVersion: common.AnyResourceVersion,
+	}
+	return o.client.WaitOnDeployments(ctx, params, func(deployments []*appsv1.Deployment) (bool, string) {
+		if len(deployments) == 0 {
+			return false, "deployment not found"
+		}
+
+		deployment := deployments[0]
+		status := deployment.Status
+		state := fmt.Sprintf("replicas %d, updated %d, available %d", status.Replicas, status.UpdatedReplicas, status.AvailableReplicas)
+		if !k8s.IsDeploymentRolledOut(deployment) {
+			return false, state
+		}
+		o.deployment = deployment
+		return true, state
+	})
+}
+
+// ---------------------------
+
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// the ReplicaSet controlled by the deployment with its current revision, the ReplicaSets
+// of the previous revisions may still have pods being terminated
+func findCurrentReplicaSet(deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) *appsv1.ReplicaSet {
+	revision := deployment.GetAnnotations()[revisionAnnotation]
+	for i := range replicaSets {
+		replicaSet := &replicaSets[i]
+		owner := metav1.GetControllerOf(replicaSet)
+		if owner != nil && owner.UID == deployment.GetUID() && replicaSet.GetAnnotations()[revisionAnnotation] == revision {
+			return replicaSet
+		}
+	}
+	return nil
+}
+
+func (o *operatorReadiness) getCurrentReplicaSet() (*appsv1.ReplicaSet, error) {
+	replicaSets, err := o.client.ListReplicaSets(common.OperatorNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	replicaSet := findCurrentReplicaSet(o.deployment, replicaSets.Items)
+	if replicaSet == nil {
+		return nil, fmt.Errorf("no ReplicaSet of revision %s of deployment %s/%s",
+			o.deployment.GetAnnotations()[revisionAnnotation], common.OperatorNamespace, k8s.OperatorDeployment)
+	}
+	return replicaSet, nil
+}
+
+// the pods of the ReplicaSet which are ready and not being deleted
+func selectReadyPods(replicaSet *appsv1.ReplicaSet, pods []*corev1.Pod) []*corev1.Pod {
+	readyPods := []*corev1.Pod{}
+	for _, pod := range pods {
+		owner := metav1.GetControllerOf(pod)
+		if owner != nil && owner.UID == replicaSet.GetUID() && k8s.IsPodReady(pod) {
+			readyPods = append(readyPods, pod)
+		}
+	}
+	return readyPods
+}
+
+func (o *operatorReadiness) waitOnPodsReady(ctx context.Context, replicaSet *appsv1.ReplicaSet) ([]*corev1.Pod, error) {
+	log.Info.Printf("Waiting for pods of %s to be ready", replicaSet.GetName())
+	replicas := 1
+	if replicaSet.Spec.Replicas != nil {
+		replicas = int(*replicaSet.Spec.Replicas)
+	}
+
+	var readyPods []*corev1.Pod
+	params := k8s.WatchParams{
+		Namespace:            common.OperatorNamespace,
+		SinceResourceVersion: common.AnyResourceVersion,
+	}
+	err := o.client.WaitOnPods(ctx, params, func(pods []*corev1.Pod) (bool, string) {
+		readyPods = selectReadyPods(replicaSet, pods)
+		state := fmt.Sprintf("%d of %d pods of %s ready", len(readyPods), replicas, replicaSet.GetName())
+		return len(readyPods) >= replicas, state
+	})
+	return readyPods, err
+}
+
+// ---------------------------
+
+func findLogLine(logs io.Reader, rx *regexp.Regexp) (bool, error) {
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		if rx.Match(scanner.Bytes()) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// follows the logs of the pod until a line matches the pattern
+func (o *operatorReadiness) waitOnLogLine(ctx context.Context, podName string, rx *regexp.Regexp) error {
+	stream, err := o.client.FollowLogs(ctx, common.OperatorNamespace, podName, k8s.Operator)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	found, err := findLogLine(stream, rx)
+	switch {
+	case found:
+		return nil
+	case ctx.Err() != nil:
+		return fmt.Errorf("timeout waiting for pattern '%s' in logs of %s/%s", rx, common.OperatorNamespace, podName)
+	case err != nil:
+		return err
+	default:
+		return fmt.Errorf("pattern '%s' not found in logs of %s/%s, the container has stopped", rx, common.OperatorNamespace, podName)
+	}
+}
+
+func (o *operatorReadiness) waitOnHandlersRegistered(ctx context.Context) error {
+	rx, err := regexp.Compile(o.cfg.Operator.ReadyLogPattern)
+	if err != nil {
+		return err
+	}
+
+	replicaSet, err := o.getCurrentReplicaSet()
+	if err != nil {
+		return err
+	}
+
+	pods, err := o.waitOnPodsReady(ctx, replicaSet)
+	if err != nil {
+		return err
+	}
+
+	log.Info.Print("Waiting for operator handlers to be registered")
+	for _, pod := range pods {
+		if err := o.waitOnLogLine(ctx, pod.GetName(), rx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ---------------------------
+
+func (o *operatorReadiness) dumpOperatorLogs(diagnostics *bytes.Buffer) {
+	pods, err := o.client.ListPodsWithFilter(common.OperatorNamespace, k8s.OperatorDeployment+"-.*")
+	if err != nil {
+		fmt.Fprintf(diagnostics, "cannot list operator pods: %s\n", err)
+		return
+	}
+
+	for _, pod := range pods.Items {
+		fmt.Fprintf(diagnostics, "=== logs of %s/%s (phase %s)\n", common.OperatorNamespace, pod.GetName(), pod.Status.Phase)
+		logs, err := o.client.Logs(common.OperatorNamespace, pod.GetName(), k8s.Operator)
+		if err != nil {
+			fmt.Fprintf(diagnostics, "cannot get logs: %s\n", err)
+			continue
+		}
+		diagnostics.WriteString(logs)
+	}
+}
+
+func (o *operatorReadiness) dumpOperatorEvents(diagnostics *bytes.Buffer) {
+	fmt.Fprintf(diagnostics, "=== events in %s\n", common.OperatorNamespace)
+	events, err := o.client.ListEvents(common.OperatorNamespace, "", common.AnyResourceVersion)
+	if err != nil {
+		fmt.Fprintf(diagnostics, "cannot list events: %s\n", err)
+		return
+	}
+
+	for _, event := range events.Items {
+		fmt.Fprintf(diagnostics, "%s %s %s/%s %s: %s\n", event.LastTimestamp.Format(time.RFC3339), event.Type,
+			event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Reason, event.Message)
+	}
+}
+
+func (o *operatorReadiness) dumpDiagnostics() {
+	var diagnostics bytes.Buffer
+	o.dumpOperatorLogs(&diagnostics)
+	o.dumpOperatorEvents(&diagnostics)
+
+	log.Error.Print(diagnostics.String())
+
+	const DiagnosticsFileName = "operator-readiness.log"
+	diagnosticsPath := o.cfg.GetOutputPath(DiagnosticsFileName)
+	if err := os.WriteFile(diagnosticsPath, diagnostics.Bytes(), 0644); err != nil {
+		log.Error.Printf("cannot store diagnostics in %s: %s", diagnosticsPath, err)
+	}
+}
+
+// ---------------------------
+
+// the timeout is common for all stages
+func (o *operatorReadiness) run(ctx context.Context) error {
+	if err := o.waitOnCRDsEstablished(ctx); err != nil {
+		return err
+	}
+
+	if err := o.waitOnDeploymentRolledOut(ctx); err != nil {
+		return err
+	}
+
+	return o.waitOnHandlersRegistered(ctx)
+}
+
+func waitOnOperatorReady(cfg *setup.Configuration) error {
+	if cfg.Operator.ReadyTimeout <= 0 {
+		return nil
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	timeout := cfg.ScaleTimeout(time.Duration(cfg.Operator.ReadyTimeout) * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	readiness := operatorReadiness{
+		cfg:    cfg,
+		client: client,
+	}
+
+	if err := readiness.run(ctx); err != nil {
+		readiness.dumpDiagnostics()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestCRD(name string, established string) *unstructured.Unstructured {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	crd.SetName(name)
+	if len(established) > 0 {
+		conditions := []interface{}{map[string]interface{}{"type": "Established", "status": established}}
+		unstructured.SetNestedSlice(crd.Object, conditions, "status", "conditions")
+	}
+	return crd
+}
+
+func TestCheckCRDsEstablished(t *testing.T) {
+	names := k8s.GetOperatorCRDNames()
+	tests := []struct {
+		name     string
+		crds     []*unstructured.Unstructured
+		expected bool
+		state    string
+	}{
+		{"none", nil, false, names[0] + " not found"},
+		{"one missing", []*unstructured.Unstructured{newTestCRD(names[0], "True")}, false, names[1] + " not found"},
+		{
+			name:     "not established",
+			crds:     []*unstructured.Unstructured{newTestCRD(names[0], "True"), newTestCRD(names[1], "False")},
+			expected: false,
+			state:    names[1] + " not established",
+		},
+		{
+			name:     "no conditions yet",
+			crds:     []*unstructured.Unstructured{newTestCRD(names[0], ""), newTestCRD(names[1], "True")},
+			expected: false,
+			state:    names[0] + " not established",
+		},
+		{
+			// CRDs of others are ignored
+			name:     "established",
+			crds:     []*unstructured.Unstructured{newTestCRD("others.example.com", "False"), newTestCRD(names[0], "True"), newTestCRD(names[1], "True")},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		if established, state := checkCRDsEstablished(test.crds); established != test.expected || state != test.state {
+			t.Errorf("%s: expected %t '%s', got %t '%s'", test.name, test.expected, test.state, established, state)
+		}
+	}
+}
+
+// ---------------------------
+
+func newControllerRef(kind string, uid types.UID) []metav1.OwnerReference {
+	isController := true
+	return []metav1.OwnerReference{{Kind: kind, UID: uid, Controller: &isController}}
+}
+
+func newTestReplicaSet(name string, revision string, ownerUID types.UID) appsv1.ReplicaSet {
+	return appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		UID:             types.UID(name),
+		Annotations:     map[string]string{revisionAnnotation: revision},
+		OwnerReferences: newControllerRef("Deployment", ownerUID),
+	}}
+}
+
+func TestFindCurrentReplicaSet(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:        k8s.OperatorDeployment,
+		UID:         "deployment",
+		Annotations: map[string]string{revisionAnnotation: "2"},
+	}}
+
+	tests := []struct {
+		name        string
+		replicaSets []appsv1.ReplicaSet
+		expected    string
+	}{
+		{
+			name: "current revision",
+			replicaSets: []appsv1.ReplicaSet{
+				newTestReplicaSet("mysql-operator-old", "1", "deployment"),
+				newTestReplicaSet("mysql-operator-new", "2", "deployment"),
+			},
+			expected: "mysql-operator-new",
+		},
+		{
+			// e.g. left by a former deployment of the same name
+			name: "other owner",
+			replicaSets: []appsv1.ReplicaSet{
+				newTestReplicaSet("mysql-operator-stale", "2", "former-deployment"),
+			},
+			expected: "",
+		},
+		{
+			name: "only previous revision",
+			replicaSets: []appsv1.ReplicaSet{
+				newTestReplicaSet("mysql-operator-old", "1", "deployment"),
+			},
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		replicaSet := findCurrentReplicaSet(deployment, test.replicaSets)
+		name := ""
+		if replicaSet != nil {
+			name = replicaSet.GetName()
+		}
+		if name != test.expected {
+			t.Errorf("%s: expected '%s', got '%s'", test.name, test.expected, name)
+		}
+	}
+}
+
+// ---------------------------
+
+func newTestPod(name string, ownerUID types.UID, ready bool, deleting bool) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			OwnerReferences: newControllerRef("ReplicaSet", ownerUID),
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	if deleting {
+		now := metav1.Now()
+		pod.SetDeletionTimestamp(&now)
+	}
+	return pod
+}
+
+func TestSelectReadyPods(t *testing.T) {
+	replicaSet := newTestReplicaSet("mysql-operator-new", "2", "deployment")
+	pods := []*corev1.Pod{
+		newTestPod("mysql-operator-new-ready", "mysql-operator-new", true, false),
+		newTestPod("mysql-operator-new-starting", "mysql-operator-new", false, false),
+		newTestPod("mysql-operator-new-deleting", "mysql-operator-new", true, true),
+		newTestPod("mysql-operator-old-ready", "mysql-operator-old", true, false),
+		newTestPod("mysql-operator-old-deleting", "mysql-operator-old", true, true),
+	}
+
+	names := []string{}
+	for _, pod := range selectReadyPods(&replicaSet, pods) {
+		names = append(names, pod.GetName())
+	}
+	if expected := []string{"mysql-operator-new-ready"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestFindLogLine(t *testing.T) {
+	rx := regexp.MustCompile("Activity 'on_startup' succeeded")
+	tests := []struct {
+		logs     string
+		expected bool
+	}{
+		{"", false},
+		{"[INFO] kopf.activities.startup: Activity 'on_startup' succeeded.\n", true},
+		{"starting\n[INFO] kopf.activities.startup: Activity 'on_startup' succeeded.", true},
+		{"[INFO] kopf.activities.startup: Activity 'on_startup' failed\n", false},
+	}
+	for _, test := range tests {
+		found, err := findLogLine(strings.NewReader(test.logs), rx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != test.expected {
+			t.Errorf("%q: expected %t, got %t", test.logs, test.expected, found)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"github.com/marinesovitch/ote/test-suite/util/container"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+	"github.com/marinesovitch/ote/test-suite/util/system"
+)
+
+type localRegistry struct {
+	cfg             *setup.Configuration
+	containerEngine container.Engine
+	name            string
+}
+
+func (r *localRegistry) getPort() string {
+	const DefaultRegistryPort = "5000"
+	registryHostPort := r.cfg.GetRegistryHostPort()
+	if len(registryHostPort) < 2 || len(registryHostPort[1]) == 0 {
+		return DefaultRegistryPort
+	}
+	return registryHostPort[1]
+}
+
+func (r *localRegistry) run() error {
+	const RegistryInternalPort = "5000"
+	const RegistryDataPath = "/var/lib/registry"
+	spec := container.ContainerSpec{
+		Name:    r.name,
+		Image:   r.cfg.LocalRegistry.Image,
+		Ports:   []string{r.getPort() + ":" + RegistryInternalPort},
+		Volumes: []string{r.cfg.LocalRegistry.Volume + ":" + RegistryDataPath},
+		Restart: "always",
+	}
+	log.Info.Printf("creating local registry %s", r.name)
+	return r.containerEngine.RunContainer(spec)
+}
+
+func (r *localRegistry) ensureRunning() error {
+	exists, err := r.containerEngine.DoesContainerExist(r.name)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return r.run()
+	}
+
+	running, err := r.containerEngine.IsContainerRunning(r.name)
+	if err != nil || running {
+		return err
+	}
+
+	log.Info.Printf("starting local registry %s", r.name)
+	return r.containerEngine.StartContainer(r.name)
+}
+
+func (r *localRegistry) remove() error {
+	exists, err := r.containerEngine.DoesContainerExist(r.name)
+	if err != nil || !exists {
+		return err
+	}
+
+	log.Info.Printf("removing local registry %s", r.name)
+	return r.containerEngine.RemoveContainer(r.name)
+}
+
+// returns nil if the registry is not configured, not managed or not local
+func getLocalRegistry(cfg *setup.Configuration) (*localRegistry, error) {
+	if !cfg.HasRegistry() || !cfg.LocalRegistry.Manage {
+		return nil, nil
+	}
+
+	registryHost, err := cfg.GetRegistryHost()
+	if err != nil {
+		return nil, err
+	}
+
+	if loopback, err := system.IsLoopback(registryHost); !loopback || err != nil {
+		return nil, err
+	}
+
+	containerEngine, err := container.GetEngine(cfg.Images.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	// the container is named after the registry host, so it can be connected to
+	// the cluster network and reached from the nodes under the same name
+	return &localRegistry{cfg: cfg, containerEngine: containerEngine, name: registryHost}, nil
+}
+
+func startLocalRegistry(cfg *setup.Configuration) error {
+	registry, err := getLocalRegistry(cfg)
+	if registry == nil || err != nil {
+		return err
+	}
+	return registry.ensureRunning()
+}
+
+func removeLocalRegistry(cfg *setup.Configuration) error {
+	if !cfg.LocalRegistry.RemoveAtStop {
+		return nil
+	}
+
+	registry, err := getLocalRegistry(cfg)
+	if registry == nil || err != nil {
+		return err
+	}
+	return registry.remove()
+}
@@ -0,0 +1,85 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marinesovitch/ote/test-suite/util/container"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+)
+
+const testRegistryName = "registry.localhost"
+
+func newLocalRegistry() (*localRegistry, *container.FakeEngine) {
+	cfg := &setup.Configuration{}
+	cfg.Images.Registry = testRegistryName + ":5001"
+	cfg.LocalRegistry.Image = "registry:2"
+	cfg.LocalRegistry.Volume = "ote-registry"
+	engine := container.NewFakeEngine()
+	return &localRegistry{cfg: cfg, containerEngine: engine, name: testRegistryName}, engine
+}
+
+func assertCalls(t *testing.T, engine *container.FakeEngine, expectedCalls []string) {
+	if calls := engine.GetCalls(); !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, calls)
+	}
+}
+
+func assertRegistryRunning(t *testing.T, engine *container.FakeEngine) {
+	if running, _ := engine.IsContainerRunning(testRegistryName); !running {
+		t.Errorf("the registry %s is not running", testRegistryName)
+	}
+}
+
+func TestEnsureRunningCreates(t *testing.T) {
+	registry, engine := newLocalRegistry()
+
+	if err := registry.ensureRunning(); err != nil {
+		t.Fatal(err)
+	}
+	assertCalls(t, engine, []string{
+		"DoesContainerExist " + testRegistryName,
+		"RunContainer " + testRegistryName + " registry:2",
+	})
+	assertRegistryRunning(t, engine)
+}
+
+func TestEnsureRunningStartsStopped(t *testing.T) {
+	registry, engine := newLocalRegistry()
+	if err := registry.run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.StopContainer(testRegistryName); err != nil {
+		t.Fatal(err)
+	}
+	callsBefore := len(engine.GetCalls())
+
+	if err := registry.ensureRunning(); err != nil {
+		t.Fatal(err)
+	}
+	assertCalls(t, engine, append(engine.GetCalls()[:callsBefore],
+		"DoesContainerExist "+testRegistryName,
+		"IsContainerRunning "+testRegistryName,
+		"StartContainer "+testRegistryName,
+	))
+	assertRegistryRunning(t, engine)
+}
+
+func TestEnsureRunningKeepsRunning(t *testing.T) {
+	registry, engine := newLocalRegistry()
+	if err := registry.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.ensureRunning(); err != nil {
+		t.Fatal(err)
+	}
+	// neither created again nor restarted
+	assertCalls(t, engine, []string{
+		"RunContainer " + testRegistryName + " registry:2",
+		"DoesContainerExist " + testRegistryName,
+		"IsContainerRunning " + testRegistryName,
+	})
+}
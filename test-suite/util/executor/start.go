@@ -20,6 +20,12 @@ func start(cfg *setup.Configuration) error {
 		}
 	}
 
+	// the registry has to run before the cluster starts to get connected to its network
+	err = startLocalRegistry(cfg)
+	if err != nil {
+		return err
+	}
+
 	err = env.StartCluster()
 	if err != nil {
 		return err
@@ -0,0 +1,218 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+)
+
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Details string `json:"details"`
+}
+
+type StatusReport struct {
+	Healthy    bool              `json:"healthy"`
+	Components []ComponentStatus `json:"components"`
+}
+
+type statusCollector struct {
+	cfg    *setup.Configuration
+	client *k8s.Client
+}
+
+func healthy(name string, details string) ComponentStatus {
+	return ComponentStatus{Name: name, Healthy: true, Details: details}
+}
+
+func unhealthy(name string, details string) ComponentStatus {
+	return ComponentStatus{Name: name, Healthy: false, Details: details}
+}
+
+// ---------------------------
+
+func (s *statusCollector) checkCluster() ComponentStatus {
+	const Name = "cluster"
+	context := s.cfg.GetContextName()
+
+	kubeCfg, err := k8s.BuildConfigForContext(s.cfg)
+	if err != nil {
+		return unhealthy(Name, err.Error())
+	}
+
+	client, err := k8s.NewClient(s.cfg, kubeCfg)
+	if err != nil {
+		return unhealthy(Name, err.Error())
+	}
+
+	version, err := client.GetServerVersion()
+	if err != nil {
+		return unhealthy(Name, fmt.Sprintf("context %s is unreachable: %s", context, err))
+	}
+
+	s.client = client
+	return healthy(Name, fmt.Sprintf("context %s, server %s", context, version))
+}
+
+// ---------------------------
+
+func (s *statusCollector) checkRegistry() ComponentStatus {
+	const Name = "registry"
+	if !s.cfg.HasRegistry() {
+		return healthy(Name, "not configured")
+	}
+
+	registryUrl := s.cfg.GetRegistryUrl()
+	httpClient := http.Client{Timeout: 5 * time.Second}
+	const RegistryApiPath = "/v2/"
+	response, err := httpClient.Get(registryUrl + RegistryApiPath)
+	if err != nil {
+		return unhealthy(Name, err.Error())
+	}
+	defer response.Body.Close()
+
+	// a registry requiring authentication answers too
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusUnauthorized {
+		return unhealthy(Name, fmt.Sprintf("%s answered with %s", registryUrl, response.Status))
+	}
+
+	return healthy(Name, registryUrl)
+}
+
+// ---------------------------
+
+func getImageTag(image string) string {
+	const TagSeparator = ":"
+	tagPos := strings.LastIndex(image, TagSeparator)
+	if tagPos == -1 || strings.Contains(image[tagPos:], "/") {
+		return "latest"
+	}
+	return image[tagPos+len(TagSeparator):]
+}
+
+func (s *statusCollector) checkOperator() ComponentStatus {
+	const Name = "operator"
+	if s.client == nil {
+		return unhealthy(Name, "cluster is unreachable")
+	}
+
+	deployment, err := s.client.GetDeployment(common.OperatorNamespace, k8s.OperatorDeployment)
+	if err != nil {
+		if k8s.IsNotFoundError(err) {
+			return unhealthy(Name, "not deployed")
+		}
+		return unhealthy(Name, err.Error())
+	}
+
+	var image string
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		image = containers[0].Image
+	}
+
+	details := fmt.Sprintf("%s/%s, image %s, tag %s", common.OperatorNamespace, k8s.OperatorDeployment, image, getImageTag(image))
+	if !k8s.IsDeploymentAvailable(deployment) {
+		return unhealthy(Name, "not available: "+details)
+	}
+	return healthy(Name, details)
+}
+
+// ---------------------------
+
+func (s *statusCollector) checkCRDs() ComponentStatus {
+	const Name = "crds"
+	if s.client == nil {
+		return unhealthy(Name, "cluster is unreachable")
+	}
+
+	var problems []string
+	crdNames := k8s.GetOperatorCRDNames()
+	for _, crdName := range crdNames {
+		crd, err := s.client.GetCustomResourceDefinition(crdName)
+		if err != nil {
+			if k8s.IsNotFoundError(err) {
+				problems = append(problems, crdName+" not installed")
+			} else {
+				problems = append(problems, fmt.Sprintf("%s: %s", crdName, err))
+			}
+			continue
+		}
+
+		if !k8s.IsCRDEstablished(crd) {
+			problems = append(problems, crdName+" not established")
+		}
+	}
+
+	if len(problems) > 0 {
+		return unhealthy(Name, strings.Join(problems, ", "))
+	}
+	return healthy(Name, strings.Join(crdNames, ", "))
+}
+
+// ---------------------------
+
+func (s *statusCollector) run() StatusReport {
+	components := []ComponentStatus{
+		s.checkCluster(),
+		s.checkRegistry(),
+		s.checkOperator(),
+		s.checkCRDs(),
+	}
+
+	report := StatusReport{Healthy: true, Components: components}
+	for _, component := range components {
+		if !component.Healthy {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+func printStatusText(report StatusReport) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, component := range report.Components {
+		state := "OK"
+		if !component.Healthy {
+			state = "FAIL"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", component.Name, state, component.Details)
+	}
+	return writer.Flush()
+}
+
+func printStatusJson(report StatusReport) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(report)
+}
+
+func status(cfg *setup.Configuration) error {
+	collector := statusCollector{cfg: cfg}
+	report := collector.run()
+
+	var err error
+	if cfg.Status.Format == "json" {
+		err = printStatusJson(report)
+	} else {
+		err = printStatusText(report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !report.Healthy {
+		return errors.New("the environment is unhealthy")
+	}
+	return nil
+}
@@ -25,5 +25,5 @@ func stop(cfg *setup.Configuration) error {
 		}
 	}
 
-	return nil
+	return removeLocalRegistry(cfg)
 }
@@ -0,0 +1,102 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package executor
+
+import (
+	"os"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+	"github.com/marinesovitch/ote/test-suite/util/suite"
+	"github.com/marinesovitch/ote/test-suite/util/system"
+)
+
+const undeploymentFileName = "ote-undeployment.yaml"
+
+type Undeployer struct {
+	cfg *setup.Configuration
+}
+
+// ---------------------------
+
+func (u *Undeployer) wipeOperatorResources() error {
+	client, err := newClient(u.cfg)
+	if err != nil {
+		return err
+	}
+
+	return suite.WipeOperatorResources(client)
+}
+
+// ---------------------------
+
+func (u *Undeployer) resolveDeploymentPath() (string, error) {
+	deploymentPath := u.cfg.GetOutputPath(deploymentFileName)
+	if system.DoesFileExist(deploymentPath) {
+		return deploymentPath, nil
+	}
+
+	// nothing was deployed from this output directory, so recreate the deployment
+	deployer := Deployer{cfg: u.cfg}
+	return deployer.prepare()
+}
+
+func splitYamlDocuments(yaml string) []string {
+	const DocumentSeparator = "\n---"
+	var documents []string
+	for _, document := range strings.Split("\n"+yaml, DocumentSeparator) {
+		if strings.TrimSpace(document) != "" {
+			documents = append(documents, strings.TrimPrefix(document, "\n"))
+		}
+	}
+	return documents
+}
+
+func (u *Undeployer) generateReversedDeployment(deploymentPath string) (string, error) {
+	deployment, err := os.ReadFile(deploymentPath)
+	if err != nil {
+		return "", err
+	}
+
+	// delete resources in the reverse order of creation, i.e. the operator
+	// deployment goes first and its namespace and CRDs at the very end
+	documents := splitYamlDocuments(string(deployment))
+	for i, j := 0, len(documents)-1; i < j; i, j = i+1, j-1 {
+		documents[i], documents[j] = documents[j], documents[i]
+	}
+
+	undeploymentPath := u.cfg.GetOutputPath(undeploymentFileName)
+	undeployment := strings.Join(documents, "\n---\n")
+	return undeploymentPath, os.WriteFile(undeploymentPath, []byte(undeployment), 0644)
+}
+
+func (u *Undeployer) undeployOperator() error {
+	deploymentPath, err := u.resolveDeploymentPath()
+	if err != nil {
+		return err
+	}
+
+	undeploymentPath, err := u.generateReversedDeployment(deploymentPath)
+	if err != nil {
+		return err
+	}
+
+	kubectl := k8s.Kubectl{}
+	return kubectl.Delete(undeploymentPath)
+}
+
+// ---------------------------
+
+func (u *Undeployer) run() error {
+	if err := u.wipeOperatorResources(); err != nil {
+		return err
+	}
+
+	return u.undeployOperator()
+}
+
+func undeploy(cfg *setup.Configuration) error {
+	undeployer := Undeployer{cfg: cfg}
+	return undeployer.run()
+}
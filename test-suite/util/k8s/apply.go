@@ -0,0 +1,210 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/log"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the owner of fields set by the test suite, in terms of server-side apply
+const FieldManager = "ote"
+
+// resolves kinds into resources, the discovery is cached, so the mapper is created once per client
+func (c *Client) getRESTMapper() *restmapper.DeferredDiscoveryRESTMapper {
+	c.restMapperOnce.Do(func() {
+		c.restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(c.clientset.Discovery()))
+	})
+	return c.restMapper
+}
+
+func (c *Client) getRESTMapping(obj *unstructured.Unstructured) (*meta.RESTMapping, error) {
+	gvk := obj.GroupVersionKind()
+	mapper := c.getRESTMapper()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// the kind may come with a CRD installed after the discovery was cached
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	return mapping, err
+}
+
+// splits a multi-document yaml into objects, empty documents are skipped
+func decodeObjects(content []byte) ([]*unstructured.Unstructured, error) {
+	const BufferSize = 4096
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), BufferSize)
+	var objects []*unstructured.Unstructured
+	for {
+		var fields map[string]interface{}
+		if err := decoder.Decode(&fields); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: fields})
+	}
+	return objects, nil
+}
+
+// e.g. innodbcluster.mysql.oracle.com/mycluster
+func describeAppliedObject(mapping *meta.RESTMapping, obj *unstructured.Unstructured) string {
+	kind := strings.ToLower(mapping.GroupVersionKind.Kind)
+	if len(mapping.GroupVersionKind.Group) > 0 {
+		kind += "." + mapping.GroupVersionKind.Group
+	}
+	return fmt.Sprintf("%s/%s", kind, obj.GetName())
+}
+
+// the client of the resource of the object, objects without a namespace are put into the given one
+func (c *Client) getResourceClient(namespace string, obj *unstructured.Unstructured) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	mapping, err := c.getRESTMapping(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resourceClient dynamic.ResourceInterface = c.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if len(obj.GetNamespace()) == 0 {
+			obj.SetNamespace(namespace)
+		}
+		resourceClient = c.dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	}
+	return resourceClient, mapping, nil
+}
+
+func (c *Client) applyObject(ctx context.Context, namespace string, obj *unstructured.Unstructured) (string, error) {
+	if len(obj.GetName()) == 0 {
+		// the patch goes to the url of the object, use Create to get the verdict of the server
+		return "", fmt.Errorf("%s without a name cannot be applied", obj.GetKind())
+	}
+
+	resourceClient, mapping, err := c.getResourceClient(namespace, obj)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+
+	force := true
+	options := metav1.PatchOptions{
+		FieldManager:    FieldManager,
+		Force:           &force,
+		FieldValidation: metav1.FieldValidationStrict,
+	}
+	err = retryOnTransientError(func() error {
+		_, err := resourceClient.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, options)
+		return classifyApiError(err)
+	})
+	if err != nil {
+		return "", err
+	}
+	return describeAppliedObject(mapping, obj) + " serverside-applied", nil
+}
+
+// a plain create (POST), unlike a server-side apply the server validates the object as a whole
+// and reports the rejected fields in the details of its status, e.g. for a lack of name
+func (c *Client) createObject(ctx context.Context, namespace string, obj *unstructured.Unstructured) (string, error) {
+	resourceClient, mapping, err := c.getResourceClient(namespace, obj)
+	if err != nil {
+		return "", err
+	}
+
+	options := metav1.CreateOptions{
+		FieldManager:    FieldManager,
+		FieldValidation: metav1.FieldValidationStrict,
+	}
+	err = retryOnTransientError(func() error {
+		_, err := resourceClient.Create(ctx, obj, options)
+		return classifyApiError(err)
+	})
+	if err != nil {
+		return "", err
+	}
+	return describeAppliedObject(mapping, obj) + " created", nil
+}
+
+type objectHandler func(ctx context.Context, namespace string, obj *unstructured.Unstructured) (string, error)
+
+func (c *Client) handleYaml(ctx context.Context, namespace string, content []byte, handler objectHandler) (string, error) {
+	objects, err := decodeObjects(content)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for _, obj := range objects {
+		result, err := handler(ctx, namespace, obj)
+		if err != nil {
+			return output.String(), err
+		}
+		log.Info.With(log.Fields{log.NamespaceField: namespace}).Print(result)
+		output.WriteString(result + "\n")
+	}
+	return output.String(), nil
+}
+
+// applies all objects of a multi-document yaml (server-side), objects without a namespace are
+// put into the given one, the output lists the applied objects in the style of kubectl
+func (c *Client) ApplyYaml(ctx context.Context, namespace string, content []byte) (string, error) {
+	return c.handleYaml(ctx, namespace, content, c.applyObject)
+}
+
+// creates all objects of a multi-document yaml, they must not exist yet
+func (c *Client) CreateYaml(ctx context.Context, namespace string, content []byte) (string, error) {
+	return c.handleYaml(ctx, namespace, content, c.createObject)
+}
+
+func (c *Client) ApplyGetOutput(namespace string, path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	output, err := c.ApplyYaml(context.Background(), namespace, content)
+	if err != nil {
+		return output, fmt.Errorf("cannot apply %s: %w", path, err)
+	}
+	return output, nil
+}
+
+func (c *Client) CreateGetOutput(namespace string, path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	output, err := c.CreateYaml(context.Background(), namespace, content)
+	if err != nil {
+		return output, fmt.Errorf("cannot create %s: %w", path, err)
+	}
+	return output, nil
+}
+
+func (c *Client) Apply(namespace string, path string) error {
+	_, err := c.ApplyGetOutput(namespace, path)
+	return err
+}
@@ -0,0 +1,193 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// builds an InnoDBCluster object in code, so a test doesn't need its own yaml template, e.g.
+//
+//	ic := k8s.NewInnoDBClusterBuilder("mycluster").
+//		Instances(3).
+//		Routers(2).
+//		SecretName("mypwds").
+//		TlsUseSelfSigned().
+//		Build()
+type InnoDBClusterBuilder struct {
+	ic InnoDBCluster
+}
+
+func NewInnoDBClusterBuilder(name string) *InnoDBClusterBuilder {
+	b := &InnoDBClusterBuilder{}
+	b.ic.APIVersion = OperatorGroup + "/" + OperatorVersion
+	b.ic.Kind = "InnoDBCluster"
+	b.ic.Name = name
+	return b
+}
+
+// if not set, the namespace is assigned at creation
+func (b *InnoDBClusterBuilder) Namespace(namespace string) *InnoDBClusterBuilder {
+	b.ic.Namespace = namespace
+	return b
+}
+
+func (b *InnoDBClusterBuilder) Labels(labels map[string]string) *InnoDBClusterBuilder {
+	b.ic.Labels = labels
+	return b
+}
+
+func (b *InnoDBClusterBuilder) Instances(instances int) *InnoDBClusterBuilder {
+	b.ic.Spec.Instances = instances
+	return b
+}
+
+func (b *InnoDBClusterBuilder) Routers(instances int) *InnoDBClusterBuilder {
+	b.ic.Spec.Router.Instances = &instances
+	return b
+}
+
+func (b *InnoDBClusterBuilder) RouterVersion(version string) *InnoDBClusterBuilder {
+	b.ic.Spec.Router.Version = version
+	return b
+}
+
+func (b *InnoDBClusterBuilder) RouterPodSpec(podSpec map[string]interface{}) *InnoDBClusterBuilder {
+	b.ic.Spec.Router.PodSpec = podSpec
+	return b
+}
+
+func (b *InnoDBClusterBuilder) Version(version string) *InnoDBClusterBuilder {
+	b.ic.Spec.Version = version
+	return b
+}
+
+func (b *InnoDBClusterBuilder) Edition(edition string) *InnoDBClusterBuilder {
+	b.ic.Spec.Edition = edition
+	return b
+}
+
+func (b *InnoDBClusterBuilder) ImageRepository(repository string) *InnoDBClusterBuilder {
+	b.ic.Spec.ImageRepository = repository
+	return b
+}
+
+func (b *InnoDBClusterBuilder) ImagePullPolicy(policy corev1.PullPolicy) *InnoDBClusterBuilder {
+	b.ic.Spec.ImagePullPolicy = policy
+	return b
+}
+
+func (b *InnoDBClusterBuilder) SecretName(secretName string) *InnoDBClusterBuilder {
+	b.ic.Spec.SecretName = secretName
+	return b
+}
+
+func (b *InnoDBClusterBuilder) TlsUseSelfSigned() *InnoDBClusterBuilder {
+	b.ic.Spec.TlsUseSelfSigned = true
+	return b
+}
+
+func (b *InnoDBClusterBuilder) TlsSecrets(caSecretName string, tlsSecretName string) *InnoDBClusterBuilder {
+	b.ic.Spec.TlsUseSelfSigned = false
+	b.ic.Spec.TlsCASecretName = caSecretName
+	b.ic.Spec.TlsSecretName = tlsSecretName
+	return b
+}
+
+func (b *InnoDBClusterBuilder) BaseServerId(baseServerId int) *InnoDBClusterBuilder {
+	b.ic.Spec.BaseServerId = baseServerId
+	return b
+}
+
+func (b *InnoDBClusterBuilder) Mycnf(mycnf string) *InnoDBClusterBuilder {
+	b.ic.Spec.Mycnf = mycnf
+	return b
+}
+
+// the podSpec is merged by the operator into the spec of server pods, e.g.
+// {"terminationGracePeriodSeconds": 60} or {"containers": [...]}
+func (b *InnoDBClusterBuilder) PodSpec(podSpec map[string]interface{}) *InnoDBClusterBuilder {
+	b.ic.Spec.PodSpec = podSpec
+	return b
+}
+
+func (b *InnoDBClusterBuilder) DatadirVolumeClaimTemplate(template map[string]interface{}) *InnoDBClusterBuilder {
+	b.ic.Spec.DatadirVolumeClaimTemplate = template
+	return b
+}
+
+func (b *InnoDBClusterBuilder) BackupProfile(profile BackupProfile) *InnoDBClusterBuilder {
+	b.ic.Spec.BackupProfiles = append(b.ic.Spec.BackupProfiles, profile)
+	return b
+}
+
+func (b *InnoDBClusterBuilder) BackupSchedule(schedule BackupSchedule) *InnoDBClusterBuilder {
+	b.ic.Spec.BackupSchedules = append(b.ic.Spec.BackupSchedules, schedule)
+	return b
+}
+
+func (b *InnoDBClusterBuilder) InitDB(initDB InitDB) *InnoDBClusterBuilder {
+	b.ic.Spec.InitDB = &initDB
+	return b
+}
+
+func (b *InnoDBClusterBuilder) Service(service InnoDBClusterService) *InnoDBClusterBuilder {
+	b.ic.Spec.Service = &service
+	return b
+}
+
+// returns a deep copy, so the builder may be reused to build similar clusters, changes of the
+// built object (e.g. of its podSpec) affect neither the builder nor other built objects
+func (b *InnoDBClusterBuilder) Build() *InnoDBCluster {
+	return b.ic.DeepCopy()
+}
+
+// ---------------------------
+// helpers to compose the parts of the spec
+
+func NewPersistentVolumeClaimStorage(claimName string) BackupStorage {
+	return BackupStorage{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+	}
+}
+
+func NewOciObjectStorage(prefix string, bucketName string, credentials string) BackupStorage {
+	return BackupStorage{
+		OciObjectStorage: &OciObjectStorage{Prefix: prefix, BucketName: bucketName, Credentials: credentials},
+	}
+}
+
+func NewS3Storage(s3 S3Storage) BackupStorage {
+	return BackupStorage{S3: &s3}
+}
+
+// the dumpOptions are passed to util.dumpInstance(), e.g. {"excludeSchemas": ["excludeme"]}, may be nil
+func NewDumpInstanceProfile(name string, storage BackupStorage, dumpOptions map[string]interface{}) BackupProfile {
+	return BackupProfile{
+		Name:         name,
+		DumpInstance: &DumpInstance{DumpOptions: dumpOptions, Storage: storage},
+	}
+}
+
+func NewSnapshotProfile(name string, storage BackupStorage) BackupProfile {
+	return BackupProfile{
+		Name:     name,
+		Snapshot: &Snapshot{Storage: storage},
+	}
+}
+
+func NewInitDBFromClone(donorUrl string, rootUser string, secretName string) InitDB {
+	return InitDB{
+		Clone: &InitDBClone{
+			DonorUrl:     donorUrl,
+			RootUser:     rootUser,
+			SecretKeyRef: corev1.LocalObjectReference{Name: secretName},
+		},
+	}
+}
+
+func NewInitDBFromDump(name string, storage BackupStorage, options map[string]interface{}) InitDB {
+	return InitDB{
+		Dump: &InitDBDump{Name: name, Storage: storage, Options: options},
+	}
+}
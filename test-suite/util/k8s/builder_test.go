@@ -0,0 +1,56 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestBuilder() *InnoDBClusterBuilder {
+	podSpec := map[string]interface{}{
+		"terminationGracePeriodSeconds": 60,
+		"containers": []interface{}{
+			map[string]interface{}{"name": "mysql", "args": []interface{}{"--log-error-verbosity=3"}},
+		},
+	}
+	dumpOptions := map[string]interface{}{"excludeSchemas": []interface{}{"excludeme"}}
+	return NewInnoDBClusterBuilder("mycluster").
+		Labels(map[string]string{"app": "ote"}).
+		Instances(3).
+		Routers(1).
+		SecretName("mypwds").
+		PodSpec(podSpec).
+		RouterPodSpec(map[string]interface{}{"terminationGracePeriodSeconds": 30}).
+		DatadirVolumeClaimTemplate(map[string]interface{}{"accessModes": []interface{}{"ReadWriteOnce"}}).
+		BackupProfile(NewDumpInstanceProfile("dump", NewPersistentVolumeClaimStorage("backup-volume"), dumpOptions)).
+		BackupSchedule(BackupSchedule{Name: "daily", Schedule: "0 0 * * *", BackupProfileName: "dump"}).
+		InitDB(InitDB{Dump: &InitDBDump{Name: "init", Storage: NewPersistentVolumeClaimStorage("init-volume")}}).
+		Service(InnoDBClusterService{Type: "ClusterIP", Labels: map[string]string{"app": "ote"}})
+}
+
+func TestBuildDeepCopy(t *testing.T) {
+	builder := newTestBuilder()
+	ic := builder.Build()
+	if expected := newTestBuilder().Build(); !reflect.DeepEqual(ic, expected) {
+		t.Fatalf("the copy differs from the original:\n%+v\n%+v", ic, expected)
+	}
+
+	// change everything reachable through references in the built object
+	ic.Labels["app"] = "changed"
+	ic.Spec.PodSpec["terminationGracePeriodSeconds"] = 1
+	container := ic.Spec.PodSpec["containers"].([]interface{})[0].(map[string]interface{})
+	container["args"].([]interface{})[0] = "changed"
+	*ic.Spec.Router.Instances = 5
+	ic.Spec.Router.PodSpec["terminationGracePeriodSeconds"] = 1
+	ic.Spec.DatadirVolumeClaimTemplate["accessModes"].([]interface{})[0] = "changed"
+	ic.Spec.BackupProfiles[0].DumpInstance.DumpOptions["excludeSchemas"].([]interface{})[0] = "changed"
+	ic.Spec.BackupProfiles[0].DumpInstance.Storage.PersistentVolumeClaim.ClaimName = "changed"
+	ic.Spec.BackupSchedules[0].Schedule = "changed"
+	ic.Spec.InitDB.Dump.Storage.PersistentVolumeClaim.ClaimName = "changed"
+	ic.Spec.Service.Labels["app"] = "changed"
+
+	if rebuilt := builder.Build(); !reflect.DeepEqual(rebuilt, newTestBuilder().Build()) {
+		t.Errorf("changes of a built object leaked into the builder:\n%+v", rebuilt)
+	}
+}
@@ -7,25 +7,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"os"
-	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/auxi"
+	"github.com/marinesovitch/ote/test-suite/util/oci"
 	"github.com/marinesovitch/ote/test-suite/util/setup"
-	"github.com/marinesovitch/ote/test-suite/util/system"
 	"sigs.k8s.io/yaml"
 
-	"gopkg.in/ini.v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/util/retry"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -34,10 +34,14 @@ import (
 )
 
 type Client struct {
-	cfg       *setup.Configuration
-	kubectl   Kubectl
-	clientset *kubernetes.Clientset
-	dynamic   dynamic.Interface
+	cfg        *setup.Configuration
+	kubectl    Kubectl
+	restConfig *rest.Config
+	clientset  *kubernetes.Clientset
+	dynamic    dynamic.Interface
+
+	restMapperOnce sync.Once
+	restMapper     *restmapper.DeferredDiscoveryRESTMapper
 }
 
 func NewClient(oteCfg *setup.Configuration, kubeCfg *rest.Config) (*Client, error) {
@@ -53,7 +57,13 @@ func NewClient(oteCfg *setup.Configuration, kubeCfg *rest.Config) (*Client, erro
 		return nil, err
 	}
 
-	return &Client{oteCfg, kubectl, clientset, dynamic}, nil
+	return &Client{
+		cfg:        oteCfg,
+		kubectl:    kubectl,
+		restConfig: kubeCfg,
+		clientset:  clientset,
+		dynamic:    dynamic,
+	}, nil
 }
 
 func (c *Client) ListConfigMaps(namespace string) (*corev1.ConfigMapList, error) {
@@ -135,13 +145,16 @@ func (c *Client) ListStatefulSets(namespace string) (*appsv1.StatefulSetList, er
 	return c.clientset.AppsV1().StatefulSets(namespace).List(context.Background(), metav1.ListOptions{})
 }
 
-func (c *Client) ListCustomResources(namespace string, resource Kind) (*unstructured.UnstructuredList, error) {
-	gvr := schema.GroupVersionResource{
+func getCustomResourceGVR(resource Kind) schema.GroupVersionResource {
+	return schema.GroupVersionResource{
 		Group:    OperatorGroup,
 		Version:  OperatorVersion,
 		Resource: resource.String(),
 	}
+}
 
+func (c *Client) ListCustomResources(namespace string, resource Kind) (*unstructured.UnstructuredList, error) {
+	gvr := getCustomResourceGVR(resource)
 	return c.dynamic.Resource(gvr).Namespace(namespace).List(context.Background(), metav1.ListOptions{})
 }
 
@@ -166,6 +179,24 @@ func (c *Client) ListInnoDBClusterEvents(namespace string, icname string, sinceR
 	return c.ListEvents(namespace, selector, sinceResourceVersion)
 }
 
+func (c *Client) GetServerVersion() (string, error) {
+	version, err := c.clientset.Discovery().ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	return version.GitVersion, nil
+}
+
+var customResourceDefinitionGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+func (c *Client) GetCustomResourceDefinition(name string) (*unstructured.Unstructured, error) {
+	return c.dynamic.Resource(customResourceDefinitionGVR).Get(context.Background(), name, metav1.GetOptions{})
+}
+
 func (c *Client) HasDeployment(namespace string, name string) (bool, error) {
 	deployment, err := c.GetDeployment(namespace, name)
 	if err != nil {
@@ -234,6 +265,10 @@ func (c *Client) HasReplicaSet(namespace string, name string) (bool, error) {
 	return rs != nil && rs.GetName() == name, nil
 }
 
+func (c *Client) GetConfigMap(namespace string, name string) (*corev1.ConfigMap, error) {
+	return c.clientset.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
 func (c *Client) GetReplicaSet(namespace string, name string) (*appsv1.ReplicaSet, error) {
 	return c.clientset.AppsV1().ReplicaSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
 }
@@ -247,12 +282,7 @@ func (c *Client) GetStatefulSet(namespace string, name string) (*appsv1.Stateful
 }
 
 func (c *Client) getCustomResource(namespace string, name string, resource Kind) (*unstructured.Unstructured, error) {
-	gvr := schema.GroupVersionResource{
-		Group:    OperatorGroup,
-		Version:  OperatorVersion,
-		Resource: resource.String(),
-	}
-
+	gvr := getCustomResourceGVR(resource)
 	return c.dynamic.Resource(gvr).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
 }
 
@@ -269,12 +299,18 @@ func (c *Client) HasInnoDBCluster(namespace string, name string) (bool, error) {
 
 func (c *Client) GetInnoDBCluster(namespace string, name string) (*InnoDBCluster, error) {
 	idbc, err := c.getCustomResource(namespace, name, CRDInnoDBCluster)
-	return &InnoDBCluster{Unstructured: idbc, CRDFields: NewCRDFields(idbc)}, err
+	if err != nil {
+		return nil, err
+	}
+	return NewInnoDBCluster(idbc)
 }
 
 func (c *Client) GetMySQLBackup(namespace string, name string) (*MySQLBackup, error) {
 	mbk, err := c.getCustomResource(namespace, name, CRDMySQLBackup)
-	return &MySQLBackup{Unstructured: mbk, CRDFields: NewCRDFields(mbk)}, err
+	if err != nil {
+		return nil, err
+	}
+	return NewMySQLBackup(mbk)
 }
 
 type deleterFunc func(ctx context.Context, namespace string, name string) error
@@ -356,6 +392,11 @@ func (c *Client) DeletePodWithTimeout(namespace string, name string, timeout tim
 	)
 }
 
+// the pod is only admitted, i.e. passes mutating webhooks, but is not persisted
+func (c *Client) CreatePodDryRun(namespace string, pod *corev1.Pod) (*corev1.Pod, error) {
+	return c.clientset.CoreV1().Pods(namespace).Create(context.Background(), pod, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+}
+
 func (c *Client) DeletePod(namespace string, name string) error {
 	const Timeout = 120
 	return c.DeletePodWithTimeout(namespace, name, Timeout)
@@ -446,12 +487,7 @@ func (c *Client) DeleteStatefulSet(namespace string, name string) error {
 }
 
 func (c *Client) DeleteCustomResource(namespace string, resource Kind, name string, timeout time.Duration) error {
-	gvr := schema.GroupVersionResource{
-		Group:    OperatorGroup,
-		Version:  OperatorVersion,
-		Resource: resource.String(),
-	}
-
+	gvr := getCustomResourceGVR(resource)
 	return c.deleteItem(
 		namespace,
 		name,
@@ -472,47 +508,38 @@ func (c *Client) DeleteMySQLBackup(namespace string, name string) error {
 	return c.DeleteCustomResource(namespace, CRDMySQLBackup, name, Timeout)
 }
 
-func (c *Client) describe(namespace string, resource Kind, name string) (string, error) {
-	return c.kubectl.Describe(namespace, resource, name)
-}
-
-func (c *Client) DescribeInnoDBCluster(namespace string, name string) (string, error) {
-	return c.describe(namespace, CRDInnoDBCluster, name)
-}
-
-func (c *Client) Logs(namespace string, name string, containerId ContainerId) (string, error) {
-	return c.kubectl.Logs(namespace, name, containerId)
-}
-
-func (c *Client) Cat(namespace string, name string, containerId ContainerId, path string) (string, error) {
-	return c.kubectl.ExecuteGetOutput(namespace, name, containerId, "cat", path)
-}
+// the counterpart of 'kubectl describe' for our custom resources, i.e. the object (without
+// managed fields) followed by the events related to it
+func (c *Client) describeCustomResource(namespace string, resource Kind, name string) (string, error) {
+	gvr := getCustomResourceGVR(resource)
+	obj, err := c.dynamic.Resource(gvr).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	obj.SetManagedFields(nil)
 
-func (c *Client) Kill(namespace string, name string, containerId ContainerId, sig int, pid int) (err error) {
-	killCmd := fmt.Sprintf("kill -%d %d", sig, pid)
-	const MaxTrials = 5
-	for i := 0; i < MaxTrials; i++ {
-		err = c.Execute(namespace, name, containerId, "/bin/sh", "-c", killCmd)
-		if err == nil {
-			break
-		}
-		err = fmt.Errorf("cannot kill container %s on %s/%s: %v", GetContainerName(containerId), namespace, name, err)
-		log.Print(err)
-		time.Sleep(2 * time.Second)
+	content, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
 	}
-	return
-}
 
-func (c *Client) Execute(namespace string, name string, containerId ContainerId, cmd ...string) error {
-	return c.kubectl.Execute(namespace, name, containerId, cmd...)
-}
+	var description strings.Builder
+	description.Write(content)
 
-func (c *Client) Apply(namespace string, path string) error {
-	return c.kubectl.ApplyInNamespace(namespace, path)
+	selector := fmt.Sprintf("involvedObject.kind=%s,involvedObject.name=%s", obj.GetKind(), name)
+	events, err := c.ListEvents(namespace, selector, "")
+	if err != nil {
+		return "", err
+	}
+	description.WriteString("\nEvents:\n")
+	for _, event := range events.Items {
+		fmt.Fprintf(&description, "  %s\t%s\t%s\t%s\n", event.Type, event.Reason, event.Source.Component, event.Message)
+	}
+	return description.String(), nil
 }
 
-func (c *Client) ApplyGetOutput(namespace string, path string) (string, error) {
-	return c.kubectl.ApplyGetOutput(namespace, path)
+func (c *Client) DescribeInnoDBCluster(namespace string, name string) (string, error) {
+	return c.describeCustomResource(namespace, CRDInnoDBCluster, name)
 }
 
 // https://erosb.github.io/post/json-patch-vs-merge-patch/
@@ -606,6 +633,15 @@ func (c *Client) JSONPatchInnoDBCluster(namespace string, name string, patch Jso
 	return c.patchCustomResource(namespace, name, CRDInnoDBCluster, types.JSONPatchType, payload)
 }
 
+func (c *Client) JSONPatchMySQLBackup(namespace string, name string, patch JsonPatch) error {
+	payload, err := c.prepareJsonPatchPayload(patch)
+	if err != nil {
+		return err
+	}
+
+	return c.patchCustomResource(namespace, name, CRDMySQLBackup, types.JSONPatchType, payload)
+}
+
 func (c *Client) MergePatchInnoDBCluster(namespace string, name string, patch []byte) error {
 	return c.patchCustomResource(namespace, name, CRDInnoDBCluster, types.MergePatchType, patch)
 }
@@ -634,114 +670,72 @@ func (c *Client) PatchPod(namespace string, name string, patch JsonPatch) error
 	return err
 }
 
-func (c *Client) WaitOnPodGone(namespace string, name string, sinceResourceVersion string, timeout time.Duration) error {
-	if podExists, err := c.HasPod(namespace, name); !podExists || err != nil {
-		return err
-	}
-
-	watcher, err := c.clientset.CoreV1().Pods(namespace).Watch(context.Background(), metav1.ListOptions{ResourceVersion: sinceResourceVersion})
-	if err != nil {
-		return err
-	}
-	defer watcher.Stop()
-
-	podGone := make(chan bool)
-	go func() {
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Deleted:
-				pod := event.Object.(*corev1.Pod)
-				if pod.GetNamespace() == namespace && pod.GetName() == name {
-					podGone <- true
-					return
-				}
+// removes only the finalizers matched by the filter, others (e.g. of other controllers) stay
+func (c *Client) RemovePodFinalizers(namespace string, name string, filter func(finalizer string) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pod, err := c.GetPod(namespace, name)
+		if err != nil {
+			return err
+		}
+		var kept []string
+		for _, finalizer := range pod.GetFinalizers() {
+			if !filter(finalizer) {
+				kept = append(kept, finalizer)
 			}
 		}
-	}()
-
-	select {
-	case <-podGone:
-		return nil
-	case <-time.After(timeout * time.Second):
-		return fmt.Errorf("timeout waiting for pod %s/%s gone", namespace, name)
-	}
+		if len(kept) == len(pod.GetFinalizers()) {
+			return nil
+		}
+		pod.SetFinalizers(kept)
+		_, err = c.clientset.CoreV1().Pods(namespace).Update(context.Background(), pod, metav1.UpdateOptions{})
+		return err
+	})
 }
 
-func (c *Client) WaitOnPod(namespace string, name string, sinceResourceVersion string, status corev1.PodPhase, timeout time.Duration) error {
-	watcher, err := c.clientset.CoreV1().Pods(namespace).Watch(context.Background(), metav1.ListOptions{ResourceVersion: sinceResourceVersion})
-	if err != nil {
+func (c *Client) WaitOnPodGone(ctx context.Context, namespace string, name string, sinceResourceVersion string) error {
+	if podExists, err := c.HasPod(namespace, name); !podExists || err != nil {
 		return err
 	}
-	defer watcher.Stop()
 
-	podOk := make(chan bool)
-	go func() {
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Added, watch.Modified:
-				pod := event.Object.(*corev1.Pod)
-				if pod.GetNamespace() == namespace && pod.GetName() == name && pod.Status.Phase == status {
-					podOk <- true
-					return
-				}
-			}
+	params := WatchParams{Namespace: namespace, Name: name, SinceResourceVersion: sinceResourceVersion}
+	return c.WaitOnPods(ctx, params, func(pods []*corev1.Pod) (bool, string) {
+		if len(pods) == 0 {
+			return true, ""
 		}
-	}()
-
-	select {
-	case <-podOk:
-		return nil
-	case <-time.After(timeout * time.Second):
-		return fmt.Errorf("timeout waiting for pod %s/%s", namespace, name)
-	}
+		return false, DescribePods(pods)
+	})
 }
 
-func getInnoDBClusterGVR() schema.GroupVersionResource {
-	return schema.GroupVersionResource{
-		Group:    OperatorGroup,
-		Version:  OperatorVersion,
-		Resource: OperatorInnoDBClusters,
-	}
+func (c *Client) WaitOnPod(ctx context.Context, namespace string, name string, sinceResourceVersion string, status corev1.PodPhase) error {
+	params := WatchParams{Namespace: namespace, Name: name, SinceResourceVersion: sinceResourceVersion}
+	return c.WaitOnPods(ctx, params, func(pods []*corev1.Pod) (bool, string) {
+		if len(pods) == 0 {
+			return false, "pod doesn't exist"
+		}
+		return pods[0].Status.Phase == status, DescribePods(pods)
+	})
 }
 
-func (c *Client) WaitOnInnoDBClusterGone(namespace string, name string, sinceResourceVersion string, timeout time.Duration) error {
+func (c *Client) WaitOnInnoDBClusterGone(ctx context.Context, namespace string, name string, sinceResourceVersion string) error {
 	if icExists, err := c.HasInnoDBCluster(namespace, name); !icExists || err != nil {
 		return err
 	}
 
-	gvr := getInnoDBClusterGVR()
-	watcher, err := c.dynamic.Resource(gvr).Namespace(namespace).Watch(context.Background(), metav1.ListOptions{ResourceVersion: sinceResourceVersion})
-	if err != nil {
-		return err
-	}
-	defer watcher.Stop()
-
-	icGone := make(chan bool)
-	go func() {
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Deleted:
-				ic := event.Object.(*unstructured.Unstructured)
-				if ic.GetNamespace() == namespace && ic.GetName() == name {
-					icGone <- true
-					return
-				}
-			}
+	params := WatchParams{Namespace: namespace, Name: name, SinceResourceVersion: sinceResourceVersion}
+	return c.WaitOnInnoDBClusters(ctx, params, func(ics []*InnoDBCluster) (bool, string) {
+		if len(ics) == 0 {
+			return true, ""
 		}
-	}()
+		return false, describeInnoDBClusterState(ics[0])
+	})
+}
 
-	select {
-	case <-icGone:
-		return nil
-	case <-time.After(timeout * time.Second):
-		return fmt.Errorf("timeout waiting for ic %s/%s gone", namespace, name)
-	}
+func describeInnoDBClusterState(ic *InnoDBCluster) string {
+	return fmt.Sprintf("ic %s status '%s', online instances %d", ic.GetName(), ic.Status.Cluster.Status, ic.Status.Cluster.OnlineInstances)
 }
 
-func verifyInnoDBClusterState(ic *unstructured.Unstructured, expectedStatus []string, expectedNumOnline int64) bool {
-	icStatus, found, err := unstructured.NestedString(ic.Object, "status", "cluster", "status")
-	statusOk := err == nil && found && auxi.Contains(expectedStatus, icStatus)
-	if !statusOk {
+func verifyInnoDBClusterState(ic *InnoDBCluster, expectedStatus []string, expectedNumOnline int64) bool {
+	if !auxi.Contains(expectedStatus, ic.Status.Cluster.Status) {
 		return false
 	}
 
@@ -749,8 +743,7 @@ func verifyInnoDBClusterState(ic *unstructured.Unstructured, expectedStatus []st
 		return true
 	}
 
-	icNumOnline, found, err := unstructured.NestedInt64(ic.Object, "status", "cluster", "onlineInstances")
-	return err == nil && found && icNumOnline >= expectedNumOnline
+	return int64(ic.Status.Cluster.OnlineInstances) >= expectedNumOnline
 }
 
 type WaitOnInnoDBClusterParams struct {
@@ -762,35 +755,22 @@ type WaitOnInnoDBClusterParams struct {
 	Timeout              time.Duration
 }
 
-func (c *Client) WaitOnInnoDBCluster(params WaitOnInnoDBClusterParams) error {
-	gvr := getInnoDBClusterGVR()
-	watcher, err := c.dynamic.Resource(gvr).Namespace(params.Namespace).Watch(context.Background(), metav1.ListOptions{ResourceVersion: params.SinceResourceVersion})
-	if err != nil {
-		return err
-	}
-	defer watcher.Stop()
-
-	icOk := make(chan bool)
-	go func() {
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Added, watch.Modified:
-				ic := event.Object.(*unstructured.Unstructured)
-				if ic.GetNamespace() == params.Namespace && ic.GetName() == params.Name &&
-					verifyInnoDBClusterState(ic, params.ExpectedStatus, params.ExpectedNumOnline) {
-					icOk <- true
-					return
-				}
-			}
+// the params.Timeout is applied by the caller, here the wait lasts until ctx is done,
+// it returns the ic in the awaited state
+func (c *Client) WaitOnInnoDBCluster(ctx context.Context, params WaitOnInnoDBClusterParams) (*InnoDBCluster, error) {
+	var awaited *InnoDBCluster
+	watchParams := WatchParams{Namespace: params.Namespace, Name: params.Name, SinceResourceVersion: params.SinceResourceVersion}
+	err := c.WaitOnInnoDBClusters(ctx, watchParams, func(ics []*InnoDBCluster) (bool, string) {
+		if len(ics) == 0 {
+			return false, "ic doesn't exist"
 		}
-	}()
-
-	select {
-	case <-icOk:
-		return nil
-	case <-time.After(params.Timeout * time.Second):
-		return fmt.Errorf("timeout waiting for ic %s/%s", params.Namespace, params.Name)
-	}
+		if verifyInnoDBClusterState(ics[0], params.ExpectedStatus, params.ExpectedNumOnline) {
+			awaited = ics[0]
+			return true, ""
+		}
+		return false, describeInnoDBClusterState(ics[0])
+	})
+	return awaited, err
 }
 
 func (c *Client) CreateNamespace(name string) error {
@@ -799,6 +779,22 @@ func (c *Client) CreateNamespace(name string) error {
 	return err
 }
 
+// creates the object straight through the dynamic client, the status is left to the operator
+func (c *Client) CreateInnoDBCluster(ic *InnoDBCluster) (*InnoDBCluster, error) {
+	icObj, err := ic.ToUnstructured()
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(icObj.Object, "status")
+
+	gvr := getCustomResourceGVR(CRDInnoDBCluster)
+	created, err := c.dynamic.Resource(gvr).Namespace(ic.GetNamespace()).Create(context.Background(), icObj, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return NewInnoDBCluster(created)
+}
+
 func (c *Client) CreateUserSecrets(namespace string, name string, rootUser string, rootHost string, rootPass string) error {
 	data := setup.GenerateUserSecretsData{
 		Name:         name,
@@ -813,62 +809,29 @@ func (c *Client) CreateUserSecrets(namespace string, name string, rootUser strin
 	return c.Apply(namespace, userSecretsYamlPath)
 }
 
-func adjustKeyFilePath(cfgPath string, cfgKeyFilePath string) (string, error) {
-	cfgKeyFilePath = system.PathExpand(cfgKeyFilePath)
-	if filepath.IsAbs(cfgKeyFilePath) {
-		return cfgKeyFilePath, nil
+// an opaque secret, the values are given as plain strings (they are encoded by the server)
+func (c *Client) CreateSecret(namespace string, name string, stringData map[string]string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: stringData,
 	}
-
-	// kubectl doesn't like relative paths
-	cfgDir := filepath.Dir(cfgPath)
-	keyFilePath := filepath.Join(cfgDir, cfgKeyFilePath)
-
-	if !system.DoesFileExist(keyFilePath) {
-		return keyFilePath, fmt.Errorf("key file %s doesn't exist", keyFilePath)
-	}
-
-	return keyFilePath, nil
+	_, err := c.clientset.CoreV1().Secrets(namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	return err
 }
 
 func (c *Client) CreateApikeySecret(namespace string, name string, cfgPath string, profileName string) error {
-	var err error
-
-	if !filepath.IsAbs(cfgPath) {
-		cfgPath, err = filepath.Abs(cfgPath)
-		if err != nil {
-			return err
-		}
-	}
-
-	err = system.VerifyFileExist(cfgPath)
+	profile, err := oci.LoadProfile(cfgPath, profileName)
 	if err != nil {
 		return err
 	}
 
-	iniCfg, err := ini.Load(cfgPath)
-	if err != nil {
-		return err
-	}
-
-	profile, err := iniCfg.GetSection(profileName)
-	if err != nil {
-		return err
-	}
-
-	const KeyFileOption = "key_file"
 	args := []string{"create", "secret", "generic", name, "-n", namespace}
-
-	for _, key := range profile.Keys() {
-		optionName := key.Name()
-		optionValue := key.Value()
-		if optionName != KeyFileOption {
-			args = append(args, fmt.Sprintf("--from-literal=%s=%s", optionName, optionValue))
+	for _, option := range profile.Options {
+		if option.Name != oci.KeyFileOption {
+			args = append(args, fmt.Sprintf("--from-literal=%s=%s", option.Name, option.Value))
 		} else {
-			privateKeyPath, err := adjustKeyFilePath(cfgPath, optionValue)
-			if err != nil {
-				return err
-			}
-			args = append(args, "--from-file=privatekey="+privateKeyPath)
+			args = append(args, "--from-file=privatekey="+option.Value)
 		}
 	}
 
@@ -17,16 +17,24 @@ const (
 	Sidecar
 	Mysql
 	Router
+	Operator
+	Minio
+	VolumeReader
+	ChaosAgent
 	UnknownContainer
 )
 
 var contIdToName = map[ContainerId]string{
-	FixDataDir: "fixdatadir",
-	InitConf:   "initconf",
-	InitMysql:  "initmysql",
-	Sidecar:    "sidecar",
-	Mysql:      "mysql",
-	Router:     "router",
+	FixDataDir:   "fixdatadir",
+	InitConf:     "initconf",
+	InitMysql:    "initmysql",
+	Sidecar:      "sidecar",
+	Mysql:        "mysql",
+	Router:       "router",
+	Operator:     "mysql-operator",
+	Minio:        "minio",
+	VolumeReader: "volume-reader",
+	ChaosAgent:   "chaos-agent",
 }
 
 func GetContainerName(contId ContainerId) string {
@@ -37,12 +45,16 @@ func GetContainerName(contId ContainerId) string {
 }
 
 var nameToContId = map[string]ContainerId{
-	"fixdatadir": FixDataDir,
-	"initconf":   InitConf,
-	"initmysql":  InitMysql,
-	"sidecar":    Sidecar,
-	"mysql":      Mysql,
-	"router":     Router,
+	"fixdatadir":     FixDataDir,
+	"initconf":       InitConf,
+	"initmysql":      InitMysql,
+	"sidecar":        Sidecar,
+	"mysql":          Mysql,
+	"router":         Router,
+	"mysql-operator": Operator,
+	"minio":          Minio,
+	"volume-reader":  VolumeReader,
+	"chaos-agent":    ChaosAgent,
 }
 
 func GetContainerId(name string) (ContainerId, error) {
@@ -83,7 +95,7 @@ func GetContainer(pod *corev1.Pod, contId ContainerId) (*corev1.Container, error
 	switch contId {
 	case FixDataDir, InitConf, InitMysql:
 		return getContainer(pod.Spec.InitContainers, contId)
-	case Sidecar, Mysql, Router:
+	case Sidecar, Mysql, Router, Operator, Minio, VolumeReader, ChaosAgent:
 		return getContainer(pod.Spec.Containers, contId)
 	default:
 		return nil, fmt.Errorf("incorrect container id %d", contId)
@@ -114,7 +126,7 @@ func GetContainerStatus(pod *corev1.Pod, contId ContainerId) (*corev1.ContainerS
 	switch contId {
 	case FixDataDir, InitConf, InitMysql:
 		return getContainerStatus(pod.Status.InitContainerStatuses, contId)
-	case Sidecar, Mysql, Router:
+	case Sidecar, Mysql, Router, Operator, Minio, VolumeReader, ChaosAgent:
 		return getContainerStatus(pod.Status.ContainerStatuses, contId)
 	default:
 		return nil, fmt.Errorf("incorrect container id %d", contId)
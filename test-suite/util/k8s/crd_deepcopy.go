@@ -0,0 +1,198 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// deep copies of our CRD types in the style of deepcopy-gen, so a built or fetched object may be
+// changed without affecting others, e.g. the builder reused for similar clusters
+
+// generic values, i.e. those kept as x-kubernetes-preserve-unknown-fields, are copied
+// recursively, scalars are immutable, so they are shared
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i := range v {
+			copied[i] = deepCopyValue(v[i])
+		}
+		return copied
+	case []map[string]interface{}:
+		copied := make([]map[string]interface{}, len(v))
+		for i := range v {
+			copied[i] = deepCopyMap(v[i])
+		}
+		return copied
+	case map[string]string:
+		return deepCopyStringMap(v)
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return value
+	}
+}
+
+func deepCopyMap(in map[string]interface{}) map[string]interface{} {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(in))
+	for key, value := range in {
+		out[key] = deepCopyValue(value)
+	}
+	return out
+}
+
+func deepCopyStringMap(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for key, value := range in {
+		out[key] = value
+	}
+	return out
+}
+
+// ---------------------------
+// storage and backup profiles
+
+func (in *BackupStorage) DeepCopyInto(out *BackupStorage) {
+	*out = *in
+	if in.OciObjectStorage != nil {
+		out.OciObjectStorage = new(OciObjectStorage)
+		*out.OciObjectStorage = *in.OciObjectStorage
+	}
+	if in.S3 != nil {
+		out.S3 = new(S3Storage)
+		*out.S3 = *in.S3
+	}
+	if in.PersistentVolumeClaim != nil {
+		out.PersistentVolumeClaim = new(corev1.PersistentVolumeClaimVolumeSource)
+		*out.PersistentVolumeClaim = *in.PersistentVolumeClaim
+	}
+}
+
+func (in *DumpInstance) DeepCopyInto(out *DumpInstance) {
+	*out = *in
+	out.DumpOptions = deepCopyMap(in.DumpOptions)
+	in.Storage.DeepCopyInto(&out.Storage)
+}
+
+func (in *Snapshot) DeepCopyInto(out *Snapshot) {
+	*out = *in
+	out.SnapshotOptions = deepCopyMap(in.SnapshotOptions)
+	in.Storage.DeepCopyInto(&out.Storage)
+}
+
+func (in *BackupProfile) DeepCopyInto(out *BackupProfile) {
+	*out = *in
+	if in.DumpInstance != nil {
+		out.DumpInstance = new(DumpInstance)
+		in.DumpInstance.DeepCopyInto(out.DumpInstance)
+	}
+	if in.Snapshot != nil {
+		out.Snapshot = new(Snapshot)
+		in.Snapshot.DeepCopyInto(out.Snapshot)
+	}
+}
+
+func (in *BackupProfile) DeepCopy() *BackupProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *BackupSchedule) DeepCopyInto(out *BackupSchedule) {
+	*out = *in
+	out.BackupProfile = in.BackupProfile.DeepCopy()
+}
+
+// ---------------------------
+// InnoDBCluster
+
+func (in *InnoDBClusterRouterSpec) DeepCopyInto(out *InnoDBClusterRouterSpec) {
+	*out = *in
+	if in.Instances != nil {
+		out.Instances = new(int)
+		*out.Instances = *in.Instances
+	}
+	out.PodSpec = deepCopyMap(in.PodSpec)
+	out.PodAnnotations = deepCopyStringMap(in.PodAnnotations)
+	out.PodLabels = deepCopyStringMap(in.PodLabels)
+}
+
+func (in *InitDB) DeepCopyInto(out *InitDB) {
+	*out = *in
+	if in.Clone != nil {
+		out.Clone = new(InitDBClone)
+		*out.Clone = *in.Clone
+	}
+	if in.Dump != nil {
+		out.Dump = new(InitDBDump)
+		*out.Dump = *in.Dump
+		out.Dump.Options = deepCopyMap(in.Dump.Options)
+		in.Dump.Storage.DeepCopyInto(&out.Dump.Storage)
+	}
+}
+
+func (in *InnoDBClusterService) DeepCopyInto(out *InnoDBClusterService) {
+	*out = *in
+	out.Annotations = deepCopyStringMap(in.Annotations)
+	out.Labels = deepCopyStringMap(in.Labels)
+}
+
+func (in *InnoDBClusterSpec) DeepCopyInto(out *InnoDBClusterSpec) {
+	*out = *in
+	if in.ImagePullSecrets != nil {
+		out.ImagePullSecrets = make([]corev1.LocalObjectReference, len(in.ImagePullSecrets))
+		copy(out.ImagePullSecrets, in.ImagePullSecrets)
+	}
+	out.DatadirVolumeClaimTemplate = deepCopyMap(in.DatadirVolumeClaimTemplate)
+	out.PodSpec = deepCopyMap(in.PodSpec)
+	out.PodAnnotations = deepCopyStringMap(in.PodAnnotations)
+	out.PodLabels = deepCopyStringMap(in.PodLabels)
+	in.Router.DeepCopyInto(&out.Router)
+	if in.BackupProfiles != nil {
+		out.BackupProfiles = make([]BackupProfile, len(in.BackupProfiles))
+		for i := range in.BackupProfiles {
+			in.BackupProfiles[i].DeepCopyInto(&out.BackupProfiles[i])
+		}
+	}
+	if in.BackupSchedules != nil {
+		out.BackupSchedules = make([]BackupSchedule, len(in.BackupSchedules))
+		for i := range in.BackupSchedules {
+			in.BackupSchedules[i].DeepCopyInto(&out.BackupSchedules[i])
+		}
+	}
+	if in.InitDB != nil {
+		out.InitDB = new(InitDB)
+		in.InitDB.DeepCopyInto(out.InitDB)
+	}
+	if in.Service != nil {
+		out.Service = new(InnoDBClusterService)
+		in.Service.DeepCopyInto(out.Service)
+	}
+}
+
+func (in *InnoDBCluster) DeepCopyInto(out *InnoDBCluster) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+func (in *InnoDBCluster) DeepCopy() *InnoDBCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(InnoDBCluster)
+	in.DeepCopyInto(out)
+	return out
+}
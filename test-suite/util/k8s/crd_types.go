@@ -0,0 +1,245 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// our CRD types, they follow the schema of mysql.oracle.com/v2 CRDs from
+// mysql-operator/deploy/deploy-crds.yaml, fields declared there as
+// x-kubernetes-preserve-unknown-fields are kept as generic maps, crd_types_test.go checks
+// them against the schema
+
+// ---------------------------
+// storage and backup profiles, common for InnoDBCluster and MySQLBackup
+
+type OciObjectStorage struct {
+	Prefix      string `json:"prefix,omitempty"`
+	BucketName  string `json:"bucketName"`
+	Credentials string `json:"credentials"`
+}
+
+type S3Storage struct {
+	Prefix     string `json:"prefix,omitempty"`
+	BucketName string `json:"bucketName"`
+	Config     string `json:"config"`
+	Profile    string `json:"profile,omitempty"`
+	Endpoint   string `json:"endpoint,omitempty"`
+}
+
+type BackupStorage struct {
+	OciObjectStorage      *OciObjectStorage                         `json:"ociObjectStorage,omitempty"`
+	S3                    *S3Storage                                `json:"s3,omitempty"`
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+}
+
+type DumpInstance struct {
+	DumpOptions map[string]interface{} `json:"dumpOptions,omitempty"`
+	Storage     BackupStorage          `json:"storage,omitempty"`
+}
+
+type Snapshot struct {
+	SnapshotOptions map[string]interface{} `json:"snapshotOptions,omitempty"`
+	Storage         BackupStorage          `json:"storage,omitempty"`
+}
+
+type BackupProfile struct {
+	Name         string        `json:"name,omitempty"`
+	DumpInstance *DumpInstance `json:"dumpInstance,omitempty"`
+	Snapshot     *Snapshot     `json:"snapshot,omitempty"`
+}
+
+type BackupSchedule struct {
+	Name              string         `json:"name"`
+	Schedule          string         `json:"schedule"`
+	BackupProfileName string         `json:"backupProfileName,omitempty"`
+	BackupProfile     *BackupProfile `json:"backupProfile,omitempty"`
+	DeleteBackupData  bool           `json:"deleteBackupData,omitempty"`
+	Enabled           bool           `json:"enabled,omitempty"`
+}
+
+// ---------------------------
+// InnoDBCluster
+
+type InnoDBClusterRouterSpec struct {
+	// nil if not set in the spec
+	Instances      *int                   `json:"instances,omitempty"`
+	Version        string                 `json:"version,omitempty"`
+	TlsSecretName  string                 `json:"tlsSecretName,omitempty"`
+	PodSpec        map[string]interface{} `json:"podSpec,omitempty"`
+	PodAnnotations map[string]string      `json:"podAnnotations,omitempty"`
+	PodLabels      map[string]string      `json:"podLabels,omitempty"`
+}
+
+func (r *InnoDBClusterRouterSpec) GetInstances() int {
+	if r.Instances == nil {
+		return 0
+	}
+	return *r.Instances
+}
+
+type InitDBClone struct {
+	DonorUrl     string                      `json:"donorUrl"`
+	RootUser     string                      `json:"rootUser,omitempty"`
+	SecretKeyRef corev1.LocalObjectReference `json:"secretKeyRef"`
+}
+
+type InitDBDump struct {
+	Name    string                 `json:"name,omitempty"`
+	Path    string                 `json:"path,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"`
+	Storage BackupStorage          `json:"storage"`
+}
+
+type InitDB struct {
+	Clone *InitDBClone `json:"clone,omitempty"`
+	Dump  *InitDBDump  `json:"dump,omitempty"`
+}
+
+type InnoDBClusterService struct {
+	Type        corev1.ServiceType `json:"type,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	Labels      map[string]string  `json:"labels,omitempty"`
+	DefaultPort string             `json:"defaultPort,omitempty"`
+}
+
+type InnoDBClusterSpec struct {
+	SecretName                 string                        `json:"secretName"`
+	TlsCASecretName            string                        `json:"tlsCASecretName,omitempty"`
+	TlsSecretName              string                        `json:"tlsSecretName,omitempty"`
+	TlsUseSelfSigned           bool                          `json:"tlsUseSelfSigned,omitempty"`
+	Version                    string                        `json:"version,omitempty"`
+	Edition                    string                        `json:"edition,omitempty"`
+	ImageRepository            string                        `json:"imageRepository,omitempty"`
+	ImagePullPolicy            corev1.PullPolicy             `json:"imagePullPolicy,omitempty"`
+	ImagePullSecrets           []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	ServiceAccountName         string                        `json:"serviceAccountName,omitempty"`
+	BaseServerId               int                           `json:"baseServerId,omitempty"`
+	DatadirVolumeClaimTemplate map[string]interface{}        `json:"datadirVolumeClaimTemplate,omitempty"`
+	Mycnf                      string                        `json:"mycnf,omitempty"`
+	Instances                  int                           `json:"instances,omitempty"`
+	PodSpec                    map[string]interface{}        `json:"podSpec,omitempty"`
+	PodAnnotations             map[string]string             `json:"podAnnotations,omitempty"`
+	PodLabels                  map[string]string             `json:"podLabels,omitempty"`
+	Router                     InnoDBClusterRouterSpec       `json:"router,omitempty"`
+	BackupProfiles             []BackupProfile               `json:"backupProfiles,omitempty"`
+	BackupSchedules            []BackupSchedule              `json:"backupSchedules,omitempty"`
+	InitDB                     *InitDB                       `json:"initDB,omitempty"`
+	Service                    *InnoDBClusterService         `json:"service,omitempty"`
+}
+
+type InnoDBClusterStatusCluster struct {
+	Status          string `json:"status,omitempty"`
+	OnlineInstances int    `json:"onlineInstances,omitempty"`
+	LastProbeTime   string `json:"lastProbeTime,omitempty"`
+}
+
+// the status is written by the operator, the CRD doesn't declare its schema
+// (x-kubernetes-preserve-unknown-fields), so only the fields used by tests are modelled
+type InnoDBClusterStatus struct {
+	Cluster    InnoDBClusterStatusCluster `json:"cluster,omitempty"`
+	CreateTime string                     `json:"createTime,omitempty"`
+	// the version of the servers, it is empty until the operator sets it (older ones never do)
+	Version string `json:"version,omitempty"`
+}
+
+type InnoDBCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   InnoDBClusterSpec   `json:"spec,omitempty"`
+	Status InnoDBClusterStatus `json:"status,omitempty"`
+}
+
+// ---------------------------
+// MySQLBackup
+
+type MySQLBackupSpec struct {
+	ClusterName                   string         `json:"clusterName"`
+	BackupProfileName             string         `json:"backupProfileName,omitempty"`
+	BackupProfile                 *BackupProfile `json:"backupProfile,omitempty"`
+	DeleteBackupData              bool           `json:"deleteBackupData,omitempty"`
+	AddTimestampToBackupDirectory *bool          `json:"addTimestampToBackupDirectory,omitempty"`
+}
+
+type MySQLBackupStatus struct {
+	Status         string `json:"status,omitempty"`
+	StartTime      string `json:"startTime,omitempty"`
+	CompletionTime string `json:"completionTime,omitempty"`
+	ElapsedTime    string `json:"elapsedTime,omitempty"`
+	Output         string `json:"output,omitempty"`
+	Method         string `json:"method,omitempty"`
+	Source         string `json:"source,omitempty"`
+	Bucket         string `json:"bucket,omitempty"`
+	OciTenancy     string `json:"ociTenancy,omitempty"`
+	SpaceAvailable string `json:"spaceAvailable,omitempty"`
+	Size           string `json:"size,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+// the operator writes times in RFC 3339, e.g. 2022-10-26T10:22:02Z
+func (s *MySQLBackupStatus) GetStartTime() (time.Time, error) {
+	return time.Parse(time.RFC3339, s.StartTime)
+}
+
+func (s *MySQLBackupStatus) GetCompletionTime() (time.Time, error) {
+	return time.Parse(time.RFC3339, s.CompletionTime)
+}
+
+type MySQLBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MySQLBackupSpec   `json:"spec,omitempty"`
+	Status MySQLBackupStatus `json:"status,omitempty"`
+}
+
+// ---------------------------
+// conversion
+
+func fromUnstructured(u *unstructured.Unstructured, obj interface{}) error {
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+		return fmt.Errorf("cannot convert %s %s/%s: %v", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+	return nil
+}
+
+func toUnstructured(obj interface{}) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+func NewInnoDBCluster(u *unstructured.Unstructured) (*InnoDBCluster, error) {
+	var ic InnoDBCluster
+	if err := fromUnstructured(u, &ic); err != nil {
+		return nil, err
+	}
+	return &ic, nil
+}
+
+func (ic *InnoDBCluster) ToUnstructured() (*unstructured.Unstructured, error) {
+	return toUnstructured(ic)
+}
+
+func NewMySQLBackup(u *unstructured.Unstructured) (*MySQLBackup, error) {
+	var mbk MySQLBackup
+	if err := fromUnstructured(u, &mbk); err != nil {
+		return nil, err
+	}
+	return &mbk, nil
+}
+
+func (mbk *MySQLBackup) ToUnstructured() (*unstructured.Unstructured, error) {
+	return toUnstructured(mbk)
+}
@@ -0,0 +1,253 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// our CRD types are hand-written, the fields are checked against the openAPIV3Schema of the CRDs:
+// each field has to be declared in the schema with a matching type, fields of the schema which
+// are not modelled are only logged, tests may not need them
+
+const crdsFile = "deploy-crds.yaml"
+
+// the operator directory as in the config (operator.directory), relative to the test-suite
+// directory, it may be overridden like the config with OTE_OPERATOR_DIRECTORY
+func getOperatorDirectory() string {
+	if directory := os.Getenv("OTE_OPERATOR_DIRECTORY"); len(directory) > 0 {
+		return directory
+	}
+	return filepath.Join("..", "..", "..", "mysql-operator", "deploy")
+}
+
+type schemaReport struct {
+	mismatches []string
+	unmodelled []string
+}
+
+func getString(schema map[string]interface{}, key string) string {
+	value, _ := schema[key].(string)
+	return value
+}
+
+func getMap(schema map[string]interface{}, key string) map[string]interface{} {
+	value, _ := schema[key].(map[string]interface{})
+	return value
+}
+
+func getJsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" || !field.IsExported() {
+		return "", false
+	}
+	if len(name) == 0 {
+		name = field.Name
+	}
+	return name, true
+}
+
+var localPkgPath = reflect.TypeOf(InnoDBCluster{}).PkgPath()
+
+func (r *schemaReport) check(path string, t reflect.Type, schema map[string]interface{}) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schemaType := getString(schema, "type")
+	preserveUnknown, _ := schema["x-kubernetes-preserve-unknown-fields"].(bool)
+	intOrString, _ := schema["x-kubernetes-int-or-string"].(bool)
+
+	expectType := func(expected ...string) bool {
+		for _, e := range expected {
+			if schemaType == e {
+				return true
+			}
+		}
+		r.mismatches = append(r.mismatches, path+": "+t.String()+" declared as '"+schemaType+"'")
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if !expectType("object") || t.PkgPath() != localPkgPath {
+			// types of k8s (e.g. a claim of a volume) follow their own schemas
+			return
+		}
+		properties := getMap(schema, "properties")
+		modelled := map[string]bool{}
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := getJsonName(t.Field(i))
+			if !ok {
+				continue
+			}
+			modelled[name] = true
+			property := getMap(properties, name)
+			if property == nil {
+				if !preserveUnknown {
+					r.mismatches = append(r.mismatches, path+"."+name+": not declared in schema")
+				}
+				continue
+			}
+			r.check(path+"."+name, t.Field(i).Type, property)
+		}
+		for name := range properties {
+			if !modelled[name] {
+				r.unmodelled = append(r.unmodelled, path+"."+name)
+			}
+		}
+	case reflect.Map:
+		if !expectType("object") {
+			return
+		}
+		if additional := getMap(schema, "additionalProperties"); additional != nil {
+			r.check(path+".*", t.Elem(), additional)
+		} else if t.Elem().Kind() != reflect.Interface && !preserveUnknown && getMap(schema, "properties") != nil {
+			r.mismatches = append(r.mismatches, path+": a map of "+t.Elem().String()+" declared with fixed properties")
+		}
+	case reflect.Slice:
+		if expectType("array") {
+			if items := getMap(schema, "items"); items != nil {
+				r.check(path+"[]", t.Elem(), items)
+			}
+		}
+	case reflect.String:
+		if !intOrString {
+			expectType("string")
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if !intOrString {
+			expectType("integer")
+		}
+	case reflect.Bool:
+		expectType("boolean")
+	}
+}
+
+// checks a part of an object (e.g. spec) against the schema of the same part
+func checkAgainstSchema(root string, t reflect.Type, schema map[string]interface{}) schemaReport {
+	var report schemaReport
+	report.check(root, t, schema)
+	sort.Strings(report.mismatches)
+	sort.Strings(report.unmodelled)
+	return report
+}
+
+func TestCheckAgainstSchema(t *testing.T) {
+	type item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count,omitempty"`
+	}
+	type spec struct {
+		Instances int               `json:"instances"`
+		Labels    map[string]string `json:"labels,omitempty"`
+		Items     []item            `json:"items,omitempty"`
+		Options   map[string]interface{}
+		Bogus     string `json:"bogus,omitempty"`
+		Ignored   string `json:"-"`
+	}
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"instances": map[string]interface{}{"type": "string"},
+			"labels": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"items": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name":  map[string]interface{}{"type": "string"},
+						"count": map[string]interface{}{"type": "integer"},
+					},
+				},
+			},
+			"Options":  map[string]interface{}{"type": "object", "x-kubernetes-preserve-unknown-fields": true},
+			"extended": map[string]interface{}{"type": "boolean"},
+		},
+	}
+
+	report := checkAgainstSchema("spec", reflect.TypeOf(spec{}), schema)
+	expectedMismatches := []string{"spec.bogus: not declared in schema", "spec.instances: int declared as 'string'"}
+	if !reflect.DeepEqual(report.mismatches, expectedMismatches) {
+		t.Errorf("expected mismatches %v, got %v", expectedMismatches, report.mismatches)
+	}
+	if expectedUnmodelled := []string{"spec.extended"}; !reflect.DeepEqual(report.unmodelled, expectedUnmodelled) {
+		t.Errorf("expected unmodelled %v, got %v", expectedUnmodelled, report.unmodelled)
+	}
+}
+
+// returns the schema of the served version of the CRD with the given name, e.g. innodbclusters.mysql.oracle.com
+func findCrdSchema(t *testing.T, crds map[string]map[string]interface{}, name string) map[string]interface{} {
+	crd, ok := crds[name]
+	if !ok {
+		t.Fatalf("no CRD %s found in %s", name, crdsFile)
+	}
+	spec := getMap(crd, "spec")
+	versions, _ := spec["versions"].([]interface{})
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		if getString(version, "name") == OperatorVersion {
+			return getMap(getMap(version, "schema"), "openAPIV3Schema")
+		}
+	}
+	t.Fatalf("no version %s of the CRD %s found in %s", OperatorVersion, name, crdsFile)
+	return nil
+}
+
+func TestCrdTypesMatchSchema(t *testing.T) {
+	crdsPath := filepath.Join(getOperatorDirectory(), crdsFile)
+	content, err := os.ReadFile(crdsPath)
+	if os.IsNotExist(err) {
+		t.Skipf("%s not found, the operator sources are needed (operator.directory)", crdsPath)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := decodeObjects(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crds := map[string]map[string]interface{}{}
+	for _, obj := range objects {
+		if obj.GetKind() == "CustomResourceDefinition" {
+			crds[obj.GetName()] = obj.Object
+		}
+	}
+
+	tests := []struct {
+		crd    string
+		object interface{}
+	}{
+		{"innodbclusters." + OperatorGroup, InnoDBCluster{}},
+		{"mysqlbackups." + OperatorGroup, MySQLBackup{}},
+	}
+	for _, test := range tests {
+		schema := findCrdSchema(t, crds, test.crd)
+		objectType := reflect.TypeOf(test.object)
+		// the status isn't declared in the schema (it is preserved as is), metadata is standard
+		for _, part := range []string{"spec", "status"} {
+			field, _ := objectType.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, part) })
+			partSchema := getMap(getMap(schema, "properties"), part)
+			if partSchema == nil {
+				t.Errorf("%s: %s not declared in schema", test.crd, part)
+				continue
+			}
+			report := checkAgainstSchema(part, field.Type, partSchema)
+			for _, mismatch := range report.mismatches {
+				t.Errorf("%s: %s", test.crd, mismatch)
+			}
+			if len(report.unmodelled) > 0 {
+				t.Logf("%s: not modelled: %v", test.crd, report.unmodelled)
+			}
+		}
+	}
+}
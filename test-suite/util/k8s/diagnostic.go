@@ -1,9 +1,17 @@
 package k8s
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/watch"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8s_err "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func IsNotFoundError(err error) bool {
@@ -29,3 +37,106 @@ func GetStsNames(stss *appsv1.StatefulSetList) []string {
 	}
 	return names
 }
+
+func GetOperatorCRDNames() []string {
+	return []string{
+		OperatorInnoDBClusters + "." + OperatorGroup,
+		OperatorMySQLBackups + "." + OperatorGroup,
+	}
+}
+
+func IsCRDEstablished(crd *unstructured.Unstructured) bool {
+	conditions, found, err := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	if err != nil || !found {
+		return false
+	}
+
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if ok && fields["type"] == "Established" {
+			return fields["status"] == string(corev1.ConditionTrue)
+		}
+	}
+	return false
+}
+
+// running, ready and not being deleted
+func IsPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning || pod.GetDeletionTimestamp() != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func IsDeploymentAvailable(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// the counterpart of 'kubectl rollout status', i.e. the newest generation
+// is observed and all its replicas are updated and available
+func IsDeploymentRolledOut(deployment *appsv1.Deployment) bool {
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return status.UpdatedReplicas == replicas &&
+		status.Replicas == replicas &&
+		status.AvailableReplicas == replicas
+}
+
+func logWatchEvent(event watch.Event) {
+	object, err := meta.Accessor(event.Object)
+	if err != nil {
+		log.Debug.Printf("watch %s %T", event.Type, event.Object)
+		return
+	}
+	// typed objects usually come without TypeMeta
+	kind := event.Object.GetObjectKind().GroupVersionKind().Kind
+	if len(kind) == 0 {
+		kind = fmt.Sprintf("%T", event.Object)
+	}
+	log.Debug.With(log.Fields{log.NamespaceField: object.GetNamespace()}).Printf(
+		"watch %s %s %s (resourceVersion %s)", event.Type, kind, object.GetName(), object.GetResourceVersion())
+}
+
+// e.g. "mycluster-0 Running, mycluster-1 Pending", used to report the last observed state of a wait
+func DescribePods(pods []*corev1.Pod) string {
+	if len(pods) == 0 {
+		return "no pods"
+	}
+	states := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		state := string(pod.Status.Phase)
+		if pod.GetDeletionTimestamp() != nil {
+			state += " (terminating)"
+		}
+		states = append(states, pod.GetName()+" "+state)
+	}
+	return strings.Join(states, ", ")
+}
+
+func DescribeStatefulSet(sts *appsv1.StatefulSet) string {
+	return fmt.Sprintf("sts %s ready %d/%d, updated %d", sts.GetName(),
+		sts.Status.ReadyReplicas, sts.Status.Replicas, sts.Status.UpdatedReplicas)
+}
+
+func DescribeDeployment(deployment *appsv1.Deployment) string {
+	return fmt.Sprintf("deployment %s available %d/%d, updated %d", deployment.GetName(),
+		deployment.Status.AvailableReplicas, deployment.Status.Replicas, deployment.Status.UpdatedReplicas)
+}
@@ -8,13 +8,16 @@ import (
 	"strings"
 
 	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/container"
 	"github.com/marinesovitch/ote/test-suite/util/setup"
+	"github.com/marinesovitch/ote/test-suite/util/system"
 )
 
 type K8sEnvironment interface {
 	StartCluster() error
 	StopCluster() error
 	DeleteCluster() error
+	LoadImage(image string) error
 }
 
 func GetEnvironment(cfg *setup.Configuration) (K8sEnvironment, error) {
@@ -23,7 +26,44 @@ func GetEnvironment(cfg *setup.Configuration) (K8sEnvironment, error) {
 		return NewMinikubeEnv(cfg)
 	case common.EnvK3d:
 		return NewK3dEnv(cfg)
+	case common.EnvKind:
+		return NewKindEnv(cfg)
 	default:
 		return nil, errors.New("unknown k8s environment " + cfg.K8s.Environment)
 	}
 }
+
+// connect a local registry container to the network of the k8s cluster nodes,
+// so the nodes can pull images from it under the same host name as the host does
+func connectRegistryNetwork(cfg *setup.Configuration, containerEngine container.Engine, networkName string) error {
+	if !cfg.HasRegistry() {
+		return nil
+	}
+
+	registryHost, err := cfg.GetRegistryHost()
+	if err != nil {
+		return err
+	}
+
+	if loopback, err := system.IsLoopback(registryHost); !loopback || err != nil {
+		return err
+	}
+
+	networkExists, err := containerEngine.DoesNetworkExist(networkName)
+	if err != nil {
+		return err
+	}
+
+	if networkExists {
+		isRegistryHostConnected, err := containerEngine.IsNetworkConnectedTo(networkName, registryHost)
+		if err != nil {
+			return err
+		}
+
+		if isRegistryHostConnected {
+			return nil
+		}
+	}
+
+	return containerEngine.ConnectNetwork(networkName, registryHost)
+}
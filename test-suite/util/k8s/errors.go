@@ -0,0 +1,217 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/log"
+
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the request was rejected by the API server on its merits, e.g. by the CRD schema
+// validation or by an admission webhook (also one failing to answer), repeating it won't help
+type AdmissionError struct {
+	error
+	// the HTTP status, e.g. 422 for invalid objects, 400 for malformed ones
+	Code   int32
+	Reason metav1.StatusReason
+	// the rejected fields as reported by the server in the details of the status, e.g.
+	// {FieldValueInvalid, "spec.instances: Invalid value...", "spec.instances"}
+	Causes []metav1.StatusCause
+	// fields rejected by the strict field validation, parsed from the message (see parseUnknownFields)
+	UnknownFields []string
+}
+
+func (e AdmissionError) Unwrap() error {
+	return e.error
+}
+
+// returns the first cause for the given field (e.g. "spec.instances") or nil
+func (e AdmissionError) GetCause(field string) *metav1.StatusCause {
+	for i := range e.Causes {
+		if e.Causes[i].Field == field {
+			return &e.Causes[i]
+		}
+	}
+	return nil
+}
+
+func (e AdmissionError) HasUnknownField(field string) bool {
+	for _, unknownField := range e.UnknownFields {
+		if unknownField == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (e AdmissionError) GetFields() []string {
+	fields := make([]string, 0, len(e.Causes))
+	for _, cause := range e.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+// FALLBACK: unknown fields rejected with FieldValidation=Strict come without causes, the server
+// only lists them in the message of a bad request, e.g.
+// strict decoding error: unknown field "spec.bogus", unknown field "spec.other"
+// they are kept apart from the causes, which come only from the server
+var unknownFieldRx = regexp.MustCompile(`unknown field "([^"]+)"`)
+
+const strictDecodingErrorPrefix = "strict decoding error:"
+
+func parseUnknownFields(message string) []string {
+	if !strings.Contains(message, strictDecodingErrorPrefix) {
+		return nil
+	}
+	var fields []string
+	for _, match := range unknownFieldRx.FindAllStringSubmatch(message, -1) {
+		fields = append(fields, match[1])
+	}
+	return fields
+}
+
+func newAdmissionError(err error) AdmissionError {
+	admissionErr := AdmissionError{error: err}
+	var apiStatus k8s_err.APIStatus
+	if !errors.As(err, &apiStatus) {
+		return admissionErr
+	}
+
+	status := apiStatus.Status()
+	admissionErr.Code = status.Code
+	admissionErr.Reason = status.Reason
+	if status.Details != nil {
+		admissionErr.Causes = status.Details.Causes
+	}
+	admissionErr.UnknownFields = parseUnknownFields(status.Message)
+	return admissionErr
+}
+
+// the request failed for a reason which may pass, e.g. the API server is overloaded,
+// a timeout or a broken connection, it is worth repeating
+type TransientError struct {
+	error
+	// as suggested by the server (Retry-After), 0 if it didn't
+	RetryAfter time.Duration
+}
+
+func (e TransientError) Unwrap() error {
+	return e.error
+}
+
+func IsAdmissionError(err error) bool {
+	_, ok := AsAdmissionError(err)
+	return ok
+}
+
+func AsAdmissionError(err error) (*AdmissionError, bool) {
+	var admissionErr AdmissionError
+	if !errors.As(err, &admissionErr) {
+		return nil, false
+	}
+	return &admissionErr, true
+}
+
+func IsTransientError(err error) bool {
+	var transientErr TransientError
+	return errors.As(err, &transientErr)
+}
+
+// a webhook the API server failed to call (failurePolicy Fail) yields 500 like a failure of the
+// server itself, the message (and the only cause) tells, e.g.
+// Internal error occurred: failed calling webhook "x.mysql.oracle.com": ... connection refused
+// it is up to the webhook, so it is an admission error, not a transient one
+func isWebhookCallError(status metav1.Status) bool {
+	const WebhookCallFailure = "failed calling webhook"
+	if status.Reason != metav1.StatusReasonInternalError {
+		return false
+	}
+	if status.Details != nil {
+		for _, cause := range status.Details.Causes {
+			if strings.Contains(cause.Message, WebhookCallFailure) {
+				return true
+			}
+		}
+	}
+	return strings.Contains(status.Message, WebhookCallFailure)
+}
+
+// admission plugins (e.g. webhooks, quotas, pod security) and RBAC both reject with Forbidden,
+// the message of an authorization denial names the user, e.g.
+// pods is forbidden: User "system:serviceaccount:ns:sa" cannot create resource "pods" ...
+var authorizationDeniedRx = regexp.MustCompile(`User "[^"]*" cannot `)
+
+func isAuthorizationError(status metav1.Status) bool {
+	return status.Reason == metav1.StatusReasonForbidden && authorizationDeniedRx.MatchString(status.Message)
+}
+
+func newTransientError(err error) TransientError {
+	transientErr := TransientError{error: err}
+	if seconds, ok := k8s_err.SuggestsClientDelay(err); ok {
+		transientErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return transientErr
+}
+
+// wraps an error returned by client-go into one of the above types, other errors
+// (e.g. not found, conflict, authorization denials, local failures) are returned as they are
+func classifyApiError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiStatus k8s_err.APIStatus
+	if errors.As(err, &apiStatus) {
+		status := apiStatus.Status()
+		switch {
+		case isAuthorizationError(status):
+			return err
+		case isWebhookCallError(status):
+			return newAdmissionError(err)
+		}
+	}
+
+	switch {
+	case k8s_err.IsInvalid(err), k8s_err.IsBadRequest(err), k8s_err.IsForbidden(err),
+		k8s_err.IsAlreadyExists(err), k8s_err.IsRequestEntityTooLargeError(err):
+		return newAdmissionError(err)
+	case k8s_err.IsServerTimeout(err), k8s_err.IsTimeout(err), k8s_err.IsTooManyRequests(err),
+		k8s_err.IsServiceUnavailable(err), k8s_err.IsInternalError(err), k8s_err.IsUnexpectedServerError(err):
+		return newTransientError(err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return TransientError{error: err}
+	}
+	return err
+}
+
+// repeats f as long as it fails with a transient error
+func retryOnTransientError(f func() error) (err error) {
+	const MaxTrials = 5
+	const Interval = 2 * time.Second
+	for i := 0; i < MaxTrials; i++ {
+		err = f()
+		var transientErr TransientError
+		if !errors.As(err, &transientErr) {
+			return err
+		}
+		log.Warning.Printf("transient error (trial %d of %d): %v", i+1, MaxTrials, err)
+		if transientErr.RetryAfter > Interval {
+			time.Sleep(transientErr.RetryAfter)
+		} else {
+			time.Sleep(Interval)
+		}
+	}
+	return err
+}
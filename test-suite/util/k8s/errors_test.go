@@ -0,0 +1,110 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	k8s_err "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestParseUnknownFields(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected []string
+	}{
+		{`strict decoding error: unknown field "spec.bogus"`, []string{"spec.bogus"}},
+		{`strict decoding error: unknown field "spec.bogus", unknown field "spec.podSpec.other"`,
+			[]string{"spec.bogus", "spec.podSpec.other"}},
+		{`strict decoding error: duplicate field "spec.instances"`, nil},
+		// only messages of the strict field validation count
+		{`admission webhook denied the request: unknown field "spec.bogus"`, nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if fields := parseUnknownFields(test.message); !reflect.DeepEqual(fields, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.message, test.expected, fields)
+		}
+	}
+}
+
+func TestClassifyInvalid(t *testing.T) {
+	gk := schema.GroupKind{Group: "mysql.oracle.com", Kind: "InnoDBCluster"}
+	errs := field.ErrorList{
+		field.Invalid(field.NewPath("spec", "instances"), 0, "should be greater than or equal to 1"),
+		field.Required(field.NewPath("spec", "secretName"), ""),
+	}
+	err := classifyApiError(k8s_err.NewInvalid(gk, "mycluster", errs))
+
+	admissionErr, ok := AsAdmissionError(err)
+	if !ok {
+		t.Fatalf("expected an admission error, got %v", err)
+	}
+	if !reflect.DeepEqual(admissionErr.GetFields(), []string{"spec.instances", "spec.secretName"}) {
+		t.Errorf("unexpected causes %v", admissionErr.Causes)
+	}
+	if cause := admissionErr.GetCause("spec.secretName"); cause == nil || cause.Type != "FieldValueRequired" {
+		t.Errorf("unexpected cause of spec.secretName %v", cause)
+	}
+	if len(admissionErr.UnknownFields) > 0 {
+		t.Errorf("unexpected unknown fields %v", admissionErr.UnknownFields)
+	}
+}
+
+func TestClassifyStrictDecodingError(t *testing.T) {
+	err := classifyApiError(k8s_err.NewBadRequest(`strict decoding error: unknown field "spec.bogus"`))
+
+	admissionErr, ok := AsAdmissionError(err)
+	if !ok {
+		t.Fatalf("expected an admission error, got %v", err)
+	}
+	// nothing is made up, the causes come only from the server
+	if len(admissionErr.Causes) > 0 {
+		t.Errorf("unexpected causes %v", admissionErr.Causes)
+	}
+	if !admissionErr.HasUnknownField("spec.bogus") {
+		t.Errorf("expected spec.bogus among unknown fields, got %v", admissionErr.UnknownFields)
+	}
+}
+
+func TestClassifyServerErrors(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	webhookErr := k8s_err.NewInternalError(errors.New(
+		`failed calling webhook "fake-oci.ote.mysql.oracle.com": Post "https://172.18.0.1:43567/ote/mutate-pods": dial tcp 172.18.0.1:43567: connect: connection refused`))
+	tests := []struct {
+		name       string
+		err        error
+		admission  bool
+		transient  bool
+		retryAfter time.Duration
+	}{
+		{"webhook call", webhookErr, true, false, 0},
+		{"webhook denial", k8s_err.NewForbidden(pods, "mypod",
+			errors.New(`admission webhook "x.mysql.oracle.com" denied the request: no way`)), true, false, 0},
+		{"quota", k8s_err.NewForbidden(pods, "mypod",
+			errors.New(`exceeded quota: q, requested: pods=1, used: pods=1, limited: pods=1`)), true, false, 0},
+		{"rbac", k8s_err.NewForbidden(pods, "mypod",
+			errors.New(`User "system:serviceaccount:ns:sa" cannot create resource "pods" in API group "" in the namespace "ns"`)), false, false, 0},
+		{"internal", k8s_err.NewInternalError(errors.New("etcdserver: leader changed")), false, true, 0},
+		{"overload", k8s_err.NewTooManyRequests("too many requests", 7), false, true, 7 * time.Second},
+		{"unavailable", k8s_err.NewServiceUnavailable("the server is shutting down"), false, true, 0},
+	}
+	for _, test := range tests {
+		err := classifyApiError(test.err)
+		if IsAdmissionError(err) != test.admission {
+			t.Errorf("%s: expected admission error %v, got %T %v", test.name, test.admission, err, err)
+		}
+		var transientErr TransientError
+		if errors.As(err, &transientErr) != test.transient {
+			t.Errorf("%s: expected transient error %v, got %T %v", test.name, test.transient, err, err)
+		}
+		if transientErr.RetryAfter != test.retryAfter {
+			t.Errorf("%s: expected retry after %s, got %s", test.name, test.retryAfter, transientErr.RetryAfter)
+		}
+	}
+}
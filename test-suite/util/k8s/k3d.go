@@ -4,6 +4,9 @@
 package k8s
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/marinesovitch/ote/test-suite/util/container"
 	"github.com/marinesovitch/ote/test-suite/util/setup"
 	"github.com/marinesovitch/ote/test-suite/util/system"
@@ -30,38 +33,38 @@ func (k *k3dEnv) prepareRegistryArgs(args []string) ([]string, error) {
 	return args, nil
 }
 
-func (k *k3dEnv) connectNetwork() error {
-	if !k.cfg.HasRegistry() {
-		return nil
+func (k *k3dEnv) prepareTopologyArgs(args []string) ([]string, error) {
+	args = append(args,
+		"--servers", strconv.Itoa(k.cfg.K8s.Nodes),
+		"--agents", strconv.Itoa(k.cfg.K8s.Agents))
+
+	if k.cfg.K8s.Agents == 0 {
+		return args, nil
 	}
 
-	registryHost, err := k.cfg.GetRegistryHost()
+	const AllAgentsFilter = "@agent:*"
+	labels, err := setup.ParseNodeLabels(k.cfg.K8s.AgentLabels)
 	if err != nil {
-		return err
+		return args, err
 	}
-
-	if loopback, err := system.IsLoopback(registryHost); !loopback || err != nil {
-		return err
+	for key, value := range labels {
+		args = append(args, "--k3s-arg", fmt.Sprintf("--node-label=%s=%s%s", key, value, AllAgentsFilter))
 	}
 
-	networkName := k.cfg.GetContextName()
-	networkExists, err := k.containerEngine.DoesNetworkExist(networkName)
+	taints, err := setup.ParseNodeTaints(k.cfg.K8s.AgentTaints)
 	if err != nil {
-		return err
+		return args, err
 	}
-
-	if networkExists {
-		isRegistryHostConnected, err := k.containerEngine.IsNetworkConnectedTo(networkName, registryHost)
-		if err != nil {
-			return err
-		}
-
-		if isRegistryHostConnected {
-			return nil
-		}
+	for _, taint := range taints {
+		args = append(args, "--k3s-arg", fmt.Sprintf("--node-taint=%s%s", taint, AllAgentsFilter))
 	}
 
-	return k.containerEngine.ConnectNetwork(networkName, registryHost)
+	return args, nil
+}
+
+func (k *k3dEnv) connectNetwork() error {
+	networkName := k.cfg.GetContextName()
+	return connectRegistryNetwork(k.cfg, k.containerEngine, networkName)
 }
 
 func (k *k3dEnv) StartCluster() error {
@@ -71,7 +74,12 @@ func (k *k3dEnv) StartCluster() error {
 		k.cfg.K8s.ClusterName,
 	}
 
-	args, err := k.prepareRegistryArgs(args)
+	args, err := k.prepareTopologyArgs(args)
+	if err != nil {
+		return err
+	}
+
+	args, err = k.prepareRegistryArgs(args)
 	if err != nil {
 		return err
 	}
@@ -102,6 +110,17 @@ func (k *k3dEnv) DeleteCluster() error {
 	return k.executeCmd(args)
 }
 
+func (k *k3dEnv) LoadImage(image string) error {
+	args := []string{
+		"image",
+		"import",
+		image,
+		"--cluster",
+		k.cfg.K8s.ClusterName,
+	}
+	return k.executeCmd(args)
+}
+
 func NewK3dEnv(cfg *setup.Configuration) (K8sEnvironment, error) {
 	containerEngine, err := container.GetEngine(cfg.Images.Engine)
 	if err != nil {
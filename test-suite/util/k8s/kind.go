@@ -0,0 +1,164 @@
+// Kind
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
+	"github.com/marinesovitch/ote/test-suite/util/container"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+	"github.com/marinesovitch/ote/test-suite/util/system"
+)
+
+// all kind clusters share the same network, unless overridden with
+// KIND_EXPERIMENTAL_DOCKER_NETWORK
+const kindNetworkName = "kind"
+
+type kindEnv struct {
+	cfg             *setup.Configuration
+	containerEngine container.Engine
+}
+
+func (k *kindEnv) executeCmd(args []string) error {
+	return system.Execute(k.cfg.K8s.Environment, args...)
+}
+
+func (k *kindEnv) executeCmdGetOutput(args []string) ([]string, error) {
+	output, err := system.ExecuteGetOutput(k.cfg.K8s.Environment, args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+func (k *kindEnv) getClusters() ([]string, error) {
+	args := []string{
+		"get",
+		"clusters",
+	}
+	return k.executeCmdGetOutput(args)
+}
+
+func (k *kindEnv) getNodes() ([]string, error) {
+	args := []string{
+		"get",
+		"nodes",
+		"--name",
+		k.cfg.K8s.ClusterName,
+	}
+	return k.executeCmdGetOutput(args)
+}
+
+func (k *kindEnv) doesClusterExist() (bool, error) {
+	clusters, err := k.getClusters()
+	if err != nil {
+		return false, err
+	}
+	return auxi.Contains(clusters, k.cfg.K8s.ClusterName), nil
+}
+
+func (k *kindEnv) prepareConfigArgs(args []string) ([]string, error) {
+	clusterConfigPath, err := setup.GenerateKindClusterConfig(k.cfg)
+	if err != nil {
+		return args, err
+	}
+	return append(args, "--config", clusterConfigPath), nil
+}
+
+func (k *kindEnv) connectNetwork() error {
+	return connectRegistryNetwork(k.cfg, k.containerEngine, kindNetworkName)
+}
+
+func (k *kindEnv) createCluster() error {
+	args := []string{
+		"create",
+		"cluster",
+		"--name",
+		k.cfg.K8s.ClusterName,
+	}
+
+	args, err := k.prepareConfigArgs(args)
+	if err != nil {
+		return err
+	}
+
+	return k.executeCmd(args)
+}
+
+// kind has no notion of a stopped cluster, it is enough to (re)start the node containers
+func (k *kindEnv) startNodes() error {
+	nodes, err := k.getNodes()
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := k.containerEngine.StartContainer(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *kindEnv) StartCluster() error {
+	clusterExists, err := k.doesClusterExist()
+	if err != nil {
+		return err
+	}
+
+	if clusterExists {
+		err = k.startNodes()
+	} else {
+		err = k.createCluster()
+	}
+	if err != nil {
+		return err
+	}
+
+	return k.connectNetwork()
+}
+
+func (k *kindEnv) StopCluster() error {
+	nodes, err := k.getNodes()
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := k.containerEngine.StopContainer(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *kindEnv) DeleteCluster() error {
+	args := []string{
+		"delete",
+		"cluster",
+		"--name",
+		k.cfg.K8s.ClusterName,
+	}
+	return k.executeCmd(args)
+}
+
+func (k *kindEnv) LoadImage(image string) error {
+	args := []string{
+		"load",
+		"docker-image",
+		image,
+		"--name",
+		k.cfg.K8s.ClusterName,
+	}
+	return k.executeCmd(args)
+}
+
+func NewKindEnv(cfg *setup.Configuration) (K8sEnvironment, error) {
+	containerEngine, err := container.GetEngine(cfg.Images.Engine)
+	if err != nil {
+		return nil, err
+	}
+	return &kindEnv{cfg: cfg, containerEngine: containerEngine}, nil
+}
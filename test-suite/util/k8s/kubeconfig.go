@@ -0,0 +1,37 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"fmt"
+
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func HasContext(kubeConfigPath string, context string) (bool, error) {
+	kubeCfg, err := clientcmd.LoadFromFile(kubeConfigPath)
+	if err != nil {
+		return false, err
+	}
+	_, found := kubeCfg.Contexts[context]
+	return found, nil
+}
+
+// build the rest config for the context of the cluster set up by ote
+func BuildConfigForContext(cfg *setup.Configuration) (*rest.Config, error) {
+	context := cfg.GetContextName()
+	hasContext, err := HasContext(cfg.K8s.KubeConfig, context)
+	if err != nil {
+		return nil, err
+	}
+	if !hasContext {
+		return nil, fmt.Errorf("context %s not found in %s", context, cfg.K8s.KubeConfig)
+	}
+
+	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: cfg.K8s.KubeConfig}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
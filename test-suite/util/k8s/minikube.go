@@ -4,6 +4,9 @@
 package k8s
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/marinesovitch/ote/test-suite/util/setup"
 	"github.com/marinesovitch/ote/test-suite/util/system"
 )
@@ -28,6 +31,50 @@ func (m *minikubeEnv) prepareRegistryArgs(args []string) ([]string, error) {
 	return args, nil
 }
 
+func (m *minikubeEnv) prepareTopologyArgs(args []string) []string {
+	return append(args, "--nodes", strconv.Itoa(m.cfg.GetNodesCount()))
+}
+
+// minikube names nodes <profile>, <profile>-m02, <profile>-m03, ...
+func (m *minikubeEnv) getNodeName(index int) string {
+	if index == 0 {
+		return m.cfg.K8s.ClusterName
+	}
+	return fmt.Sprintf("%s-m%02d", m.cfg.K8s.ClusterName, index+1)
+}
+
+// minikube cannot label nor taint nodes at start, so do it afterwards
+func (m *minikubeEnv) setupAgents() error {
+	labels, err := setup.ParseNodeLabels(m.cfg.K8s.AgentLabels)
+	if err != nil {
+		return err
+	}
+
+	taints, err := setup.ParseNodeTaints(m.cfg.K8s.AgentTaints)
+	if err != nil {
+		return err
+	}
+
+	kubectl := Kubectl{}
+	context := m.cfg.GetContextName()
+	for i := m.cfg.K8s.Nodes; i < m.cfg.GetNodesCount(); i++ {
+		nodeName := m.getNodeName(i)
+		for key, value := range labels {
+			label := fmt.Sprintf("%s=%s", key, value)
+			if err := kubectl.Run("label", "node", nodeName, label, "--overwrite", "--context", context); err != nil {
+				return err
+			}
+		}
+
+		for _, taint := range taints {
+			if err := kubectl.Run("taint", "node", nodeName, taint.String(), "--overwrite", "--context", context); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (m *minikubeEnv) StartCluster() error {
 	args := []string{
 		"start",
@@ -35,12 +82,19 @@ func (m *minikubeEnv) StartCluster() error {
 		m.cfg.K8s.ClusterName,
 	}
 
+	args = m.prepareTopologyArgs(args)
+
 	args, err := m.prepareRegistryArgs(args)
 	if err != nil {
 		return err
 	}
 
-	return m.executeCmd(args)
+	err = m.executeCmd(args)
+	if err != nil {
+		return err
+	}
+
+	return m.setupAgents()
 }
 
 func (m *minikubeEnv) StopCluster() error {
@@ -61,6 +115,17 @@ func (m *minikubeEnv) DeleteCluster() error {
 	return m.executeCmd(args)
 }
 
+func (m *minikubeEnv) LoadImage(image string) error {
+	args := []string{
+		"image",
+		"load",
+		image,
+		"-p",
+		m.cfg.K8s.ClusterName,
+	}
+	return m.executeCmd(args)
+}
+
 func NewMinikubeEnv(cfg *setup.Configuration) (K8sEnvironment, error) {
 	return &minikubeEnv{cfg}, nil
 }
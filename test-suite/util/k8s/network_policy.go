@@ -0,0 +1,42 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"context"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the label set by the statefulset controller on each of its pods
+const StatefulSetPodNameLabel = "statefulset.kubernetes.io/pod-name"
+
+// a policy selecting the given pods without any allowed traffic, i.e. it cuts them
+// off the network, please note it takes effect only if the CNI enforces policies
+func NewIsolatingNetworkPolicy(namespace string, name string, podSelector map[string]string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podSelector},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+func (c *Client) CreateNetworkPolicy(policy *networkingv1.NetworkPolicy) error {
+	_, err := c.clientset.NetworkingV1().NetworkPolicies(policy.GetNamespace()).Create(context.Background(), policy, metav1.CreateOptions{})
+	return err
+}
+
+func (c *Client) DeleteNetworkPolicy(namespace string, name string) error {
+	const Timeout = 30
+	return c.deleteItem(
+		namespace,
+		name,
+		func(ctx context.Context, namespace string, name string) error {
+			return c.clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+		Timeout,
+	)
+}
@@ -0,0 +1,42 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (c *Client) GetNode(name string) (*corev1.Node, error) {
+	return c.clientset.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// the counterpart of 'kubectl cordon' (true) and 'kubectl uncordon' (false)
+func (c *Client) SetNodeUnschedulable(name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err := c.clientset.CoreV1().Nodes().Patch(context.Background(), name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+func (c *Client) ListPodsOnNode(nodeName string) (*corev1.PodList, error) {
+	const AllNamespaces = ""
+	options := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	}
+	return c.clientset.CoreV1().Pods(AllNamespaces).List(context.Background(), options)
+}
+
+// unlike deletion, the eviction respects pod disruption budgets
+func (c *Client) EvictPod(namespace string, name string) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}
+	return c.clientset.CoreV1().Pods(namespace).EvictV1(context.Background(), eviction)
+}
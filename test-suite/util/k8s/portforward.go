@@ -0,0 +1,172 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// an in-process tunnel to a pod port, it works like 'kubectl port-forward'
+// but doesn't need kubectl nor spawns any subprocess
+type PortForward struct {
+	LocalPort uint16
+	cancel    context.CancelFunc
+	doneCh    chan struct{}
+}
+
+const portForwardReadyTimeout = 30 * time.Second
+
+func (c *Client) PortForward(ctx context.Context, namespace string, podName string, podPort int) (*PortForward, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(c.restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	const LocalAddress = "127.0.0.1"
+	// local port 0 means a random free port
+	ports := []string{fmt.Sprintf("0:%d", podPort)}
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{LocalAddress}, ports, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	forwardCtx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		errCh <- forwarder.ForwardPorts()
+	}()
+	go func() {
+		select {
+		case <-forwardCtx.Done():
+			close(stopCh)
+		case <-doneCh:
+		}
+	}()
+
+	portForward := &PortForward{cancel: cancel, doneCh: doneCh}
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		cancel()
+		if err == nil {
+			err = errors.New("port forwarding stopped before it got ready")
+		}
+		return nil, fmt.Errorf("cannot forward port %d of %s/%s: %v", podPort, namespace, podName, err)
+	case <-time.After(portForwardReadyTimeout):
+		portForward.Close()
+		return nil, fmt.Errorf("timeout waiting for port forwarding to %s/%s:%d", namespace, podName, podPort)
+	case <-ctx.Done():
+		portForward.Close()
+		return nil, ctx.Err()
+	}
+
+	forwardedPorts, err := forwarder.GetPorts()
+	if err != nil {
+		portForward.Close()
+		return nil, err
+	}
+	if len(forwardedPorts) == 0 {
+		portForward.Close()
+		return nil, fmt.Errorf("no port forwarded to %s/%s:%d", namespace, podName, podPort)
+	}
+
+	portForward.LocalPort = forwardedPorts[0].Local
+	return portForward, nil
+}
+
+// stops forwarding and waits until the tunnel is torn down
+func (p *PortForward) Close() {
+	p.cancel()
+	<-p.doneCh
+}
+
+// ---------------------------
+
+// the port may be given by its name (e.g. mysql-ro) or number (e.g. 6447)
+func findServicePort(service *corev1.Service, port string) (*corev1.ServicePort, error) {
+	for i, servicePort := range service.Spec.Ports {
+		if servicePort.Name == port || strconv.Itoa(int(servicePort.Port)) == port {
+			return &service.Spec.Ports[i], nil
+		}
+	}
+	return nil, fmt.Errorf("service %s/%s has no port %s", service.GetNamespace(), service.GetName(), port)
+}
+
+func resolveTargetPort(pod *corev1.Pod, servicePort *corev1.ServicePort) (int, error) {
+	targetPort := servicePort.TargetPort
+	if targetPort.Type == intstr.Int {
+		if targetPort.IntVal == 0 {
+			return int(servicePort.Port), nil
+		}
+		return int(targetPort.IntVal), nil
+	}
+
+	for _, container := range pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == targetPort.StrVal {
+				return int(containerPort.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s/%s has no port %s", pod.GetNamespace(), pod.GetName(), targetPort.StrVal)
+}
+
+// port-forwarding can't balance the load, so like 'kubectl port-forward svc/...'
+// it tunnels to a single ready pod behind the service
+func (c *Client) PortForwardService(ctx context.Context, namespace string, serviceName string, port string) (*PortForward, error) {
+	service, err := c.GetService(namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	servicePort, err := findServicePort(service, port)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := labels.SelectorFromSet(service.Spec.Selector).String()
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !IsPodReady(pod) {
+			continue
+		}
+
+		podPort, err := resolveTargetPort(pod, servicePort)
+		if err != nil {
+			return nil, err
+		}
+		return c.PortForward(ctx, namespace, pod.GetName(), podPort)
+	}
+	return nil, fmt.Errorf("no ready pods behind service %s/%s", namespace, serviceName)
+}
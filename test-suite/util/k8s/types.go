@@ -5,6 +5,8 @@ type Kind int
 const OperatorGroup = "mysql.oracle.com"
 const OperatorVersion = "v2"
 const OperatorInnoDBClusters = "innodbclusters"
+const OperatorMySQLBackups = "mysqlbackups"
+const OperatorDeployment = "mysql-operator"
 
 const (
 	ConfigMap Kind = iota
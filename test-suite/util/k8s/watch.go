@@ -0,0 +1,317 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the scope of a watch-based wait
+type WatchParams struct {
+	Namespace string
+	// if set, only the object of the given name is watched, otherwise all objects in the namespace
+	Name string
+	// if set, only the changes after the given version are considered, otherwise the wait
+	// starts with the current state of objects
+	SinceResourceVersion string
+}
+
+func (p *WatchParams) describe(resource string) string {
+	if len(p.Name) > 0 {
+		return fmt.Sprintf("%s %s/%s", resource, p.Namespace, p.Name)
+	}
+	return fmt.Sprintf("%s in namespace %s", resource, p.Namespace)
+}
+
+// besides the verdict, conditions return a short description of the observed state,
+// it is reported if the wait times out
+type PodsCondition func(pods []*corev1.Pod) (bool, string)
+type StatefulSetsCondition func(stss []*appsv1.StatefulSet) (bool, string)
+type DeploymentsCondition func(deployments []*appsv1.Deployment) (bool, string)
+type EventsCondition func(events []*corev1.Event) (bool, string)
+type CustomResourcesCondition func(crs []*unstructured.Unstructured) (bool, string)
+type InnoDBClustersCondition func(ics []*InnoDBCluster) (bool, string)
+
+type objectsCondition func(objects []runtime.Object) (bool, string)
+
+// ---------------------------
+
+// the observed objects, kept up to date with the watch events
+type observedObjects map[string]runtime.Object
+
+func (o observedObjects) update(event watch.Event) {
+	object, err := meta.Accessor(event.Object)
+	if err != nil {
+		return
+	}
+
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		o[object.GetName()] = event.Object
+	case watch.Deleted:
+		delete(o, object.GetName())
+	}
+}
+
+func (o observedObjects) sorted() []runtime.Object {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	objects := make([]runtime.Object, 0, len(names))
+	for _, name := range names {
+		objects = append(objects, o[name])
+	}
+	return objects
+}
+
+type watchWaiter struct {
+	what      string
+	lw        cache.ListerWatcher
+	objType   runtime.Object
+	params    WatchParams
+	condition objectsCondition
+
+	observed  observedObjects
+	lastState string
+}
+
+func (w *watchWaiter) check() bool {
+	done, state := w.condition(w.observed.sorted())
+	w.lastState = state
+	return done
+}
+
+func (w *watchWaiter) onEvent(event watch.Event) (bool, error) {
+	logWatchEvent(event)
+	if event.Type == watch.Error {
+		return false, fmt.Errorf("error while watching %s: %v", w.what, event.Object)
+	}
+	w.observed.update(event)
+	return w.check(), nil
+}
+
+// the informer lists the current state first, then follows with the changes
+func (w *watchWaiter) untilWithSync(ctx context.Context) error {
+	precondition := func(store cache.Store) (bool, error) {
+		for _, item := range store.List() {
+			if object, ok := item.(runtime.Object); ok {
+				w.observed.update(watch.Event{Type: watch.Added, Object: object})
+			}
+		}
+		return w.check(), nil
+	}
+	_, err := watchtools.UntilWithSync(ctx, w.lw, w.objType, precondition, w.onEvent)
+	return err
+}
+
+// the current state is listed only to know which objects exist, but the condition
+// is evaluated for the first time when a change after the given version comes
+func (w *watchWaiter) untilSinceVersion(ctx context.Context) error {
+	list, err := w.lw.List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		w.observed.update(watch.Event{Type: watch.Added, Object: item})
+	}
+	w.lastState = "no changes since resource version " + w.params.SinceResourceVersion
+
+	_, err = watchtools.Until(ctx, w.params.SinceResourceVersion, w.lw, w.onEvent)
+	return err
+}
+
+func (w *watchWaiter) run(ctx context.Context) error {
+	w.observed = make(observedObjects)
+
+	var err error
+	if w.params.SinceResourceVersion == common.AnyResourceVersion {
+		err = w.untilWithSync(ctx)
+	} else {
+		err = w.untilSinceVersion(ctx)
+	}
+
+	if errors.Is(err, wait.ErrWaitTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return fmt.Errorf("timeout waiting for %s, last observed state: %s", w.what, w.lastState)
+	}
+	return err
+}
+
+func newWatchFieldSelector(params WatchParams) string {
+	if len(params.Name) == 0 {
+		return fields.Everything().String()
+	}
+	return fields.OneTermEqualSelector("metadata.name", params.Name).String()
+}
+
+func newListWatch(getter cache.Getter, resource string, params WatchParams, fieldSelector string) cache.ListerWatcher {
+	return cache.NewFilteredListWatchFromClient(getter, resource, params.Namespace, func(options *metav1.ListOptions) {
+		options.FieldSelector = fieldSelector
+	})
+}
+
+// ---------------------------
+
+// the wait is abandoned when ctx is done, on timeout the last observed state is reported
+func (c *Client) WaitOnPods(ctx context.Context, params WatchParams, condition PodsCondition) error {
+	waiter := watchWaiter{
+		what:    params.describe("pods"),
+		lw:      newListWatch(c.clientset.CoreV1().RESTClient(), "pods", params, newWatchFieldSelector(params)),
+		objType: &corev1.Pod{},
+		params:  params,
+		condition: func(objects []runtime.Object) (bool, string) {
+			pods := make([]*corev1.Pod, 0, len(objects))
+			for _, object := range objects {
+				pods = append(pods, object.(*corev1.Pod))
+			}
+			return condition(pods)
+		},
+	}
+	return waiter.run(ctx)
+}
+
+func (c *Client) WaitOnStatefulSets(ctx context.Context, params WatchParams, condition StatefulSetsCondition) error {
+	waiter := watchWaiter{
+		what:    params.describe("statefulsets"),
+		lw:      newListWatch(c.clientset.AppsV1().RESTClient(), "statefulsets", params, newWatchFieldSelector(params)),
+		objType: &appsv1.StatefulSet{},
+		params:  params,
+		condition: func(objects []runtime.Object) (bool, string) {
+			stss := make([]*appsv1.StatefulSet, 0, len(objects))
+			for _, object := range objects {
+				stss = append(stss, object.(*appsv1.StatefulSet))
+			}
+			return condition(stss)
+		},
+	}
+	return waiter.run(ctx)
+}
+
+func (c *Client) WaitOnDeployments(ctx context.Context, params WatchParams, condition DeploymentsCondition) error {
+	waiter := watchWaiter{
+		what:    params.describe("deployments"),
+		lw:      newListWatch(c.clientset.AppsV1().RESTClient(), "deployments", params, newWatchFieldSelector(params)),
+		objType: &appsv1.Deployment{},
+		params:  params,
+		condition: func(objects []runtime.Object) (bool, string) {
+			deployments := make([]*appsv1.Deployment, 0, len(objects))
+			for _, object := range objects {
+				deployments = append(deployments, object.(*appsv1.Deployment))
+			}
+			return condition(deployments)
+		},
+	}
+	return waiter.run(ctx)
+}
+
+// events are matched against all existing ones, so the
+// params.SinceResourceVersion is not applied, and params.Name is the involved object name
+func (c *Client) WaitOnEvents(ctx context.Context, params WatchParams, involvedKind string, condition EventsCondition) error {
+	selector := fields.Set{"involvedObject.kind": involvedKind}
+	if len(params.Name) > 0 {
+		selector["involvedObject.name"] = params.Name
+	}
+	what := params.describe("events of " + involvedKind)
+	params.SinceResourceVersion = common.AnyResourceVersion
+	waiter := watchWaiter{
+		what:    what,
+		lw:      newListWatch(c.clientset.CoreV1().RESTClient(), "events", params, selector.AsSelector().String()),
+		objType: &corev1.Event{},
+		params:  params,
+		condition: func(objects []runtime.Object) (bool, string) {
+			events := make([]*corev1.Event, 0, len(objects))
+			for _, object := range objects {
+				events = append(events, object.(*corev1.Event))
+			}
+			return condition(events)
+		},
+	}
+	return waiter.run(ctx)
+}
+
+func newDynamicListWatch(ctx context.Context, resourceClient dynamic.ResourceInterface, fieldSelector string) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return resourceClient.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return resourceClient.Watch(ctx, options)
+		},
+	}
+}
+
+func newUnstructuredCondition(condition CustomResourcesCondition) objectsCondition {
+	return func(objects []runtime.Object) (bool, string) {
+		crs := make([]*unstructured.Unstructured, 0, len(objects))
+		for _, object := range objects {
+			crs = append(crs, object.(*unstructured.Unstructured))
+		}
+		return condition(crs)
+	}
+}
+
+func (c *Client) WaitOnCustomResources(ctx context.Context, params WatchParams, resource Kind, condition CustomResourcesCondition) error {
+	resourceClient := c.dynamic.Resource(getCustomResourceGVR(resource)).Namespace(params.Namespace)
+	waiter := watchWaiter{
+		what:      params.describe(resource.String()),
+		lw:        newDynamicListWatch(ctx, resourceClient, newWatchFieldSelector(params)),
+		objType:   &unstructured.Unstructured{},
+		params:    params,
+		condition: newUnstructuredCondition(condition),
+	}
+	return waiter.run(ctx)
+}
+
+// CRDs are cluster-scoped, all of them are watched starting with the current state
+func (c *Client) WaitOnCustomResourceDefinitions(ctx context.Context, condition CustomResourcesCondition) error {
+	params := WatchParams{SinceResourceVersion: common.AnyResourceVersion}
+	waiter := watchWaiter{
+		what:      "customresourcedefinitions",
+		lw:        newDynamicListWatch(ctx, c.dynamic.Resource(customResourceDefinitionGVR), newWatchFieldSelector(params)),
+		objType:   &unstructured.Unstructured{},
+		params:    params,
+		condition: newUnstructuredCondition(condition),
+	}
+	return waiter.run(ctx)
+}
+
+func (c *Client) WaitOnInnoDBClusters(ctx context.Context, params WatchParams, condition InnoDBClustersCondition) error {
+	return c.WaitOnCustomResources(ctx, params, CRDInnoDBCluster, func(crs []*unstructured.Unstructured) (bool, string) {
+		ics := make([]*InnoDBCluster, 0, len(crs))
+		for _, cr := range crs {
+			ic, err := NewInnoDBCluster(cr)
+			if err != nil {
+				return false, err.Error()
+			}
+			ics = append(ics, ic)
+		}
+		return condition(ics)
+	})
+}
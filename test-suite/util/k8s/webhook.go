@@ -0,0 +1,29 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package k8s
+
+import (
+	"context"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// webhook configurations are cluster-wide, so the ones registered by units are labelled with
+// their namespaces, e.g. to find the leftovers of a crashed run
+func (c *Client) ListMutatingWebhookConfigurations(labelSelector string) (*admissionregistrationv1.MutatingWebhookConfigurationList, error) {
+	return c.clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().List(
+		context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+}
+
+func (c *Client) DeleteMutatingWebhookConfiguration(name string) error {
+	const Timeout = 30
+	return c.deleteItem(
+		"",
+		name,
+		func(ctx context.Context, _ string, name string) error {
+			return c.clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(ctx, name, metav1.DeleteOptions{})
+		},
+		Timeout,
+	)
+}
@@ -0,0 +1,288 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarningLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel:   "DEBUG",
+	InfoLevel:    "INFO",
+	WarningLevel: "WARNING",
+	ErrorLevel:   "ERROR",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+func ParseLevel(levelStr string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(levelStr, name) {
+			return level, nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %s - expected one of [debug|info|warning|error]", levelStr)
+}
+
+type Format int
+
+const (
+	TextFormat Format = iota
+	JsonFormat
+)
+
+func ParseFormat(formatStr string) (Format, error) {
+	switch strings.ToLower(formatStr) {
+	case "text":
+		return TextFormat, nil
+	case "json":
+		return JsonFormat, nil
+	default:
+		return TextFormat, fmt.Errorf("unknown log format %s - expected one of [text|json]", formatStr)
+	}
+}
+
+// additional data attached to a record, e.g. namespace, test
+type Fields map[string]string
+
+const NamespaceField = "namespace"
+const TestField = "test"
+
+// ---------------------------
+
+type record struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Fields  Fields `json:"fields,omitempty"`
+}
+
+func (r *record) text() string {
+	var line strings.Builder
+	fmt.Fprintf(&line, "%s %-7s ", r.Time, r.Level)
+	if len(r.Fields) > 0 {
+		keys := make([]string, 0, len(r.Fields))
+		for key := range r.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		line.WriteString("[")
+		for i, key := range keys {
+			if i > 0 {
+				line.WriteString(" ")
+			}
+			fmt.Fprintf(&line, "%s=%s", key, r.Fields[key])
+		}
+		line.WriteString("] ")
+	}
+	line.WriteString(r.Message)
+	return line.String()
+}
+
+func (r *record) format(format Format) string {
+	if format == JsonFormat {
+		if data, err := json.Marshal(r); err == nil {
+			return string(data)
+		}
+	}
+	return r.text()
+}
+
+// ---------------------------
+
+// the console gets records of the configured level, while the unit files get all of them,
+// a record goes to the file of the unit whose namespace it is tagged with, units running
+// one at a time get also the records without a namespace
+type sink struct {
+	mutex     sync.Mutex
+	level     Level
+	format    Format
+	fields    Fields
+	stdout    io.Writer
+	stderr    io.Writer
+	unitFiles map[string]*os.File
+}
+
+var output = sink{
+	level:     InfoLevel,
+	format:    TextFormat,
+	fields:    make(Fields),
+	stdout:    os.Stdout,
+	stderr:    os.Stderr,
+	unitFiles: make(map[string]*os.File),
+}
+
+func (s *sink) getUnitFile(fields Fields) *os.File {
+	if namespace, ok := fields[NamespaceField]; ok {
+		if file, ok := s.unitFiles[namespace]; ok {
+			return file
+		}
+	}
+	if len(s.unitFiles) == 1 {
+		for _, file := range s.unitFiles {
+			return file
+		}
+	}
+	return nil
+}
+
+func (s *sink) write(level Level, message string, fields Fields) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := record{
+		Time:    time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		Level:   level.String(),
+		Message: strings.TrimSuffix(message, "\n"),
+	}
+	if len(s.fields)+len(fields) > 0 {
+		r.Fields = make(Fields)
+		for key, value := range s.fields {
+			r.Fields[key] = value
+		}
+		for key, value := range fields {
+			r.Fields[key] = value
+		}
+	}
+
+	if level >= s.level {
+		console := s.stdout
+		if level >= ErrorLevel {
+			console = s.stderr
+		}
+		fmt.Fprintln(console, r.format(s.format))
+	}
+
+	if unitFile := s.getUnitFile(r.Fields); unitFile != nil {
+		fmt.Fprintln(unitFile, r.format(s.format))
+	}
+}
+
+func Configure(level Level, format Format) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	output.level = level
+	output.format = format
+}
+
+// the field is attached to all following records until it is removed
+func SetField(key string, value string) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	output.fields[key] = value
+}
+
+func RemoveField(key string) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	delete(output.fields, key)
+}
+
+// all following records (of any level) of the given namespace are written also to
+// the given file, until it is closed
+func OpenUnitFile(namespace string, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	if unitFile, ok := output.unitFiles[namespace]; ok {
+		unitFile.Close()
+	}
+	output.unitFiles[namespace] = file
+	return nil
+}
+
+func CloseUnitFile(namespace string) error {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	unitFile, ok := output.unitFiles[namespace]
+	if !ok {
+		return nil
+	}
+	delete(output.unitFiles, namespace)
+	return unitFile.Close()
+}
+
+func closeUnitFiles() {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	for namespace, unitFile := range output.unitFiles {
+		unitFile.Close()
+		delete(output.unitFiles, namespace)
+	}
+}
+
+// ---------------------------
+
+type Logger struct {
+	level  Level
+	fields Fields
+}
+
+var Debug = &Logger{level: DebugLevel}
+var Info = &Logger{level: InfoLevel}
+var Warning = &Logger{level: WarningLevel}
+var Error = &Logger{level: ErrorLevel}
+
+// returns a logger attaching the given fields to its records
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields)
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Logger{level: l.level, fields: merged}
+}
+
+func (l *Logger) Print(v ...interface{}) {
+	output.write(l.level, fmt.Sprint(v...), l.fields)
+}
+
+func (l *Logger) Printf(format string, v ...interface{}) {
+	output.write(l.level, fmt.Sprintf(format, v...), l.fields)
+}
+
+func (l *Logger) Println(v ...interface{}) {
+	output.write(l.level, fmt.Sprintln(v...), l.fields)
+}
+
+func (l *Logger) Fatal(v ...interface{}) {
+	l.Print(v...)
+	closeUnitFiles()
+	os.Exit(1)
+}
+
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.Printf(format, v...)
+	closeUnitFiles()
+	os.Exit(1)
+}
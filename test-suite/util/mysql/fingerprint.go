@@ -0,0 +1,391 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package mysql
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
+)
+
+// A snapshot of data which should survive a backup and restore (dump, clone), e.g. taken on
+// the source cluster before the backup and compared with CompareFingerprints against the one
+// taken on the cluster restored from it. Please note CHECKSUM TABLE depends on the row format,
+// so both sides should run the same server version.
+type Fingerprint struct {
+	// by schema name
+	Schemas map[string]*SchemaFingerprint
+	// by schema.table, views included
+	Tables map[string]*TableFingerprint
+	// by user@host
+	Accounts map[string]*AccountFingerprint
+}
+
+// the hash of SHOW CREATE DATABASE, routines, triggers and events
+type SchemaFingerprint struct {
+	Name    string
+	DdlHash string
+}
+
+type TableFingerprint struct {
+	Schema string
+	Name   string
+	IsView bool
+	// the hash of SHOW CREATE TABLE (AUTO_INCREMENT=N left out) or SHOW CREATE VIEW
+	DdlHash string
+	// views have neither rows nor checksum
+	Rows     int64
+	Checksum string
+}
+
+type AccountFingerprint struct {
+	Account string
+	// SHOW GRANTS, sorted
+	Grants []string
+}
+
+type FingerprintOptions struct {
+	// nil - all but system schemas
+	Schemas         []string
+	ExcludedSchemas []string
+	// glob patterns of user@host, e.g. "clone@%" or "app*@*", nil - accounts are skipped,
+	// as they are not loaded from dumps by default
+	Accounts []string
+}
+
+var systemSchemas = []string{
+	"information_schema",
+	"mysql",
+	"mysql_innodb_cluster_metadata",
+	"performance_schema",
+	"sys",
+}
+
+func (f *TableFingerprint) GetFullName() string {
+	return f.Schema + "." + f.Name
+}
+
+func hashDdl(ddl ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(ddl, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func quoteString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (p *PodSession) GetFingerprint(options FingerprintOptions) (*Fingerprint, error) {
+	fingerprint := &Fingerprint{
+		Schemas:  map[string]*SchemaFingerprint{},
+		Tables:   map[string]*TableFingerprint{},
+		Accounts: map[string]*AccountFingerprint{},
+	}
+
+	schemas, err := p.getFingerprintSchemas(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, schema := range schemas {
+		schemaFingerprint, err := p.getSchemaFingerprint(schema)
+		if err != nil {
+			return nil, err
+		}
+		fingerprint.Schemas[schema] = schemaFingerprint
+
+		tables, err := p.getTablesFingerprint(schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range tables {
+			fingerprint.Tables[table.GetFullName()] = table
+		}
+	}
+
+	if len(options.Accounts) > 0 {
+		accounts, err := p.getAccountsFingerprint(options.Accounts)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range accounts {
+			fingerprint.Accounts[account.Account] = account
+		}
+	}
+	return fingerprint, nil
+}
+
+// the schemas which exist, so the missing ones show up in the diff
+func (p *PodSession) getFingerprintSchemas(options FingerprintOptions) ([]string, error) {
+	records, err := p.FetchAll("select schema_name from information_schema.schemata order by schema_name")
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas []string
+	for _, schema := range records.ToStringsSlice(0) {
+		if options.Schemas == nil {
+			if auxi.Contains(systemSchemas, schema) {
+				continue
+			}
+		} else if !auxi.Contains(options.Schemas, schema) {
+			continue
+		}
+		if auxi.Contains(options.ExcludedSchemas, schema) {
+			continue
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
+
+func (p *PodSession) getSchemaFingerprint(schema string) (*SchemaFingerprint, error) {
+	createDatabase, err := p.FetchOne("show create database " + quoteIdentifier(schema))
+	if err != nil {
+		return nil, err
+	}
+	ddl := []string{*createDatabase.Row[1].(*string)}
+
+	queries := []string{
+		"select routine_type, routine_name, coalesce(routine_definition, '') from information_schema.routines where routine_schema = %s order by routine_type, routine_name",
+		"select trigger_name, event_object_table, action_timing, event_manipulation, action_statement from information_schema.triggers where trigger_schema = %s order by trigger_name",
+		"select event_name, coalesce(event_definition, '') from information_schema.events where event_schema = %s order by event_name",
+	}
+	for _, query := range queries {
+		records, err := p.FetchAll(fmt.Sprintf(query, quoteString(schema)))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range records.ToStrings() {
+			ddl = append(ddl, strings.Join(row, " "))
+		}
+	}
+	return &SchemaFingerprint{Name: schema, DdlHash: hashDdl(ddl...)}, nil
+}
+
+// the counter depends on the history of the table, not on its data, e.g. rows deleted at its end
+var autoIncrementPattern = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+func (p *PodSession) getTablesFingerprint(schema string) ([]*TableFingerprint, error) {
+	query := fmt.Sprintf("select table_name, table_type from information_schema.tables where table_schema = %s order by table_name", quoteString(schema))
+	records, err := p.FetchAll(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []*TableFingerprint
+	for _, row := range records.ToStrings() {
+		table := &TableFingerprint{Schema: schema, Name: row[0], IsView: row[1] == "VIEW"}
+		fullName := quoteIdentifier(schema) + "." + quoteIdentifier(table.Name)
+		if table.IsView {
+			createView, err := p.FetchOne("show create view " + fullName)
+			if err != nil {
+				return nil, err
+			}
+			table.DdlHash = hashDdl(*createView.Row[1].(*string))
+			tables = append(tables, table)
+			continue
+		}
+
+		createTable, err := p.FetchOne("show create table " + fullName)
+		if err != nil {
+			return nil, err
+		}
+		table.DdlHash = hashDdl(autoIncrementPattern.ReplaceAllString(*createTable.Row[1].(*string), ""))
+
+		count, err := p.FetchOne("select count(*) from " + fullName)
+		if err != nil {
+			return nil, err
+		}
+		table.Rows, err = strconv.ParseInt(*count.Row[0].(*string), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		// the checksum is NULL for a table which can't be read, it goes to the diff as empty
+		var checksum sql.NullString
+		if err := p.QueryOne("checksum table "+fullName).Scan(new(string), &checksum); err != nil {
+			return nil, fmt.Errorf("cannot get the checksum of %s: %w", fullName, err)
+		}
+		table.Checksum = checksum.String
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func (p *PodSession) getAccountsFingerprint(patterns []string) ([]*AccountFingerprint, error) {
+	records, err := p.FetchAll("select user, host from mysql.user order by user, host")
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []*AccountFingerprint
+	for _, row := range records.ToStrings() {
+		user, host := row[0], row[1]
+		account := user + "@" + host
+		if !matchesAnyPattern(patterns, account) {
+			continue
+		}
+		grants, err := p.FetchAll(fmt.Sprintf("show grants for %s@%s", quoteString(user), quoteString(host)))
+		if err != nil {
+			return nil, err
+		}
+		accountGrants := grants.ToStringsSlice(0)
+		sort.Strings(accountGrants)
+		accounts = append(accounts, &AccountFingerprint{Account: account, Grants: accountGrants})
+	}
+	return accounts, nil
+}
+
+func matchesAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------------
+
+// one line per object which doesn't match, e.g.
+// table sakila.payment: rows 16044 != 16049, checksum 3129104577 != 2211397043
+type FingerprintDiff []string
+
+func (d FingerprintDiff) Empty() bool {
+	return len(d) == 0
+}
+
+func (d FingerprintDiff) String() string {
+	return strings.Join(d, "\n")
+}
+
+func getShortHash(hash string) string {
+	const ShortHashLen = 12
+	if len(hash) > ShortHashLen {
+		return hash[:ShortHashLen]
+	}
+	return hash
+}
+
+func getSortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compares the fingerprint of a restored cluster (actual) against the source (expected)
+func CompareFingerprints(expected *Fingerprint, actual *Fingerprint) FingerprintDiff {
+	var diff FingerprintDiff
+	diff = append(diff, compareSchemas(expected.Schemas, actual.Schemas)...)
+	diff = append(diff, compareTables(expected.Tables, actual.Tables)...)
+	diff = append(diff, compareAccounts(expected.Accounts, actual.Accounts)...)
+	return diff
+}
+
+func compareSchemas(expected map[string]*SchemaFingerprint, actual map[string]*SchemaFingerprint) FingerprintDiff {
+	var diff FingerprintDiff
+	for _, name := range getSortedKeys(expected) {
+		schema, ok := actual[name]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("schema %s: missing", name))
+			continue
+		}
+		if schema.DdlHash != expected[name].DdlHash {
+			diff = append(diff, fmt.Sprintf("schema %s: ddl of schema, routines, triggers or events %s != %s",
+				name, getShortHash(expected[name].DdlHash), getShortHash(schema.DdlHash)))
+		}
+	}
+	for _, name := range getSortedKeys(actual) {
+		if _, ok := expected[name]; !ok {
+			diff = append(diff, fmt.Sprintf("schema %s: unexpected", name))
+		}
+	}
+	return diff
+}
+
+func getTableKind(table *TableFingerprint) string {
+	if table.IsView {
+		return "view"
+	}
+	return "table"
+}
+
+func compareTables(expected map[string]*TableFingerprint, actual map[string]*TableFingerprint) FingerprintDiff {
+	var diff FingerprintDiff
+	for _, name := range getSortedKeys(expected) {
+		expectedTable := expected[name]
+		kind := getTableKind(expectedTable)
+		table, ok := actual[name]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("%s %s: missing", kind, name))
+			continue
+		}
+
+		var mismatches []string
+		if table.IsView != expectedTable.IsView {
+			mismatches = append(mismatches, fmt.Sprintf("view %t != %t", expectedTable.IsView, table.IsView))
+		}
+		if table.DdlHash != expectedTable.DdlHash {
+			mismatches = append(mismatches, fmt.Sprintf("ddl %s != %s", getShortHash(expectedTable.DdlHash), getShortHash(table.DdlHash)))
+		}
+		if table.Rows != expectedTable.Rows {
+			mismatches = append(mismatches, fmt.Sprintf("rows %d != %d", expectedTable.Rows, table.Rows))
+		}
+		if table.Checksum != expectedTable.Checksum {
+			mismatches = append(mismatches, fmt.Sprintf("checksum %s != %s", expectedTable.Checksum, table.Checksum))
+		}
+		if len(mismatches) > 0 {
+			diff = append(diff, fmt.Sprintf("%s %s: %s", kind, name, strings.Join(mismatches, ", ")))
+		}
+	}
+	for _, name := range getSortedKeys(actual) {
+		if _, ok := expected[name]; !ok {
+			diff = append(diff, fmt.Sprintf("%s %s: unexpected", getTableKind(actual[name]), name))
+		}
+	}
+	return diff
+}
+
+func compareAccounts(expected map[string]*AccountFingerprint, actual map[string]*AccountFingerprint) FingerprintDiff {
+	var diff FingerprintDiff
+	for _, name := range getSortedKeys(expected) {
+		account, ok := actual[name]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("account %s: missing", name))
+			continue
+		}
+		expectedGrants := auxi.SliceToSet(expected[name].Grants)
+		grants := auxi.SliceToSet(account.Grants)
+		for _, grant := range expected[name].Grants {
+			if _, ok := grants[grant]; !ok {
+				diff = append(diff, fmt.Sprintf("account %s: missing grant %s", name, grant))
+			}
+		}
+		for _, grant := range account.Grants {
+			if _, ok := expectedGrants[grant]; !ok {
+				diff = append(diff, fmt.Sprintf("account %s: unexpected grant %s", name, grant))
+			}
+		}
+	}
+	for _, name := range getSortedKeys(actual) {
+		if _, ok := expected[name]; !ok {
+			diff = append(diff, fmt.Sprintf("account %s: unexpected", name))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,166 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package mysql
+
+import (
+	"strings"
+	"testing"
+)
+
+// the source of the tests, each case spoils a copy of it
+func newTestFingerprint() *Fingerprint {
+	return &Fingerprint{
+		Schemas: map[string]*SchemaFingerprint{
+			"sakila": {Name: "sakila", DdlHash: "aaaaaaaaaaaaaaaaaaaa"},
+			"world":  {Name: "world", DdlHash: "bbbbbbbbbbbbbbbbbbbb"},
+		},
+		Tables: map[string]*TableFingerprint{
+			"sakila.actor":      {Schema: "sakila", Name: "actor", DdlHash: "cccccccccccccccccccc", Rows: 200, Checksum: "1702520518"},
+			"sakila.payment":    {Schema: "sakila", Name: "payment", DdlHash: "dddddddddddddddddddd", Rows: 16049, Checksum: "2211397043"},
+			"sakila.actor_info": {Schema: "sakila", Name: "actor_info", IsView: true, DdlHash: "eeeeeeeeeeeeeeeeeeee"},
+			"world.city":        {Schema: "world", Name: "city", DdlHash: "ffffffffffffffffffff", Rows: 4079, Checksum: "3496120221"},
+		},
+		Accounts: map[string]*AccountFingerprint{
+			"app@%": {Account: "app@%", Grants: []string{"GRANT SELECT ON `sakila`.* TO `app`@`%`", "GRANT USAGE ON *.* TO `app`@`%`"}},
+		},
+	}
+}
+
+func TestCompareFingerprints(t *testing.T) {
+	tests := []struct {
+		name     string
+		spoil    func(f *Fingerprint)
+		expected []string
+	}{
+		{
+			name:     "equal",
+			spoil:    func(f *Fingerprint) {},
+			expected: nil,
+		},
+		{
+			name: "missing schema",
+			spoil: func(f *Fingerprint) {
+				delete(f.Schemas, "world")
+				delete(f.Tables, "world.city")
+			},
+			expected: []string{
+				"schema world: missing",
+				"table world.city: missing",
+			},
+		},
+		{
+			name: "extra table",
+			spoil: func(f *Fingerprint) {
+				f.Tables["sakila.staff"] = &TableFingerprint{Schema: "sakila", Name: "staff", DdlHash: "1234", Rows: 2, Checksum: "1"}
+				f.Tables["sakila.staff_list"] = &TableFingerprint{Schema: "sakila", Name: "staff_list", IsView: true, DdlHash: "5678"}
+			},
+			expected: []string{
+				"table sakila.staff: unexpected",
+				"view sakila.staff_list: unexpected",
+			},
+		},
+		{
+			name: "row count mismatch",
+			spoil: func(f *Fingerprint) {
+				f.Tables["sakila.payment"].Rows = 16044
+			},
+			expected: []string{"table sakila.payment: rows 16049 != 16044"},
+		},
+		{
+			name: "checksum mismatch",
+			spoil: func(f *Fingerprint) {
+				f.Tables["world.city"].Checksum = "1234567890"
+			},
+			expected: []string{"table world.city: checksum 3496120221 != 1234567890"},
+		},
+		{
+			name: "rows and checksum mismatch",
+			spoil: func(f *Fingerprint) {
+				f.Tables["sakila.payment"].Rows = 16044
+				f.Tables["sakila.payment"].Checksum = "3129104577"
+			},
+			expected: []string{"table sakila.payment: rows 16049 != 16044, checksum 2211397043 != 3129104577"},
+		},
+		{
+			name: "ddl mismatch",
+			spoil: func(f *Fingerprint) {
+				f.Schemas["sakila"].DdlHash = "99999999999999999999"
+				f.Tables["sakila.actor_info"].DdlHash = "88888888888888888888"
+			},
+			// hashes are shortened
+			expected: []string{
+				"schema sakila: ddl of schema, routines, triggers or events aaaaaaaaaaaa != 999999999999",
+				"view sakila.actor_info: ddl eeeeeeeeeeee != 888888888888",
+			},
+		},
+		{
+			name: "table became a view",
+			spoil: func(f *Fingerprint) {
+				f.Tables["sakila.actor"] = &TableFingerprint{Schema: "sakila", Name: "actor", IsView: true, DdlHash: "cccccccccccccccccccc"}
+			},
+			expected: []string{"table sakila.actor: view false != true, rows 200 != 0, checksum 1702520518 != "},
+		},
+		{
+			name: "accounts",
+			spoil: func(f *Fingerprint) {
+				f.Accounts["app@%"].Grants = []string{"GRANT USAGE ON *.* TO `app`@`%`", "GRANT ALL ON *.* TO `app`@`%`"}
+				f.Accounts["intruder@%"] = &AccountFingerprint{Account: "intruder@%"}
+			},
+			expected: []string{
+				"account app@%: missing grant GRANT SELECT ON `sakila`.* TO `app`@`%`",
+				"account app@%: unexpected grant GRANT ALL ON *.* TO `app`@`%`",
+				"account intruder@%: unexpected",
+			},
+		},
+		{
+			// schemas first, then tables, then accounts, each sorted by name
+			name: "order",
+			spoil: func(f *Fingerprint) {
+				delete(f.Accounts, "app@%")
+				f.Tables["world.city"].Rows = 1
+				f.Tables["sakila.actor"].Rows = 1
+				delete(f.Schemas, "world")
+			},
+			expected: []string{
+				"schema world: missing",
+				"table sakila.actor: rows 200 != 1",
+				"table world.city: rows 4079 != 1",
+				"account app@%: missing",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := newTestFingerprint()
+			test.spoil(actual)
+			diff := CompareFingerprints(newTestFingerprint(), actual)
+
+			if diff.Empty() != (len(test.expected) == 0) {
+				t.Errorf("expected Empty() %t for %q", len(test.expected) == 0, diff.String())
+			}
+			if expected := strings.Join(test.expected, "\n"); diff.String() != expected {
+				t.Errorf("expected the diff\n%s\ngot\n%s", expected, diff.String())
+			}
+		})
+	}
+}
+
+func TestMatchesAnyPattern(t *testing.T) {
+	tests := []struct {
+		account  string
+		expected bool
+	}{
+		{"clone@%", true},
+		{"app1@localhost", true},
+		{"app@10.0.0.1", true},
+		{"root@%", false},
+		{"clone@localhost", false},
+	}
+	patterns := []string{"clone@%", "app*@*"}
+	for _, test := range tests {
+		if matched := matchesAnyPattern(patterns, test.account); matched != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.account, test.expected, matched)
+		}
+	}
+}
@@ -7,8 +7,7 @@ import (
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
 )
 
-func LoadScript(namespace string, podName string, containerId k8s.ContainerId, script string) error {
-	kubectl := k8s.Kubectl{}
-	return kubectl.ExecuteWithInput(script, namespace, podName, containerId,
-		"-i", "--", "mysql", "-u"+common.RootUser, "-p"+common.RootPassword)
+func LoadScript(client *k8s.Client, namespace string, podName string, containerId k8s.ContainerId, script string) error {
+	return client.ExecuteWithInput(script, namespace, podName, containerId,
+		"mysql", "-u"+common.RootUser, "-p"+common.RootPassword)
 }
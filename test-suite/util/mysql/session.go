@@ -3,77 +3,133 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
-	"os/exec"
-	"syscall"
 	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 type PodSession struct {
-	Database  *sql.DB
-	portFwCmd *exec.Cmd
+	Database    *sql.DB
+	portForward *k8s.PortForward
+	logger      *log.Logger
 }
 
-func trySetupNewSession(namespace string, podName string, user string, password string) (*PodSession, error) {
-	kubectl := k8s.Kubectl{}
-	const DefaultPort = 3306
-	portFwCmd, port, err := kubectl.PortForward(namespace, podName, DefaultPort)
+// MySQL Router ports
+const (
+	RouterPortRW  = 6446
+	RouterPortRO  = 6447
+	RouterPortXRW = 6448
+	RouterPortXRO = 6449
+)
+
+type portForwardOpener func() (*k8s.PortForward, error)
+
+func trySetupNewSession(logger *log.Logger, openPortForward portForwardOpener, user string, password string) (*PodSession, error) {
+	portForward, err := openPortForward()
 	if err != nil {
 		return nil, err
 	}
 	const DriverName = "mysql"
 	const DefaultScheme = "mysql"
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(127.0.0.1:%d)/%s", user, password, port, DefaultScheme)
+	dataSourceName := fmt.Sprintf("%s:%s@tcp(127.0.0.1:%d)/%s", user, password, portForward.LocalPort, DefaultScheme)
 	db, err := sql.Open(DriverName, dataSourceName)
 	if err != nil {
-		portFwCmd.Process.Signal(syscall.SIGTERM)
+		portForward.Close()
 		return nil, err
 	}
 	session := PodSession{
-		Database:  db,
-		portFwCmd: portFwCmd,
+		Database:    db,
+		portForward: portForward,
+		logger:      logger,
 	}
 	return &session, nil
 }
 
-func NewSession(namespace string, podName string, user string, password string) (session *PodSession, err error) {
+func setupNewSession(ctx context.Context, namespace string, target string, openPortForward portForwardOpener, user string, password string) (session *PodSession, err error) {
+	logger := log.Debug.With(log.Fields{log.NamespaceField: namespace, "target": target})
 	const MaxTrials = 5
+	const RetryInterval = 2 * time.Second
 	for i := 0; i < MaxTrials; i++ {
-		session, err = trySetupNewSession(namespace, podName, user, password)
+		session, err = trySetupNewSession(logger, openPortForward, user, password)
 		if err == nil {
 			break
 		}
-		err = fmt.Errorf("cannot setup a new session on %s/%s for %s@%s: %v", namespace, podName, user, password, err)
-		log.Print(err)
-		time.Sleep(2 * time.Second)
+		err = fmt.Errorf("cannot setup a new session on %s for %s@%s: %v", target, user, password, err)
+		log.Warning.Print(err)
+		select {
+		case <-time.After(RetryInterval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s, given up: %w", err, ctx.Err())
+		}
 	}
 	return session, err
 }
 
+// the session is tunnelled to the pod through the client, the tunnel lives until the session
+// is closed or ctx is done, e.g. the context of the unit, so sessions don't outlive it
+func NewSession(ctx context.Context, client *k8s.Client, namespace string, podName string, user string, password string) (session *PodSession, err error) {
+	const DefaultPort = 3306
+	return NewSessionOnPort(ctx, client, namespace, podName, DefaultPort, user, password)
+}
+
+func NewSessionOnPort(ctx context.Context, client *k8s.Client, namespace string, podName string, port int, user string, password string) (session *PodSession, err error) {
+	target := fmt.Sprintf("%s/%s:%d", namespace, podName, port)
+	openPortForward := func() (*k8s.PortForward, error) {
+		return client.PortForward(ctx, namespace, podName, port)
+	}
+	return setupNewSession(ctx, namespace, target, openPortForward, user, password)
+}
+
+// routerPort is one of RouterPortRW, RouterPortRO (X protocol ports are not supported by the driver)
+func NewRouterSession(ctx context.Context, client *k8s.Client, namespace string, routerPodName string, routerPort int, user string, password string) (session *PodSession, err error) {
+	return NewSessionOnPort(ctx, client, namespace, routerPodName, routerPort, user, password)
+}
+
+// port is a name (e.g. mysql-ro) or a number (e.g. 6447) of a service port
+func NewServiceSession(ctx context.Context, client *k8s.Client, namespace string, serviceName string, port string, user string, password string) (session *PodSession, err error) {
+	target := fmt.Sprintf("%s/svc/%s:%s", namespace, serviceName, port)
+	openPortForward := func() (*k8s.PortForward, error) {
+		return client.PortForwardService(ctx, namespace, serviceName, port)
+	}
+	return setupNewSession(ctx, namespace, target, openPortForward, user, password)
+}
+
 func (p *PodSession) Close() {
 	if p.Database != nil {
 		p.Database.Close()
 	}
-	if p.portFwCmd != nil {
-		p.portFwCmd.Process.Signal(syscall.SIGTERM)
+	if p.portForward != nil {
+		p.portForward.Close()
+		p.portForward = nil
+	}
+}
+
+func (p *PodSession) logStatement(statement string, args []interface{}) {
+	if len(args) > 0 {
+		p.logger.Printf("%s %v", statement, args)
+	} else {
+		p.logger.Print(statement)
 	}
 }
 
 func (p *PodSession) Exec(statement string, args ...interface{}) (sql.Result, error) {
+	p.logStatement(statement, args)
 	return p.Database.Exec(statement, args...)
 }
 
 func (p *PodSession) QueryOne(query string, args ...interface{}) *sql.Row {
+	p.logStatement(query, args)
 	return p.Database.QueryRow(query, args...)
 }
 
 func (p *PodSession) QueryAll(query string, args ...interface{}) (*sql.Rows, error) {
+	p.logStatement(query, args)
 	return p.Database.Query(query, args...)
 }
 
@@ -96,7 +152,7 @@ func (p *PodSession) FetchOne(query string, args ...interface{}) (*Record, error
 }
 
 func (p *PodSession) FetchAll(query string, args ...interface{}) (*Records, error) {
-	rows, err := p.Database.Query(query, args...)
+	rows, err := p.QueryAll(query, args...)
 	if err != nil {
 		return nil, err
 	}
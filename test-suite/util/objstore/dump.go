@@ -0,0 +1,207 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// a dump of util.dumpInstance() consists of metadata (json and sql files, e.g. @.json,
+// @.done.json, sakila.json, sakila@actor.sql), data chunks (e.g. sakila@actor@@0.tsv.zst)
+// and their indexes (sakila@actor@@0.tsv.zst.idx)
+func IsDumpMetadata(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".json" || ext == ".sql"
+}
+
+func IsDumpDataChunk(name string) bool {
+	return !IsDumpMetadata(name) && filepath.Ext(name) != ".idx"
+}
+
+// the file written by the shell as the last one, so it exists only for a complete dump
+const DumpDoneFile = "@.done.json"
+
+const DumpInstanceFile = "@.json"
+
+// the name of an object relative to the dump prefix, e.g. @.json, S3 drops the leading slash
+// of names, OCI keeps it
+func getDumpRelativeName(prefix string, name string) string {
+	relativeName := strings.TrimPrefix(strings.TrimLeft(name, "/"), strings.TrimLeft(prefix, "/"))
+	return strings.TrimLeft(relativeName, "/")
+}
+
+// downloads the metadata files of the dump under the prefix into destDir (it keeps the paths
+// relative to the prefix), returns the paths of the downloaded files
+func DownloadDumpMetadata(ctx context.Context, store ObjectStore, prefix string, destDir string) ([]string, error) {
+	objects, err := store.List(ctx, DirPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, object := range objects {
+		if !IsDumpMetadata(object.Name) {
+			continue
+		}
+		content, err := store.Get(ctx, object.Name)
+		if err != nil {
+			return paths, err
+		}
+
+		relativeName := getDumpRelativeName(prefix, object.Name)
+		path := filepath.Join(destDir, filepath.FromSlash(relativeName))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return paths, err
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no dump metadata found under %s in %s", prefix, store)
+	}
+	return paths, nil
+}
+
+// ---------------------------
+// the metadata of a dump, only fields tests are interested in
+
+// @.json
+type DumpInstanceMetadata struct {
+	Dumper        string   `json:"dumper"`
+	Version       string   `json:"version"`
+	Origin        string   `json:"origin"`
+	Schemas       []string `json:"schemas"`
+	ServerVersion string   `json:"serverVersion"`
+	Begin         string   `json:"begin"`
+	// schema -> the base of names of its files, e.g. sakila -> sakila, special characters
+	// of names are encoded
+	Basenames map[string]string `json:"basenames"`
+}
+
+// @.done.json
+type DumpDoneMetadata struct {
+	End       string `json:"end"`
+	DataBytes int64  `json:"dataBytes"`
+	// schema -> table -> bytes of data (uncompressed)
+	TableDataBytes map[string]map[string]int64 `json:"tableDataBytes"`
+	// chunk file -> its size, written by newer versions of the shell only
+	ChunkFileBytes map[string]int64 `json:"chunkFileBytes"`
+}
+
+// e.g. sakila.json
+type DumpSchemaMetadata struct {
+	Schema       string   `json:"schema"`
+	IncludesData bool     `json:"includesData"`
+	Tables       []string `json:"tables"`
+	Views        []string `json:"views"`
+	// table -> the base of names of its files, e.g. actor -> sakila@actor
+	Basenames map[string]string `json:"basenames"`
+}
+
+type DumpMetadata struct {
+	Prefix   string
+	Instance DumpInstanceMetadata
+	Done     DumpDoneMetadata
+	// by schema name
+	Schemas map[string]*DumpSchemaMetadata
+	// data chunks as found in the store, names relative to the prefix, sorted
+	Chunks []ObjectInfo
+}
+
+func getDumpJson(ctx context.Context, store ObjectStore, prefix string, name string, value interface{}) error {
+	content, err := store.Get(ctx, path.Join(prefix, name))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, value); err != nil {
+		return fmt.Errorf("cannot parse %s of the dump %s: %w", name, prefix, err)
+	}
+	return nil
+}
+
+// reads @.json, @.done.json and the json of each schema of the dump under the prefix, and
+// lists its data chunks
+func ReadDumpMetadata(ctx context.Context, store ObjectStore, prefix string) (*DumpMetadata, error) {
+	metadata := &DumpMetadata{Prefix: prefix, Schemas: map[string]*DumpSchemaMetadata{}}
+	if err := getDumpJson(ctx, store, prefix, DumpInstanceFile, &metadata.Instance); err != nil {
+		return nil, err
+	}
+	if err := getDumpJson(ctx, store, prefix, DumpDoneFile, &metadata.Done); err != nil {
+		return nil, err
+	}
+
+	for _, schema := range metadata.Instance.Schemas {
+		basename, ok := metadata.Instance.Basenames[schema]
+		if !ok {
+			basename = schema
+		}
+		schemaMetadata := &DumpSchemaMetadata{}
+		if err := getDumpJson(ctx, store, prefix, basename+".json", schemaMetadata); err != nil {
+			return nil, err
+		}
+		metadata.Schemas[schema] = schemaMetadata
+	}
+
+	objects, err := store.List(ctx, DirPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		if IsDumpDataChunk(object.Name) {
+			object.Name = getDumpRelativeName(prefix, object.Name)
+			metadata.Chunks = append(metadata.Chunks, object)
+		}
+	}
+	sort.Slice(metadata.Chunks, func(i, j int) bool { return metadata.Chunks[i].Name < metadata.Chunks[j].Name })
+	return metadata, nil
+}
+
+func (m *DumpMetadata) HasSchema(schema string) bool {
+	_, ok := m.Schemas[schema]
+	return ok
+}
+
+func (m *DumpMetadata) GetTables(schema string) []string {
+	if schemaMetadata, ok := m.Schemas[schema]; ok {
+		return schemaMetadata.Tables
+	}
+	return nil
+}
+
+// the chunks of a table, e.g. sakila@actor@@0.tsv.zst or sakila@actor.tsv.zst (not chunked)
+func (m *DumpMetadata) GetTableChunks(schema string, table string) []ObjectInfo {
+	schemaMetadata, ok := m.Schemas[schema]
+	if !ok {
+		return nil
+	}
+	basename, ok := schemaMetadata.Basenames[table]
+	if !ok {
+		basename = schema + "@" + table
+	}
+
+	var chunks []ObjectInfo
+	for _, chunk := range m.Chunks {
+		if strings.HasPrefix(chunk.Name, basename+"@") || strings.HasPrefix(chunk.Name, basename+".") {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+func (m *DumpMetadata) GetChunksSize() int64 {
+	var size int64
+	for _, chunk := range m.Chunks {
+		size += chunk.Size
+	}
+	return size
+}
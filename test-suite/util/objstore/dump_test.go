@@ -0,0 +1,134 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetDumpRelativeName(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		name     string
+		expected string
+	}{
+		{"dump", "dump/@.json", "@.json"},
+		{"dump/", "dump/@.json", "@.json"},
+		// OCI keeps the leading slash of names, S3 drops it
+		{"/dump", "/dump/@.json", "@.json"},
+		{"/dump", "dump/@.json", "@.json"},
+		{"dump", "/dump/@.json", "@.json"},
+		{"e2etest/ote-mysql/dump-20221208", "e2etest/ote-mysql/dump-20221208/sakila@actor.sql", "sakila@actor.sql"},
+		{"", "dump/@.json", "dump/@.json"},
+	}
+	for _, test := range tests {
+		if relativeName := getDumpRelativeName(test.prefix, test.name); relativeName != test.expected {
+			t.Errorf("%s, %s: expected %s, got %s", test.prefix, test.name, test.expected, relativeName)
+		}
+	}
+}
+
+func TestIsDumpDataChunk(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"@.json", false},
+		{"@.done.json", false},
+		{"@.sql", false},
+		{"@.post.sql", false},
+		{"sakila.json", false},
+		{"sakila@actor.sql", false},
+		{"sakila@actor@@0.tsv.zst.idx", false},
+		{"sakila@actor@@0.tsv.zst", true},
+		{"sakila@actor@@1.tsv", true},
+		{"sakila@actor.tsv.zst", true},
+		{"dump/sakila@film@@0.tsv.gz", true},
+	}
+	for _, test := range tests {
+		if isChunk := IsDumpDataChunk(test.name); isChunk != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, isChunk)
+		}
+	}
+}
+
+func getChunkNames(chunks []ObjectInfo) []string {
+	names := []string{}
+	for _, chunk := range chunks {
+		names = append(names, chunk.Name)
+	}
+	return names
+}
+
+func TestGetTableChunks(t *testing.T) {
+	metadata := &DumpMetadata{
+		Schemas: map[string]*DumpSchemaMetadata{
+			"sakila": {
+				Schema: "sakila",
+				Tables: []string{"actor", "actor_info", "film"},
+			},
+			// names with special characters are encoded
+			"my schema": {
+				Schema:    "my schema",
+				Tables:    []string{"my@table"},
+				Basenames: map[string]string{"my@table": "my%20schema@my%40table"},
+			},
+		},
+		Chunks: []ObjectInfo{
+			{Name: "my%20schema@my%40table@@0.tsv.zst"},
+			{Name: "sakila@actor@@0.tsv.zst"},
+			{Name: "sakila@actor@@1.tsv.zst"},
+			{Name: "sakila@actor_info@@0.tsv.zst"},
+			{Name: "sakila@film.tsv.zst"},
+		},
+	}
+
+	tests := []struct {
+		schema   string
+		table    string
+		expected []string
+	}{
+		// actor_info shares the beginning of the name, but not the table
+		{"sakila", "actor", []string{"sakila@actor@@0.tsv.zst", "sakila@actor@@1.tsv.zst"}},
+		{"sakila", "actor_info", []string{"sakila@actor_info@@0.tsv.zst"}},
+		// not chunked
+		{"sakila", "film", []string{"sakila@film.tsv.zst"}},
+		{"my schema", "my@table", []string{"my%20schema@my%40table@@0.tsv.zst"}},
+		{"sakila", "none", []string{}},
+		{"none", "actor", []string{}},
+	}
+	for _, test := range tests {
+		chunks := metadata.GetTableChunks(test.schema, test.table)
+		if names := getChunkNames(chunks); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s.%s: expected %v, got %v", test.schema, test.table, test.expected, names)
+		}
+	}
+}
+
+func TestReadDumpMetadataSkipsSiblings(t *testing.T) {
+	store := newTestLocalStore(t, map[string]string{
+		"dump-x/@.json":                   `{"schemas": ["sakila"]}`,
+		"dump-x/@.done.json":              `{"dataBytes": 5}`,
+		"dump-x/sakila.json":              `{"schema": "sakila", "tables": ["actor"]}`,
+		"dump-x/sakila@actor@@0.tsv.zst":  "chunk",
+		"dump-x2/@.json":                  `{"schemas": ["sakila"]}`,
+		"dump-x2/sakila@actor@@0.tsv.zst": "chunk",
+		"dump-x2/sakila@actor@@1.tsv.zst": "chunk",
+	})
+
+	for _, prefix := range []string{"dump-x", "dump-x/"} {
+		metadata, err := ReadDumpMetadata(context.Background(), store, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"sakila@actor@@0.tsv.zst"}
+		if names := getChunkNames(metadata.Chunks); !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expected %v, got %v", prefix, expected, names)
+		}
+		if chunks := metadata.GetTableChunks("sakila", "actor"); len(chunks) != 1 {
+			t.Errorf("%s: expected 1 chunk of sakila.actor, got %v", prefix, getChunkNames(chunks))
+		}
+	}
+}
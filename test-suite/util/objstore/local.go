@@ -0,0 +1,105 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// a local directory seen as a bucket, e.g. a dump copied from a volume, or downloaded
+type localStore struct {
+	root string
+}
+
+func NewLocalStore(root string) ObjectStore {
+	return &localStore{root: root}
+}
+
+func (s *localStore) String() string {
+	return "file://" + s.root
+}
+
+func (s *localStore) getPath(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+func (s *localStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relativePath)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Name: name, Size: fileInfo.Size(), ModTime: fileInfo.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+func (s *localStore) Get(ctx context.Context, name string) ([]byte, error) {
+	content, err := os.ReadFile(s.getPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, NewObjectNotFoundError(s, name)
+	}
+	return content, err
+}
+
+func (s *localStore) Stat(ctx context.Context, name string) (ObjectInfo, error) {
+	fileInfo, err := os.Stat(s.getPath(name))
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && fileInfo.IsDir()) {
+		return ObjectInfo{}, NewObjectNotFoundError(s, name)
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Name: name, Size: fileInfo.Size(), ModTime: fileInfo.ModTime()}, nil
+}
+
+// only files are deleted, directories are left, like prefixes of a bucket they cost nothing
+func (s *localStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	prefix, err := getDeletePrefix(s, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	for i, object := range objects {
+		if err := os.Remove(s.getPath(object.Name)); err != nil {
+			return i, err
+		}
+	}
+	return len(objects), nil
+}
@@ -0,0 +1,140 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestLocalStore(t *testing.T, files map[string]string) ObjectStore {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewLocalStore(root)
+}
+
+func listNames(t *testing.T, store ObjectStore, prefix string) []string {
+	t.Helper()
+	objects, err := store.List(context.Background(), prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, object := range objects {
+		names = append(names, object.Name)
+	}
+	return names
+}
+
+func TestLocalStoreList(t *testing.T) {
+	store := newTestLocalStore(t, map[string]string{
+		"dump/@.json":                  "{}",
+		"dump/sakila@actor@@0.tsv.zst": "chunk",
+		"dump/sub/nested.json":         "{}",
+		"other/@.json":                 "{}",
+	})
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"dump/", []string{"dump/@.json", "dump/sakila@actor@@0.tsv.zst", "dump/sub/nested.json"}},
+		{"dump/sub", []string{"dump/sub/nested.json"}},
+		{"", []string{"dump/@.json", "dump/sakila@actor@@0.tsv.zst", "dump/sub/nested.json", "other/@.json"}},
+		{"none/", []string{}},
+	}
+	for _, test := range tests {
+		if names := listNames(t, store, test.prefix); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.prefix, test.expected, names)
+		}
+	}
+}
+
+func TestLocalStoreGetStat(t *testing.T) {
+	store := newTestLocalStore(t, map[string]string{"dump/@.json": `{"version": "2.0.1"}`})
+	ctx := context.Background()
+
+	content, err := store.Get(ctx, "dump/@.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `{"version": "2.0.1"}` {
+		t.Errorf("unexpected content %s", content)
+	}
+
+	info, err := store.Stat(ctx, "dump/@.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "dump/@.json" || info.Size != int64(len(content)) || info.ModTime.IsZero() {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	if _, err := store.Get(ctx, "dump/@.done.json"); !IsObjectNotFoundError(err) {
+		t.Errorf("expected a missing file not found, got %v", err)
+	}
+	// a directory is rather a prefix than an object
+	if _, err := store.Stat(ctx, "dump"); !IsObjectNotFoundError(err) {
+		t.Errorf("expected a directory not found, got %v", err)
+	}
+}
+
+func TestLocalStoreDeletePrefix(t *testing.T) {
+	store := newTestLocalStore(t, map[string]string{
+		"dump/@.json":          "{}",
+		"dump/sub/nested.json": "{}",
+		"other/@.json":         "{}",
+	})
+	ctx := context.Background()
+
+	deleted, err := store.DeletePrefix(ctx, "dump/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 files deleted, got %d", deleted)
+	}
+	if names := listNames(t, store, ""); !reflect.DeepEqual(names, []string{"other/@.json"}) {
+		t.Errorf("expected only the file out of the prefix left, got %v", names)
+	}
+
+	for _, prefix := range []string{"", "/", "//"} {
+		if _, err := store.DeletePrefix(ctx, prefix); err == nil {
+			t.Errorf("expected deleting everything with '%s' refused", prefix)
+		}
+	}
+	if names := listNames(t, store, ""); len(names) != 1 {
+		t.Errorf("expected nothing deleted by a refused prefix, got %v", names)
+	}
+}
+
+func TestLocalStoreDeletePrefixSkipsSiblings(t *testing.T) {
+	store := newTestLocalStore(t, map[string]string{
+		"dump-x/@.json":  "{}",
+		"dump-x2/@.json": "{}",
+		"dump-xy":        "{}",
+	})
+
+	deleted, err := store.DeletePrefix(context.Background(), "dump-x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 file deleted, got %d", deleted)
+	}
+	if names := listNames(t, store, ""); !reflect.DeepEqual(names, []string{"dump-x2/@.json", "dump-xy"}) {
+		t.Errorf("expected the siblings of the prefix left, got %v", names)
+	}
+}
@@ -0,0 +1,101 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// an object of a bucket (or a file of a local directory), the name is relative to the bucket
+// (or the directory) with slashes as separators, e.g. e2etest/ote-mysql/dump-20221208/@.json
+type ObjectInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// a client of the storage where backups are written to, so tests may check what a backup
+// actually wrote, not just that it finished
+type ObjectStore interface {
+	// a short description used in logs and errors, e.g. "s3://ote-bucket"
+	String() string
+	// the objects whose names begin with the prefix, sorted by name, for the objects of
+	// a directory (e.g. a dump) pass the prefix through DirPrefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Get(ctx context.Context, name string) ([]byte, error)
+	Stat(ctx context.Context, name string) (ObjectInfo, error)
+	// deletes all objects under the prefix taken as a directory, i.e. dump-x doesn't
+	// delete dump-x2/@.json, returns their number
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+}
+
+type ObjectNotFoundError struct {
+	error
+}
+
+func (e ObjectNotFoundError) Unwrap() error {
+	return e.error
+}
+
+// also for stores implemented out of the package, e.g. on a volume read through a pod
+func NewObjectNotFoundError(store ObjectStore, name string) error {
+	return ObjectNotFoundError{error: fmt.Errorf("object %s not found in %s", name, store)}
+}
+
+func IsObjectNotFoundError(err error) bool {
+	var notFoundErr ObjectNotFoundError
+	return errors.As(err, &notFoundErr)
+}
+
+// the prefix of the objects of a directory, e.g. dump-x => dump-x/, so it doesn't match its
+// siblings like dump-x2/@.json
+func DirPrefix(prefix string) string {
+	if len(prefix) == 0 || strings.HasSuffix(prefix, "/") {
+		return prefix
+	}
+	return prefix + "/"
+}
+
+// an empty prefix would wipe the whole bucket, it is rather a bug in a test than an intent,
+// returns the prefix as a directory
+func getDeletePrefix(store ObjectStore, prefix string) (string, error) {
+	if len(strings.Trim(prefix, "/")) == 0 {
+		return "", fmt.Errorf("refusing to delete all objects of %s", store)
+	}
+	return DirPrefix(prefix), nil
+}
+
+// ---------------------------
+// helpers of stores talking http
+
+const requestTimeout = 60 * time.Second
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// an error for a response out of 2xx, the body usually explains it (xml of S3, json of OCI)
+func checkResponse(store ObjectStore, name string, response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+	if response.StatusCode == http.StatusNotFound && len(name) > 0 {
+		return NewObjectNotFoundError(store, name)
+	}
+
+	const MaxBodySize = 4096
+	body, _ := io.ReadAll(io.LimitReader(response.Body, MaxBodySize))
+	return fmt.Errorf("%s %s failed: %s: %s", response.Request.Method, response.Request.URL.Redacted(), response.Status, strings.TrimSpace(string(body)))
+}
+
+func getObjectInfo(name string, response *http.Response) ObjectInfo {
+	info := ObjectInfo{Name: name, Size: response.ContentLength}
+	if modTime, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info
+}
@@ -0,0 +1,23 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import "testing"
+
+func TestDirPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"dump-x", "dump-x/"},
+		{"dump-x/", "dump-x/"},
+		{"/e2etest/dump-x", "/e2etest/dump-x/"},
+		// all objects
+		{"", ""},
+	}
+	for _, test := range tests {
+		if prefix := DirPrefix(test.prefix); prefix != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.prefix, test.expected, prefix)
+		}
+	}
+}
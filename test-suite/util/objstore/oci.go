@@ -0,0 +1,256 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/oci"
+)
+
+// talks the OCI Object Storage rest api with requests signed with the api key of
+// a profile, there is no need of the oci cli
+type ociStore struct {
+	profile    *oci.Profile
+	privateKey *rsa.PrivateKey
+	bucketName string
+	endpoint   string
+	client     *http.Client
+
+	// the object storage namespace of the tenancy, fetched with the first request
+	namespaceMutex sync.Mutex
+	namespace      string
+}
+
+func NewOciStore(profile *oci.Profile, bucketName string) (ObjectStore, error) {
+	return NewOciStoreWithEndpoint(profile, bucketName, oci.GetObjectStorageEndpoint(profile.Region), nil)
+}
+
+// the endpoint is e.g. a fake server (see util/ocifake), rootCAs verify its certificate,
+// nil means the system ones
+func NewOciStoreWithEndpoint(profile *oci.Profile, bucketName string, endpoint string, rootCAs *x509.CertPool) (ObjectStore, error) {
+	privateKey, err := profile.LoadPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	client := httpClient
+	if rootCAs != nil {
+		client = &http.Client{
+			Timeout:   requestTimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}},
+		}
+	}
+	return &ociStore{
+		profile:    profile,
+		privateKey: privateKey,
+		bucketName: bucketName,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		client:     client,
+	}, nil
+}
+
+// e.g. NewOciStoreFromConfig(cfg.Oci.ConfigPath, common.OciProfileDelete, cfg.Oci.BucketName)
+func NewOciStoreFromConfig(cfgPath string, profileName string, bucketName string) (ObjectStore, error) {
+	profile, err := oci.LoadProfile(cfgPath, profileName)
+	if err != nil {
+		return nil, err
+	}
+	return NewOciStore(profile, bucketName)
+}
+
+func (s *ociStore) String() string {
+	return "oci://" + s.bucketName
+}
+
+// https://docs.oracle.com/en-us/iaas/Content/API/Concepts/signingrequests.htm, the requests
+// have no body, so only date, (request-target) and host are signed
+func (s *ociStore) sign(request *http.Request, now time.Time) error {
+	request.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+
+	requestTarget := strings.ToLower(request.Method) + " " + request.URL.RequestURI()
+	signingString := strings.Join([]string{
+		"date: " + request.Header.Get("Date"),
+		"(request-target): " + requestTarget,
+		"host: " + request.URL.Host,
+	}, "\n")
+
+	digest := sha256.Sum256([]byte(signingString))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="date (request-target) host",signature="%s"`,
+		s.profile.GetKeyId(), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+func (s *ociStore) do(ctx context.Context, method string, escapedPath string, query url.Values, name string) (*http.Response, error) {
+	rawUrl := s.endpoint + escapedPath
+	if len(query) > 0 {
+		rawUrl += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, method, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sign(request, time.Now()); err != nil {
+		return nil, err
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(s, name, response); err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *ociStore) getNamespace(ctx context.Context) (string, error) {
+	s.namespaceMutex.Lock()
+	defer s.namespaceMutex.Unlock()
+	if len(s.namespace) > 0 {
+		return s.namespace, nil
+	}
+
+	response, err := s.do(ctx, http.MethodGet, "/n/", nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if err := json.NewDecoder(response.Body).Decode(&s.namespace); err != nil {
+		return "", fmt.Errorf("cannot decode the namespace of %s: %w", s, err)
+	}
+	return s.namespace, nil
+}
+
+// e.g. /n/mytenancy/b/my-bucket/o/e2etest%2Fote-mysql%2F%40.json, the name is escaped as
+// a whole, slashes included
+func (s *ociStore) getPath(ctx context.Context, name string) (string, error) {
+	namespace, err := s.getNamespace(ctx)
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("/n/%s/b/%s/o", url.PathEscape(namespace), url.PathEscape(s.bucketName))
+	if len(name) > 0 {
+		path += "/" + url.PathEscape(name)
+	}
+	return path, nil
+}
+
+type ociListResult struct {
+	Objects []struct {
+		Name        string    `json:"name"`
+		Size        int64     `json:"size"`
+		TimeCreated time.Time `json:"timeCreated"`
+	} `json:"objects"`
+	NextStartWith string `json:"nextStartWith"`
+}
+
+func (s *ociStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	path, err := s.getPath(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []ObjectInfo
+	start := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"name,size,timeCreated"}}
+		if len(start) > 0 {
+			query.Set("start", start)
+		}
+		response, err := s.do(ctx, http.MethodGet, path, query, "")
+		if err != nil {
+			return nil, err
+		}
+		var result ociListResult
+		err = json.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode the list of %s: %w", s, err)
+		}
+
+		for _, object := range result.Objects {
+			objects = append(objects, ObjectInfo{Name: object.Name, Size: object.Size, ModTime: object.TimeCreated})
+		}
+		if len(result.NextStartWith) == 0 {
+			break
+		}
+		start = result.NextStartWith
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+func (s *ociStore) Get(ctx context.Context, name string) ([]byte, error) {
+	path, err := s.getPath(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.do(ctx, http.MethodGet, path, nil, name)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+func (s *ociStore) Stat(ctx context.Context, name string) (ObjectInfo, error) {
+	path, err := s.getPath(ctx, name)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	response, err := s.do(ctx, http.MethodHead, path, nil, name)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	response.Body.Close()
+	return getObjectInfo(name, response), nil
+}
+
+func (s *ociStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	prefix, err := getDeletePrefix(s, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	for i, object := range objects {
+		path, err := s.getPath(ctx, object.Name)
+		if err != nil {
+			return i, err
+		}
+		response, err := s.do(ctx, http.MethodDelete, path, nil, object.Name)
+		if err != nil {
+			return i, err
+		}
+		response.Body.Close()
+	}
+	return len(objects), nil
+}
@@ -0,0 +1,120 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/oci"
+	"github.com/marinesovitch/ote/test-suite/util/ocifake"
+)
+
+const testBucket = "ote-bucket"
+
+// the store against the fake which verifies signatures like the real service
+func newFakeOciStore(t *testing.T) (*ociStore, *ocifake.Server) {
+	t.Helper()
+	cfgPath, err := ocifake.GenerateConfig(t.TempDir(), "us-ashburn-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ocifake.NewServer(ocifake.Config{ConfigPath: cfgPath, Profiles: ocifake.Profiles, Hosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	server.CreateBucket(testBucket)
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(server.GetCaCertPem()) {
+		t.Fatal("cannot parse the CA of the fake OCI")
+	}
+	profile, err := oci.LoadProfile(cfgPath, common.OciProfileDelete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewOciStoreWithEndpoint(profile, testBucket, server.GetLocalEndpoint(), rootCAs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store.(*ociStore), server
+}
+
+func TestOciSignAccepted(t *testing.T) {
+	store, server := newFakeOciStore(t)
+	ctx := context.Background()
+
+	// the namespace, then the listing of the bucket, both signed
+	objects, err := store.List(ctx, "dump/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("expected an empty bucket, got %v", objects)
+	}
+	if store.namespace != server.GetNamespace() {
+		t.Errorf("expected the namespace %s, got %s", server.GetNamespace(), store.namespace)
+	}
+
+	if _, err := store.Get(ctx, "dump/@.json"); !IsObjectNotFoundError(err) {
+		t.Errorf("expected a missing object not found, got %v", err)
+	}
+}
+
+func TestOciSignCoversRequest(t *testing.T) {
+	store, _ := newFakeOciStore(t)
+
+	tests := []struct {
+		name string
+		// applied after signing
+		tamper func(request *http.Request)
+		now    time.Time
+		status int
+	}{
+		{name: "signed now", now: time.Now(), status: http.StatusOK},
+		{name: "date skewed", now: time.Now().Add(-time.Hour), status: http.StatusUnauthorized},
+		{
+			name:   "date replaced",
+			now:    time.Now(),
+			tamper: func(r *http.Request) { r.Header.Set("Date", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)) },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "path replaced",
+			now:    time.Now(),
+			tamper: func(r *http.Request) { r.URL.Path = "/n/other/" },
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, store.endpoint+"/n/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.sign(request, test.now); err != nil {
+				t.Fatal(err)
+			}
+			if test.tamper != nil {
+				test.tamper(request)
+			}
+			response, err := store.client.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != test.status {
+				t.Errorf("expected %d, got %s", test.status, response.Status)
+			}
+		})
+	}
+}
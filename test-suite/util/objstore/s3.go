@@ -0,0 +1,230 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package objstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	// e.g. http://127.0.0.1:40123 (a port forwarded to MinIO), https://s3.eu-west-1.amazonaws.com
+	Endpoint   string
+	Region     string
+	BucketName string
+	AccessKey  string
+	SecretKey  string
+}
+
+// talks the S3 rest api (path-style, so it works with MinIO) with requests signed
+// with AWS signature version 4, there is no need of any aws sdk nor cli
+type s3Store struct {
+	cfg S3Config
+}
+
+func NewS3Store(cfg S3Config) ObjectStore {
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &s3Store{cfg: cfg}
+}
+
+func (s *s3Store) String() string {
+	return "s3://" + s.cfg.BucketName
+}
+
+// the keys don't begin with a slash, S3 servers drop it
+func toS3Key(name string) string {
+	return strings.TrimLeft(name, "/")
+}
+
+// escapes everything but unreserved characters, as required by the signature,
+// e.g. sakila@actor.sql => sakila%40actor.sql
+func escapeS3(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// slashes separate segments of the path, so they are kept
+func escapeS3Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escapeS3(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func escapeS3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, escapeS3(key)+"="+escapeS3(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// https://docs.aws.amazon.com/general/latest/gr/sigv4-signed-request-examples.html
+func (s *s3Store) sign(request *http.Request, escapedPath string, escapedQuery string, now time.Time) {
+	const Algorithm = "AWS4-HMAC-SHA256"
+	const Service = "s3"
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	// the requests have no body
+	payloadHash := sha256Hex(nil)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", request.URL.Host, payloadHash, amzDate)
+	canonicalRequest := strings.Join([]string{
+		request.Method, escapedPath, escapedQuery, canonicalHeaders, SignedHeaders, payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.cfg.Region, Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{Algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+s.cfg.SecretKey), date)
+	signingKey = hmacSha256(signingKey, s.cfg.Region)
+	signingKey = hmacSha256(signingKey, Service)
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		Algorithm, s.cfg.AccessKey, scope, SignedHeaders, signature))
+}
+
+// the key may be empty for requests on the bucket
+func (s *s3Store) do(ctx context.Context, method string, key string, query url.Values) (*http.Response, error) {
+	escapedPath := "/" + escapeS3Path(s.cfg.BucketName)
+	if len(key) > 0 {
+		escapedPath += "/" + escapeS3Path(key)
+	}
+	escapedQuery := escapeS3Query(query)
+
+	rawUrl := s.cfg.Endpoint + escapedPath
+	if len(escapedQuery) > 0 {
+		rawUrl += "?" + escapedQuery
+	}
+	request, err := http.NewRequestWithContext(ctx, method, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(request, escapedPath, escapedQuery, time.Now())
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(s, key, response); err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+	return response, nil
+}
+
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+func (s *s3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {toS3Key(prefix)}}
+		if len(continuationToken) > 0 {
+			query.Set("continuation-token", continuationToken)
+		}
+		response, err := s.do(ctx, http.MethodGet, "", query)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode the list of %s: %w", s, err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, ObjectInfo{Name: content.Key, Size: content.Size, ModTime: content.LastModified})
+		}
+		if !result.IsTruncated {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+
+	// S3 lists keys in the order of their utf-8 bytes anyway
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+func (s *s3Store) Get(ctx context.Context, name string) ([]byte, error) {
+	response, err := s.do(ctx, http.MethodGet, toS3Key(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+func (s *s3Store) Stat(ctx context.Context, name string) (ObjectInfo, error) {
+	response, err := s.do(ctx, http.MethodHead, toS3Key(name), nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	response.Body.Close()
+	return getObjectInfo(toS3Key(name), response), nil
+}
+
+func (s *s3Store) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	prefix, err := getDeletePrefix(s, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	for i, object := range objects {
+		response, err := s.do(ctx, http.MethodDelete, object.Name, nil)
+		if err != nil {
+			return i, err
+		}
+		response.Body.Close()
+	}
+	return len(objects), nil
+}
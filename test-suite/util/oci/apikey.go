@@ -0,0 +1,59 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package oci
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// e.g. https://objectstorage.us-ashburn-1.oraclecloud.com
+func GetObjectStorageEndpoint(region string) string {
+	return "https://" + GetObjectStorageHost(region)
+}
+
+func GetObjectStorageHost(region string) string {
+	return fmt.Sprintf("objectstorage.%s.oraclecloud.com", region)
+}
+
+func loadPrivateKey(path string, passphrase string) (*rsa.PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no pem data found in %s", path)
+	}
+
+	der := block.Bytes
+	// the oci cli writes keys encrypted in the legacy pem way (deprecated, but still there)
+	if x509.IsEncryptedPEMBlock(block) {
+		der, err = x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt %s: %w", path, err)
+		}
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the private key %s: %w", path, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key %s is not an rsa one", path)
+	}
+	return rsaKey, nil
+}
+
+// the api key requests are signed with
+func (p *Profile) LoadPrivateKey() (*rsa.PrivateKey, error) {
+	return loadPrivateKey(p.KeyFile, p.Passphrase)
+}
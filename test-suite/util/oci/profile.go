@@ -0,0 +1,104 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package oci
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/marinesovitch/ote/test-suite/util/system"
+
+	"gopkg.in/ini.v1"
+)
+
+// a profile of the OCI config file, see config.oci.sample
+type Profile struct {
+	Name        string
+	User        string
+	Fingerprint string
+	Tenancy     string
+	Region      string
+	Passphrase  string
+	// resolved to an absolute path
+	KeyFile string
+	// all options in the order of the file, with key_file resolved too
+	Options []ProfileOption
+}
+
+type ProfileOption struct {
+	Name  string
+	Value string
+}
+
+const KeyFileOption = "key_file"
+
+func resolveKeyFilePath(cfgPath string, keyFilePath string) (string, error) {
+	keyFilePath = system.PathExpand(keyFilePath)
+	if !filepath.IsAbs(keyFilePath) {
+		// relative to the config file
+		keyFilePath = filepath.Join(filepath.Dir(cfgPath), keyFilePath)
+	}
+
+	if !system.DoesFileExist(keyFilePath) {
+		return keyFilePath, fmt.Errorf("key file %s doesn't exist", keyFilePath)
+	}
+
+	return keyFilePath, nil
+}
+
+func LoadProfile(cfgPath string, profileName string) (*Profile, error) {
+	var err error
+
+	if !filepath.IsAbs(cfgPath) {
+		cfgPath, err = filepath.Abs(cfgPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = system.VerifyFileExist(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	iniCfg, err := ini.Load(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	section, err := iniCfg.GetSection(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{Name: profileName}
+	for _, key := range section.Keys() {
+		option := ProfileOption{Name: key.Name(), Value: key.Value()}
+		switch option.Name {
+		case "user":
+			profile.User = option.Value
+		case "fingerprint":
+			profile.Fingerprint = option.Value
+		case "tenancy":
+			profile.Tenancy = option.Value
+		case "region":
+			profile.Region = option.Value
+		case "passphrase":
+			profile.Passphrase = option.Value
+		case KeyFileOption:
+			option.Value, err = resolveKeyFilePath(cfgPath, option.Value)
+			if err != nil {
+				return nil, err
+			}
+			profile.KeyFile = option.Value
+		}
+		profile.Options = append(profile.Options, option)
+	}
+
+	return profile, nil
+}
+
+// the id of the api key used to sign requests
+func (p *Profile) GetKeyId() string {
+	return fmt.Sprintf("%s/%s/%s", p.Tenancy, p.User, p.Fingerprint)
+}
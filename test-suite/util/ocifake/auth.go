@@ -0,0 +1,128 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requests older (or newer) than that are rejected like by the real service
+const maxClockSkew = 5 * time.Minute
+
+// e.g. Signature version="1",keyId="<tenancy>/<user>/<fingerprint>",algorithm="rsa-sha256",
+// headers="date (request-target) host",signature="<base64>"
+func parseAuthorization(authorization string) (map[string]string, error) {
+	const Scheme = "Signature "
+	if !strings.HasPrefix(authorization, Scheme) {
+		return nil, fmt.Errorf("unsupported authorization scheme")
+	}
+
+	params := map[string]string{}
+	for _, param := range strings.Split(strings.TrimPrefix(authorization, Scheme), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			return nil, fmt.Errorf("malformed authorization parameter '%s'", param)
+		}
+		params[name] = strings.Trim(value, `"`)
+	}
+
+	for _, name := range []string{"keyId", "headers", "signature"} {
+		if len(params[name]) == 0 {
+			return nil, fmt.Errorf("authorization parameter %s is missing", name)
+		}
+	}
+	if algorithm, ok := params["algorithm"]; ok && algorithm != "rsa-sha256" {
+		return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+	return params, nil
+}
+
+func getSignedValue(request *http.Request, header string) string {
+	switch header {
+	case "(request-target)":
+		return strings.ToLower(request.Method) + " " + request.URL.RequestURI()
+	case "host":
+		return request.Host
+	case "content-length":
+		return strconv.FormatInt(request.ContentLength, 10)
+	default:
+		return request.Header.Get(header)
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// https://docs.oracle.com/en-us/iaas/Content/API/Concepts/signingrequests.htm, put of objects
+// and parts is exempt from signing the body, returns the profile the request was signed with
+func (s *Server) authenticate(request *http.Request, body []byte) (string, error) {
+	params, err := parseAuthorization(request.Header.Get("Authorization"))
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := s.keys[params["keyId"]]
+	if !ok {
+		return "", fmt.Errorf("unknown key %s", params["keyId"])
+	}
+
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	required := []string{"(request-target)", "host"}
+	if request.Method == http.MethodPost {
+		required = append(required, "content-length", "content-type", "x-content-sha256")
+	}
+	for _, header := range required {
+		if !contains(headers, header) {
+			return "", fmt.Errorf("header %s is not signed", header)
+		}
+	}
+
+	dateHeader := "date"
+	if contains(headers, "x-date") {
+		dateHeader = "x-date"
+	} else if !contains(headers, dateHeader) {
+		return "", fmt.Errorf("neither date nor x-date is signed")
+	}
+	date, err := http.ParseTime(request.Header.Get(dateHeader))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", dateHeader, err)
+	}
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return "", fmt.Errorf("the request is out of the allowed clock skew: %s", date)
+	}
+
+	if contains(headers, "x-content-sha256") {
+		sum := sha256.Sum256(body)
+		if request.Header.Get("x-content-sha256") != base64.StdEncoding.EncodeToString(sum[:]) {
+			return "", fmt.Errorf("x-content-sha256 doesn't match the body")
+		}
+	}
+
+	lines := make([]string, len(headers))
+	for i, header := range headers {
+		lines[i] = header + ": " + getSignedValue(request, header)
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return "", fmt.Errorf("malformed signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	if err := rsa.VerifyPKCS1v15(key.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return "", fmt.Errorf("signature verification failed for %s", params["keyId"])
+	}
+	return key.profileName, nil
+}
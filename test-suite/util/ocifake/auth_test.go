@@ -0,0 +1,111 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+)
+
+func expectNotAuthenticated(t *testing.T, env *testEnv, request *http.Request, reason string) {
+	t.Helper()
+	status, body := env.send(request)
+	if status != http.StatusUnauthorized || !strings.Contains(string(body), "NotAuthenticated") {
+		t.Fatalf("expected the request rejected, got %d %s", status, body)
+	}
+	if !strings.Contains(string(body), reason) {
+		t.Errorf("expected the rejection to say '%s', got %s", reason, body)
+	}
+}
+
+func TestAuthenticateAllProfiles(t *testing.T) {
+	env := newTestEnv(t)
+	for _, profileName := range Profiles {
+		profile, privateKey := env.loadProfile(profileName)
+		request := env.newRequest(http.MethodGet, "/n/", nil)
+		if err := signRequest(request, nil, profile.GetKeyId(), privateKey, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if status, body := env.send(request); status != http.StatusOK {
+			t.Errorf("%s: expected the request accepted, got %d %s", profileName, status, body)
+		}
+	}
+}
+
+func TestRejectUnsigned(t *testing.T) {
+	env := newTestEnv(t)
+	expectNotAuthenticated(t, env, env.newRequest(http.MethodGet, "/n/", nil), "unsupported authorization scheme")
+}
+
+func TestRejectTamperedSignature(t *testing.T) {
+	env := newTestEnv(t)
+	request := env.newRequest(http.MethodGet, env.getPath("o", ""), nil)
+	env.sign(request, nil)
+
+	// the signature of another request
+	other := env.newRequest(http.MethodGet, "/n/", nil)
+	env.sign(other, nil)
+	request.Header.Set("Authorization", other.Header.Get("Authorization"))
+	expectNotAuthenticated(t, env, request, "signature verification failed")
+}
+
+func TestRejectTamperedBody(t *testing.T) {
+	env := newTestEnv(t)
+	body := []byte(`{"object": "data"}`)
+	request := env.newRequest(http.MethodPost, env.getPath("u", ""), []byte(`{"object": "other"}`))
+	env.sign(request, body)
+	expectNotAuthenticated(t, env, request, "x-content-sha256 doesn't match the body")
+}
+
+func TestRejectWrongKey(t *testing.T) {
+	env := newTestEnv(t)
+
+	// the key id of one profile, the key of another
+	_, otherKey := env.loadProfile(common.OciProfileRestore)
+	request := env.newRequest(http.MethodGet, "/n/", nil)
+	if err := signRequest(request, nil, env.profile.GetKeyId(), otherKey, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	expectNotAuthenticated(t, env, request, "signature verification failed")
+
+	// a key the server doesn't know at all
+	request = env.newRequest(http.MethodGet, "/n/", nil)
+	if err := signRequest(request, nil, Tenancy+"/ocid1.user.oc1..unknown/00:11", otherKey, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	expectNotAuthenticated(t, env, request, "unknown key")
+}
+
+func TestRejectSkewedDate(t *testing.T) {
+	env := newTestEnv(t)
+	for _, skew := range []time.Duration{-maxClockSkew - time.Minute, maxClockSkew + time.Minute} {
+		request := env.newRequest(http.MethodGet, "/n/", nil)
+		if err := signRequest(request, nil, env.profile.GetKeyId(), env.privateKey, time.Now().Add(skew)); err != nil {
+			t.Fatal(err)
+		}
+		expectNotAuthenticated(t, env, request, "out of the allowed clock skew")
+	}
+
+	// a skew within the limit is fine
+	request := env.newRequest(http.MethodGet, "/n/", nil)
+	if err := signRequest(request, nil, env.profile.GetKeyId(), env.privateKey, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := env.send(request); status != http.StatusOK {
+		t.Errorf("expected a small skew accepted, got %d %s", status, body)
+	}
+}
+
+func TestRejectUnsignedPostHeaders(t *testing.T) {
+	env := newTestEnv(t)
+	// signed like a get, i.e. without the body
+	request := env.newRequest(http.MethodPost, env.getPath("u", ""), []byte(`{"object": "data"}`))
+	request.Method = http.MethodGet
+	env.sign(request, nil)
+	request.Method = http.MethodPost
+	expectNotAuthenticated(t, env, request, "is not signed")
+}
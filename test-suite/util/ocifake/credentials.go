@@ -0,0 +1,87 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/oci"
+)
+
+// the profiles the tests sign requests with, the same as in config.oci.sample
+var Profiles = []string{common.OciProfileBackup, common.OciProfileRestore, common.OciProfileDelete}
+
+const Tenancy = "ocid1.tenancy.oc1..otefake"
+
+const keyBits = 2048
+
+// the fingerprint of an api key is the md5 of its public key in der, e.g. 00:11:22:...:ff
+func getFingerprint(publicKey *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(der)
+	hexBytes := make([]string, len(sum))
+	for i, b := range sum {
+		hexBytes[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hexBytes, ":"), nil
+}
+
+func writePrivateKey(path string, key *rsa.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	content := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return os.WriteFile(path, content, 0600)
+}
+
+// generates an api key for each of Profiles and writes them with the config file (config.oci)
+// into dir, the region goes into the host of the endpoint, objectstorage.<region>.oraclecloud.com,
+// returns the path of the config file
+func GenerateConfig(dir string, region string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	var cfg strings.Builder
+	for _, profileName := range Profiles {
+		key, err := rsa.GenerateKey(rand.Reader, keyBits)
+		if err != nil {
+			return "", err
+		}
+		fingerprint, err := getFingerprint(&key.PublicKey)
+		if err != nil {
+			return "", err
+		}
+
+		keyFile := filepath.Join(dir, strings.ToLower(profileName)+"-key.pem")
+		if err := writePrivateKey(keyFile, key); err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&cfg, "[%s]\n", profileName)
+		fmt.Fprintf(&cfg, "user=ocid1.user.oc1..otefake%s\n", strings.ToLower(profileName))
+		fmt.Fprintf(&cfg, "fingerprint=%s\n", fingerprint)
+		fmt.Fprintf(&cfg, "tenancy=%s\n", Tenancy)
+		fmt.Fprintf(&cfg, "region=%s\n", region)
+		fmt.Fprintf(&cfg, "%s=%s\n\n", oci.KeyFileOption, keyFile)
+	}
+
+	cfgPath := filepath.Join(dir, "config.oci")
+	if err := os.WriteFile(cfgPath, []byte(cfg.String()), 0600); err != nil {
+		return "", err
+	}
+	return cfgPath, nil
+}
@@ -0,0 +1,207 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// mysqlsh writes bigger chunks of a dump in parts
+
+type uploadPart struct {
+	data []byte
+	md5  string
+	etag string
+}
+
+type multipartUpload struct {
+	id          string
+	object      string
+	contentType string
+	timeCreated time.Time
+	// by the part number, 1..10000
+	parts map[int]*uploadPart
+}
+
+func (b *bucket) getUpload(r *route) (*multipartUpload, *apiError) {
+	uploadId := r.request.URL.Query().Get("uploadId")
+	upload, ok := b.uploads[uploadId]
+	if !ok || upload.object != r.name {
+		return nil, newApiError(http.StatusNotFound, "NoSuchUpload", "upload %s of %s not found", uploadId, r.name)
+	}
+	return upload, nil
+}
+
+func (s *Server) serveMultipartUpload(w http.ResponseWriter, r *route) *apiError {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, err := s.getBucket(r.bucket)
+	if err != nil {
+		return err
+	}
+
+	if len(r.name) == 0 {
+		switch r.request.Method {
+		case http.MethodPost:
+			return s.createMultipartUpload(w, r, b)
+		case http.MethodGet:
+			return s.listMultipartUploads(w, b)
+		}
+		return newMethodNotAllowedError(r)
+	}
+
+	upload, err := b.getUpload(r)
+	if err != nil {
+		return err
+	}
+	switch r.request.Method {
+	case http.MethodPut:
+		return uploadPartData(w, r, upload)
+	case http.MethodPost:
+		return commitMultipartUpload(w, r, b, upload)
+	case http.MethodDelete:
+		delete(b.uploads, upload.id)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	case http.MethodGet:
+		return listUploadParts(w, upload)
+	}
+	return newMethodNotAllowedError(r)
+}
+
+type multipartUploadSummary struct {
+	Namespace   string    `json:"namespace"`
+	Bucket      string    `json:"bucket"`
+	Object      string    `json:"object"`
+	UploadId    string    `json:"uploadId"`
+	TimeCreated time.Time `json:"timeCreated"`
+}
+
+func (s *Server) newUploadSummary(b *bucket, upload *multipartUpload) multipartUploadSummary {
+	return multipartUploadSummary{
+		Namespace:   s.namespace,
+		Bucket:      b.name,
+		Object:      upload.object,
+		UploadId:    upload.id,
+		TimeCreated: upload.timeCreated,
+	}
+}
+
+func (s *Server) createMultipartUpload(w http.ResponseWriter, r *route, b *bucket) *apiError {
+	var details struct {
+		Object      string `json:"object"`
+		ContentType string `json:"contentType"`
+	}
+	if err := readJson(r.body, &details); err != nil {
+		return err
+	}
+	if len(details.Object) == 0 {
+		return newInvalidParameterError("the object of the upload is missing")
+	}
+
+	upload := &multipartUpload{
+		id:          newRequestId(),
+		object:      details.Object,
+		contentType: details.ContentType,
+		timeCreated: time.Now().UTC(),
+		parts:       map[int]*uploadPart{},
+	}
+	b.uploads[upload.id] = upload
+	writeJson(w, http.StatusOK, s.newUploadSummary(b, upload))
+	return nil
+}
+
+func (s *Server) listMultipartUploads(w http.ResponseWriter, b *bucket) *apiError {
+	uploads := []multipartUploadSummary{}
+	for _, upload := range b.uploads {
+		uploads = append(uploads, s.newUploadSummary(b, upload))
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Object < uploads[j].Object })
+	writeJson(w, http.StatusOK, uploads)
+	return nil
+}
+
+func uploadPartData(w http.ResponseWriter, r *route, upload *multipartUpload) *apiError {
+	const MaxPartNum = 10000
+	rawPartNum := r.request.URL.Query().Get("uploadPartNum")
+	partNum, err := strconv.Atoi(rawPartNum)
+	if err != nil || partNum < 1 || partNum > MaxPartNum {
+		return newInvalidParameterError("invalid uploadPartNum %s", rawPartNum)
+	}
+
+	sum := md5.Sum(r.body)
+	part := &uploadPart{
+		data: r.body,
+		md5:  base64.StdEncoding.EncodeToString(sum[:]),
+		etag: newRequestId(),
+	}
+	upload.parts[partNum] = part
+	w.Header().Set("ETag", part.etag)
+	w.Header().Set("opc-content-md5", part.md5)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func commitMultipartUpload(w http.ResponseWriter, r *route, b *bucket, upload *multipartUpload) *apiError {
+	var details struct {
+		PartsToCommit []struct {
+			PartNum int    `json:"partNum"`
+			Etag    string `json:"etag"`
+		} `json:"partsToCommit"`
+	}
+	if err := readJson(r.body, &details); err != nil {
+		return err
+	}
+	if len(details.PartsToCommit) == 0 {
+		return newInvalidParameterError("no parts to commit for %s", upload.object)
+	}
+	sort.Slice(details.PartsToCommit, func(i, j int) bool {
+		return details.PartsToCommit[i].PartNum < details.PartsToCommit[j].PartNum
+	})
+
+	var data []byte
+	var partMd5s []byte
+	for _, committed := range details.PartsToCommit {
+		part, ok := upload.parts[committed.PartNum]
+		if !ok || part.etag != committed.Etag {
+			return newInvalidParameterError("part %d of %s not found or its etag doesn't match", committed.PartNum, upload.object)
+		}
+		data = append(data, part.data...)
+		rawMd5, _ := base64.StdEncoding.DecodeString(part.md5)
+		partMd5s = append(partMd5s, rawMd5...)
+	}
+
+	o := newObject(data, upload.contentType)
+	// like the real service: the md5 of the md5s of the parts with their count
+	multipartMd5 := md5.Sum(partMd5s)
+	o.md5 = base64.StdEncoding.EncodeToString(multipartMd5[:]) + "-" + strconv.Itoa(len(details.PartsToCommit))
+	b.objects[upload.object] = o
+	delete(b.uploads, upload.id)
+
+	w.Header().Set("ETag", o.etag)
+	w.Header().Set("opc-multipart-md5", o.md5)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+type uploadPartSummary struct {
+	PartNumber int    `json:"partNumber"`
+	Etag       string `json:"etag"`
+	Md5        string `json:"md5"`
+	Size       int64  `json:"size"`
+}
+
+func listUploadParts(w http.ResponseWriter, upload *multipartUpload) *apiError {
+	parts := []uploadPartSummary{}
+	for partNum, part := range upload.parts {
+		parts = append(parts, uploadPartSummary{PartNumber: partNum, Etag: part.etag, Md5: part.md5, Size: int64(len(part.data))})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	writeJson(w, http.StatusOK, parts)
+	return nil
+}
@@ -0,0 +1,129 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func createUpload(env *testEnv, object string) string {
+	env.t.Helper()
+	var upload multipartUploadSummary
+	env.postJson(env.getPath("u", ""), map[string]string{"object": object}, &upload)
+	if upload.Object != object || len(upload.UploadId) == 0 {
+		env.t.Fatalf("unexpected upload %+v", upload)
+	}
+	return upload.UploadId
+}
+
+// e.g. /n/otefake/b/ote-bucket/u/dump%2Fchunk.zst?uploadId=...
+func getUploadPath(env *testEnv, object string, query url.Values) string {
+	return env.getPath("u", object) + "?" + query.Encode()
+}
+
+// returns the etag of the part
+func putPart(env *testEnv, object string, uploadId string, partNum int, data string) string {
+	env.t.Helper()
+	query := url.Values{"uploadId": {uploadId}, "uploadPartNum": {fmt.Sprint(partNum)}}
+	request := env.newRequest(http.MethodPut, getUploadPath(env, object, query), []byte(data))
+	env.sign(request, []byte(data))
+	response, err := env.client.Do(request)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		env.t.Fatalf("upload of part %d failed: %s", partNum, response.Status)
+	}
+	return response.Header.Get("ETag")
+}
+
+type partToCommit struct {
+	PartNum int    `json:"partNum"`
+	Etag    string `json:"etag"`
+}
+
+func commitUpload(env *testEnv, object string, uploadId string, parts []partToCommit) (int, []byte) {
+	env.t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"partsToCommit": parts})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return env.do(http.MethodPost, getUploadPath(env, object, url.Values{"uploadId": {uploadId}}), body)
+}
+
+func listUploads(env *testEnv) []multipartUploadSummary {
+	env.t.Helper()
+	var uploads []multipartUploadSummary
+	if err := json.Unmarshal(env.expect(http.StatusOK, http.MethodGet, env.getPath("u", ""), nil), &uploads); err != nil {
+		env.t.Fatal(err)
+	}
+	return uploads
+}
+
+func TestMultipartUploadCommit(t *testing.T) {
+	env := newTestEnv(t)
+	const Object = "dump/sakila@film@@0.tsv.zst"
+	uploadId := createUpload(env, Object)
+
+	// parts may come in any order, they are committed by their numbers
+	etag2 := putPart(env, Object, uploadId, 2, "second")
+	etag1 := putPart(env, Object, uploadId, 1, "first-")
+	if names := env.server.ListObjectNames(testBucket); len(names) != 0 {
+		t.Errorf("expected no object before the commit, got %v", names)
+	}
+
+	if status, body := commitUpload(env, Object, uploadId, []partToCommit{{2, etag2}, {1, etag1}}); status != http.StatusOK {
+		t.Fatalf("commit failed: %d %s", status, body)
+	}
+	if data := env.getObject(Object); data != "first-second" {
+		t.Errorf("expected the parts concatenated, got %s", data)
+	}
+	if uploads := listUploads(env); len(uploads) != 0 {
+		t.Errorf("expected the upload gone after the commit, got %+v", uploads)
+	}
+}
+
+func TestMultipartUploadCommitChecksEtags(t *testing.T) {
+	env := newTestEnv(t)
+	const Object = "dump/chunk.zst"
+	uploadId := createUpload(env, Object)
+	putPart(env, Object, uploadId, 1, "data")
+
+	status, body := commitUpload(env, Object, uploadId, []partToCommit{{1, "stale-etag"}})
+	if status != http.StatusBadRequest || !strings.Contains(string(body), "etag") {
+		t.Errorf("expected a part with a wrong etag rejected, got %d %s", status, body)
+	}
+	if names := env.server.ListObjectNames(testBucket); len(names) != 0 {
+		t.Errorf("expected no object after a failed commit, got %v", names)
+	}
+}
+
+func TestMultipartUploadAbort(t *testing.T) {
+	env := newTestEnv(t)
+	const Object = "dump/chunk.zst"
+	uploadId := createUpload(env, Object)
+	etag := putPart(env, Object, uploadId, 1, "data")
+
+	if uploads := listUploads(env); len(uploads) != 1 || uploads[0].UploadId != uploadId {
+		t.Fatalf("expected the upload in progress, got %+v", uploads)
+	}
+
+	env.expect(http.StatusNoContent, http.MethodDelete, getUploadPath(env, Object, url.Values{"uploadId": {uploadId}}), nil)
+	if uploads := listUploads(env); len(uploads) != 0 {
+		t.Errorf("expected no upload after the abort, got %+v", uploads)
+	}
+
+	status, body := commitUpload(env, Object, uploadId, []partToCommit{{1, etag}})
+	if status != http.StatusNotFound || !strings.Contains(string(body), "NoSuchUpload") {
+		t.Errorf("expected an aborted upload not committed, got %d %s", status, body)
+	}
+	if names := env.server.ListObjectNames(testBucket); len(names) != 0 {
+		t.Errorf("expected no object after the abort, got %v", names)
+	}
+}
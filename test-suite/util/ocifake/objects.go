@@ -0,0 +1,364 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type object struct {
+	data        []byte
+	md5         string
+	etag        string
+	contentType string
+	timeCreated time.Time
+}
+
+type bucket struct {
+	name        string
+	timeCreated time.Time
+	objects     map[string]*object
+	// by upload id
+	uploads map[string]*multipartUpload
+}
+
+func newObject(data []byte, contentType string) *object {
+	sum := md5.Sum(data)
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	return &object{
+		data:        data,
+		md5:         base64.StdEncoding.EncodeToString(sum[:]),
+		etag:        newRequestId(),
+		contentType: contentType,
+		timeCreated: time.Now().UTC(),
+	}
+}
+
+// buckets may also be created through the api, like with 'oci os bucket create'
+func (s *Server) CreateBucket(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.buckets[name]; !ok {
+		s.addBucket(name)
+	}
+}
+
+// the caller holds the mutex
+func (s *Server) addBucket(name string) *bucket {
+	b := &bucket{
+		name:        name,
+		timeCreated: time.Now().UTC(),
+		objects:     map[string]*object{},
+		uploads:     map[string]*multipartUpload{},
+	}
+	s.buckets[name] = b
+	return b
+}
+
+// the names of all objects in the bucket, sorted
+func (s *Server) ListObjectNames(bucketName string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, ok := s.buckets[bucketName]
+	if !ok {
+		return nil
+	}
+	return b.getSortedNames()
+}
+
+// the caller holds the mutex
+func (s *Server) getBucket(name string) (*bucket, *apiError) {
+	b, ok := s.buckets[name]
+	if !ok {
+		return nil, newApiError(http.StatusNotFound, "BucketNotFound", "bucket %s not found", name)
+	}
+	return b, nil
+}
+
+func (b *bucket) getObject(name string) (*object, *apiError) {
+	o, ok := b.objects[name]
+	if !ok {
+		return nil, newApiError(http.StatusNotFound, "ObjectNotFound", "object %s not found in bucket %s", name, b.name)
+	}
+	return o, nil
+}
+
+func (b *bucket) getSortedNames() []string {
+	names := make([]string, 0, len(b.objects))
+	for name := range b.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ---------------------------
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *route) *apiError {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var b *bucket
+	switch {
+	case r.request.Method == http.MethodPost && len(r.bucket) == 0 && r.par == nil:
+		var details struct {
+			Name string `json:"name"`
+		}
+		if err := readJson(r.body, &details); err != nil {
+			return err
+		}
+		if _, ok := s.buckets[details.Name]; ok {
+			return newApiError(http.StatusConflict, "BucketAlreadyExists", "bucket %s already exists", details.Name)
+		}
+		b = s.addBucket(details.Name)
+	case r.request.Method == http.MethodGet || r.request.Method == http.MethodHead:
+		var err *apiError
+		if b, err = s.getBucket(r.bucket); err != nil {
+			return err
+		}
+	default:
+		return newMethodNotAllowedError(r)
+	}
+
+	w.Header().Set("ETag", b.timeCreated.Format(time.RFC3339Nano))
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"namespace":   s.namespace,
+		"name":        b.name,
+		"timeCreated": b.timeCreated,
+	})
+	return nil
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, r *route) *apiError {
+	if len(r.name) == 0 {
+		if r.request.Method != http.MethodGet {
+			return newMethodNotAllowedError(r)
+		}
+		return s.listObjects(w, r)
+	}
+
+	switch r.request.Method {
+	case http.MethodGet, http.MethodHead:
+		return s.getObject(w, r)
+	case http.MethodPut:
+		return s.putObject(w, r)
+	case http.MethodDelete:
+		return s.deleteObject(w, r)
+	}
+	return newMethodNotAllowedError(r)
+}
+
+func writeObjectHeaders(w http.ResponseWriter, o *object) {
+	w.Header().Set("ETag", o.etag)
+	w.Header().Set("Content-MD5", o.md5)
+	w.Header().Set("opc-content-md5", o.md5)
+	w.Header().Set("Last-Modified", o.timeCreated.Format(http.TimeFormat))
+}
+
+// ranges and conditions (if-match, if-none-match, ...) are handled by http.ServeContent
+func (s *Server) getObject(w http.ResponseWriter, r *route) *apiError {
+	s.mutex.Lock()
+	b, err := s.getBucket(r.bucket)
+	var o *object
+	if err == nil {
+		o, err = b.getObject(r.name)
+	}
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	writeObjectHeaders(w, o)
+	w.Header().Set("Content-Type", o.contentType)
+	http.ServeContent(w, r.request, r.name, o.timeCreated, bytes.NewReader(o.data))
+	return nil
+}
+
+func checkPreconditions(r *route, existing *object) *apiError {
+	ifMatch := r.request.Header.Get("if-match")
+	if len(ifMatch) > 0 && (existing == nil || existing.etag != ifMatch) {
+		return newApiError(http.StatusPreconditionFailed, "IfMatchFailed", "the etag of %s doesn't match", r.name)
+	}
+	if r.request.Header.Get("if-none-match") == "*" && existing != nil {
+		return newApiError(http.StatusPreconditionFailed, "IfNoneMatchFailed", "object %s already exists", r.name)
+	}
+	return nil
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *route) *apiError {
+	if expectedMd5 := r.request.Header.Get("Content-MD5"); len(expectedMd5) > 0 {
+		sum := md5.Sum(r.body)
+		if expectedMd5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return newApiError(http.StatusBadRequest, "InvalidContentMD5", "Content-MD5 doesn't match the body of %s", r.name)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, err := s.getBucket(r.bucket)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(r, b.objects[r.name]); err != nil {
+		return err
+	}
+
+	o := newObject(r.body, r.request.Header.Get("Content-Type"))
+	b.objects[r.name] = o
+	writeObjectHeaders(w, o)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, r *route) *apiError {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, err := s.getBucket(r.bucket)
+	if err != nil {
+		return err
+	}
+	o, err := b.getObject(r.name)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(r, o); err != nil {
+		return err
+	}
+
+	delete(b.objects, r.name)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type objectSummary struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	Md5          string    `json:"md5"`
+	Etag         string    `json:"etag"`
+	TimeCreated  time.Time `json:"timeCreated"`
+	TimeModified time.Time `json:"timeModified"`
+}
+
+type listObjectsResult struct {
+	Objects       []objectSummary `json:"objects"`
+	Prefixes      []string        `json:"prefixes,omitempty"`
+	NextStartWith string          `json:"nextStartWith,omitempty"`
+}
+
+// all fields are returned, whatever is asked with 'fields', clients don't mind extra ones
+func (s *Server) listObjects(w http.ResponseWriter, r *route) *apiError {
+	query := r.request.URL.Query()
+	prefix := query.Get("prefix")
+	start := query.Get("start")
+	end := query.Get("end")
+	delimiter := query.Get("delimiter")
+	if len(delimiter) > 0 && delimiter != "/" {
+		return newInvalidParameterError("only '/' is supported as a delimiter")
+	}
+	limit := 1000
+	if rawLimit := query.Get("limit"); len(rawLimit) > 0 {
+		var err error
+		if limit, err = strconv.Atoi(rawLimit); err != nil || limit <= 0 || limit > 1000 {
+			return newInvalidParameterError("invalid limit %s", rawLimit)
+		}
+	}
+	if r.par != nil {
+		prefix = r.par.restrictPrefix(prefix)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, err := s.getBucket(r.bucket)
+	if err != nil {
+		return err
+	}
+
+	result := listObjectsResult{Objects: []objectSummary{}}
+	seenPrefixes := map[string]bool{}
+	count := 0
+	for _, name := range b.getSortedNames() {
+		if !strings.HasPrefix(name, prefix) || name < start || (len(end) > 0 && name >= end) {
+			continue
+		}
+		if count == limit {
+			result.NextStartWith = name
+			break
+		}
+
+		if len(delimiter) > 0 {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				subPrefix := name[:len(prefix)+i+1]
+				if !seenPrefixes[subPrefix] {
+					seenPrefixes[subPrefix] = true
+					result.Prefixes = append(result.Prefixes, subPrefix)
+					count++
+				}
+				continue
+			}
+		}
+
+		o := b.objects[name]
+		result.Objects = append(result.Objects, objectSummary{
+			Name:         name,
+			Size:         int64(len(o.data)),
+			Md5:          o.md5,
+			Etag:         o.etag,
+			TimeCreated:  o.timeCreated,
+			TimeModified: o.timeCreated,
+		})
+		count++
+	}
+
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// ---------------------------
+
+func (s *Server) serveAction(w http.ResponseWriter, r *route) *apiError {
+	if r.name != "renameObject" || r.request.Method != http.MethodPost {
+		return newMethodNotAllowedError(r)
+	}
+
+	var details struct {
+		SourceName    string `json:"sourceName"`
+		NewName       string `json:"newName"`
+		SrcObjIfMatch string `json:"srcObjIfMatchETag"`
+		NewObjIfMatch string `json:"newObjIfMatchETag"`
+		NewObjIfNone  string `json:"newObjIfNoneMatchETag"`
+	}
+	if err := readJson(r.body, &details); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, err := s.getBucket(r.bucket)
+	if err != nil {
+		return err
+	}
+	o, err := b.getObject(details.SourceName)
+	if err != nil {
+		return err
+	}
+	existing := b.objects[details.NewName]
+	if (len(details.SrcObjIfMatch) > 0 && details.SrcObjIfMatch != o.etag) ||
+		(len(details.NewObjIfMatch) > 0 && (existing == nil || details.NewObjIfMatch != existing.etag)) ||
+		(details.NewObjIfNone == "*" && existing != nil) {
+		return newApiError(http.StatusPreconditionFailed, "IfMatchFailed", "cannot rename %s to %s", details.SourceName, details.NewName)
+	}
+
+	delete(b.objects, details.SourceName)
+	b.objects[details.NewName] = o
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
@@ -0,0 +1,110 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/marinesovitch/ote/test-suite/util/objstore"
+)
+
+func getNames(objects []objstore.ObjectInfo) []string {
+	names := []string{}
+	for _, object := range objects {
+		names = append(names, object.Name)
+	}
+	return names
+}
+
+func TestObjectsRoundTrip(t *testing.T) {
+	env := newTestEnv(t)
+	store := env.newStore()
+	ctx := context.Background()
+
+	env.putObject("dump/@.json", `{"version": "2.0.1"}`)
+	env.putObject("dump/@.done.json", "{}")
+	env.putObject("dump/sakila@actor@@0.tsv.zst", "chunk")
+	env.putObject("other/@.json", "{}")
+
+	data, err := store.Get(ctx, "dump/@.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"version": "2.0.1"}` {
+		t.Errorf("unexpected content %s", data)
+	}
+
+	info, err := store.Stat(ctx, "dump/sakila@actor@@0.tsv.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len("chunk")) || info.ModTime.IsZero() {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	objects, err := store.List(ctx, "dump/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"dump/@.done.json", "dump/@.json", "dump/sakila@actor@@0.tsv.zst"}
+	if names := getNames(objects); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	deleted, err := store.DeletePrefix(ctx, "dump/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != len(expected) {
+		t.Errorf("expected %d objects deleted, got %d", len(expected), deleted)
+	}
+	if names := env.server.ListObjectNames(testBucket); !reflect.DeepEqual(names, []string{"other/@.json"}) {
+		t.Errorf("expected only the object out of the prefix left, got %v", names)
+	}
+
+	if _, err := store.Get(ctx, "dump/@.json"); !objstore.IsObjectNotFoundError(err) {
+		t.Errorf("expected a deleted object not found, got %v", err)
+	}
+}
+
+func TestObjectRange(t *testing.T) {
+	env := newTestEnv(t)
+	env.putObject("data", "0123456789")
+
+	request := env.newRequest(http.MethodGet, env.getPath("o", "data"), nil)
+	request.Header.Set("Range", "bytes=2-5")
+	env.sign(request, nil)
+	status, body := env.send(request)
+	if status != http.StatusPartialContent || string(body) != "2345" {
+		t.Errorf("expected 206 with 2345, got %d with %s", status, body)
+	}
+}
+
+func TestObjectPreconditions(t *testing.T) {
+	env := newTestEnv(t)
+	env.putObject("data", "first")
+
+	request := env.newRequest(http.MethodPut, env.getPath("o", "data"), []byte("second"))
+	request.Header.Set("if-none-match", "*")
+	env.sign(request, []byte("second"))
+	if status, _ := env.send(request); status != http.StatusPreconditionFailed {
+		t.Errorf("expected an existing object not overwritten, got %d", status)
+	}
+	if data := env.getObject("data"); data != "first" {
+		t.Errorf("expected the object unchanged, got %s", data)
+	}
+}
+
+func TestRenameObject(t *testing.T) {
+	env := newTestEnv(t)
+	env.putObject("dump/@.json.tmp", "{}")
+
+	path := env.getPath("actions", "renameObject")
+	env.postJson(path, map[string]string{"sourceName": "dump/@.json.tmp", "newName": "dump/@.json"}, nil)
+	if names := env.server.ListObjectNames(testBucket); !reflect.DeepEqual(names, []string{"dump/@.json"}) {
+		t.Errorf("expected the object renamed, got %v", names)
+	}
+}
@@ -0,0 +1,223 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pre-authenticated requests give access to objects of a bucket without signing, e.g. mysqlsh
+// loads a dump through a PAR of its @.manifest.json, only access to objects is supported
+// (get, head, put and listing), multipart uploads through a PAR are not
+
+type preauthenticatedRequest struct {
+	id         string
+	name       string
+	token      string
+	bucket     string
+	objectName string
+	// ObjectRead, ObjectWrite, ObjectReadWrite, AnyObjectRead, AnyObjectWrite, AnyObjectReadWrite
+	accessType string
+	// ListObjects or Deny
+	bucketListingAction string
+	timeCreated         time.Time
+	timeExpires         time.Time
+}
+
+func (s *Server) getPreauthenticatedRequest(token string) (*preauthenticatedRequest, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	par, ok := s.pars[token]
+	if !ok {
+		return nil, fmt.Errorf("unknown PAR")
+	}
+	if time.Now().After(par.timeExpires) {
+		return nil, fmt.Errorf("PAR %s expired at %s", par.name, par.timeExpires)
+	}
+	return par, nil
+}
+
+func (p *preauthenticatedRequest) isAnyObject() bool {
+	return strings.HasPrefix(p.accessType, "AnyObject")
+}
+
+func (p *preauthenticatedRequest) authorize(r *route) *apiError {
+	denied := func(reason string) *apiError {
+		return newApiError(http.StatusNotFound, "NotAuthorizedOrNotFound", "PAR %s: %s", p.name, reason)
+	}
+
+	if r.bucket != p.bucket || r.collection != "o" {
+		return denied("the request is out of its scope")
+	}
+
+	if len(r.name) == 0 {
+		if r.request.Method != http.MethodGet || p.bucketListingAction != "ListObjects" {
+			return denied("listing objects is not allowed")
+		}
+		return nil
+	}
+
+	if p.isAnyObject() {
+		if !strings.HasPrefix(r.name, p.objectName) {
+			return denied(fmt.Sprintf("object %s is out of its prefix", r.name))
+		}
+	} else if r.name != p.objectName {
+		return denied(fmt.Sprintf("object %s is out of its scope", r.name))
+	}
+
+	switch r.request.Method {
+	case http.MethodGet, http.MethodHead:
+		if !strings.HasSuffix(p.accessType, "Read") && !strings.HasSuffix(p.accessType, "ReadWrite") {
+			return denied("reading is not allowed")
+		}
+	case http.MethodPut:
+		if !strings.HasSuffix(p.accessType, "Write") {
+			return denied("writing is not allowed")
+		}
+	default:
+		return denied(r.request.Method + " is not allowed")
+	}
+	return nil
+}
+
+// listing through a PAR with a prefix doesn't go beyond the prefix
+func (p *preauthenticatedRequest) restrictPrefix(prefix string) string {
+	if strings.HasPrefix(p.objectName, prefix) {
+		return p.objectName
+	}
+	return prefix
+}
+
+// ---------------------------
+
+type preauthenticatedRequestSummary struct {
+	Id                  string    `json:"id"`
+	Name                string    `json:"name"`
+	AccessUri           string    `json:"accessUri,omitempty"`
+	ObjectName          string    `json:"objectName,omitempty"`
+	AccessType          string    `json:"accessType"`
+	BucketListingAction string    `json:"bucketListingAction,omitempty"`
+	TimeCreated         time.Time `json:"timeCreated"`
+	TimeExpires         time.Time `json:"timeExpires"`
+}
+
+func (p *preauthenticatedRequest) getSummary() preauthenticatedRequestSummary {
+	return preauthenticatedRequestSummary{
+		Id:                  p.id,
+		Name:                p.name,
+		ObjectName:          p.objectName,
+		AccessType:          p.accessType,
+		BucketListingAction: p.bucketListingAction,
+		TimeCreated:         p.timeCreated,
+		TimeExpires:         p.timeExpires,
+	}
+}
+
+// e.g. /p/<token>/n/<namespace>/b/<bucket>/o/<object>, any-object PARs end with /o/
+func (s *Server) getAccessUri(p *preauthenticatedRequest) string {
+	uri := fmt.Sprintf("/p/%s/n/%s/b/%s/o/", p.token, url.PathEscape(s.namespace), url.PathEscape(p.bucket))
+	if !p.isAnyObject() {
+		uri += url.PathEscape(p.objectName)
+	}
+	return uri
+}
+
+func (s *Server) servePreauthenticatedRequest(w http.ResponseWriter, r *route) *apiError {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.getBucket(r.bucket); err != nil {
+		return err
+	}
+
+	if len(r.name) == 0 {
+		switch r.request.Method {
+		case http.MethodPost:
+			return s.createPreauthenticatedRequest(w, r)
+		case http.MethodGet:
+			pars := []preauthenticatedRequestSummary{}
+			for _, par := range s.pars {
+				if par.bucket == r.bucket {
+					pars = append(pars, par.getSummary())
+				}
+			}
+			sort.Slice(pars, func(i, j int) bool { return pars[i].Name < pars[j].Name })
+			writeJson(w, http.StatusOK, pars)
+			return nil
+		}
+		return newMethodNotAllowedError(r)
+	}
+
+	for token, par := range s.pars {
+		if par.bucket != r.bucket || par.id != r.name {
+			continue
+		}
+		switch r.request.Method {
+		case http.MethodGet:
+			writeJson(w, http.StatusOK, par.getSummary())
+			return nil
+		case http.MethodDelete:
+			delete(s.pars, token)
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		return newMethodNotAllowedError(r)
+	}
+	return newApiError(http.StatusNotFound, "NotFound", "PAR %s not found", r.name)
+}
+
+func (s *Server) createPreauthenticatedRequest(w http.ResponseWriter, r *route) *apiError {
+	var details struct {
+		Name                string    `json:"name"`
+		ObjectName          string    `json:"objectName"`
+		AccessType          string    `json:"accessType"`
+		BucketListingAction string    `json:"bucketListingAction"`
+		TimeExpires         time.Time `json:"timeExpires"`
+	}
+	if err := readJson(r.body, &details); err != nil {
+		return err
+	}
+
+	switch details.AccessType {
+	case "ObjectRead", "ObjectWrite", "ObjectReadWrite":
+		if len(details.ObjectName) == 0 {
+			return newInvalidParameterError("objectName is required for %s", details.AccessType)
+		}
+	case "AnyObjectRead", "AnyObjectWrite", "AnyObjectReadWrite":
+	default:
+		return newInvalidParameterError("unsupported accessType '%s'", details.AccessType)
+	}
+	if !details.TimeExpires.After(time.Now()) {
+		return newInvalidParameterError("timeExpires %s is not in the future", details.TimeExpires)
+	}
+
+	const TokenSize = 48
+	token := make([]byte, TokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return newApiError(http.StatusInternalServerError, "InternalServerError", "%s", err)
+	}
+
+	par := &preauthenticatedRequest{
+		id:                  newRequestId(),
+		name:                details.Name,
+		token:               base64.RawURLEncoding.EncodeToString(token),
+		bucket:              r.bucket,
+		objectName:          details.ObjectName,
+		accessType:          details.AccessType,
+		bucketListingAction: details.BucketListingAction,
+		timeCreated:         time.Now().UTC(),
+		timeExpires:         details.TimeExpires,
+	}
+	s.pars[par.token] = par
+
+	summary := par.getSummary()
+	summary.AccessUri = s.getAccessUri(par)
+	writeJson(w, http.StatusOK, summary)
+	return nil
+}
@@ -0,0 +1,151 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func createPar(env *testEnv, details map[string]interface{}) preauthenticatedRequestSummary {
+	env.t.Helper()
+	var par preauthenticatedRequestSummary
+	env.postJson(env.getPath("p", ""), details, &par)
+	if len(par.AccessUri) == 0 {
+		env.t.Fatalf("no access uri for %+v", par)
+	}
+	return par
+}
+
+// PARs are used without signing
+func usePar(env *testEnv, method string, uri string, body []byte) (int, []byte) {
+	env.t.Helper()
+	return env.send(env.newRequest(method, uri, body))
+}
+
+func expirePars(s *Server) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, par := range s.pars {
+		par.timeExpires = time.Now().Add(-time.Second)
+	}
+}
+
+func TestParObjectRead(t *testing.T) {
+	env := newTestEnv(t)
+	env.putObject("dump/@.manifest.json", "manifest")
+	env.putObject("dump/@.json", "{}")
+
+	par := createPar(env, map[string]interface{}{
+		"name":        "manifest",
+		"objectName":  "dump/@.manifest.json",
+		"accessType":  "ObjectRead",
+		"timeExpires": time.Now().Add(time.Hour),
+	})
+
+	status, body := usePar(env, http.MethodGet, par.AccessUri, nil)
+	if status != http.StatusOK || string(body) != "manifest" {
+		t.Errorf("expected the object read through the PAR, got %d %s", status, body)
+	}
+
+	// nothing beyond the object and reading
+	other := par.AccessUri[:len(par.AccessUri)-len(url.PathEscape(par.ObjectName))] + url.PathEscape("dump/@.json")
+	if status, body := usePar(env, http.MethodGet, other, nil); status != http.StatusNotFound {
+		t.Errorf("expected another object denied, got %d %s", status, body)
+	}
+	if status, body := usePar(env, http.MethodPut, par.AccessUri, []byte("tampered")); status != http.StatusNotFound {
+		t.Errorf("expected writing denied, got %d %s", status, body)
+	}
+	if data := env.getObject("dump/@.manifest.json"); data != "manifest" {
+		t.Errorf("expected the object unchanged, got %s", data)
+	}
+}
+
+func TestParAnyObjectReadWrite(t *testing.T) {
+	env := newTestEnv(t)
+	env.putObject("dump/@.json", "{}")
+	env.putObject("other/@.json", "{}")
+
+	par := createPar(env, map[string]interface{}{
+		"name":                "dump",
+		"objectName":          "dump/",
+		"accessType":          "AnyObjectReadWrite",
+		"bucketListingAction": "ListObjects",
+		"timeExpires":         time.Now().Add(time.Hour),
+	})
+
+	if status, body := usePar(env, http.MethodPut, par.AccessUri+url.PathEscape("dump/@.done.json"), []byte("done")); status != http.StatusOK {
+		t.Fatalf("expected writing within the prefix allowed, got %d %s", status, body)
+	}
+	if data := env.getObject("dump/@.done.json"); data != "done" {
+		t.Errorf("expected the object written through the PAR, got %s", data)
+	}
+
+	// listing doesn't go beyond the prefix of the PAR
+	status, body := usePar(env, http.MethodGet, par.AccessUri, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected listing allowed, got %d %s", status, body)
+	}
+	var result listObjectsResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 2 {
+		t.Errorf("expected the 2 objects of the prefix listed, got %+v", result.Objects)
+	}
+
+	if status, body := usePar(env, http.MethodGet, par.AccessUri+url.PathEscape("other/@.json"), nil); status != http.StatusNotFound {
+		t.Errorf("expected an object out of the prefix denied, got %d %s", status, body)
+	}
+}
+
+func TestParExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	env.putObject("dump/@.manifest.json", "manifest")
+
+	par := createPar(env, map[string]interface{}{
+		"name":        "manifest",
+		"objectName":  "dump/@.manifest.json",
+		"accessType":  "ObjectRead",
+		"timeExpires": time.Now().Add(time.Hour),
+	})
+	if status, body := usePar(env, http.MethodGet, par.AccessUri, nil); status != http.StatusOK {
+		t.Fatalf("expected the PAR valid, got %d %s", status, body)
+	}
+
+	expirePars(env.server)
+	if status, body := usePar(env, http.MethodGet, par.AccessUri, nil); status != http.StatusNotFound {
+		t.Errorf("expected an expired PAR denied, got %d %s", status, body)
+	}
+
+	// and one cannot be created already expired
+	details, err := json.Marshal(map[string]interface{}{
+		"name":        "expired",
+		"objectName":  "dump/@.manifest.json",
+		"accessType":  "ObjectRead",
+		"timeExpires": time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.expect(http.StatusBadRequest, http.MethodPost, env.getPath("p", ""), details)
+}
+
+func TestParDelete(t *testing.T) {
+	env := newTestEnv(t)
+	env.putObject("data", "data")
+
+	par := createPar(env, map[string]interface{}{
+		"name":        "data",
+		"objectName":  "data",
+		"accessType":  "ObjectRead",
+		"timeExpires": time.Now().Add(time.Hour),
+	})
+	env.expect(http.StatusNoContent, http.MethodDelete, env.getPath("p", par.Id), nil)
+	if status, body := usePar(env, http.MethodGet, par.AccessUri, nil); status != http.StatusNotFound {
+		t.Errorf("expected a deleted PAR denied, got %d %s", status, body)
+	}
+}
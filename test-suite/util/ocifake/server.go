@@ -0,0 +1,323 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/oci"
+)
+
+// A fake of OCI Object Storage serving the subset of its rest api used by util.dumpInstance()
+// and util.loadDump() of mysqlsh and by util/objstore: the namespace, buckets, objects (put,
+// get with ranges, head, delete, list, rename), multipart uploads and pre-authenticated
+// requests (PARs) for objects. Requests are signed with the api keys of the profiles of
+// a config file, e.g. generated with GenerateConfig. Everything is kept in memory.
+
+type Config struct {
+	// the profiles whose api keys are accepted
+	ConfigPath string
+	Profiles   []string
+	// the object storage namespace of the fake tenancy
+	Namespace string
+	// host names and ips the certificate of the server is issued for, e.g.
+	// objectstorage.<region>.oraclecloud.com, 127.0.0.1
+	Hosts []string
+	// a CA the certificate is signed with, e.g. one trusted by the images of the operator,
+	// if empty a throwaway one is generated
+	CaCertFile string
+	CaKeyFile  string
+}
+
+const DefaultNamespace = "otefake"
+
+type apiKey struct {
+	profileName string
+	publicKey   *rsa.PublicKey
+}
+
+type Server struct {
+	namespace string
+	// by key id, i.e. <tenancy>/<user>/<fingerprint>
+	keys map[string]apiKey
+	ca   *certAuthority
+	cert tls.Certificate
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	// by the access token of the uri
+	pars map[string]*preauthenticatedRequest
+
+	// other services sharing the listener, by path
+	handlers map[string]http.Handler
+
+	listener   net.Listener
+	httpServer *http.Server
+}
+
+func NewServer(cfg Config) (*Server, error) {
+	if len(cfg.Hosts) == 0 {
+		return nil, fmt.Errorf("no hosts given for the certificate of the fake OCI")
+	}
+
+	s := &Server{
+		namespace: cfg.Namespace,
+		keys:      map[string]apiKey{},
+		buckets:   map[string]*bucket{},
+		pars:      map[string]*preauthenticatedRequest{},
+		handlers:  map[string]http.Handler{},
+	}
+	if len(s.namespace) == 0 {
+		s.namespace = DefaultNamespace
+	}
+
+	for _, profileName := range cfg.Profiles {
+		profile, err := oci.LoadProfile(cfg.ConfigPath, profileName)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load the profile %s: %w", profileName, err)
+		}
+		privateKey, err := profile.LoadPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		s.keys[profile.GetKeyId()] = apiKey{profileName: profileName, publicKey: &privateKey.PublicKey}
+	}
+
+	var err error
+	if len(cfg.CaCertFile) > 0 {
+		s.ca, err = loadCertAuthority(cfg.CaCertFile, cfg.CaKeyFile)
+	} else {
+		s.ca, err = generateCertAuthority()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.cert, err = s.ca.issue(cfg.Hosts)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// serves the path with the handler instead of the OCI api, e.g. an admission webhook, so it
+// shares the port and the certificate, it has to be called before Start
+func (s *Server) Handle(path string, handler http.Handler) {
+	s.handlers[path] = handler
+}
+
+// starts serving https on the address, e.g. 0.0.0.0:0 for any free port
+func (s *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.httpServer = &http.Server{
+		Handler:   s,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{s.cert}},
+	}
+
+	go func() {
+		if err := s.httpServer.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error.Printf("fake OCI on %s stopped: %s", listener.Addr(), err)
+		}
+	}()
+	log.Info.Printf("fake OCI listens on %s", listener.Addr())
+	return nil
+}
+
+func (s *Server) Close() error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Close()
+}
+
+func (s *Server) GetPort() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// as seen from the local host, e.g. https://127.0.0.1:43567
+func (s *Server) GetLocalEndpoint() string {
+	return fmt.Sprintf("https://127.0.0.1:%d", s.GetPort())
+}
+
+func (s *Server) GetNamespace() string {
+	return s.namespace
+}
+
+// the CA to be trusted by clients
+func (s *Server) GetCaCertPem() []byte {
+	return s.ca.certPem
+}
+
+// ---------------------------
+// errors are returned like by the real service, i.e. json with a code and a message
+
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.status, e.code, e.message)
+}
+
+func newApiError(status int, code string, format string, args ...interface{}) *apiError {
+	return &apiError{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+func newInvalidParameterError(format string, args ...interface{}) *apiError {
+	return newApiError(http.StatusBadRequest, "InvalidParameter", format, args...)
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.status)
+	json.NewEncoder(w).Encode(map[string]string{"code": err.code, "message": err.message})
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func readJson(body []byte, value interface{}) *apiError {
+	if err := json.Unmarshal(body, value); err != nil {
+		return newInvalidParameterError("malformed request body: %s", err)
+	}
+	return nil
+}
+
+// ---------------------------
+
+// the path split into unescaped segments, names of objects may come escaped as a whole
+// (e.g. o/dump%2F%40.json) or with plain slashes (o/dump/@.json), both end up the same
+func splitPath(request *http.Request) ([]string, error) {
+	segments := strings.Split(strings.TrimPrefix(request.URL.EscapedPath(), "/"), "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+// the request, e.g. /n/<namespace>/b/<bucket>/o/<object>, after authentication
+type route struct {
+	request *http.Request
+	body    []byte
+	bucket  string
+	// o, u, p or actions
+	collection string
+	// the rest of the path, e.g. the object name
+	name string
+	// not nil when accessed through a PAR
+	par *preauthenticatedRequest
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	if handler, ok := s.handlers[request.URL.Path]; ok {
+		handler.ServeHTTP(w, request)
+		return
+	}
+	w.Header().Set("opc-request-id", newRequestId())
+	if err := s.serve(w, request); err != nil {
+		log.Info.Printf("fake OCI: %s %s: %s", request.Method, request.URL.Path, err)
+		writeError(w, err)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, request *http.Request) *apiError {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return newInvalidParameterError("cannot read the body: %s", err)
+	}
+
+	segments, err := splitPath(request)
+	if err != nil {
+		return newInvalidParameterError("malformed path: %s", err)
+	}
+
+	// /p/<token>/n/... is authorized by the token, anything else by the signature
+	var par *preauthenticatedRequest
+	if len(segments) > 2 && segments[0] == "p" {
+		if par, err = s.getPreauthenticatedRequest(segments[1]); err != nil {
+			return newApiError(http.StatusNotFound, "NotAuthorizedOrNotFound", "%s", err)
+		}
+		segments = segments[2:]
+	} else if _, err := s.authenticate(request, body); err != nil {
+		return newApiError(http.StatusUnauthorized, "NotAuthenticated", "%s", err)
+	}
+
+	if len(segments) < 2 || segments[0] != "n" {
+		return newApiError(http.StatusNotFound, "NotFound", "unknown path %s", request.URL.Path)
+	}
+	if len(segments) == 2 && segments[1] == "" {
+		if par != nil {
+			return newApiError(http.StatusNotFound, "NotAuthorizedOrNotFound", "the namespace is not available through a PAR")
+		}
+		writeJson(w, http.StatusOK, s.namespace)
+		return nil
+	}
+	if segments[1] != s.namespace {
+		return newApiError(http.StatusNotFound, "NamespaceNotFound", "namespace %s not found", segments[1])
+	}
+	if len(segments) < 4 || segments[2] != "b" {
+		return newApiError(http.StatusNotFound, "NotFound", "unknown path %s", request.URL.Path)
+	}
+
+	r := &route{request: request, body: body, bucket: segments[3], par: par}
+	if len(segments) > 4 {
+		r.collection = segments[4]
+		r.name = strings.Join(segments[5:], "/")
+	}
+
+	if par != nil {
+		if err := par.authorize(r); err != nil {
+			return err
+		}
+	}
+
+	switch r.collection {
+	case "":
+		return s.serveBucket(w, r)
+	case "o":
+		return s.serveObject(w, r)
+	case "u":
+		return s.serveMultipartUpload(w, r)
+	case "p":
+		return s.servePreauthenticatedRequest(w, r)
+	case "actions":
+		return s.serveAction(w, r)
+	}
+	return newApiError(http.StatusNotFound, "NotFound", "unknown path %s", request.URL.Path)
+}
+
+func newMethodNotAllowedError(r *route) *apiError {
+	return newApiError(http.StatusMethodNotAllowed, "MethodNotAllowed", "%s is not supported on %s", r.request.Method, r.request.URL.Path)
+}
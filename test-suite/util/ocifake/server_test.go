@@ -0,0 +1,260 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package ocifake
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/objstore"
+	"github.com/marinesovitch/ote/test-suite/util/oci"
+)
+
+const testBucket = "ote-bucket"
+
+// generating the api keys takes a while, so all tests share them
+var testCfgPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ocifake")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	testCfgPath, err = GenerateConfig(dir, "us-ashburn-1")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// the fake served in process, with a client signing requests with the api key of a profile
+type testEnv struct {
+	t          *testing.T
+	server     *Server
+	httpServer *httptest.Server
+	cfgPath    string
+	rootCAs    *x509.CertPool
+	client     *http.Client
+	profile    *oci.Profile
+	privateKey *rsa.PrivateKey
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	server, err := NewServer(Config{ConfigPath: testCfgPath, Profiles: Profiles, Hosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.CreateBucket(testBucket)
+
+	httpServer := httptest.NewUnstartedServer(server)
+	httpServer.TLS = &tls.Config{Certificates: []tls.Certificate{server.cert}}
+	httpServer.StartTLS()
+	t.Cleanup(httpServer.Close)
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(server.GetCaCertPem()) {
+		t.Fatal("cannot parse the CA of the fake OCI")
+	}
+
+	env := &testEnv{
+		t:          t,
+		server:     server,
+		httpServer: httpServer,
+		cfgPath:    testCfgPath,
+		rootCAs:    rootCAs,
+		client:     &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}},
+	}
+	env.profile, env.privateKey = env.loadProfile(common.OciProfileBackup)
+	return env
+}
+
+func (e *testEnv) loadProfile(profileName string) (*oci.Profile, *rsa.PrivateKey) {
+	e.t.Helper()
+	profile, err := oci.LoadProfile(e.cfgPath, profileName)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	privateKey, err := profile.LoadPrivateKey()
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return profile, privateKey
+}
+
+func (e *testEnv) newStore() objstore.ObjectStore {
+	e.t.Helper()
+	store, err := objstore.NewOciStoreWithEndpoint(e.profile, testBucket, e.httpServer.URL, e.rootCAs)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return store
+}
+
+// e.g. /n/otefake/b/ote-bucket/o/dump%2F%40.json
+func (e *testEnv) getPath(collection string, name string) string {
+	path := fmt.Sprintf("/n/%s/b/%s/%s", e.server.GetNamespace(), testBucket, collection)
+	if len(name) > 0 {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+func (e *testEnv) newRequest(method string, path string, body []byte) *http.Request {
+	e.t.Helper()
+	request, err := http.NewRequest(method, e.httpServer.URL+path, bytes.NewReader(body))
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	if method == http.MethodPost {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return request
+}
+
+// like the oci cli, posts sign their body too
+func signRequest(request *http.Request, body []byte, keyId string, privateKey *rsa.PrivateKey, now time.Time) error {
+	request.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	headers := []string{"date", "(request-target)", "host"}
+	if request.Method == http.MethodPost {
+		sum := sha256.Sum256(body)
+		request.Header.Set("x-content-sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	lines := make([]string, len(headers))
+	for i, header := range headers {
+		var value string
+		switch header {
+		case "(request-target)":
+			value = strings.ToLower(request.Method) + " " + request.URL.RequestURI()
+		case "host":
+			value = request.URL.Host
+		case "content-length":
+			value = strconv.Itoa(len(body))
+		default:
+			value = request.Header.Get(header)
+		}
+		lines[i] = header + ": " + value
+	}
+
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", fmt.Sprintf(
+		`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyId, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// returns the status and the body of the response
+func (e *testEnv) send(request *http.Request) (int, []byte) {
+	e.t.Helper()
+	response, err := e.client.Do(request)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return response.StatusCode, body
+}
+
+// signs with the api key of the profile of the env
+func (e *testEnv) sign(request *http.Request, body []byte) {
+	e.t.Helper()
+	if err := signRequest(request, body, e.profile.GetKeyId(), e.privateKey, time.Now()); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+func (e *testEnv) do(method string, path string, body []byte) (int, []byte) {
+	e.t.Helper()
+	request := e.newRequest(method, path, body)
+	e.sign(request, body)
+	return e.send(request)
+}
+
+func (e *testEnv) expect(status int, method string, path string, body []byte) []byte {
+	e.t.Helper()
+	gotStatus, gotBody := e.do(method, path, body)
+	if gotStatus != status {
+		e.t.Fatalf("%s %s: expected %d, got %d: %s", method, path, status, gotStatus, gotBody)
+	}
+	return gotBody
+}
+
+func (e *testEnv) postJson(path string, value interface{}, result interface{}) {
+	e.t.Helper()
+	body, err := json.Marshal(value)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	response := e.expect(http.StatusOK, http.MethodPost, path, body)
+	if result != nil {
+		if err := json.Unmarshal(response, result); err != nil {
+			e.t.Fatalf("cannot decode %s: %v", response, err)
+		}
+	}
+}
+
+func (e *testEnv) putObject(name string, data string) {
+	e.t.Helper()
+	e.expect(http.StatusOK, http.MethodPut, e.getPath("o", name), []byte(data))
+}
+
+func (e *testEnv) getObject(name string) string {
+	e.t.Helper()
+	data, err := e.newStore().Get(context.Background(), name)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return string(data)
+}
+
+// ---------------------------
+
+func TestNamespace(t *testing.T) {
+	env := newTestEnv(t)
+	var namespace string
+	if err := json.Unmarshal(env.expect(http.StatusOK, http.MethodGet, "/n/", nil), &namespace); err != nil {
+		t.Fatal(err)
+	}
+	if namespace != DefaultNamespace {
+		t.Errorf("expected the namespace %s, got %s", DefaultNamespace, namespace)
+	}
+}
+
+func TestUnknownBucket(t *testing.T) {
+	env := newTestEnv(t)
+	path := fmt.Sprintf("/n/%s/b/no-such-bucket/o", env.server.GetNamespace())
+	env.expect(http.StatusNotFound, http.MethodGet, path, nil)
+}
@@ -12,7 +12,7 @@ import (
 	"github.com/marinesovitch/ote/test-suite/util/common"
 )
 
-const listOfCommands = "[start|stop|deploy]"
+const listOfCommands = "[start|stop|deploy|undeploy|status|images]"
 
 func parseCommand(args []string) (common.Command, error) {
 	if len(args) != 1 {
@@ -26,6 +26,12 @@ func parseCommand(args []string) (common.Command, error) {
 		return common.Stop, nil
 	case "deploy":
 		return common.Deploy, nil
+	case "undeploy":
+		return common.Undeploy, nil
+	case "status":
+		return common.Status, nil
+	case "images":
+		return common.Images, nil
 	default:
 		return common.Unknown, fmt.Errorf("unknown command %s - expected one of %s", cmd, listOfCommands)
 	}
@@ -48,9 +54,29 @@ func applyEnvVariableBool(envar string, setting *bool) {
 	}
 }
 
+func applyEnvVariableInt(envar string, setting *int) {
+	if enval, ok := os.LookupEnv(envar); ok {
+		if value, err := strconv.Atoi(enval); err == nil {
+			*setting = value
+		}
+	}
+}
+
+func applyEnvVariableFloat(envar string, setting *float64) {
+	if enval, ok := os.LookupEnv(envar); ok {
+		if value, err := strconv.ParseFloat(enval, 64); err == nil {
+			*setting = value
+		}
+	}
+}
+
 func applyEnvironment(initCfg Configuration) (Configuration, error) {
 	cfg := initCfg
 
+	applyEnvVariableFloat("OPERATOR_TEST_TIMEOUT_SCALE", &cfg.TestSuite.TimeoutScale)
+	applyEnvVariableBool("OPERATOR_TEST_UNIQUE_NAMESPACES", &cfg.TestSuite.UniqueNamespaces)
+	applyEnvVariableInt("OPERATOR_TEST_MAX_CLUSTERS", &cfg.TestSuite.MaxClusters)
+
 	applyEnvVariable("OPERATOR_TEST_REGISTRY", &cfg.Images.Registry)
 	applyEnvVariable("OPERATOR_TEST_REPOSITORY", &cfg.Images.Repository)
 	applyEnvVariable("OPERATOR_TEST_PULL_POLICY", &cfg.Operator.PullPolicy)
@@ -65,8 +91,16 @@ func applyEnvironment(initCfg Configuration) (Configuration, error) {
 	applyEnvVariableBool("OPERATOR_TEST_ENABLE_OCI", &cfg.Oci.Enable)
 	applyEnvVariable("OPERATOR_TEST_OCI_CONFIG_PATH", &cfg.Oci.ConfigPath)
 	applyEnvVariable("OPERATOR_TEST_OCI_BUCKET", &cfg.Oci.BucketName)
+	applyEnvVariableBool("OPERATOR_TEST_OCI_FAKE", &cfg.Oci.Fake)
+
+	applyEnvVariableBool("OPERATOR_TEST_ENABLE_S3", &cfg.S3.Enable)
+	applyEnvVariable("OPERATOR_TEST_S3_BUCKET", &cfg.S3.BucketName)
 
 	applyEnvVariable("OPERATOR_TEST_K8S_CLUSTER_NAME", &cfg.K8s.ClusterName)
+	applyEnvVariableInt("OPERATOR_TEST_K8S_NODES", &cfg.K8s.Nodes)
+	applyEnvVariableInt("OPERATOR_TEST_K8S_AGENTS", &cfg.K8s.Agents)
+	applyEnvVariable("OPERATOR_TEST_K8S_AGENT_LABELS", &cfg.K8s.AgentLabels)
+	applyEnvVariable("OPERATOR_TEST_K8S_AGENT_TAINTS", &cfg.K8s.AgentTaints)
 
 	return cfg, nil
 }
@@ -75,21 +109,33 @@ func parseCommandLine(initCfg Configuration, ignoreCommand bool) (common.Command
 	e2eDirectory := flag.String("e2e-dir", initCfg.TestSuite.E2eDirectory, "directory with e2e tests")
 	dataDirectory := flag.String("data-dir", initCfg.TestSuite.DataDirectory, "directory with e2e data")
 	outputDirectory := flag.String("output-dir", initCfg.TestSuite.OutputDirectory, "output directory for log and tmp files")
+	timeoutScale := flag.Float64("timeout-scale", initCfg.TestSuite.TimeoutScale, "scale factor of wait timeouts, e.g. 2.0 on a slow machine")
+	uniqueNamespaces := flag.Bool("unique-namespaces", initCfg.TestSuite.UniqueNamespaces, "append a random suffix to namespaces of test units")
+	maxClusters := flag.Int("max-clusters", initCfg.TestSuite.MaxClusters, "max number of units running clusters at the same time (0 - limited only by cpu and memory)")
 
 	kubeConfig := flag.String("kubecfg", initCfg.K8s.KubeConfig, "kube config path (if 'detect' it first tries ${KUBECONFIG}, then path ~/.kube/config)")
-	environment := flag.String("env", initCfg.K8s.Environment, "environment [detect|k3d|minikube]")
+	environment := flag.String("env", initCfg.K8s.Environment, "environment [detect|k3d|kind|minikube]")
 	clusterName := flag.String("cluster-name", initCfg.K8s.ClusterName, "cluster name used for testing")
 	skipDeleteCluster := flag.Bool("skip-delete", false, "skip deleting cluster")
+	nodes := flag.Int("nodes", initCfg.K8s.Nodes, "number of server (control-plane) nodes")
+	agents := flag.Int("agents", initCfg.K8s.Agents, "number of agent (worker) nodes")
+	agentLabels := flag.String("agent-labels", initCfg.K8s.AgentLabels, "labels of agent nodes, e.g. key1=value1,key2=value2")
+	agentTaints := flag.String("agent-taints", initCfg.K8s.AgentTaints, "taints of agent nodes, e.g. key1=value1:NoSchedule,key2:NoExecute")
 
 	containerEngine := flag.String("engine", initCfg.Images.Engine, "container engine [detect|docker|podman]")
 	registry := flag.String("registry", initCfg.Images.Registry, "registry, e.g. registry.localhost:5000")
 	repository := flag.String("repository", initCfg.Images.Repository, "repository, e.g. qa")
 	pullPolicy := flag.String("pull-policy", initCfg.Images.PullPolicy, "pull policy [Always|IfNotPresent|Never]")
+	manageLocalRegistry := flag.Bool("manage-registry", initCfg.LocalRegistry.Manage, "create and start a local registry container if the registry points at localhost")
+	removeLocalRegistryAtStop := flag.Bool("remove-registry-at-stop", initCfg.LocalRegistry.RemoveAtStop, "remove the local registry container at stop (its volume is kept)")
+	imagesManifest := flag.String("images-manifest", initCfg.Images.Manifest, "path to the manifest of images to preload")
 
 	minikubeRegistryInsecure := flag.Bool("minikube-registry-insecure", initCfg.Minikube.RegistryInsecure, "is minikube registry insecure")
 
 	k3dRegistryConfig := flag.String("k3d-registry-cfg", initCfg.K3d.RegistryConfig, "path to k3d registry config yaml or its template")
 
+	kindClusterConfig := flag.String("kind-cluster-cfg", initCfg.Kind.ClusterConfig, "path to kind cluster config yaml or its template")
+
 	skipDeployOperator := flag.Bool("skip-deploy", !initCfg.Operator.Deploy, "skip deploying operator")
 	operatorDir := flag.String("operator-dir", initCfg.Operator.Directory, "operator directory")
 	operatorYamls := flag.String("operator-yamls", initCfg.Operator.Yamls, "operator yamls")
@@ -100,12 +146,23 @@ func parseCommandLine(initCfg Configuration, ignoreCommand bool) (common.Command
 	operatorVersionTag := flag.String("operator-tag", initCfg.Operator.VersionTag, "version tag for operator image")
 	operatorTemplate := flag.String("operator-template", initCfg.Operator.Template, "path to operator deploy yaml or its template")
 	debugLevel := flag.Int("dbg", initCfg.Operator.DebugLevel, "debug level")
+	operatorReadyTimeout := flag.Int("operator-ready-timeout", initCfg.Operator.ReadyTimeout,
+		"timeout in seconds to wait for the operator readiness after deploying (0 - do not wait)")
+
+	statusFormat := flag.String("status-format", initCfg.Status.Format, "output format of the status command [text|json]")
+
+	logLevel := flag.String("log-level", initCfg.Log.Level, "console log level [debug|info|warning|error]")
+	logFormat := flag.String("log-format", initCfg.Log.Format, "log format [text|json]")
 
 	enterpriseEnable := flag.Bool("enterprise", initCfg.Enterprise.Enable, "run enterprise tests")
 
 	ociEnable := flag.Bool("oci", initCfg.Oci.Enable, "run OCI tests")
 	ociConfigPath := flag.String("oci-cfg-path", initCfg.Oci.ConfigPath, "path to a file with OCI profiles")
 	ociBucketName := flag.String("oci-bucket-name", initCfg.Oci.BucketName, "OCI bucket name")
+	ociFake := flag.Bool("oci-fake", initCfg.Oci.Fake, "run OCI tests against a fake Object Storage served by the tests")
+
+	s3Enable := flag.Bool("s3", initCfg.S3.Enable, "run S3 tests (against a MinIO server deployed by the tests)")
+	s3BucketName := flag.String("s3-bucket-name", initCfg.S3.BucketName, "S3 bucket name")
 
 	defaultUsage := flag.Usage
 	flag.Usage = func() {
@@ -128,6 +185,9 @@ func parseCommandLine(initCfg Configuration, ignoreCommand bool) (common.Command
 	cfg.TestSuite.E2eDirectory = *e2eDirectory
 	cfg.TestSuite.DataDirectory = *dataDirectory
 	cfg.TestSuite.OutputDirectory = *outputDirectory
+	cfg.TestSuite.TimeoutScale = *timeoutScale
+	cfg.TestSuite.UniqueNamespaces = *uniqueNamespaces
+	cfg.TestSuite.MaxClusters = *maxClusters
 
 	cfg.K8s.KubeConfig = *kubeConfig
 	cfg.K8s.Environment = *environment
@@ -137,16 +197,26 @@ func parseCommandLine(initCfg Configuration, ignoreCommand bool) (common.Command
 	} else if command == common.Stop {
 		cfg.K8s.DeleteAtStop = !*skipDeleteCluster
 	}
+	cfg.K8s.Nodes = *nodes
+	cfg.K8s.Agents = *agents
+	cfg.K8s.AgentLabels = *agentLabels
+	cfg.K8s.AgentTaints = *agentTaints
 
 	cfg.Images.Engine = *containerEngine
 	cfg.Images.Registry = *registry
 	cfg.Images.Repository = *repository
 	cfg.Images.PullPolicy = *pullPolicy
+	cfg.Images.Manifest = *imagesManifest
+
+	cfg.LocalRegistry.Manage = *manageLocalRegistry
+	cfg.LocalRegistry.RemoveAtStop = *removeLocalRegistryAtStop
 
 	cfg.Minikube.RegistryInsecure = *minikubeRegistryInsecure
 
 	cfg.K3d.RegistryConfig = *k3dRegistryConfig
 
+	cfg.Kind.ClusterConfig = *kindClusterConfig
+
 	cfg.Operator.Deploy = !*skipDeployOperator
 	cfg.Operator.Directory = *operatorDir
 	cfg.Operator.Yamls = *operatorYamls
@@ -156,12 +226,22 @@ func parseCommandLine(initCfg Configuration, ignoreCommand bool) (common.Command
 	cfg.Operator.PullPolicy = *operatorPullPolicy
 	cfg.Operator.Template = *operatorTemplate
 	cfg.Operator.DebugLevel = *debugLevel
+	cfg.Operator.ReadyTimeout = *operatorReadyTimeout
+
+	cfg.Status.Format = *statusFormat
+
+	cfg.Log.Level = *logLevel
+	cfg.Log.Format = *logFormat
 
 	cfg.Enterprise.Enable = *enterpriseEnable
 
 	cfg.Oci.Enable = *ociEnable
 	cfg.Oci.ConfigPath = *ociConfigPath
 	cfg.Oci.BucketName = *ociBucketName
+	cfg.Oci.Fake = *ociFake
+
+	cfg.S3.Enable = *s3Enable
+	cfg.S3.BucketName = *s3BucketName
 
 	return command, cfg, nil
 }
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/auxi"
 	"github.com/marinesovitch/ote/test-suite/util/common"
@@ -20,6 +21,16 @@ type Configuration struct {
 		E2eDirectory    string
 		DataDirectory   string
 		OutputDirectory string
+		TimeoutScale    float64
+		// parallel runs
+		UniqueNamespaces bool
+		MaxClusters      int
+		InstanceCpu      string
+		InstanceMemory   string
+		RouterCpu        string
+		RouterMemory     string
+		// image of chaos agents, empty means the server image, the latency needs nsenter and tc in it
+		ChaosAgentImage string
 	}
 
 	K8s struct {
@@ -28,6 +39,10 @@ type Configuration struct {
 		ClusterName   string
 		DeleteAtStart bool
 		DeleteAtStop  bool
+		Nodes         int
+		Agents        int
+		AgentLabels   string
+		AgentTaints   string
 	}
 
 	Images struct {
@@ -42,6 +57,14 @@ type Configuration struct {
 		MysqlRouterImage         string
 		MysqlServerEEImage       string
 		MysqlRouterEEImage       string
+		Manifest                 string
+	}
+
+	LocalRegistry struct {
+		Manage       bool
+		Image        string
+		Volume       string
+		RemoveAtStop bool
 	}
 
 	Minikube struct {
@@ -52,16 +75,31 @@ type Configuration struct {
 		RegistryConfig string
 	}
 
+	Kind struct {
+		ClusterConfig string
+	}
+
 	Operator struct {
-		Deploy     bool
-		Directory  string
-		Yamls      string
-		Image      string
-		ImageEE    string
-		VersionTag string
-		PullPolicy string
-		Template   string
-		DebugLevel int
+		Deploy          bool
+		Directory       string
+		Yamls           string
+		Image           string
+		ImageEE         string
+		VersionTag      string
+		PullPolicy      string
+		Template        string
+		DebugLevel      int
+		ReadyTimeout    int
+		ReadyLogPattern string
+	}
+
+	Status struct {
+		Format string
+	}
+
+	Log struct {
+		Level  string
+		Format string
 	}
 
 	Enterprise struct {
@@ -72,14 +110,49 @@ type Configuration struct {
 		Enable     bool
 		ConfigPath string
 		BucketName string
+		// a fake Object Storage served by the tests instead of a real tenancy, see util/ocifake,
+		// it is on by default, tests with a real tenancy need it off
+		Fake bool
+		// the address of the host running the tests as seen from pods and the api server, e.g.
+		// the gateway of the docker network of the cluster, detected if empty
+		FakeHostAddress string
+		// a CA the fake signs its certificate with, if empty a throwaway one is generated
+		FakeCaCertFile string
+		FakeCaKeyFile  string
+		// where the CA of the fake is mounted in containers, i.e. the bundle trusted by mysqlsh
+		FakeCaBundlePath string
+	}
+
+	// a MinIO server deployed by tests as an S3 stand-in, so it works offline
+	S3 struct {
+		Enable bool
+		// with its repository path (e.g. minio/minio), it is not put into images.repository
+		Image      string
+		VersionTag string
+		BucketName string
+		Region     string
+		AccessKey  string
+		SecretKey  string
 	}
 }
 
 func (c *Configuration) GetContextName() string {
-	if c.K8s.Environment == "k3d" {
+	switch c.K8s.Environment {
+	case common.EnvK3d:
 		return "k3d-" + c.K8s.ClusterName
+	case common.EnvKind:
+		return "kind-" + c.K8s.ClusterName
+	default:
+		return c.K8s.ClusterName
 	}
-	return c.K8s.ClusterName
+}
+
+func (c *Configuration) GetNodesCount() int {
+	return c.K8s.Nodes + c.K8s.Agents
+}
+
+func (c *Configuration) IsMultiNode() bool {
+	return c.GetNodesCount() > 1
 }
 
 func (c *Configuration) HasRegistry() bool {
@@ -122,6 +195,18 @@ func (c *Configuration) GetImageRegistryRepository() string {
 	}
 }
 
+// e.g. registry.localhost:5000/mysql/mysql-server:8.0.31, or mysql-server:8.0.31 if neither
+// the registry nor the repository is set
+func (c *Configuration) GetImageName(image string, tag string) string {
+	return prepareImageName(c.GetImageRegistryRepository(), image, tag)
+}
+
+// for images outside of the repository of mysql images, the image keeps its own repository
+// path, e.g. minio/minio => registry.localhost:5000/minio/minio:RELEASE.2022-11-26T22-43-32Z
+func (c *Configuration) GetRegistryImageName(image string, tag string) string {
+	return prepareImageName(c.Images.Registry, image, tag)
+}
+
 func (c *Configuration) GetTestDataPath(fileName string) string {
 	return filepath.Join(c.TestSuite.DataDirectory, fileName)
 }
@@ -135,6 +220,11 @@ func (c *Configuration) GetOutputPath(subpath string) string {
 	return filepath.Join(c.TestSuite.OutputDirectory, subpath)
 }
 
+// timeouts of waits are multiplied by the scale, e.g. 2.0 on a slow CI machine, 0.5 on a fast laptop
+func (c *Configuration) ScaleTimeout(timeout time.Duration) time.Duration {
+	return time.Duration(float64(timeout) * c.TestSuite.TimeoutScale)
+}
+
 func (c *Configuration) CheckEnterpriseConfig() error {
 	if !c.Enterprise.Enable {
 		return fmt.Errorf("enterprise tests are skipped")
@@ -143,6 +233,14 @@ func (c *Configuration) CheckEnterpriseConfig() error {
 }
 
 func (c *Configuration) CheckOCIConfig() error {
+	// the fake brings its own profiles and bucket, so it runs unless disabled
+	if c.Oci.Fake {
+		if len(c.Oci.FakeCaBundlePath) == 0 {
+			return fmt.Errorf("incomplete fake OCI setup, FakeCaBundlePath is empty")
+		}
+		return nil
+	}
+
 	if !c.Oci.Enable {
 		return fmt.Errorf("OCI tests are skipped")
 	}
@@ -154,6 +252,18 @@ func (c *Configuration) CheckOCIConfig() error {
 	return nil
 }
 
+func (c *Configuration) CheckS3Config() error {
+	if !c.S3.Enable {
+		return fmt.Errorf("S3 tests are skipped")
+	}
+
+	if len(c.S3.BucketName) == 0 || len(c.S3.AccessKey) == 0 || len(c.S3.SecretKey) == 0 {
+		return fmt.Errorf("incomplete S3 setup, BucketName: '%s', AccessKey: '%s'", c.S3.BucketName, c.S3.AccessKey)
+	}
+
+	return nil
+}
+
 func loadConfigFile(path string, initCfg Configuration) (Configuration, error) {
 	cfgJson, err := os.ReadFile(path)
 	if err != nil {
@@ -60,15 +60,101 @@ func GenerateK3dRegistryConfig(cfg *Configuration) (string, error) {
 	return k3dRegistryConfigYamlPath, nil
 }
 
+// whether the nodes differ from the default single one
+func hasCustomNodes(cfg *Configuration) bool {
+	return cfg.K8s.Nodes != 1 || cfg.K8s.Agents > 0 || len(cfg.K8s.AgentLabels) > 0 || len(cfg.K8s.AgentTaints) > 0
+}
+
+func GenerateKindClusterConfig(cfg *Configuration) (string, error) {
+	const kindClusterConfigFileName = "kind-cluster-config.yaml"
+	kindClusterConfigTemplatePath := cfg.Kind.ClusterConfig
+
+	isTempl, err := isTemplate(kindClusterConfigTemplatePath)
+	if err != nil {
+		return kindClusterConfigTemplatePath, err
+	}
+	if !isTempl {
+		// the nodes are set up only through the template, they would be silently ignored
+		if hasCustomNodes(cfg) {
+			return kindClusterConfigTemplatePath, fmt.Errorf(
+				"%s is not a template, so k8s.nodes, k8s.agents, k8s.agentLabels and k8s.agentTaints cannot be applied, "+
+					"please declare the nodes in it and leave these settings default", kindClusterConfigTemplatePath)
+		}
+		// as it is not a template no need to generate, just use the file as it is
+		return kindClusterConfigTemplatePath, nil
+	}
+
+	kindClusterConfigYamlPath := cfg.GetOutputPath(kindClusterConfigFileName)
+	kindClusterConfigYamlFile, err := os.Create(kindClusterConfigYamlPath)
+	if err != nil {
+		return kindClusterConfigYamlPath, err
+	}
+
+	defer kindClusterConfigYamlFile.Close()
+
+	type GenerateKindNodeData struct {
+		Role   string
+		Labels map[string]string
+		Taints []NodeTaint
+	}
+
+	type GenerateKindClusterConfigYamlData struct {
+		Registry string
+		Nodes    []GenerateKindNodeData
+	}
+
+	agentLabels, err := ParseNodeLabels(cfg.K8s.AgentLabels)
+	if err != nil {
+		return kindClusterConfigYamlPath, err
+	}
+
+	agentTaints, err := ParseNodeTaints(cfg.K8s.AgentTaints)
+	if err != nil {
+		return kindClusterConfigYamlPath, err
+	}
+
+	var nodes []GenerateKindNodeData
+	for i := 0; i < cfg.K8s.Nodes; i++ {
+		nodes = append(nodes, GenerateKindNodeData{Role: "control-plane"})
+	}
+	for i := 0; i < cfg.K8s.Agents; i++ {
+		nodes = append(nodes, GenerateKindNodeData{Role: "worker", Labels: agentLabels, Taints: agentTaints})
+	}
+
+	data := GenerateKindClusterConfigYamlData{
+		cfg.Images.Registry,
+		nodes,
+	}
+
+	tmpl := template.Must(template.ParseFiles(kindClusterConfigTemplatePath))
+	err = tmpl.Execute(kindClusterConfigYamlFile, data)
+	if err != nil {
+		return kindClusterConfigYamlPath, fmt.Errorf("cannot generate %s: %s", kindClusterConfigYamlPath, err)
+	}
+
+	return kindClusterConfigYamlPath, nil
+}
+
 func prepareImageName(registryRepository string, image string, tag string) string {
-	result := registryRepository
-	if len(result) != 0 {
-		result += "/"
+	result := JoinImagePath(registryRepository, image)
+	if len(tag) != 0 {
+		result += ":" + tag
 	}
-	result += image + ":" + tag
 	return result
 }
 
+// joins non-empty parts of the name of an image, so an empty registry or repository doesn't
+// leave a leading slash
+func JoinImagePath(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.Trim(part, "/"); len(part) > 0 {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
+
 func GenerateDeployOperatorYaml(cfg *Configuration, destDir string) error {
 	const deployOperatorFileName = "deploy-operator.yaml"
 	// deployOperatorTemplatePath := GetTemplatePath(cfg, deployOperatorFileName)
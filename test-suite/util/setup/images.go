@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 )
 
 type ImageItem int
@@ -16,6 +17,9 @@ const (
 	ShellCommercialImage
 	RouterImage
 	ServerImage
+	EnterpriseRouterImage
+	EnterpriseServerImage
+	MinioImage
 	ImageItemLast
 )
 
@@ -33,16 +37,55 @@ func ParseImageItem(itemStr string) (ImageItem, error) {
 	case "server":
 		return ServerImage, nil
 
+	case "enterprise-router":
+		return EnterpriseRouterImage, nil
+
+	case "enterprise-server":
+		return EnterpriseServerImage, nil
+
+	case "minio":
+		return MinioImage, nil
+
 	default:
 		return -1, errors.New("unknown image item " + itemStr)
 	}
 }
 
 type ImageInfo struct {
-	Item string
-	Name string
-	Id   string
-	Pull bool
+	Item string `json:"item"`
+	Name string `json:"image"`
+	Id   string `json:"id"`
+	Pull bool   `json:"pull"`
+}
+
+// the manifest keeps 'pull' as a string ("true"/"false"), but accept a plain bool too
+func (i *ImageInfo) UnmarshalJSON(data []byte) error {
+	type imageInfoAlias ImageInfo
+	rawInfo := struct {
+		*imageInfoAlias
+		Pull json.RawMessage `json:"pull"`
+	}{imageInfoAlias: (*imageInfoAlias)(i)}
+
+	if err := json.Unmarshal(data, &rawInfo); err != nil {
+		return err
+	}
+
+	if len(rawInfo.Pull) == 0 {
+		i.Pull = false
+		return nil
+	}
+
+	pull := string(rawInfo.Pull)
+	if unquoted, err := strconv.Unquote(pull); err == nil {
+		pull = unquoted
+	}
+
+	var err error
+	i.Pull, err = strconv.ParseBool(pull)
+	if err != nil {
+		return fmt.Errorf("image '%s': invalid pull value %s", i.Item, rawInfo.Pull)
+	}
+	return nil
 }
 
 type Images struct {
@@ -77,7 +120,7 @@ func (i *Images) Load(path string) error {
 }
 
 func (i *Images) GetImageInfo(item ImageItem) (ImageInfo, error) {
-	if len(i.images) < int(item) {
+	if len(i.images) <= int(item) {
 		return ImageInfo{}, errors.New("images not loaded")
 	}
 
@@ -89,5 +132,21 @@ func (i *Images) GetImageInfo(item ImageItem) (ImageInfo, error) {
 	return imageInfo, nil
 }
 
-func (i *Images) GetName(item ImageItem) {
+func (i *Images) GetName(item ImageItem) (string, error) {
+	imageInfo, err := i.GetImageInfo(item)
+	if err != nil {
+		return "", err
+	}
+	return imageInfo.Name, nil
+}
+
+// returns all loaded images in the order of items
+func (i *Images) GetAll() []ImageInfo {
+	var images []ImageInfo
+	for _, imageInfo := range i.images {
+		if len(imageInfo.Name) > 0 {
+			images = append(images, imageInfo)
+		}
+	}
+	return images
 }
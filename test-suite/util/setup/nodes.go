@@ -0,0 +1,81 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package setup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
+)
+
+const nodeItemsSeparator = ","
+const nodeKeyValueSeparator = "="
+const nodeTaintEffectSeparator = ":"
+
+var supportedTaintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+
+type NodeTaint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+func (t NodeTaint) String() string {
+	result := t.Key
+	if t.Value != "" {
+		result += nodeKeyValueSeparator + t.Value
+	}
+	return result + nodeTaintEffectSeparator + t.Effect
+}
+
+func splitNodeItems(items string) []string {
+	var result []string
+	for _, item := range strings.Split(items, nodeItemsSeparator) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parses labels in the form 'key1=value1,key2=value2'
+func ParseNodeLabels(labels string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, label := range splitNodeItems(labels) {
+		keyValue := strings.SplitN(label, nodeKeyValueSeparator, 2)
+		if len(keyValue) != 2 || keyValue[0] == "" {
+			return nil, fmt.Errorf("incorrect node label '%s', expected 'key=value'", label)
+		}
+		result[keyValue[0]] = keyValue[1]
+	}
+	return result, nil
+}
+
+// parses taints in the form 'key1=value1:Effect1,key2:Effect2'
+func ParseNodeTaints(taints string) ([]NodeTaint, error) {
+	var result []NodeTaint
+	for _, taint := range splitNodeItems(taints) {
+		effectPos := strings.LastIndex(taint, nodeTaintEffectSeparator)
+		if effectPos == -1 {
+			return nil, fmt.Errorf("incorrect node taint '%s', expected 'key[=value]:effect'", taint)
+		}
+
+		nodeTaint := NodeTaint{Effect: taint[effectPos+len(nodeTaintEffectSeparator):]}
+		if !auxi.Contains(supportedTaintEffects, nodeTaint.Effect) {
+			return nil, fmt.Errorf("incorrect effect of node taint '%s', allowed values are: %s", taint, strings.Join(supportedTaintEffects, ","))
+		}
+
+		keyValue := strings.SplitN(taint[:effectPos], nodeKeyValueSeparator, 2)
+		nodeTaint.Key = keyValue[0]
+		if nodeTaint.Key == "" {
+			return nil, fmt.Errorf("incorrect node taint '%s', the key is empty", taint)
+		}
+		if len(keyValue) == 2 {
+			nodeTaint.Value = keyValue[1]
+		}
+		result = append(result, nodeTaint)
+	}
+	return result, nil
+}
@@ -0,0 +1,246 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package setup
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/marinesovitch/ote/test-suite/util/system"
+)
+
+// every field of Configuration may be overridden with:
+// - an envar OTE_<SECTION>_<FIELD>, e.g. OTE_OCI_BUCKET_NAME, OTE_OPERATOR_VERSION_TAG
+// - under 'go test' a flag -ote.<section>.<field>, e.g. -ote.oci.bucketName, -ote.enterprise.enable
+// the whole alternate config file may be pointed with the envar OTE_CONFIG or the flag -ote.config
+
+const overridesEnvPrefix = "OTE"
+const overridesFlagPrefix = "ote"
+const alternateConfigEnvar = overridesEnvPrefix + "_CONFIG"
+const alternateConfigFlag = overridesFlagPrefix + ".config"
+
+// e.g. BucketName => BUCKET_NAME, E2eDirectory => E2E_DIRECTORY, ImageEE => IMAGE_EE
+func toEnvName(name string) string {
+	var result strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1]) {
+			result.WriteRune('_')
+		}
+		result.WriteRune(unicode.ToUpper(r))
+	}
+	return result.String()
+}
+
+// e.g. BucketName => bucketName, K8s => k8s, TestSuite => testSuite
+func toFlagName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+type configField struct {
+	envName  string
+	flagName string
+	value    reflect.Value
+}
+
+func (f *configField) set(rawValue string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(rawValue)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(rawValue)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' of %s: %v", rawValue, f.envName, err)
+		}
+		f.value.SetBool(value)
+	case reflect.Int:
+		value, err := strconv.Atoi(rawValue)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' of %s: %v", rawValue, f.envName, err)
+		}
+		f.value.SetInt(int64(value))
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' of %s: %v", rawValue, f.envName, err)
+		}
+		f.value.SetFloat(value)
+	default:
+		return fmt.Errorf("unsupported type %s of %s", f.value.Kind(), f.envName)
+	}
+	return nil
+}
+
+// lists all settable fields of the configuration, the values refer to the cfg itself
+func listConfigFields(cfg *Configuration) []configField {
+	var fields []configField
+	cfgValue := reflect.ValueOf(cfg).Elem()
+	cfgType := cfgValue.Type()
+	for i := 0; i < cfgType.NumField(); i++ {
+		section := cfgType.Field(i)
+		if section.Type.Kind() != reflect.Struct {
+			continue
+		}
+
+		sectionValue := cfgValue.Field(i)
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			fields = append(fields, configField{
+				envName:  fmt.Sprintf("%s_%s_%s", overridesEnvPrefix, toEnvName(section.Name), toEnvName(field.Name)),
+				flagName: fmt.Sprintf("%s.%s.%s", overridesFlagPrefix, toFlagName(section.Name), toFlagName(field.Name)),
+				value:    sectionValue.Field(j),
+			})
+		}
+	}
+	return fields
+}
+
+func applyOverridesEnvironment(initCfg Configuration) (Configuration, error) {
+	cfg := initCfg
+	for _, field := range listConfigFields(&cfg) {
+		if rawValue, ok := os.LookupEnv(field.envName); ok {
+			if err := field.set(rawValue); err != nil {
+				return initCfg, err
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// ---------------------------
+
+type overrideFlag struct {
+	isBool bool
+	value  string
+}
+
+func (f *overrideFlag) String() string {
+	return f.value
+}
+
+func (f *overrideFlag) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *overrideFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// flags set explicitly in the command line, flag name => value
+type overrideFlags map[string]string
+
+// the -ote.* flags registered in a flag set, flag name => flag
+type overrideFlagSet map[string]*overrideFlag
+
+func (s overrideFlagSet) register(flagSet *flag.FlagSet) {
+	var cfg Configuration
+	register := func(name string, isBool bool, usage string) {
+		if flagSet.Lookup(name) != nil {
+			return
+		}
+		s[name] = &overrideFlag{isBool: isBool}
+		flagSet.Var(s[name], name, usage)
+	}
+
+	register(alternateConfigFlag, false, "path to an alternate config file applied after custom.cfg")
+	for _, field := range listConfigFields(&cfg) {
+		register(field.flagName, field.value.Kind() == reflect.Bool, "overrides "+field.envName)
+	}
+}
+
+// only the flags set explicitly in the parsed flag set are collected
+func (s overrideFlagSet) collect(flagSet *flag.FlagSet) overrideFlags {
+	overrides := make(overrideFlags)
+	flagSet.Visit(func(f *flag.Flag) {
+		if setFlag, ok := s[f.Name]; ok {
+			overrides[f.Name] = setFlag.value
+		}
+	})
+	return overrides
+}
+
+// the -ote.* flags registered in the command line so far
+var registeredOverrideFlags = make(overrideFlagSet)
+
+// under 'go test' the command line belongs to the testing package, so the -ote.* flags
+// are registered next to the -test.* ones, it has to be done at init of every test binary
+// linking this package, otherwise tests which never create the configuration would fail
+// with 'flag provided but not defined'
+func init() {
+	if isTestBinary() {
+		registerOverrideFlags()
+	}
+}
+
+// the -test.* flags aren't registered yet at init, but 'go test' names the binary <package>.test
+func isTestBinary() bool {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	return strings.HasSuffix(name, ".test")
+}
+
+func registerOverrideFlags() {
+	registeredOverrideFlags.register(flag.CommandLine)
+}
+
+// the flags are parsed before the tests start
+func parseOverrideFlags() overrideFlags {
+	// a binary built with 'go test -c -o <name>' isn't recognized at init
+	registerOverrideFlags()
+
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	return registeredOverrideFlags.collect(flag.CommandLine)
+}
+
+func applyOverrideFlags(initCfg Configuration, overrides overrideFlags) (Configuration, error) {
+	cfg := initCfg
+	for _, field := range listConfigFields(&cfg) {
+		if rawValue, ok := overrides[field.flagName]; ok {
+			if err := field.set(rawValue); err != nil {
+				return initCfg, err
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// the flags are applied after the envars, so a flag wins over the envar of the same field
+func applyOverrides(initCfg Configuration, overrides overrideFlags) (Configuration, error) {
+	cfg, err := applyOverridesEnvironment(initCfg)
+	if err != nil {
+		return initCfg, err
+	}
+	return applyOverrideFlags(cfg, overrides)
+}
+
+// ---------------------------
+
+func getAlternateConfigPath(overrides overrideFlags) string {
+	if path, ok := overrides[alternateConfigFlag]; ok {
+		return path
+	}
+	return os.Getenv(alternateConfigEnvar)
+}
+
+func loadAlternateConfiguration(initCfg Configuration, overrides overrideFlags) (Configuration, error) {
+	cfgPath := getAlternateConfigPath(overrides)
+	if len(cfgPath) == 0 {
+		return initCfg, nil
+	}
+
+	cfgPath, err := system.ResolveFile(initCfg.TestSuite.RootDirectory, cfgPath, true)
+	if err != nil {
+		return initCfg, err
+	}
+	return loadConfigFile(cfgPath, initCfg)
+}
@@ -0,0 +1,185 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package setup
+
+import (
+	"flag"
+	"testing"
+)
+
+// a flag set like the command line of 'go test', parsed with the given args
+func parseTestOverrideFlags(t *testing.T, args ...string) overrideFlags {
+	t.Helper()
+	flagSet := flag.NewFlagSet("setup.test", flag.ContinueOnError)
+	registered := make(overrideFlagSet)
+	registered.register(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return registered.collect(flagSet)
+}
+
+func newTestOverridesConfiguration() Configuration {
+	var cfg Configuration
+	cfg.Oci.BucketName = "default-bucket"
+	cfg.Enterprise.Enable = false
+	cfg.TestSuite.TimeoutScale = 1
+	cfg.Operator.ReadyTimeout = 300
+	return cfg
+}
+
+func TestOverrideFlagsRegisteredAtInit(t *testing.T) {
+	for _, name := range []string{alternateConfigFlag, "ote.oci.bucketName", "ote.enterprise.enable", "ote.testSuite.timeoutScale"} {
+		if flag.Lookup(name) == nil {
+			t.Errorf("flag -%s not registered", name)
+		}
+	}
+}
+
+func TestIsTestBinary(t *testing.T) {
+	if !isTestBinary() {
+		t.Error("the test binary not recognized")
+	}
+}
+
+func TestToFlagName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"BucketName", "bucketName"},
+		{"K8s", "k8s"},
+		{"TestSuite", "testSuite"},
+	}
+	for _, test := range tests {
+		if got := toFlagName(test.name); got != test.expected {
+			t.Errorf("toFlagName(%s) = %s, expected %s", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestToEnvName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"BucketName", "BUCKET_NAME"},
+		{"E2eDirectory", "E2E_DIRECTORY"},
+		{"ImageEE", "IMAGE_EE"},
+	}
+	for _, test := range tests {
+		if got := toEnvName(test.name); got != test.expected {
+			t.Errorf("toEnvName(%s) = %s, expected %s", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestOverridesEnvironment(t *testing.T) {
+	t.Setenv("OTE_OCI_BUCKET_NAME", "env-bucket")
+	t.Setenv("OTE_ENTERPRISE_ENABLE", "true")
+	t.Setenv("OTE_TEST_SUITE_TIMEOUT_SCALE", "2.5")
+
+	cfg, err := applyOverridesEnvironment(newTestOverridesConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Oci.BucketName != "env-bucket" {
+		t.Errorf("expected the bucket env-bucket, got %s", cfg.Oci.BucketName)
+	}
+	if !cfg.Enterprise.Enable {
+		t.Error("expected enterprise enabled")
+	}
+	if cfg.TestSuite.TimeoutScale != 2.5 {
+		t.Errorf("expected the timeout scale 2.5, got %v", cfg.TestSuite.TimeoutScale)
+	}
+	// not overridden
+	if cfg.Operator.ReadyTimeout != 300 {
+		t.Errorf("expected the ready timeout 300, got %d", cfg.Operator.ReadyTimeout)
+	}
+}
+
+func TestOverridesEnvironmentInvalid(t *testing.T) {
+	t.Setenv("OTE_OCI_BUCKET_NAME", "env-bucket")
+	t.Setenv("OTE_OPERATOR_READY_TIMEOUT", "5m")
+
+	cfg, err := applyOverridesEnvironment(newTestOverridesConfiguration())
+	if err == nil {
+		t.Fatal("expected an invalid int refused")
+	}
+	// nothing applied
+	if cfg.Oci.BucketName != "default-bucket" || cfg.Operator.ReadyTimeout != 300 {
+		t.Errorf("expected the configuration untouched, got %s, %d", cfg.Oci.BucketName, cfg.Operator.ReadyTimeout)
+	}
+}
+
+func TestOverrideFlags(t *testing.T) {
+	overrides := parseTestOverrideFlags(t, "-ote.oci.bucketName=flag-bucket", "-ote.enterprise.enable", "-ote.operator.readyTimeout", "90")
+
+	cfg, err := applyOverrideFlags(newTestOverridesConfiguration(), overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Oci.BucketName != "flag-bucket" {
+		t.Errorf("expected the bucket flag-bucket, got %s", cfg.Oci.BucketName)
+	}
+	if !cfg.Enterprise.Enable {
+		t.Error("expected enterprise enabled by the bool flag without a value")
+	}
+	if cfg.Operator.ReadyTimeout != 90 {
+		t.Errorf("expected the ready timeout 90, got %d", cfg.Operator.ReadyTimeout)
+	}
+	// not overridden
+	if cfg.TestSuite.TimeoutScale != 1 {
+		t.Errorf("expected the timeout scale 1, got %v", cfg.TestSuite.TimeoutScale)
+	}
+}
+
+func TestOverrideFlagsOnlySetOnesCollected(t *testing.T) {
+	overrides := parseTestOverrideFlags(t, "-ote.enterprise.enable=false")
+	if len(overrides) != 1 || overrides["ote.enterprise.enable"] != "false" {
+		t.Errorf("expected only the enterprise flag, got %v", overrides)
+	}
+
+	cfg := newTestOverridesConfiguration()
+	cfg.Enterprise.Enable = true
+	cfg, err := applyOverrideFlags(cfg, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Enterprise.Enable {
+		t.Error("expected enterprise disabled by the flag")
+	}
+}
+
+func TestOverridesPrecedence(t *testing.T) {
+	t.Setenv("OTE_OCI_BUCKET_NAME", "env-bucket")
+	t.Setenv("OTE_OPERATOR_READY_TIMEOUT", "60")
+	t.Setenv("OTE_TEST_SUITE_TIMEOUT_SCALE", "2")
+	overrides := parseTestOverrideFlags(t, "-ote.oci.bucketName=flag-bucket", "-ote.operator.readyTimeout=90")
+
+	cfg, err := applyOverrides(newTestOverridesConfiguration(), overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the flag wins over the envar
+	if cfg.Oci.BucketName != "flag-bucket" {
+		t.Errorf("expected the bucket flag-bucket, got %s", cfg.Oci.BucketName)
+	}
+	if cfg.Operator.ReadyTimeout != 90 {
+		t.Errorf("expected the ready timeout 90, got %d", cfg.Operator.ReadyTimeout)
+	}
+	// the envar without a flag still applies
+	if cfg.TestSuite.TimeoutScale != 2 {
+		t.Errorf("expected the timeout scale 2, got %v", cfg.TestSuite.TimeoutScale)
+	}
+}
+
+func TestAlternateConfigPathPrecedence(t *testing.T) {
+	t.Setenv(alternateConfigEnvar, "env.cfg")
+	if path := getAlternateConfigPath(parseTestOverrideFlags(t)); path != "env.cfg" {
+		t.Errorf("expected the path of the envar, got %s", path)
+	}
+	if path := getAlternateConfigPath(parseTestOverrideFlags(t, "-ote.config=flag.cfg")); path != "flag.cfg" {
+		t.Errorf("expected the path of the flag, got %s", path)
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"strings"
 
 	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/log"
 	"github.com/marinesovitch/ote/test-suite/util/system"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const DefaultAutoDetectValue = "detect"
@@ -45,6 +48,16 @@ func verifyPullPolicy(cfgPolicy string) (string, error) {
 	return cfgPolicy, fmt.Errorf("incorrect pull policy %s, allowed values are: %s", cfgPolicy, strings.Join(allowedPolicies, ","))
 }
 
+func verifyStatusFormat(cfgFormat string) (string, error) {
+	allowedFormats := []string{"text", "json"}
+	for _, allowedFormat := range allowedFormats {
+		if strings.EqualFold(cfgFormat, allowedFormat) {
+			return allowedFormat, nil
+		}
+	}
+	return cfgFormat, fmt.Errorf("incorrect status format %s, allowed values are: %s", cfgFormat, strings.Join(allowedFormats, ","))
+}
+
 func getDefaultKubeConfigPath() string {
 	const KubeConfigEnvVar = "KUBECONFIG"
 	kubeConfigPath := os.Getenv(KubeConfigEnvVar)
@@ -67,10 +80,31 @@ func resolveK8sKubeConfig(cfgK8sKubeConfig string, baseDir string) (string, erro
 }
 
 func resolveK8sEnvironment(cfgK8sEnv string) (string, error) {
-	supportedEnvs := []string{common.EnvMinikube, common.EnvK3d}
+	supportedEnvs := []string{common.EnvMinikube, common.EnvK3d, common.EnvKind}
 	return resolveCommand(cfgK8sEnv, supportedEnvs)
 }
 
+func verifyK8sTopology(cfg Configuration) error {
+	if cfg.K8s.Nodes < 1 {
+		return fmt.Errorf("incorrect number of nodes %d, at least one is required", cfg.K8s.Nodes)
+	}
+
+	if cfg.K8s.Agents < 0 {
+		return fmt.Errorf("incorrect number of agents %d", cfg.K8s.Agents)
+	}
+
+	if cfg.K8s.Environment == common.EnvMinikube && cfg.K8s.Nodes > 1 {
+		return fmt.Errorf("%s supports a single control-plane node only, but got %d", common.EnvMinikube, cfg.K8s.Nodes)
+	}
+
+	if _, err := ParseNodeLabels(cfg.K8s.AgentLabels); err != nil {
+		return err
+	}
+
+	_, err := ParseNodeTaints(cfg.K8s.AgentTaints)
+	return err
+}
+
 func resolveImagesEngine(cfgImagesEngine string) (string, error) {
 	supportedEngines := []string{"docker", "podman"}
 	return resolveCommand(cfgImagesEngine, supportedEngines)
@@ -168,6 +202,20 @@ func resolveSettings(cfg Configuration) (Configuration, error) {
 		return cfg, err
 	}
 
+	if cfg.TestSuite.TimeoutScale <= 0 {
+		return cfg, fmt.Errorf("incorrect timeout scale %v, it should be greater than 0", cfg.TestSuite.TimeoutScale)
+	}
+
+	if cfg.TestSuite.MaxClusters < 0 {
+		return cfg, fmt.Errorf("incorrect max clusters %d, it should be 0 or greater", cfg.TestSuite.MaxClusters)
+	}
+
+	for _, quantity := range []string{cfg.TestSuite.InstanceCpu, cfg.TestSuite.InstanceMemory, cfg.TestSuite.RouterCpu, cfg.TestSuite.RouterMemory} {
+		if _, err := resource.ParseQuantity(quantity); err != nil {
+			return cfg, fmt.Errorf("incorrect resource quantity '%s': %v", quantity, err)
+		}
+	}
+
 	// k8s
 	cfg.K8s.KubeConfig, err = resolveK8sKubeConfig(cfg.K8s.KubeConfig, suiteRootDirectory)
 	if err != nil {
@@ -179,6 +227,11 @@ func resolveSettings(cfg Configuration) (Configuration, error) {
 		return cfg, err
 	}
 
+	err = verifyK8sTopology(cfg)
+	if err != nil {
+		return cfg, err
+	}
+
 	// images
 	cfg.Images.Engine, err = resolveImagesEngine(cfg.Images.Engine)
 	if err != nil {
@@ -195,12 +248,23 @@ func resolveSettings(cfg Configuration) (Configuration, error) {
 		return cfg, err
 	}
 
+	cfg.Images.Manifest, err = system.ResolveFile(suiteRootDirectory, cfg.Images.Manifest, true)
+	if err != nil {
+		return cfg, err
+	}
+
 	// k3d
 	cfg.K3d.RegistryConfig, err = system.ResolveFile(suiteRootDirectory, cfg.K3d.RegistryConfig, true)
 	if err != nil {
 		return cfg, err
 	}
 
+	// kind
+	cfg.Kind.ClusterConfig, err = system.ResolveFile(suiteRootDirectory, cfg.Kind.ClusterConfig, true)
+	if err != nil {
+		return cfg, err
+	}
+
 	// operator
 	cfg.Operator.Directory, err = system.ResolveDirectory(suiteRootDirectory, cfg.Operator.Directory, true)
 	if err != nil {
@@ -217,5 +281,20 @@ func resolveSettings(cfg Configuration) (Configuration, error) {
 		return cfg, err
 	}
 
+	// status
+	cfg.Status.Format, err = verifyStatusFormat(cfg.Status.Format)
+	if err != nil {
+		return cfg, err
+	}
+
+	// log
+	if _, err = log.ParseLevel(cfg.Log.Level); err != nil {
+		return cfg, err
+	}
+
+	if _, err = log.ParseFormat(cfg.Log.Format); err != nil {
+		return cfg, err
+	}
+
 	return cfg, nil
 }
@@ -39,6 +39,13 @@ func runsUnderTestGo() bool {
 	return flag.Lookup("test.v") != nil
 }
 
+func configureLog(cfg Configuration) {
+	// both are verified at resolving settings
+	level, _ := log.ParseLevel(cfg.Log.Level)
+	format, _ := log.ParseFormat(cfg.Log.Format)
+	log.Configure(level, format)
+}
+
 func CreateConfiguration(ignoreCommand bool) (Configuration, common.Command, error) {
 	var cfg Configuration
 	var err error
@@ -65,6 +72,22 @@ func CreateConfiguration(ignoreCommand bool) (Configuration, common.Command, err
 		return cfg, common.Unknown, err
 	}
 
+	var overrides overrideFlags
+	if runsUnderTestGo() {
+		overrides = parseOverrideFlags()
+	}
+
+	cfg, err = loadAlternateConfiguration(cfg, overrides)
+	if err != nil {
+		return cfg, common.Unknown, err
+	}
+
+	// out of 'go test' there are no override flags, only the envars
+	cfg, err = applyOverrides(cfg, overrides)
+	if err != nil {
+		return cfg, common.Unknown, err
+	}
+
 	var cmd common.Command = common.Unknown
 	if !runsUnderTestGo() {
 		cmd, cfg, err = parseCommandLine(cfg, ignoreCommand)
@@ -83,5 +106,7 @@ func CreateConfiguration(ignoreCommand bool) (Configuration, common.Command, err
 		return cfg, common.Unknown, err
 	}
 
+	configureLog(cfg)
+
 	return cfg, cmd, nil
 }
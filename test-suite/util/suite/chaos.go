@@ -0,0 +1,224 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
+	"github.com/marinesovitch/ote/test-suite/util/chaos"
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/mysql"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// runs the schedule of faults, then verifies the cluster converges, i.e. it is back ONLINE
+// with all instances, its routers are ready and CheckAll passes (restarts are expected), the
+// pods checked by CheckAll are returned for further checks, e.g. CheckData
+func (u *Unit) RunChaos(schedule *chaos.Schedule, params CheckParams) ([]*corev1.Pod, error) {
+	if err := schedule.Run(u.Context()); err != nil {
+		return nil, err
+	}
+
+	log.Info.Printf("chaos: waiting for %s/%s to converge", params.Namespace, params.Name)
+	waitParams := k8s.WaitOnInnoDBClusterParams{
+		Namespace:         params.Namespace,
+		Name:              params.Name,
+		ExpectedStatus:    []string{"ONLINE"},
+		ExpectedNumOnline: int64(params.Instances),
+	}
+	if err := u.WaitOnInnoDBCluster(waitParams); err != nil {
+		return nil, fmt.Errorf("the cluster didn't converge after chaos: %w", err)
+	}
+
+	if params.Routers > 0 {
+		if err := u.WaitOnRoutersInNamespace(params.Namespace, params.Name, params.Routers); err != nil {
+			return nil, fmt.Errorf("the routers didn't converge after chaos: %w", err)
+		}
+	}
+
+	params.RestartsExpected = true
+	return CheckAll(u, params)
+}
+
+// ---------------------------
+
+// a chaos.Check made of functions, Prepare is optional
+type chaosCheck struct {
+	description string
+	prepare     func(ctx context.Context) error
+	verify      func(ctx context.Context) error
+}
+
+func (c *chaosCheck) String() string {
+	return c.description
+}
+
+func (c *chaosCheck) Prepare(ctx context.Context) error {
+	if c.prepare == nil {
+		return nil
+	}
+	return c.prepare(ctx)
+}
+
+func (c *chaosCheck) Verify(ctx context.Context) error {
+	return c.verify(ctx)
+}
+
+// expects the cluster gets one of the statuses with the given number of online members (-1
+// means any), only the changes since the start of the schedule count, so it is meant for the
+// statuses caused by faults, the convergence is verified by RunChaos
+func (u *Unit) ExpectClusterStatus(namespace string, name string, statuses []string, numOnline int) chaos.Check {
+	waitParams := k8s.WaitOnInnoDBClusterParams{
+		Namespace:         namespace,
+		Name:              name,
+		ExpectedStatus:    statuses,
+		ExpectedNumOnline: int64(numOnline),
+	}
+	return &chaosCheck{
+		description: fmt.Sprintf("%s/%s gets %s with %d member(s) ONLINE", namespace, name, strings.Join(statuses, "|"), numOnline),
+		prepare: func(ctx context.Context) error {
+			ic, err := u.Client.GetInnoDBCluster(namespace, name)
+			if err != nil {
+				return err
+			}
+			waitParams.SinceResourceVersion = ic.GetResourceVersion()
+			return nil
+		},
+		verify: func(ctx context.Context) error {
+			return u.WaitOnInnoDBCluster(waitParams)
+		},
+	}
+}
+
+// the state of a member which isn't in the group (as seen by another member) anymore, e.g.
+// after it was expelled or it left
+const MemberMissing = "MISSING"
+
+func getMemberState(ctx context.Context, client *k8s.Client, namespace string, observerPodName string, memberPodName string) (string, error) {
+	session, err := mysql.NewSession(ctx, client, namespace, observerPodName, common.RootUser, common.RootPassword)
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	members, err := session.QueryAll("SELECT member_host, member_state FROM performance_schema.replication_group_members")
+	if err != nil {
+		return "", err
+	}
+	defer members.Close()
+
+	state := MemberMissing
+	for members.Next() {
+		var host, memberState string
+		if err := members.Scan(&host, &memberState); err != nil {
+			return "", err
+		}
+		// e.g. mycluster-1.mycluster-instances.ns.svc.cluster.local
+		if strings.HasPrefix(host, memberPodName+".") {
+			state = memberState
+		}
+	}
+	return state, members.Err()
+}
+
+// expects the observer sees the member of the group in one of the given states (see
+// MemberMissing), e.g. UNREACHABLE after the member was paused
+func (u *Unit) ExpectMemberState(namespace string, observerPodName string, memberPodName string, states ...string) chaos.Check {
+	return &chaosCheck{
+		description: fmt.Sprintf("%s/%s sees %s %s", namespace, observerPodName, memberPodName, strings.Join(states, "|")),
+		verify: func(ctx context.Context) error {
+			var lastState string
+			checker := func(args ...interface{}) (bool, error) {
+				state, err := getMemberState(ctx, u.Client, namespace, observerPodName, memberPodName)
+				if err != nil {
+					// e.g. the observer lost the connection meanwhile, it is retried
+					log.Warning.Printf("cannot get the state of %s from %s: %v", memberPodName, observerPodName, err)
+					return false, nil
+				}
+				lastState = state
+				return auxi.Contains(states, state), nil
+			}
+			if _, err := u.Wait(checker, 120*time.Second, 2*time.Second); err != nil {
+				return fmt.Errorf("%w, the last state of %s was '%s'", err, memberPodName, lastState)
+			}
+			return nil
+		},
+	}
+}
+
+// expects the container gets restarted (or the whole pod is recreated) since the start of
+// the schedule, e.g. after its main process was killed
+func (u *Unit) ExpectContainerRestart(namespace string, podName string, containerId k8s.ContainerId) chaos.Check {
+	var podUid types.UID
+	var restartCount int32
+	return &chaosCheck{
+		description: fmt.Sprintf("%s in %s/%s restarts", k8s.GetContainerName(containerId), namespace, podName),
+		prepare: func(ctx context.Context) error {
+			pod, err := u.Client.GetPod(namespace, podName)
+			if err != nil {
+				return err
+			}
+			status, err := k8s.GetContainerStatus(pod, containerId)
+			if err != nil {
+				return err
+			}
+			podUid = pod.GetUID()
+			restartCount = status.RestartCount
+			return nil
+		},
+		verify: func(ctx context.Context) error {
+			ctx, cancel := u.WithTimeout(120 * time.Second)
+			defer cancel()
+			params := k8s.WatchParams{Namespace: namespace, Name: podName}
+			return u.Client.WaitOnPods(ctx, params, func(pods []*corev1.Pod) (bool, string) {
+				if len(pods) == 0 {
+					return false, "no pod"
+				}
+				pod := pods[0]
+				if pod.GetUID() != podUid {
+					return true, "recreated"
+				}
+				status, err := k8s.GetContainerStatus(pod, containerId)
+				if err != nil {
+					return false, err.Error()
+				}
+				return status.RestartCount > restartCount, fmt.Sprintf("restarts: %d", status.RestartCount)
+			})
+		},
+	}
+}
+
+// expects the ballast of chaos.NewFillDatadir takes space in the datadir of the instance
+func (u *Unit) ExpectDatadirFilled(namespace string, podName string) chaos.Check {
+	return &chaosCheck{
+		description: fmt.Sprintf("the datadir of %s/%s holds the ballast", namespace, podName),
+		verify: func(ctx context.Context) error {
+			output, err := u.Client.ExecuteGetOutput(namespace, podName, k8s.Mysql, "du", "-k", chaos.DatadirBallastPath)
+			if err != nil {
+				return err
+			}
+			// e.g. 1048580	/var/lib/mysql/ote-chaos-ballast
+			fields := strings.Fields(output)
+			if len(fields) == 0 {
+				return fmt.Errorf("unexpected output of du: %s", output)
+			}
+			size, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("unexpected output of du: %s", output)
+			}
+			if size == 0 {
+				return fmt.Errorf("the ballast %s takes no space", chaos.DatadirBallastPath)
+			}
+			return nil
+		},
+	}
+}
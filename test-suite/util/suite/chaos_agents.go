@@ -0,0 +1,188 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/chaos"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// privileged pods sharing the pid namespace of their nodes, they send signals to the main
+// processes of containers by their pids on the node (see chaos.Signaller) and run commands
+// in the network namespaces of pods (see chaos.NetworkExecutor), by default the server image
+// is used as it is already there, signals need only sh, grep and sed, the latency needs also
+// nsenter and tc (testSuite.chaosAgentImage), the agents are started on demand, one per node,
+// they go away with the namespace of the unit
+type ChaosAgents struct {
+	unit *Unit
+	// node name -> agent pod name
+	agents map[string]string
+}
+
+func (u *Unit) GetChaosAgents() *ChaosAgents {
+	if u.chaosAgents == nil {
+		u.chaosAgents = &ChaosAgents{unit: u, agents: make(map[string]string)}
+	}
+	return u.chaosAgents
+}
+
+type generateChaosAgentData struct {
+	Name       string
+	Image      string
+	PullPolicy string
+	NodeName   string
+}
+
+func (a *ChaosAgents) ensureAgent(nodeName string) (string, error) {
+	if agentName, ok := a.agents[nodeName]; ok {
+		return agentName, nil
+	}
+
+	u := a.unit
+	image := u.Cfg.TestSuite.ChaosAgentImage
+	if len(image) == 0 {
+		image = u.GetDefaultServerImage()
+	}
+	data := generateChaosAgentData{
+		Name:       "chaos-agent-" + nodeName,
+		Image:      image,
+		PullPolicy: u.Cfg.Images.PullPolicy,
+		NodeName:   nodeName,
+	}
+	const ChaosAgentTemplate = "chaos-agent.yaml"
+	content, err := u.renderTemplate(ChaosAgentTemplate, data)
+	if err != nil {
+		return "", err
+	}
+	if _, err := u.Client.ApplyYaml(u.Context(), u.Namespace, content); err != nil {
+		return "", fmt.Errorf("cannot start the chaos agent on %s: %w", nodeName, err)
+	}
+	if err := u.WaitOnPodInNamespace(u.Namespace, data.Name, corev1.PodRunning); err != nil {
+		return "", err
+	}
+
+	a.agents[nodeName] = data.Name
+	return data.Name, nil
+}
+
+func (a *ChaosAgents) getAgentOfPod(pod *corev1.Pod) (string, error) {
+	nodeName := pod.Spec.NodeName
+	if len(nodeName) == 0 {
+		return "", fmt.Errorf("pod %s/%s is not scheduled on any node", pod.GetNamespace(), pod.GetName())
+	}
+	return a.ensureAgent(nodeName)
+}
+
+func (a *ChaosAgents) Prepare(ctx context.Context, namespace string, podName string) error {
+	pod, err := a.unit.Client.GetPod(namespace, podName)
+	if err != nil {
+		return err
+	}
+	_, err = a.getAgentOfPod(pod)
+	return err
+}
+
+// the main process of a container is the init of its pid namespace, i.e. its pid in there
+// (the last one in NSpid) is 1, and its cgroup path contains the id of the container, the
+// function prints its pid on the node or not-found
+const findContainerProcessScript = `find_container_process() {
+	pids=
+	for cgroup in $(grep -l "$1" /proc/[0-9]*/cgroup 2>/dev/null); do
+		dir=${cgroup%/cgroup}
+		if [ "$(sed -n 's/^NSpid:.*[[:space:]]//p' "$dir/status" 2>/dev/null)" = 1 ]; then
+			pids="$pids ${dir#/proc/}"
+		fi
+	done
+	set -- $pids
+	case $# in
+	0) echo "not-found" ;;
+	1) echo "$1" ;;
+	*) echo "many processes found:$pids" >&2; return 1 ;;
+	esac
+}
+pid=$(find_container_process "$1") || exit 1
+if [ "$pid" = not-found ]; then
+	echo "not-found"
+	exit 0
+fi
+shift
+`
+
+// args: container id, signal
+const signalContainerScript = findContainerProcessScript + `kill -"$1" "$pid" && echo "signalled $pid"`
+
+// args: container id, command...
+const executeInNetworkScript = findContainerProcessScript + `exec nsenter -t "$pid" -n "$@"`
+
+// runs the script on the agent of the node of the pod, the first argument of the script is
+// the runtime id of the container
+func (a *ChaosAgents) runForContainer(pod *corev1.Pod, containerId k8s.ContainerId, script string, args ...string) (string, error) {
+	namespace := pod.GetNamespace()
+	podName := pod.GetName()
+	agentName, err := a.getAgentOfPod(pod)
+	if err != nil {
+		return "", err
+	}
+
+	status, err := k8s.GetContainerStatus(pod, containerId)
+	if err != nil {
+		return "", err
+	}
+	if status.State.Running == nil || len(status.ContainerID) == 0 {
+		return "", chaos.NewProcessNotFoundError(namespace, podName, containerId)
+	}
+	// e.g. containerd://0123abcd...
+	_, runtimeId, found := strings.Cut(status.ContainerID, "://")
+	if !found {
+		runtimeId = status.ContainerID
+	}
+
+	cmd := append([]string{"sh", "-c", script, "sh", runtimeId}, args...)
+	output, err := a.unit.Client.ExecuteGetOutput(a.unit.Namespace, agentName, k8s.ChaosAgent, cmd...)
+	output = strings.TrimSpace(output)
+	if err != nil {
+		return output, fmt.Errorf("%w: %s", err, output)
+	}
+	if output == "not-found" {
+		return output, chaos.NewProcessNotFoundError(namespace, podName, containerId)
+	}
+	return output, nil
+}
+
+func (a *ChaosAgents) Signal(ctx context.Context, namespace string, podName string, containerId k8s.ContainerId, signal chaos.Signal) error {
+	pod, err := a.unit.Client.GetPod(namespace, podName)
+	if err != nil {
+		return err
+	}
+	_, err = a.runForContainer(pod, containerId, signalContainerScript, strconv.Itoa(int(signal)))
+	if err != nil && !chaos.IsProcessNotFoundError(err) {
+		return fmt.Errorf("cannot signal %s in %s/%s: %w", k8s.GetContainerName(containerId), namespace, podName, err)
+	}
+	return err
+}
+
+// the containers of a pod share its network namespace, so it is entered through the mysql
+// container, or the sidecar if mysqld doesn't run at the moment
+func (a *ChaosAgents) ExecuteInNetwork(ctx context.Context, namespace string, podName string, cmd ...string) error {
+	pod, err := a.unit.Client.GetPod(namespace, podName)
+	if err != nil {
+		return err
+	}
+	for _, containerId := range []k8s.ContainerId{k8s.Mysql, k8s.Sidecar} {
+		_, err = a.runForContainer(pod, containerId, executeInNetworkScript, cmd...)
+		if !chaos.IsProcessNotFoundError(err) {
+			break
+		}
+	}
+	if err != nil && !chaos.IsProcessNotFoundError(err) {
+		return fmt.Errorf("cannot execute %v in the network of %s/%s: %w", cmd, namespace, podName, err)
+	}
+	return err
+}
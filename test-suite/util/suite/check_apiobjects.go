@@ -62,7 +62,7 @@ func checkClusterObject(client *k8s.Client, icobj *k8s.InnoDBCluster, name strin
 	if err != nil {
 		return err
 	}
-	if icobj.HasField("spec", "router", "instances") && icobj.GetInt("spec", "router", "instances") > 0 {
+	if icobj.Spec.Router.GetInstances() > 0 {
 		if !hasRouter {
 			return fmt.Errorf("deployment %s is not found but one is expected to exist", clusterRouterName)
 		}
@@ -117,7 +117,7 @@ func checkClusterSpecCompliant(cfg *setup.Configuration, client *k8s.Client, ico
 	}
 
 	stsSpecReplicas := int(*sts.Spec.Replicas)
-	icSpecInstances := icobj.GetInt("spec", "instances")
+	icSpecInstances := icobj.Spec.Instances
 	if stsSpecReplicas != icSpecInstances {
 		return fmt.Errorf("stsSpecReplicas (%d) != icSpecInstances (%d)", stsSpecReplicas, icSpecInstances)
 	}
@@ -128,7 +128,7 @@ func checkClusterSpecCompliant(cfg *setup.Configuration, client *k8s.Client, ico
 	if err != nil {
 		return err
 	}
-	if icobj.HasField("spec", "router", "instances") && icobj.GetInt("spec", "router", "instances") > 0 {
+	if icobj.Spec.Router.GetInstances() > 0 {
 		if !hasRouter {
 			return errors.New("incorrect router deployment (nil)")
 		}
@@ -139,7 +139,7 @@ func checkClusterSpecCompliant(cfg *setup.Configuration, client *k8s.Client, ico
 		}
 
 		routerSpecReplicas := int(*clusterRouter.Spec.Replicas)
-		icSpecRouterInstances := icobj.GetInt("spec", "router", "instances")
+		icSpecRouterInstances := icobj.Spec.Router.GetInstances()
 		if routerSpecReplicas != icSpecRouterInstances {
 			return fmt.Errorf("router %s spec.replicas (%d) != ic %s spec.router.instances (%d)", clusterRouterName, routerSpecReplicas, icName, icSpecRouterInstances)
 		}
@@ -150,7 +150,7 @@ func checkClusterSpecCompliant(cfg *setup.Configuration, client *k8s.Client, ico
 	}
 
 	// check actual pod count
-	icOnlineInstances := icobj.GetInt("status", "cluster", "onlineInstances")
+	icOnlineInstances := icobj.Status.Cluster.OnlineInstances
 	if icOnlineInstances != icSpecInstances {
 		return fmt.Errorf("icOnlineInstances (%d) != icSpecInstances (%d)", icOnlineInstances, icSpecInstances)
 	}
@@ -178,23 +178,27 @@ func checkClusterSpecCompliant(cfg *setup.Configuration, client *k8s.Client, ico
 			routerPods = append(routerPods, &pod)
 		}
 	}
-	icSpecRouterInstances := icobj.GetInt("spec", "router", "instances")
+	icSpecRouterInstances := icobj.Spec.Router.GetInstances()
 	if len(routerPods) != icSpecRouterInstances {
 		return fmt.Errorf("router pods number (%d) != icSpecRouterInstances (%d)", len(routerPods), icSpecRouterInstances)
 	}
 
-	// icStatusVersion := icobj.GetString("status", "version")
-	// if icobj.HasField("spec", "version") {
-	// 	isSpecImageVersion := icobj.GetString("spec", "version")
-	// 	if isSpecImageVersion != icStatusVersion {
-	// 		return fmt.Errorf("image version in spec (%s) different than in status (%s)", isSpecImageVersion, icStatusVersion)
-	// 	}
-	// } else {
-	// 	defaultServerVersionTag := cfg.Images.DefaultServerVersionTag
-	// 	if icStatusVersion != defaultServerVersionTag {
-	// 		return fmt.Errorf("image version in spec is not declared, so it should be %s but in status it is %s", defaultServerVersionTag, icStatusVersion)
-	// 	}
-	// }
+	// the operator sets the version once the cluster is up, older ones don't set it at all
+	icStatusVersion := icobj.Status.Version
+	if len(icStatusVersion) == 0 {
+		return nil
+	}
+	if len(icobj.Spec.Version) > 0 {
+		isSpecImageVersion := icobj.Spec.Version
+		if isSpecImageVersion != icStatusVersion {
+			return fmt.Errorf("image version in spec (%s) different than in status (%s)", isSpecImageVersion, icStatusVersion)
+		}
+	} else {
+		defaultServerVersionTag := cfg.Images.DefaultServerVersionTag
+		if icStatusVersion != defaultServerVersionTag {
+			return fmt.Errorf("image version in spec is not declared, so it should be %s but in status it is %s", defaultServerVersionTag, icStatusVersion)
+		}
+	}
 	return nil
 }
 
@@ -226,17 +230,20 @@ func checkPodSpecCompliant(icobj *k8s.InnoDBCluster, pod *corev1.Pod) error {
 	}
 
 	// check imagePull stuff
-	if icobj.HasField("spec", "imagePullPolicy") {
-		specPullPolicy := icobj.GetString("spec", "imagePullPolicy")
-		mysqlContPullPolicy := string(mysqlCont.ImagePullPolicy)
+	if len(icobj.Spec.ImagePullPolicy) > 0 {
+		specPullPolicy := icobj.Spec.ImagePullPolicy
+		mysqlContPullPolicy := mysqlCont.ImagePullPolicy
 		if specPullPolicy != mysqlContPullPolicy {
 			return fmt.Errorf("ic %s spec imagePullPolicy (%s) is different than mysql container imagePullPolicy (%s)",
 				icName, specPullPolicy, mysqlContPullPolicy)
 		}
 	}
 
-	if icobj.HasField("spec", "imagePullSecrets") {
-		specPullSecrets := icobj.GetSliceOfStrings("spec", "imagePullSecrets")
+	if icobj.Spec.ImagePullSecrets != nil {
+		specPullSecrets := make([]string, len(icobj.Spec.ImagePullSecrets))
+		for i, specPullSecret := range icobj.Spec.ImagePullSecrets {
+			specPullSecrets[i] = specPullSecret.Name
+		}
 
 		podSpecImagePullSecrets := pod.Spec.ImagePullSecrets
 		podSpecPullSecretNames := make([]string, len(podSpecImagePullSecrets))
@@ -338,7 +345,7 @@ func getClusterObject(client *k8s.Client, namespace string, name string) (*k8s.I
 	}
 
 	var mysqlPods []*corev1.Pod
-	instanceCount := icobj.GetInt("spec", "instances")
+	instanceCount := icobj.Spec.Instances
 	for i := 0; i < instanceCount; i++ {
 		instanceName := fmt.Sprintf("%s-%d", name, i)
 		instancePod, err := client.GetPod(namespace, instanceName)
@@ -430,15 +437,15 @@ func checkRouterPod(pod *corev1.Pod, restarts int32) error {
 // Check that the spec matches what we expect
 func checkClusterSpec(icobj *k8s.InnoDBCluster, instances int, routers int) error {
 	if instances > 0 {
-		specInstances := icobj.GetInt("spec", "instances")
+		specInstances := icobj.Spec.Instances
 		if specInstances != instances {
 			return fmt.Errorf("according to spec there are expected %d instance(s) but got %d", specInstances, instances)
 		}
 	}
 
 	if routers != NoRouters && routers > 0 {
-		if icobj.HasField("spec", "router", "instances") {
-			specRouterInstances := icobj.GetInt("spec", "router", "instances")
+		if icobj.Spec.Router.Instances != nil {
+			specRouterInstances := *icobj.Spec.Router.Instances
 			if specRouterInstances != routers {
 				return fmt.Errorf("according to spec there are expected %d instance(s) but got %d", specRouterInstances, routers)
 			}
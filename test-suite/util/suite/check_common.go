@@ -40,6 +40,7 @@ type CheckParams struct {
 }
 
 func CheckAll(unit *Unit, params CheckParams) ([]*corev1.Pod, error) {
+	ctx := unit.Context()
 	client := unit.Client
 	namespace := params.Namespace
 	name := params.Name
@@ -66,7 +67,7 @@ func CheckAll(unit *Unit, params CheckParams) ([]*corev1.Pod, error) {
 		return nil, err
 	}
 
-	info, err := CheckGroup(icobj, allPods, user, password)
+	info, err := CheckGroup(ctx, client, icobj, allPods, user, password)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +111,7 @@ func CheckAll(unit *Unit, params CheckParams) ([]*corev1.Pod, error) {
 			}
 		}
 
-		if err := checkInstance(icobj, allPods, pod, i == primary, numSessions, version, user, password); err != nil {
+		if err := checkInstance(ctx, client, icobj, allPods, pod, i == primary, numSessions, version, user, password); err != nil {
 			return nil, err
 		}
 
@@ -147,3 +148,47 @@ func CheckAll(unit *Unit, params CheckParams) ([]*corev1.Pod, error) {
 
 	return allPods, nil
 }
+
+func isNodeSchedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute {
+			return false
+		}
+	}
+	return true
+}
+
+func CountSchedulableNodes(client *k8s.Client) (int, error) {
+	nodes, err := client.ListNodes()
+	if err != nil {
+		return 0, err
+	}
+
+	var schedulableNodes int
+	for _, node := range nodes.Items {
+		if isNodeSchedulable(&node) {
+			schedulableNodes++
+		}
+	}
+	return schedulableNodes, nil
+}
+
+// check whether the given pods are spread across separate nodes, i.e. whether
+// the loss of a single node affects at most one of them (e.g. anti-affinity rules)
+func CheckPodsOnDistinctNodes(pods []*corev1.Pod) error {
+	podsOnNodes := make(map[string]string)
+	for _, pod := range pods {
+		nodeName := pod.Spec.NodeName
+		if nodeName == "" {
+			return fmt.Errorf("pod %s/%s is not scheduled on any node", pod.GetNamespace(), pod.GetName())
+		}
+		if otherPod, found := podsOnNodes[nodeName]; found {
+			return fmt.Errorf("pods %s and %s run on the same node %s", otherPod, pod.GetName(), nodeName)
+		}
+		podsOnNodes[nodeName] = pod.GetName()
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/mysql"
+)
+
+// takes the fingerprint of data on the given instance as root, e.g. of the source cluster before
+// a backup
+func GetDataFingerprint(ctx context.Context, client *k8s.Client, namespace string, podName string, options mysql.FingerprintOptions) (*mysql.Fingerprint, error) {
+	podSession, err := mysql.NewSession(ctx, client, namespace, podName, common.RootUser, common.RootPassword)
+	if err != nil {
+		return nil, err
+	}
+	defer podSession.Close()
+
+	fingerprint, err := podSession.GetFingerprint(options)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the fingerprint of data on %s/%s: %w", namespace, podName, err)
+	}
+	return fingerprint, nil
+}
+
+// checks data of a cluster restored from a backup (dump, clone) is the same as on the source,
+// the fingerprint has to be taken with the same options, the error lists what doesn't match
+func CheckDataFingerprint(ctx context.Context, client *k8s.Client, namespace string, podName string, expected *mysql.Fingerprint, options mysql.FingerprintOptions) error {
+	fingerprint, err := GetDataFingerprint(ctx, client, namespace, podName, options)
+	if err != nil {
+		return err
+	}
+
+	if diff := mysql.CompareFingerprints(expected, fingerprint); !diff.Empty() {
+		return fmt.Errorf("data on %s/%s differs from the source:\n%s", namespace, podName, diff)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
+	"github.com/marinesovitch/ote/test-suite/util/objstore"
+)
+
+// checks what a backup actually wrote under the prefix (e.g. S3StoragePrefix + "/" + mbk.Status.Output),
+// i.e. the metadata of the dump, some data chunks and the final @.done.json, returns all objects of the dump
+func CheckDumpInStore(ctx context.Context, store objstore.ObjectStore, prefix string) ([]objstore.ObjectInfo, error) {
+	for _, name := range []string{objstore.DumpInstanceFile, objstore.DumpDoneFile} {
+		info, err := store.Stat(ctx, path.Join(prefix, name))
+		if err != nil {
+			return nil, fmt.Errorf("dump %s is incomplete: %w", prefix, err)
+		}
+		if info.Size == 0 {
+			return nil, fmt.Errorf("dump %s is incomplete: %s is empty", prefix, info.Name)
+		}
+	}
+
+	objects, err := store.List(ctx, objstore.DirPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		if objstore.IsDumpDataChunk(object.Name) {
+			return objects, nil
+		}
+	}
+	return objects, fmt.Errorf("dump %s in %s has no data chunks, found %d metadata files only", prefix, store, len(objects))
+}
+
+// ---------------------------
+
+// what is expected in a dump, e.g. for dumpOptions {"excludeSchemas": ["excludeme"]}:
+// Schemas: {"sakila": <tables of sakila>}, ExcludedSchemas: ["excludeme"]
+type DumpExpectations struct {
+	// the schemas which must be dumped, with exactly the given tables (nil - any tables)
+	Schemas map[string][]string
+	// the schemas which must not be dumped
+	ExcludedSchemas []string
+	// e.g. "sakila.actor"
+	ExcludedTables []string
+	// the minimal number of data chunks in the whole dump
+	MinChunks int
+}
+
+func (e *DumpExpectations) verifyChunks(metadata *objstore.DumpMetadata) error {
+	if len(metadata.Chunks) < e.MinChunks {
+		return fmt.Errorf("expected at least %d data chunks but got %d", e.MinChunks, len(metadata.Chunks))
+	}
+
+	// chunkFileBytes lists the sizes of the chunks as written by the shell
+	chunkSizes := map[string]int64{}
+	for _, chunk := range metadata.Chunks {
+		chunkSizes[chunk.Name] = chunk.Size
+	}
+	for name, expectedSize := range metadata.Done.ChunkFileBytes {
+		size, ok := chunkSizes[name]
+		if !ok {
+			return fmt.Errorf("chunk %s listed in %s is missing", name, objstore.DumpDoneFile)
+		}
+		if size != expectedSize {
+			return fmt.Errorf("chunk %s has %d bytes but %d are listed in %s", name, size, expectedSize, objstore.DumpDoneFile)
+		}
+	}
+	return nil
+}
+
+func (e *DumpExpectations) verifySchemas(metadata *objstore.DumpMetadata) error {
+	for schema, expectedTables := range e.Schemas {
+		if !metadata.HasSchema(schema) {
+			return fmt.Errorf("schema %s is not in the dump, dumped schemas: %v", schema, metadata.Instance.Schemas)
+		}
+		tables := metadata.GetTables(schema)
+		if expectedTables != nil && !auxi.AreStringSlicesEqual(expectedTables, tables) {
+			return fmt.Errorf("expected tables of %s: %v but got: %v", schema, expectedTables, tables)
+		}
+
+		if !metadata.Schemas[schema].IncludesData {
+			continue
+		}
+		dataBytes := metadata.Done.TableDataBytes[schema]
+		for _, table := range tables {
+			if _, ok := dataBytes[table]; !ok {
+				return fmt.Errorf("no data of %s.%s listed in %s", schema, table, objstore.DumpDoneFile)
+			}
+			if dataBytes[table] > 0 && len(metadata.GetTableChunks(schema, table)) == 0 {
+				return fmt.Errorf("table %s.%s has %d bytes of data but no chunks", schema, table, dataBytes[table])
+			}
+		}
+	}
+
+	for _, schema := range e.ExcludedSchemas {
+		if metadata.HasSchema(schema) {
+			return fmt.Errorf("schema %s should be excluded from the dump", schema)
+		}
+		if _, ok := metadata.Done.TableDataBytes[schema]; ok {
+			return fmt.Errorf("data of the excluded schema %s is in the dump", schema)
+		}
+	}
+
+	for _, schemaTable := range e.ExcludedTables {
+		schema, table, found := strings.Cut(schemaTable, ".")
+		if !found {
+			return fmt.Errorf("excluded table %s should be given as schema.table", schemaTable)
+		}
+		if auxi.Contains(metadata.GetTables(schema), table) || len(metadata.GetTableChunks(schema, table)) > 0 {
+			return fmt.Errorf("table %s should be excluded from the dump", schemaTable)
+		}
+	}
+	return nil
+}
+
+// reads the metadata of the dump under the prefix and verifies the dump against expectations,
+// returns the metadata for further checks
+func VerifyDump(ctx context.Context, store objstore.ObjectStore, prefix string, expected DumpExpectations) (*objstore.DumpMetadata, error) {
+	metadata, err := objstore.ReadDumpMetadata(ctx, store, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the dump %s in %s: %w", prefix, store, err)
+	}
+
+	if err := expected.verifySchemas(metadata); err != nil {
+		return metadata, fmt.Errorf("dump %s in %s: %w", prefix, store, err)
+	}
+	if err := expected.verifyChunks(metadata); err != nil {
+		return metadata, fmt.Errorf("dump %s in %s: %w", prefix, store, err)
+	}
+	return metadata, nil
+}
@@ -3,6 +3,7 @@
 package suite
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -36,10 +37,10 @@ func extractIndex(host string) (int, error) {
 	return index, nil
 }
 
-func CheckGroup(icobj *k8s.InnoDBCluster, allPods []*corev1.Pod, user string, password string) (map[string]int, error) {
+func CheckGroup(ctx context.Context, client *k8s.Client, icobj *k8s.InnoDBCluster, allPods []*corev1.Pod, user string, password string) (map[string]int, error) {
 	info := make(map[string]int)
 
-	session, err := mysql.NewSession(icobj.GetNamespace(), icobj.GetName()+"-0", user, password)
+	session, err := mysql.NewSession(ctx, client, icobj.GetNamespace(), icobj.GetName()+"-0", user, password)
 	if err != nil {
 		return nil, err
 	}
@@ -82,12 +83,12 @@ func CheckGroup(icobj *k8s.InnoDBCluster, allPods []*corev1.Pod, user string, pa
 		return nil, fmt.Errorf("there is expected one primary but got %d", len(primaries))
 	}
 
-	instancesCount := icobj.GetInt("spec", "instances")
+	instancesCount := icobj.Spec.Instances
 	if memberCounter != instancesCount {
 		return nil, fmt.Errorf("there are expected %d member(s) but got %d", instancesCount, memberCounter)
 	}
 
-	onlineInstancesCount := icobj.GetInt("status", "cluster", "onlineInstances")
+	onlineInstancesCount := icobj.Status.Cluster.OnlineInstances
 	if memberCounter != onlineInstancesCount {
 		return nil, fmt.Errorf("there are expected %d online member(s) but got %d", onlineInstancesCount, memberCounter)
 	}
@@ -192,7 +193,7 @@ func checkProcessList(session *mysql.PodSession, numSessions int) error {
 	return nil
 }
 
-func checkInstance(icobj *k8s.InnoDBCluster, allPods []*corev1.Pod, pod *corev1.Pod, isPrimary bool, numSessions int, version string, user string, password string) error {
+func checkInstance(ctx context.Context, client *k8s.Client, icobj *k8s.InnoDBCluster, allPods []*corev1.Pod, pod *corev1.Pod, isPrimary bool, numSessions int, version string, user string, password string) error {
 	groupSeeds := make(common.StringSet)
 	for _, p := range allPods {
 		if p != pod {
@@ -203,9 +204,12 @@ func checkInstance(icobj *k8s.InnoDBCluster, allPods []*corev1.Pod, pod *corev1.
 
 	name := pod.GetName()
 	const DefaultBaseServerId = 1000
-	baseId := icobj.GetOptionalInt(DefaultBaseServerId, "spec", "baseServerId")
+	baseId := icobj.Spec.BaseServerId
+	if baseId == 0 {
+		baseId = DefaultBaseServerId
+	}
 
-	session, err := mysql.NewSession(pod.GetNamespace(), pod.GetName(), user, password)
+	session, err := mysql.NewSession(ctx, client, pod.GetNamespace(), pod.GetName(), user, password)
 	if err != nil {
 		return err
 	}
@@ -376,7 +380,7 @@ func schemaReport(session *mysql.PodSession, schema string) (TablesInfo, error)
 	return tablesInfo, nil
 }
 
-func CheckData(allPods []*corev1.Pod, user string, password string, primary int) error {
+func CheckData(ctx context.Context, client *k8s.Client, allPods []*corev1.Pod, user string, password string, primary int) error {
 
 	ignoreSchemas := []string{"mysql", "information_schema", "performance_schema", "sys"}
 
@@ -386,7 +390,7 @@ func CheckData(allPods []*corev1.Pod, user string, password string, primary int)
 		return fmt.Errorf("primary pod name is %s but expected index is %s", primaryName, primaryIndex)
 	}
 
-	primaryPodSession, err := mysql.NewSession(
+	primaryPodSession, err := mysql.NewSession(ctx, client,
 		allPods[primary].GetNamespace(), allPods[primary].GetName(),
 		user, password)
 	if err != nil {
@@ -421,7 +425,7 @@ func CheckData(allPods []*corev1.Pod, user string, password string, primary int)
 		if i == primary {
 			continue
 		}
-		podSession, err := mysql.NewSession(
+		podSession, err := mysql.NewSession(ctx, client,
 			pod.GetNamespace(), pod.GetName(),
 			user, password)
 
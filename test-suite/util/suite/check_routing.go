@@ -3,9 +3,13 @@
 package suite
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	"github.com/marinesovitch/ote/test-suite/util/auxi"
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/mysql"
 )
 
 func CheckRouterPods(client *k8s.Client, namespace string, name string, expectedPodsNum int) error {
@@ -22,3 +26,173 @@ func CheckRouterPods(client *k8s.Client, namespace string, name string, expected
 
 	return nil
 }
+
+// ---------------------------
+
+type groupRoles struct {
+	primary     string
+	secondaries []string
+}
+
+func shortHostName(host string) string {
+	return strings.Split(host, ".")[0]
+}
+
+func getGroupRoles(ctx context.Context, client *k8s.Client, namespace string, podName string, user string, password string) (*groupRoles, error) {
+	session, err := mysql.NewSession(ctx, client, namespace, podName, user, password)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	members, err := session.FetchAll(
+		"SELECT member_host, member_role FROM performance_schema.replication_group_members WHERE member_state = 'ONLINE'")
+	if err != nil {
+		return nil, err
+	}
+
+	var roles groupRoles
+	for memberHost, memberRole := range members.ToStringToStringMap(0, 1) {
+		host := shortHostName(memberHost)
+		if memberRole == "PRIMARY" {
+			roles.primary = host
+		} else {
+			roles.secondaries = append(roles.secondaries, host)
+		}
+	}
+
+	if len(roles.primary) == 0 {
+		return nil, fmt.Errorf("no primary found in the group seen from %s/%s", namespace, podName)
+	}
+	return &roles, nil
+}
+
+// every query is run on a new connection, so the router routes each of them anew
+func collectHostnames(session *mysql.PodSession, connections int) (map[string]int, error) {
+	session.Database.SetMaxIdleConns(0)
+	hostnames := make(map[string]int)
+	for i := 0; i < connections; i++ {
+		var hostname string
+		if err := session.QueryOne("SELECT @@hostname").Scan(&hostname); err != nil {
+			return nil, err
+		}
+		hostnames[shortHostName(hostname)]++
+	}
+	return hostnames, nil
+}
+
+func checkRoutedToPrimary(target string, hostnames map[string]int, roles *groupRoles) error {
+	for hostname, count := range hostnames {
+		if hostname != roles.primary {
+			return fmt.Errorf("%d RW connections through %s landed on %s but the primary is %s", count, target, hostname, roles.primary)
+		}
+	}
+	return nil
+}
+
+func checkRoutedToSecondaries(target string, hostnames map[string]int, roles *groupRoles) error {
+	for hostname, count := range hostnames {
+		if !auxi.Contains(roles.secondaries, hostname) {
+			return fmt.Errorf("%d RO connections through %s landed on %s which is not a secondary %v", count, target, hostname, roles.secondaries)
+		}
+	}
+
+	for _, secondary := range roles.secondaries {
+		if hostnames[secondary] == 0 {
+			return fmt.Errorf("none of RO connections through %s landed on secondary %s, got %v", target, secondary, hostnames)
+		}
+	}
+	return nil
+}
+
+type routingCheck struct {
+	target      string
+	openSession func() (*mysql.PodSession, error)
+	check       func(target string, hostnames map[string]int, roles *groupRoles) error
+}
+
+func (r *routingCheck) run(connections int, roles *groupRoles) error {
+	session, err := r.openSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	hostnames, err := collectHostnames(session, connections)
+	if err != nil {
+		return err
+	}
+	return r.check(r.target, hostnames, roles)
+}
+
+// checks that through every router pod and through the cluster service, RW traffic
+// lands on the primary and RO traffic is spread over all secondaries
+func CheckRouting(ctx context.Context, client *k8s.Client, namespace string, name string, user string, password string, connections int) error {
+	roles, err := getGroupRoles(ctx, client, namespace, name+"-0", user, password)
+	if err != nil {
+		return err
+	}
+
+	if len(roles.secondaries) > 0 && connections < 2*len(roles.secondaries) {
+		return fmt.Errorf("%d connections are too few to check RO traffic is spread over %d secondaries", connections, len(roles.secondaries))
+	}
+
+	pattern := fmt.Sprintf("%s-router-.*", name)
+	routers, err := client.ListPodsWithFilter(namespace, pattern)
+	if err != nil {
+		return err
+	}
+	if len(routers.Items) == 0 {
+		return fmt.Errorf("no router pods for %s/%s", namespace, name)
+	}
+
+	var checks []routingCheck
+	for _, router := range routers.Items {
+		routerName := router.GetName()
+		checks = append(checks,
+			routingCheck{
+				target: fmt.Sprintf("%s:%d", routerName, mysql.RouterPortRW),
+				openSession: func() (*mysql.PodSession, error) {
+					return mysql.NewRouterSession(ctx, client, namespace, routerName, mysql.RouterPortRW, user, password)
+				},
+				check: checkRoutedToPrimary,
+			})
+		if len(roles.secondaries) > 0 {
+			checks = append(checks,
+				routingCheck{
+					target: fmt.Sprintf("%s:%d", routerName, mysql.RouterPortRO),
+					openSession: func() (*mysql.PodSession, error) {
+						return mysql.NewRouterSession(ctx, client, namespace, routerName, mysql.RouterPortRO, user, password)
+					},
+					check: checkRoutedToSecondaries,
+				})
+		}
+	}
+
+	// the service routes to the router pods, "mysql" is the classic protocol RW port
+	checks = append(checks,
+		routingCheck{
+			target: "svc/" + name + ":mysql",
+			openSession: func() (*mysql.PodSession, error) {
+				return mysql.NewServiceSession(ctx, client, namespace, name, "mysql", user, password)
+			},
+			check: checkRoutedToPrimary,
+		})
+	if len(roles.secondaries) > 0 {
+		checks = append(checks,
+			routingCheck{
+				target: "svc/" + name + ":mysql-ro",
+				openSession: func() (*mysql.PodSession, error) {
+					return mysql.NewServiceSession(ctx, client, namespace, name, "mysql-ro", user, password)
+				},
+				check: checkRoutedToSecondaries,
+			})
+	}
+
+	for _, check := range checks {
+		if err := check.run(connections, roles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,12 +3,14 @@
 package suite
 
 import (
+	"context"
 	"errors"
 
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
 	"github.com/marinesovitch/ote/test-suite/util/mysql"
 )
 
-func CrossSyncGtids(namespace string, pods []string, user string, password string) error {
+func CrossSyncGtids(ctx context.Context, client *k8s.Client, namespace string, pods []string, user string, password string) error {
 	var sessions []*mysql.PodSession
 
 	defer func() {
@@ -18,7 +20,7 @@ func CrossSyncGtids(namespace string, pods []string, user string, password strin
 	}()
 
 	for _, pod := range pods {
-		session, err := mysql.NewSession(namespace, pod, user, password)
+		session, err := mysql.NewSession(ctx, client, namespace, pod, user, password)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,275 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/common"
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/mysql"
+	"github.com/marinesovitch/ote/test-suite/util/system"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const diagnosticsSubdir = "diagnostics"
+
+type diagnostics struct {
+	unit *Unit
+	dir  string
+}
+
+// e.g. TestCluster3Defaults/RecoverCrash2of3=3 => TestCluster3Defaults_RecoverCrash2of3_3
+func getDiagnosticsDirName(testName string) string {
+	rx := regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	return rx.ReplaceAllString(testName, "_")
+}
+
+func (d *diagnostics) write(fileName string, content string) {
+	path := filepath.Join(d.dir, fileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		log.Error.Printf("cannot write diagnostics %s: %s", path, err)
+	}
+}
+
+func (d *diagnostics) writeError(fileName string, err error) {
+	d.write(fileName, fmt.Sprintf("error: %s\n", err))
+}
+
+// ---------------------------
+
+func (d *diagnostics) collectInnoDBClusters(namespace string) {
+	ics, err := d.unit.Client.ListInnoDBClusters(namespace)
+	if err != nil {
+		d.writeError(namespace+"-ic.txt", err)
+		return
+	}
+
+	for _, ic := range ics.Items {
+		fileName := fmt.Sprintf("%s-ic-%s.txt", namespace, ic.GetName())
+		description, err := d.unit.Client.DescribeInnoDBCluster(namespace, ic.GetName())
+		if err != nil {
+			d.writeError(fileName, err)
+			continue
+		}
+		d.write(fileName, description)
+	}
+}
+
+func getContainerRestarts(pod *corev1.Pod, containerName string) int32 {
+	var statuses []corev1.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.Name == containerName {
+			return status.RestartCount
+		}
+	}
+	return 0
+}
+
+func (d *diagnostics) collectPodLogs(pod *corev1.Pod) {
+	var containers []corev1.Container
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, container := range containers {
+		containerId, err := k8s.GetContainerId(container.Name)
+		if err != nil {
+			continue
+		}
+
+		fileName := fmt.Sprintf("%s-%s-%s.log", pod.GetNamespace(), pod.GetName(), container.Name)
+		logs, err := d.unit.Client.Logs(pod.GetNamespace(), pod.GetName(), containerId)
+		if err != nil {
+			d.writeError(fileName, err)
+		} else {
+			d.write(fileName, logs)
+		}
+
+		if getContainerRestarts(pod, container.Name) == 0 {
+			continue
+		}
+
+		fileName = fmt.Sprintf("%s-%s-%s-previous.log", pod.GetNamespace(), pod.GetName(), container.Name)
+		logs, err = d.unit.Client.PreviousLogs(pod.GetNamespace(), pod.GetName(), containerId)
+		if err != nil {
+			d.writeError(fileName, err)
+		} else {
+			d.write(fileName, logs)
+		}
+	}
+}
+
+func (d *diagnostics) collectEvents(namespace string) {
+	fileName := namespace + "-events.txt"
+	events, err := d.unit.Client.ListEvents(namespace, "", common.AnyResourceVersion)
+	if err != nil {
+		d.writeError(fileName, err)
+		return
+	}
+
+	var content bytes.Buffer
+	for _, event := range events.Items {
+		fmt.Fprintf(&content, "%s %s %s/%s %s: %s\n", event.LastTimestamp.Format(time.RFC3339), event.Type,
+			event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Reason, event.Message)
+	}
+	d.write(fileName, content.String())
+}
+
+// ---------------------------
+
+func isMySQLInstance(pod *corev1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == k8s.GetContainerName(k8s.Mysql) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *diagnostics) collectGroupMembers(pod *corev1.Pod) {
+	fileName := fmt.Sprintf("%s-%s-group-members.txt", pod.GetNamespace(), pod.GetName())
+	session, err := mysql.NewSession(d.unit.Context(), d.unit.Client, pod.GetNamespace(), pod.GetName(), common.RootUser, common.RootPassword)
+	if err != nil {
+		d.writeError(fileName, err)
+		return
+	}
+	defer session.Close()
+
+	members, err := session.FetchAll("SELECT * FROM performance_schema.replication_group_members")
+	if err != nil {
+		d.writeError(fileName, err)
+		return
+	}
+
+	var content bytes.Buffer
+	content.WriteString(strings.Join(members.Columns, "\t") + "\n")
+	for _, member := range members.ToStrings() {
+		content.WriteString(strings.Join(member, "\t") + "\n")
+	}
+	d.write(fileName, content.String())
+}
+
+func (d *diagnostics) collectClusterStatus(pod *corev1.Pod) {
+	fileName := fmt.Sprintf("%s-%s-cluster-status.json", pod.GetNamespace(), pod.GetName())
+	uri := fmt.Sprintf("%s:%s@localhost", common.RootUser, common.RootPassword)
+	status, err := d.unit.Client.ExecuteGetOutput(pod.GetNamespace(), pod.GetName(), k8s.Sidecar,
+		"mysqlsh", uri, "--", "cluster", "status", "--extended=1")
+	if err != nil {
+		d.writeError(fileName, err)
+		return
+	}
+	d.write(fileName, status)
+}
+
+func (d *diagnostics) collectPods(namespace string) {
+	pods, err := d.unit.Client.ListPods(namespace)
+	if err != nil {
+		d.writeError(namespace+"-pods.txt", err)
+		return
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		d.collectPodLogs(pod)
+
+		// only running instances are reachable
+		if isMySQLInstance(pod) && pod.Status.Phase == corev1.PodRunning {
+			d.collectGroupMembers(pod)
+			d.collectClusterStatus(pod)
+		}
+	}
+}
+
+func (d *diagnostics) collectOperatorLogs() {
+	pods, err := d.unit.Client.ListPodsWithFilter(common.OperatorNamespace, k8s.OperatorDeployment+"-.*")
+	if err != nil {
+		d.writeError("operator.log", err)
+		return
+	}
+
+	for i := range pods.Items {
+		d.collectPodLogs(&pods.Items[i])
+	}
+}
+
+func (d *diagnostics) collectNamespace(namespace string) {
+	if len(namespace) == 0 {
+		return
+	}
+
+	d.collectInnoDBClusters(namespace)
+	d.collectPods(namespace)
+	d.collectEvents(namespace)
+}
+
+func (d *diagnostics) collect() {
+	d.collectNamespace(d.unit.Namespace)
+	d.collectNamespace(d.unit.AuxNamespace)
+	d.collectOperatorLogs()
+}
+
+// ---------------------------
+
+func (u *Unit) CollectDiagnostics(testName string) error {
+	dir := u.Cfg.GetOutputPath(filepath.Join(diagnosticsSubdir, getDiagnosticsDirName(testName)))
+	if err := system.EnsureDirExist(dir); err != nil {
+		return err
+	}
+
+	log.Info.Printf("collecting diagnostics of %s into %s", testName, dir)
+	d := diagnostics{unit: u, dir: dir}
+	d.collect()
+	return nil
+}
+
+// if the test fails, diagnostics are collected when it finishes (before the next test starts)
+func (u *Unit) CollectDiagnosticsOnFailure(t *testing.T) {
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+
+		if err := u.CollectDiagnostics(t.Name()); err != nil {
+			t.Logf("cannot collect diagnostics: %s", err)
+		}
+	})
+}
+
+// runs f as a subtest of t and collects diagnostics if it fails
+func (u *Unit) Run(t *testing.T, name string, f func(t *testing.T)) bool {
+	if u.parallel {
+		// other units run at the same time, so the global fields are left intact
+		return t.Run(name, func(t *testing.T) {
+			log.Info.With(log.Fields{log.NamespaceField: u.Namespace, log.TestField: t.Name()}).Print("started")
+			u.CollectDiagnosticsOnFailure(t)
+			f(t)
+		})
+	}
+
+	defer log.SetField(log.TestField, t.Name())
+	return t.Run(name, func(t *testing.T) {
+		log.SetField(log.TestField, t.Name())
+		log.Info.Print("started")
+		u.CollectDiagnosticsOnFailure(t)
+		f(t)
+	})
+}
+
+// a test scenario getting its unit explicitly, so it doesn't depend on package globals
+type Scenario func(t *testing.T, u *Unit)
+
+func (u *Unit) RunScenario(t *testing.T, name string, scenario Scenario) bool {
+	return u.Run(t, name, func(t *testing.T) {
+		scenario(t, u)
+	})
+}
@@ -0,0 +1,431 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/objstore"
+	"github.com/marinesovitch/ote/test-suite/util/oci"
+	"github.com/marinesovitch/ote/test-suite/util/ocifake"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the bucket of OCI tests, either a real one (oci.configPath, oci.bucketName) or one of
+// a fake Object Storage served by the unit (oci.fake), tests don't need to care which one
+type OciBucket struct {
+	// the config file with the profiles BACKUP, RESTORE and DELETE
+	ConfigPath string
+	BucketName string
+
+	fake *FakeOci
+}
+
+// starts the fake if oci.fake is set, OCI tests should be skipped on CheckOCIConfig before
+func (u *Unit) PrepareOciBucket() (*OciBucket, error) {
+	if err := u.Cfg.CheckOCIConfig(); err != nil {
+		return nil, err
+	}
+
+	if !u.Cfg.Oci.Fake {
+		return &OciBucket{ConfigPath: u.Cfg.Oci.ConfigPath, BucketName: u.Cfg.Oci.BucketName}, nil
+	}
+
+	fake, err := u.StartFakeOci(u.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &OciBucket{ConfigPath: fake.ConfigPath, BucketName: fake.BucketName, fake: fake}, nil
+}
+
+// as seen from the test suite, requests are signed with the given profile
+func (b *OciBucket) GetObjectStore(profileName string) (objstore.ObjectStore, error) {
+	if b.fake != nil {
+		return b.fake.GetObjectStore(profileName)
+	}
+	return objstore.NewOciStoreFromConfig(b.ConfigPath, profileName, b.BucketName)
+}
+
+// stops the fake (if any), a real bucket is left as is
+func (b *OciBucket) Release() error {
+	if b.fake == nil {
+		return nil
+	}
+	return b.fake.Stop()
+}
+
+// ---------------------------
+
+// A fake OCI Object Storage (see util/ocifake) served by the test suite, so OCI backups may be
+// tested without a tenancy. Pods reach it as objectstorage.<region>.oraclecloud.com, like the
+// real service, the region is made up for the unit. Pods of the namespace are patched at
+// creation by a mutating webhook served by the fake itself: a host alias maps the name to
+// a service without selector, whose endpoint is the host running the tests (oci.fakeHostAddress
+// or detected), and the CA of the fake is mounted as the trusted bundle (oci.fakeCaBundlePath),
+// so mysqlsh accepts its certificate. Nothing cluster-wide but the webhook is changed and it
+// only matches the namespace of the unit, it is removed on stop or at the next wipe of the namespace.
+type FakeOci struct {
+	Region     string
+	BucketName string
+	// generated with fresh api keys for each start
+	ConfigPath string
+	Server     *ocifake.Server
+
+	unit        *Unit
+	namespace   string
+	hostAddress string
+	// of the service the host alias points to
+	serviceIp string
+	stopOnce  sync.Once
+}
+
+const FakeOciName = "objectstorage"
+const fakeOciDefaultBucket = "ote-fake-bucket"
+
+const fakeOciWebhookPath = "/ote/mutate-pods"
+const fakeOciCaBundleName = "objectstorage-ca"
+const fakeOciCaBundleKey = "ca-bundle.crt"
+
+// webhook configurations are labelled with the namespace they serve, so leftovers of a crashed
+// run may be found
+const fakeOciNamespaceLabel = "ote/namespace"
+
+type generateFakeOciData struct {
+	Name        string
+	Namespace   string
+	Port        int
+	HostAddress string
+	WebhookName string
+	WebhookUrl  string
+	CaBundle    string
+	CaBundleKey string
+	CaBundleCfg string
+}
+
+// serves the fake for pods of the given namespace, it is stopped at teardown of the unit at the latest
+func (u *Unit) StartFakeOci(namespace string) (*FakeOci, error) {
+	hostAddress, err := u.getFakeOciHostAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	fake := &FakeOci{
+		Region:      "ote-" + namespace,
+		BucketName:  u.Cfg.Oci.BucketName,
+		unit:        u,
+		namespace:   namespace,
+		hostAddress: hostAddress,
+	}
+	if len(fake.BucketName) == 0 {
+		fake.BucketName = fakeOciDefaultBucket
+	}
+
+	cfgDir := u.Cfg.GetOutputPath(filepath.Join("fake-oci", namespace))
+	if err := os.RemoveAll(cfgDir); err != nil {
+		return nil, err
+	}
+	fake.ConfigPath, err = ocifake.GenerateConfig(cfgDir, fake.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	fake.Server, err = ocifake.NewServer(ocifake.Config{
+		ConfigPath: fake.ConfigPath,
+		Profiles:   ocifake.Profiles,
+		// the api server calls the webhook by the ip of the host
+		Hosts:      []string{oci.GetObjectStorageHost(fake.Region), hostAddress, "127.0.0.1", "localhost"},
+		CaCertFile: u.Cfg.Oci.FakeCaCertFile,
+		CaKeyFile:  u.Cfg.Oci.FakeCaKeyFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fake.Server.Handle(fakeOciWebhookPath, http.HandlerFunc(fake.mutatePod))
+	// on all interfaces, pods and the api server come through the network of the cluster
+	if err := fake.Server.Start(":0"); err != nil {
+		return nil, err
+	}
+	fake.Server.CreateBucket(fake.BucketName)
+
+	go func() {
+		<-u.Context().Done()
+		fake.Server.Close()
+	}()
+
+	if err := fake.expose(); err != nil {
+		fake.Stop()
+		return nil, err
+	}
+	return fake, nil
+}
+
+// the address of the host as seen from the cluster, if not given it is the one of a local
+// interface in the network of the nodes (kind, k3d, minikube with the docker driver), else the
+// one the cluster publishes as host.<env>.internal
+func (u *Unit) getFakeOciHostAddress() (string, error) {
+	if hostAddress := u.Cfg.Oci.FakeHostAddress; len(hostAddress) > 0 {
+		if net.ParseIP(hostAddress) == nil {
+			return "", fmt.Errorf("oci.fakeHostAddress should be an ip, but it is '%s'", hostAddress)
+		}
+		return hostAddress, nil
+	}
+
+	if hostAddress, err := u.findLocalAddressInNodesNetwork(); err != nil || len(hostAddress) > 0 {
+		return hostAddress, err
+	}
+	if hostAddress := u.findPublishedHostAddress(); len(hostAddress) > 0 {
+		return hostAddress, nil
+	}
+	return "", fmt.Errorf("cannot detect the address of the host as seen from the cluster, set oci.fakeHostAddress")
+}
+
+func (u *Unit) findLocalAddressInNodesNetwork() (string, error) {
+	nodes, err := u.Client.ListNodes()
+	if err != nil {
+		return "", err
+	}
+	localAddresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			nodeIp := net.ParseIP(address.Address)
+			if address.Type != corev1.NodeInternalIP || nodeIp == nil {
+				continue
+			}
+			for _, localAddress := range localAddresses {
+				localNet, ok := localAddress.(*net.IPNet)
+				if ok && !localNet.IP.IsLoopback() && !localNet.IP.Equal(nodeIp) && localNet.Contains(nodeIp) {
+					return localNet.IP.String(), nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+var publishedHostRx = regexp.MustCompile(`(?m)^\s*(\S+)\s+host\.(k3d|minikube|docker)\.internal\b`)
+
+// k3d and minikube add the host to the config of CoreDNS, it is only read
+func (u *Unit) findPublishedHostAddress() string {
+	const CoreDnsNamespace = "kube-system"
+	const CoreDnsName = "coredns"
+	cm, err := u.Client.GetConfigMap(CoreDnsNamespace, CoreDnsName)
+	if err != nil {
+		return ""
+	}
+	for _, key := range []string{"NodeHosts", "Corefile"} {
+		if match := publishedHostRx.FindStringSubmatch(cm.Data[key]); match != nil && net.ParseIP(match[1]) != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+func (f *FakeOci) getWebhookName() string {
+	return "ote-fake-oci-" + f.namespace
+}
+
+func (f *FakeOci) expose() error {
+	data := generateFakeOciData{
+		Name:        FakeOciName,
+		Namespace:   f.namespace,
+		Port:        f.Server.GetPort(),
+		HostAddress: f.hostAddress,
+		WebhookName: f.getWebhookName(),
+		WebhookUrl:  fmt.Sprintf("https://%s%s", net.JoinHostPort(f.hostAddress, strconv.Itoa(f.Server.GetPort())), fakeOciWebhookPath),
+		CaBundle:    base64.StdEncoding.EncodeToString(f.Server.GetCaCertPem()),
+		CaBundleKey: fakeOciCaBundleKey,
+		CaBundleCfg: fakeOciCaBundleName,
+	}
+	const FakeOciTemplate = "fake-oci.yaml"
+	content, err := f.unit.renderTemplate(FakeOciTemplate, data)
+	if err != nil {
+		return err
+	}
+	// the service has to be there before the webhook patches pods with its ip
+	if _, err := f.unit.Client.ApplyYaml(f.unit.Context(), f.namespace, content); err != nil {
+		return fmt.Errorf("cannot expose the fake OCI: %w", err)
+	}
+
+	service, err := f.unit.Client.GetService(f.namespace, FakeOciName)
+	if err != nil {
+		return err
+	}
+	f.serviceIp = service.Spec.ClusterIP
+	if net.ParseIP(f.serviceIp) == nil {
+		return fmt.Errorf("the service %s/%s has no cluster ip", f.namespace, FakeOciName)
+	}
+	return f.waitOnWebhook()
+}
+
+// the api server may need a while to pick up the webhook, or not reach the host at all, so
+// a probe pod is admitted (but not created) until it gets patched
+func (f *FakeOci) waitOnWebhook() error {
+	probe := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "fake-oci-probe", Namespace: f.namespace},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "probe", Image: f.unit.GetDefaultServerImage()}},
+		},
+	}
+	host := oci.GetObjectStorageHost(f.Region)
+	checker := func(args ...interface{}) (bool, error) {
+		pod, err := f.unit.Client.CreatePodDryRun(f.namespace, probe)
+		if err != nil {
+			return false, err
+		}
+		for _, alias := range pod.Spec.HostAliases {
+			if alias.IP == f.serviceIp && len(alias.Hostnames) > 0 && alias.Hostnames[0] == host {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if _, err := f.unit.Wait(checker, 30*time.Second, 2*time.Second); err != nil {
+		return fmt.Errorf("pods are not patched by the webhook of the fake OCI on %s, the api server may not reach the host (see oci.fakeHostAddress): %w",
+			f.hostAddress, err)
+	}
+	return nil
+}
+
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// a list is created if there is none yet, else the item is appended
+func addToList(patch []jsonPatchOperation, path string, isEmpty bool, item interface{}) []jsonPatchOperation {
+	if isEmpty {
+		return append(patch, jsonPatchOperation{Op: "add", Path: path, Value: []interface{}{item}})
+	}
+	return append(patch, jsonPatchOperation{Op: "add", Path: path + "/-", Value: item})
+}
+
+// the host alias and the CA bundle, mounted in each container over the one of the image
+func (f *FakeOci) getPodPatch(pod *corev1.Pod) []jsonPatchOperation {
+	var patch []jsonPatchOperation
+	patch = addToList(patch, "/spec/hostAliases", len(pod.Spec.HostAliases) == 0, corev1.HostAlias{
+		IP:        f.serviceIp,
+		Hostnames: []string{oci.GetObjectStorageHost(f.Region)},
+	})
+
+	patch = addToList(patch, "/spec/volumes", len(pod.Spec.Volumes) == 0, corev1.Volume{
+		Name: fakeOciCaBundleName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: fakeOciCaBundleName},
+			},
+		},
+	})
+
+	mount := corev1.VolumeMount{
+		Name:      fakeOciCaBundleName,
+		MountPath: f.unit.Cfg.Oci.FakeCaBundlePath,
+		SubPath:   fakeOciCaBundleKey,
+		ReadOnly:  true,
+	}
+	for i, container := range pod.Spec.InitContainers {
+		path := fmt.Sprintf("/spec/initContainers/%d/volumeMounts", i)
+		patch = addToList(patch, path, len(container.VolumeMounts) == 0, mount)
+	}
+	for i, container := range pod.Spec.Containers {
+		path := fmt.Sprintf("/spec/containers/%d/volumeMounts", i)
+		patch = addToList(patch, path, len(container.VolumeMounts) == 0, mount)
+	}
+	return patch
+}
+
+func (f *FakeOci) reviewPod(request *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		return nil, err
+	}
+	patch, err := json.Marshal(f.getPodPatch(&pod))
+	if err != nil {
+		return nil, err
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch = patch
+	response.PatchType = &patchType
+	log.Info.Printf("fake OCI patches the pod %s/%s%s", request.Namespace, pod.GetName(), pod.GetGenerateName())
+	return response, nil
+}
+
+func (f *FakeOci) mutatePod(w http.ResponseWriter, r *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("unexpected admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response, err := f.reviewPod(review.Request)
+	if err != nil {
+		log.Error.Printf("fake OCI cannot patch the pod: %s", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review.Request = nil
+	review.Response = response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+func (f *FakeOci) GetObjectStore(profileName string) (objstore.ObjectStore, error) {
+	profile, err := oci.LoadProfile(f.ConfigPath, profileName)
+	if err != nil {
+		return nil, err
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AppendCertsFromPEM(f.Server.GetCaCertPem())
+	return objstore.NewOciStoreWithEndpoint(profile, f.BucketName, f.Server.GetLocalEndpoint(), rootCAs)
+}
+
+// the service goes away with the namespace, the webhook is cluster-wide, so it has to be removed
+func (f *FakeOci) Stop() error {
+	var err error
+	f.stopOnce.Do(func() {
+		err = f.unit.Client.DeleteMutatingWebhookConfiguration(f.getWebhookName())
+		if k8s.IsNotFoundError(err) {
+			err = nil
+		}
+		f.Server.Close()
+		log.Info.Printf("fake OCI for %s stopped", f.namespace)
+	})
+	return err
+}
+
+// removes webhooks left behind by fakes of the namespace, e.g. after a crash
+func (u *Unit) deleteFakeOciWebhooks(namespace string) error {
+	webhooks, err := u.Client.ListMutatingWebhookConfigurations(fakeOciNamespaceLabel + "=" + namespace)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range webhooks.Items {
+		log.Info.Printf("deleting the stale webhook %s", webhook.GetName())
+		if err := u.Client.DeleteMutatingWebhookConfiguration(webhook.GetName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@
 package suite
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -21,6 +22,12 @@ func hasFinalizers(client *k8s.Client, namespace string, resource k8s.Kind, name
 			return false, err
 		}
 		return len(ic.GetFinalizers()) > 0, nil
+	case k8s.CRDMySQLBackup:
+		mbk, err := client.GetMySQLBackup(namespace, name)
+		if err != nil {
+			return false, err
+		}
+		return len(mbk.GetFinalizers()) > 0, nil
 	case k8s.Pod:
 		pod, err := client.GetPod(namespace, name)
 		if err != nil {
@@ -49,6 +56,8 @@ func stripFinalizers(client *k8s.Client, namespace string, resource k8s.Kind, na
 	switch resource {
 	case k8s.CRDInnoDBCluster:
 		return client.JSONPatchInnoDBCluster(namespace, name, patch)
+	case k8s.CRDMySQLBackup:
+		return client.JSONPatchMySQLBackup(namespace, name, patch)
 	case k8s.Pod:
 		return client.PatchPod(namespace, name, patch)
 	default:
@@ -71,7 +80,7 @@ func LoadSakilaScript(unit *Unit, podName string, containerId k8s.ContainerId) e
 
 	script := sakilaSchema
 	script = append(script, sakilaData...)
-	return mysql.LoadScript(unit.Namespace, podName, containerId, string(script))
+	return mysql.LoadScript(unit.Client, unit.Namespace, podName, containerId, string(script))
 }
 
 func CheckMySQLBackup(client *k8s.Client, namespace string, name string) (bool, *k8s.MySQLBackup, error) {
@@ -80,15 +89,11 @@ func CheckMySQLBackup(client *k8s.Client, namespace string, name string) (bool,
 		return false, nil, err
 	}
 
-	status, found, err := mbk.AskStatus()
-	if err != nil {
-		return false, nil, err
-	}
-	return found && status == k8s.MBKStatusCompleted, mbk, nil
+	return mbk.Status.Status == k8s.MBKStatusCompleted, mbk, nil
 }
 
-func QuerySet(namespace string, podName string, user string, password string, query string, column int) (common.StringSet, error) {
-	session, err := mysql.NewSession(namespace, podName, user, password)
+func QuerySet(ctx context.Context, client *k8s.Client, namespace string, podName string, user string, password string, query string, column int) (common.StringSet, error) {
+	session, err := mysql.NewSession(ctx, client, namespace, podName, user, password)
 	if err != nil {
 		return nil, err
 	}
@@ -4,14 +4,18 @@ package suite
 
 import (
 	"bytes"
+	"errors"
 	"strings"
 	"time"
 
+	"github.com/marinesovitch/ote/test-suite/util/common"
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 type collectPendingItems struct {
@@ -388,7 +392,7 @@ func (w *wipeNamespace) deleteCustomResources(kind k8s.Kind) error {
 	for _, item := range customResources.Items {
 		name := item.GetName()
 
-		if kind == k8s.CRDInnoDBCluster {
+		if kind == k8s.CRDInnoDBCluster || kind == k8s.CRDMySQLBackup {
 			stripFinalizers(w.client, w.namespace, kind, name)
 		}
 
@@ -399,3 +403,127 @@ func (w *wipeNamespace) deleteCustomResources(kind k8s.Kind) error {
 	}
 	return nil
 }
+
+// the finalizers the operator puts on pods, e.g. mysql.oracle.com/membership, it removes them
+// itself as long as it runs
+func isOperatorFinalizer(finalizer string) bool {
+	const KopfGroup = "kopf.zalando.org"
+	return strings.HasPrefix(finalizer, k8s.OperatorGroup+"/") || strings.HasPrefix(finalizer, KopfGroup+"/")
+}
+
+func (w *wipeNamespace) listPodsWithOperatorFinalizers() ([]string, error) {
+	pods, err := w.client.ListPods(w.namespace)
+	if err != nil {
+		return nil, err
+	}
+	var podNames []string
+	for _, pod := range pods.Items {
+		for _, finalizer := range pod.GetFinalizers() {
+			if isOperatorFinalizer(finalizer) {
+				podNames = append(podNames, pod.GetName())
+				break
+			}
+		}
+	}
+	return podNames, nil
+}
+
+func isOperatorRunning(client *k8s.Client) (bool, error) {
+	deployment, err := client.GetDeployment(common.OperatorNamespace, k8s.OperatorDeployment)
+	if err != nil {
+		if k8s.IsNotFoundError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return deployment.Status.AvailableReplicas > 0, nil
+}
+
+// a running operator removes its finalizers itself, e.g. after a member has left the group, so
+// it is given time, only what it leaves (or everything, if it isn't running) is stripped,
+// finalizers of others are kept anyway
+func (w *wipeNamespace) stripPodFinalizers() error {
+	running, err := isOperatorRunning(w.client)
+	if err != nil {
+		return err
+	}
+
+	if running {
+		const Timeout = 120 * time.Second
+		const Interval = 2 * time.Second
+		err := wait.PollImmediate(Interval, Timeout, func() (bool, error) {
+			podNames, err := w.listPodsWithOperatorFinalizers()
+			return len(podNames) == 0, err
+		})
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, wait.ErrWaitTimeout) {
+			return err
+		}
+	}
+
+	podNames, err := w.listPodsWithOperatorFinalizers()
+	if err != nil {
+		return err
+	}
+	for _, podName := range podNames {
+		log.Warning.Printf("stripping operator finalizers of %s/%s (operator running: %v)", w.namespace, podName, running)
+		err = w.client.RemovePodFinalizers(w.namespace, podName, isOperatorFinalizer)
+		if err != nil && !k8s.IsNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// delete ic and mbk objects but leave the namespace itself intact
+func (w *wipeNamespace) wipeCustomResources() error {
+	if err := w.deleteCustomResources(k8s.CRDInnoDBCluster); err != nil {
+		return err
+	}
+
+	// a stopped operator would never remove the membership finalizers
+	if err := w.stripPodFinalizers(); err != nil {
+		return err
+	}
+
+	return w.deleteCustomResources(k8s.CRDMySQLBackup)
+}
+
+func collectNamespacesWithCustomResources(client *k8s.Client, namespaces common.StringSet, kind k8s.Kind) error {
+	const AllNamespaces = ""
+	customResources, err := client.ListCustomResources(AllNamespaces, kind)
+	if err != nil {
+		if k8s.IsNotFoundError(err) {
+			// CRD is not installed
+			return nil
+		}
+		return err
+	}
+	for _, item := range customResources.Items {
+		namespaces[item.GetNamespace()] = common.MarkExists
+	}
+	return nil
+}
+
+// wipe all ic and mbk objects in all namespaces, so the operator can be
+// undeployed without leaving dangling objects blocked by finalizers
+func WipeOperatorResources(client *k8s.Client) error {
+	namespaces := make(common.StringSet)
+	if err := collectNamespacesWithCustomResources(client, namespaces, k8s.CRDInnoDBCluster); err != nil {
+		return err
+	}
+
+	if err := collectNamespacesWithCustomResources(client, namespaces, k8s.CRDMySQLBackup); err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces.ToSortedSlice() {
+		wn := wipeNamespace{client, namespace}
+		if err := wn.wipeCustomResources(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// what a unit runs at the same time, e.g. a cluster of 3 instances and 1 router,
+// or 2 clusters of 1 instance without routers (then Instances is 2)
+type ClusterDemand struct {
+	Instances int
+	Routers   int
+}
+
+// cpu in millicores, memory in bytes
+type resources struct {
+	cpu    int64
+	memory int64
+}
+
+func (r resources) add(other resources) resources {
+	return resources{cpu: r.cpu + other.cpu, memory: r.memory + other.memory}
+}
+
+func (r resources) sub(other resources) resources {
+	return resources{cpu: r.cpu - other.cpu, memory: r.memory - other.memory}
+}
+
+func (r resources) fits(capacity resources) bool {
+	return r.cpu <= capacity.cpu && r.memory <= capacity.memory
+}
+
+func (r resources) String() string {
+	return fmt.Sprintf("cpu %dm, memory %dMi", r.cpu, r.memory/(1024*1024))
+}
+
+func getRequests(requests corev1.ResourceList) resources {
+	return resources{cpu: requests.Cpu().MilliValue(), memory: requests.Memory().Value()}
+}
+
+// the quantities are validated with the configuration
+func parseResources(cpu string, memory string) resources {
+	cpuQuantity := resource.MustParse(cpu)
+	memoryQuantity := resource.MustParse(memory)
+	return resources{cpu: cpuQuantity.MilliValue(), memory: memoryQuantity.Value()}
+}
+
+// the demand is estimated with the configured requests of a server instance and a router
+func getDemandResources(cfg *setup.Configuration, demand ClusterDemand) resources {
+	instance := parseResources(cfg.TestSuite.InstanceCpu, cfg.TestSuite.InstanceMemory)
+	router := parseResources(cfg.TestSuite.RouterCpu, cfg.TestSuite.RouterMemory)
+	return resources{
+		cpu:    int64(demand.Instances)*instance.cpu + int64(demand.Routers)*router.cpu,
+		memory: int64(demand.Instances)*instance.memory + int64(demand.Routers)*router.memory,
+	}
+}
+
+// what is left for tests, i.e. allocatable resources of schedulable nodes, minus requests
+// of pods already running there (the operator, k8s system pods, etc.)
+func getAvailableResources(client *k8s.Client) (resources, error) {
+	var available resources
+
+	nodes, err := client.ListNodes()
+	if err != nil {
+		return available, err
+	}
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		available = available.add(getRequests(node.Status.Allocatable))
+	}
+
+	const AllNamespaces = ""
+	pods, err := client.ListPods(AllNamespaces)
+	if err != nil {
+		return available, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			available = available.sub(getRequests(container.Resources.Requests))
+		}
+	}
+	return available, nil
+}
+
+// ---------------------------
+
+// lets parallel units run their clusters as long as they fit into the k8s cluster, a unit
+// exceeding the capacity on its own still gets its turn, but it runs alone
+// the units are admitted in the order of arrival, so a large unit waiting for resources
+// blocks the smaller ones behind it instead of being starved by them
+type Scheduler struct {
+	cfg         *setup.Configuration
+	capacity    resources
+	maxClusters int
+
+	mutex   sync.Mutex
+	used    resources
+	running int
+	// tickets of the waiting units, the first one is the next to run
+	queue      []uint64
+	nextTicket uint64
+	// closed and replaced on every release or change of the queue head, so the waiting
+	// units may check again
+	changed chan struct{}
+}
+
+func newScheduler(cfg *setup.Configuration, client *k8s.Client) (*Scheduler, error) {
+	capacity, err := getAvailableResources(client)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get available resources of the cluster: %v", err)
+	}
+	log.Info.Printf("scheduler capacity: %s, max clusters: %d", capacity, cfg.TestSuite.MaxClusters)
+
+	return &Scheduler{
+		cfg:         cfg,
+		capacity:    capacity,
+		maxClusters: cfg.TestSuite.MaxClusters,
+		changed:     make(chan struct{}),
+	}, nil
+}
+
+func (s *Scheduler) canRun(demand resources) bool {
+	if s.running == 0 {
+		return true
+	}
+	if s.maxClusters > 0 && s.running >= s.maxClusters {
+		return false
+	}
+	return s.used.add(demand).fits(s.capacity)
+}
+
+// blocks until the unit is the first in the queue and its demand fits, returns the function
+// to release it
+func (s *Scheduler) Acquire(ctx context.Context, namespace string, clusterDemand ClusterDemand) (func(), error) {
+	demand := getDemandResources(s.cfg, clusterDemand)
+	logger := log.Info.With(log.Fields{log.NamespaceField: namespace})
+
+	s.mutex.Lock()
+	ticket := s.nextTicket
+	s.nextTicket++
+	s.queue = append(s.queue, ticket)
+	s.mutex.Unlock()
+
+	for {
+		s.mutex.Lock()
+		if s.queue[0] == ticket && s.canRun(demand) {
+			s.dequeue(ticket)
+			s.used = s.used.add(demand)
+			s.running++
+			logger.Printf("scheduled (%s), running %d units using %s of %s", demand, s.running, s.used, s.capacity)
+			s.mutex.Unlock()
+			return func() { s.release(demand) }, nil
+		}
+		position := s.position(ticket)
+		changed := s.changed
+		s.mutex.Unlock()
+
+		logger.Printf("waiting for %s to be released, %d units ahead", demand, position)
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			s.mutex.Lock()
+			s.dequeue(ticket)
+			s.mutex.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Scheduler) position(ticket uint64) int {
+	for i, queued := range s.queue {
+		if queued == ticket {
+			return i
+		}
+	}
+	return -1
+}
+
+// the next unit may fit even if nothing was released, so it is woken up as well
+func (s *Scheduler) dequeue(ticket uint64) {
+	if i := s.position(ticket); i >= 0 {
+		s.queue = append(s.queue[:i], s.queue[i+1:]...)
+		if i == 0 {
+			s.notify()
+		}
+	}
+}
+
+func (s *Scheduler) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Scheduler) release(demand resources) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.used = s.used.sub(demand)
+	s.running--
+	s.notify()
+}
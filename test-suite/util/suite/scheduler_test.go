@@ -0,0 +1,199 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marinesovitch/ote/test-suite/util/setup"
+)
+
+func newTestScheduler(capacityInstances int) *Scheduler {
+	cfg := &setup.Configuration{}
+	cfg.TestSuite.InstanceCpu = "1"
+	cfg.TestSuite.InstanceMemory = "1Gi"
+	cfg.TestSuite.RouterCpu = "100m"
+	cfg.TestSuite.RouterMemory = "128Mi"
+	return &Scheduler{
+		cfg:      cfg,
+		capacity: getDemandResources(cfg, ClusterDemand{Instances: capacityInstances}),
+		changed:  make(chan struct{}),
+	}
+}
+
+type acquired struct {
+	name    string
+	release func()
+	err     error
+}
+
+func acquireAsync(ctx context.Context, s *Scheduler, name string, instances int, done chan<- acquired) {
+	go func() {
+		release, err := s.Acquire(ctx, name, ClusterDemand{Instances: instances})
+		done <- acquired{name: name, release: release, err: err}
+	}()
+}
+
+func waitQueued(t *testing.T, s *Scheduler, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mutex.Lock()
+		queued := len(s.queue)
+		s.mutex.Unlock()
+		if queued == expected {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queued units", expected)
+}
+
+func expectAcquired(t *testing.T, done <-chan acquired, name string) acquired {
+	t.Helper()
+	select {
+	case result := <-done:
+		if result.err != nil {
+			t.Fatalf("%s: unexpected error %v", result.name, result.err)
+		}
+		if result.name != name {
+			t.Fatalf("expected %s to be scheduled, got %s", name, result.name)
+		}
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s not scheduled", name)
+	}
+	return acquired{}
+}
+
+func expectNothingAcquired(t *testing.T, done <-chan acquired) {
+	t.Helper()
+	select {
+	case result := <-done:
+		t.Fatalf("unexpected %s scheduled", result.name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSchedulerAdmitsInArrivalOrder(t *testing.T) {
+	s := newTestScheduler(4)
+	ctx := context.Background()
+	done := make(chan acquired)
+
+	releaseFirst, err := s.Acquire(ctx, "first", ClusterDemand{Instances: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the large unit doesn't fit while the first one runs
+	acquireAsync(ctx, s, "large", 4, done)
+	waitQueued(t, s, 1)
+
+	// the small one would fit, but it must not overtake the large one
+	acquireAsync(ctx, s, "small", 1, done)
+	waitQueued(t, s, 2)
+	expectNothingAcquired(t, done)
+
+	releaseFirst()
+	large := expectAcquired(t, done, "large")
+	expectNothingAcquired(t, done)
+
+	large.release()
+	small := expectAcquired(t, done, "small")
+	small.release()
+}
+
+func TestSchedulerCancelledWaiterLetsNextRun(t *testing.T) {
+	s := newTestScheduler(4)
+	done := make(chan acquired)
+
+	releaseFirst, err := s.Acquire(context.Background(), "first", ClusterDemand{Instances: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseFirst()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	acquireAsync(ctx, s, "large", 4, done)
+	waitQueued(t, s, 1)
+	acquireAsync(context.Background(), s, "small", 1, done)
+	waitQueued(t, s, 2)
+	expectNothingAcquired(t, done)
+
+	cancel()
+	select {
+	case result := <-done:
+		if result.name != "large" || result.err != context.Canceled {
+			t.Fatalf("expected the large unit cancelled, got %s, %v", result.name, result.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("large not cancelled")
+	}
+
+	small := expectAcquired(t, done, "small")
+	small.release()
+}
+
+func TestSchedulerRunsOversizedUnitAlone(t *testing.T) {
+	s := newTestScheduler(2)
+	ctx := context.Background()
+
+	release, err := s.Acquire(ctx, "huge", ClusterDemand{Instances: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan acquired)
+	acquireAsync(ctx, s, "small", 1, done)
+	waitQueued(t, s, 1)
+	expectNothingAcquired(t, done)
+
+	release()
+	small := expectAcquired(t, done, "small")
+	small.release()
+}
+
+func TestAcquireInTimeFailsWhenNotScheduled(t *testing.T) {
+	s := newTestScheduler(2)
+	release, err := s.Acquire(context.Background(), "first", ClusterDemand{Instances: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = acquireInTime(ctx, s, "second", ClusterDemand{Instances: 1, Routers: 1})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline exceeded, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "unit second (instances: 1, routers: 1) not scheduled in time") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the unit which gave up doesn't hold back the next ones
+	waitQueued(t, s, 0)
+}
+
+func TestScheduleContextBeforeTestDeadline(t *testing.T) {
+	s := &Suite{}
+	s.Cfg.TestSuite.TimeoutScale = 1.0
+	ctx, cancel := s.getScheduleContext(t)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("expected the wait for room limited")
+	}
+	if testDeadline, ok := t.Deadline(); ok {
+		if expected := testDeadline.Add(-scheduleReserve); !deadline.Equal(expected) {
+			t.Errorf("expected the deadline %s, got %s", expected, deadline)
+		}
+	} else if deadline.After(time.Now().Add(maxScheduleWait)) {
+		t.Errorf("expected the wait for room limited to %s, got %s", maxScheduleWait, deadline)
+	}
+}
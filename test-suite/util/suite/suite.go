@@ -1,11 +1,21 @@
 package suite
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/log"
 	"github.com/marinesovitch/ote/test-suite/util/setup"
 
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -13,6 +23,10 @@ type Suite struct {
 	Cfg    setup.Configuration
 	Client *k8s.Client
 	Dir    string
+
+	schedulerOnce sync.Once
+	scheduler     *Scheduler
+	schedulerErr  error
 }
 
 func CreateSuite() (*Suite, error) {
@@ -36,27 +50,137 @@ func CreateSuite() (*Suite, error) {
 		return nil, err
 	}
 
-	suite := Suite{
+	suite := &Suite{
 		Cfg:    cfg,
 		Client: client,
 		Dir:    suiteDir,
 	}
 
-	return &suite, nil
+	return suite, nil
+}
+
+// the scheduler is created with the first parallel unit, so serial runs don't depend on it
+func (s *Suite) getScheduler() (*Scheduler, error) {
+	s.schedulerOnce.Do(func() {
+		s.scheduler, s.schedulerErr = newScheduler(&s.Cfg, s.Client)
+	})
+	return s.scheduler, s.schedulerErr
+}
+
+// e.g. cluster-races => cluster-races-x7k2p
+func (s *Suite) GenerateNamespace(prefix string) string {
+	const SuffixLength = 5
+	// a namespace is a DNS label
+	maxPrefixLength := validation.DNS1123LabelMaxLength - SuffixLength - 1
+	if len(prefix) > maxPrefixLength {
+		prefix = prefix[:maxPrefixLength]
+	}
+	return prefix + "-" + rand.String(SuffixLength)
+}
+
+func (s *Suite) resolveNamespace(namespace string) string {
+	if len(namespace) == 0 || !s.Cfg.TestSuite.UniqueNamespaces {
+		return namespace
+	}
+	return s.GenerateNamespace(namespace)
 }
 
 func (s *Suite) NewUnitSetup(namespace string) (*Unit, error) {
 	return s.NewUnitSetupWithAuxNamespace(namespace, "")
 }
 
+// if unique namespaces are configured, the given ones are used as prefixes
 func (s *Suite) NewUnitSetupWithAuxNamespace(namespace string, auxNamespace string) (*Unit, error) {
+	unit, err := s.newUnit(s.resolveNamespace(namespace), s.resolveNamespace(auxNamespace), false)
+	if err != nil {
+		return nil, err
+	}
+	log.SetField(log.NamespaceField, unit.Namespace)
+	return unit, unit.Setup()
+}
+
+func (s *Suite) newUnit(namespace string, auxNamespace string, parallel bool) (*Unit, error) {
+	const LogsSubdir = "logs"
+	if err := log.OpenUnitFile(namespace, s.Cfg.GetOutputPath(filepath.Join(LogsSubdir, namespace+".log"))); err != nil {
+		return nil, err
+	}
+
 	unit := Unit{
 		Cfg:          s.Cfg,
 		Client:       s.Client,
 		Namespace:    namespace,
 		AuxNamespace: auxNamespace,
 		SuiteDir:     s.Dir,
+		parallel:     parallel,
+	}
+	unit.ctx, unit.cancel = context.WithCancel(context.Background())
+	return &unit, nil
+}
+
+// a scheduled unit still needs time to set up, run and tear down its clusters, so it stops
+// waiting for room that long before the deadline of the test binary (-timeout)
+const scheduleReserve = 5 * time.Minute
+
+// how long a unit waits for room if the test binary has no deadline (-timeout 0)
+const maxScheduleWait = 60 * time.Minute
+
+// both are scaled with testSuite.timeoutScale
+func (s *Suite) getScheduleContext(t *testing.T) (context.Context, context.CancelFunc) {
+	if deadline, ok := t.Deadline(); ok {
+		return context.WithDeadline(context.Background(), deadline.Add(-s.Cfg.ScaleTimeout(scheduleReserve)))
+	}
+	return context.WithTimeout(context.Background(), s.Cfg.ScaleTimeout(maxScheduleWait))
+}
+
+// a unit which doesn't get room before ctx is done fails on its own, instead of the whole
+// test binary panicking on its timeout with all units still queued
+func acquireInTime(ctx context.Context, scheduler *Scheduler, namespace string, demand ClusterDemand) (func(), error) {
+	release, err := scheduler.Acquire(ctx, namespace, demand)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("unit %s (instances: %d, routers: %d) not scheduled in time, other units held the cluster resources, consider a longer -timeout or a lower -parallel: %w",
+			namespace, demand.Instances, demand.Routers, err)
+	}
+	return release, err
+}
+
+// a unit running in parallel with other such units (t.Parallel() is called here), it always
+// gets unique namespaces (the given ones are prefixes), the unit starts when the scheduler
+// finds room for its clusters, it is torn down when t finishes
+func (s *Suite) NewParallelUnitSetup(t *testing.T, namespace string, demand ClusterDemand) (*Unit, error) {
+	return s.NewParallelUnitSetupWithAuxNamespace(t, namespace, "", demand)
+}
+
+func (s *Suite) NewParallelUnitSetupWithAuxNamespace(t *testing.T, namespace string, auxNamespace string, demand ClusterDemand) (*Unit, error) {
+	t.Parallel()
+
+	scheduler, err := s.getScheduler()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace = s.GenerateNamespace(namespace)
+	if len(auxNamespace) > 0 {
+		auxNamespace = s.GenerateNamespace(auxNamespace)
+	}
+
+	ctx, cancel := s.getScheduleContext(t)
+	defer cancel()
+	release, err := acquireInTime(ctx, scheduler, namespace, demand)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := s.newUnit(namespace, auxNamespace, true)
+	if err != nil {
+		release()
+		return nil, err
 	}
 
-	return &unit, unit.Setup()
+	t.Cleanup(func() {
+		defer release()
+		if err := unit.Teardown(); err != nil {
+			t.Error(err)
+		}
+	})
+	return unit, unit.Setup()
 }
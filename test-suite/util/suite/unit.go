@@ -3,11 +3,15 @@
 package suite
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/marinesovitch/ote/test-suite/util/common"
@@ -16,6 +20,7 @@ import (
 	"github.com/marinesovitch/ote/test-suite/util/mysql"
 	"github.com/marinesovitch/ote/test-suite/util/setup"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -27,6 +32,16 @@ type Unit struct {
 	Namespace    string
 	AuxNamespace string
 	SuiteDir     string
+
+	// all waits of the unit are abandoned at teardown
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// parallel units don't tag records through the global log fields
+	parallel bool
+
+	// started on demand, see GetChaosAgents
+	chaosAgents *ChaosAgents
 }
 
 func (u *Unit) Setup() error {
@@ -43,6 +58,14 @@ func (u *Unit) Setup() error {
 }
 
 func (u *Unit) Teardown() error {
+	defer func() {
+		if !u.parallel {
+			log.RemoveField(log.NamespaceField)
+		}
+		log.CloseUnitFile(u.Namespace)
+	}()
+	defer u.cancel()
+
 	if err := u.WipeNamespace(u.AuxNamespace); err != nil {
 		return err
 	}
@@ -50,7 +73,7 @@ func (u *Unit) Teardown() error {
 }
 
 func (u *Unit) GetServerImage(versionTag string) string {
-	return fmt.Sprintf("%s/%s:%s", u.Cfg.GetImageRegistryRepository(), u.Cfg.Images.MysqlServerImage, versionTag)
+	return u.Cfg.GetImageName(u.Cfg.Images.MysqlServerImage, versionTag)
 }
 
 func (u *Unit) GetDefaultServerImage() string {
@@ -58,7 +81,7 @@ func (u *Unit) GetDefaultServerImage() string {
 }
 
 func (u *Unit) GetRouterImage(versionTag string) string {
-	return fmt.Sprintf("%s/%s:%s", u.Cfg.GetImageRegistryRepository(), u.Cfg.Images.MysqlRouterImage, versionTag)
+	return u.Cfg.GetImageName(u.Cfg.Images.MysqlRouterImage, versionTag)
 }
 
 func (u *Unit) GetDefaultRouterImage() string {
@@ -66,7 +89,7 @@ func (u *Unit) GetDefaultRouterImage() string {
 }
 
 func (u *Unit) GetOperatorImage(versionTag string) string {
-	return fmt.Sprintf("%s/%s:%s", u.Cfg.GetImageRegistryRepository(), u.Cfg.Operator.Image, versionTag)
+	return u.Cfg.GetImageName(u.Cfg.Operator.Image, versionTag)
 }
 
 func (u *Unit) GetDefaultOperatorImage() string {
@@ -83,6 +106,12 @@ func (u *Unit) ApplyInNamespaceGetOutput(namespace string, yamlFilename string)
 	return u.Client.ApplyGetOutput(namespace, yamlPath)
 }
 
+// unlike apply, it fails if the objects exist, admission errors come with the causes of the server
+func (u *Unit) CreateGetOutput(yamlFilename string) (string, error) {
+	yamlPath := filepath.Join(u.SuiteDir, yamlSubdir, yamlFilename)
+	return u.Client.CreateGetOutput(u.Namespace, yamlPath)
+}
+
 func (u *Unit) Apply(yamlFilename string) error {
 	return u.ApplyInNamespace(u.Namespace, yamlFilename)
 }
@@ -112,8 +141,34 @@ func (u *Unit) GenerateAndApply(yamlTemplateFilename string, data interface{}) e
 	return u.GenerateAndApplyInNamespace(u.Namespace, yamlTemplateFilename, data)
 }
 
+// renders a template of the suite (see Cfg.GetTemplatePath) in memory, parallel units would
+// overwrite each other's generated files
+func (u *Unit) renderTemplate(templateName string, data interface{}) ([]byte, error) {
+	templatePath := u.Cfg.GetTemplatePath(templateName)
+	tmpl, err := template.ParseFiles(templatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var content bytes.Buffer
+	if err := tmpl.Execute(&content, data); err != nil {
+		return nil, fmt.Errorf("cannot generate %s: %s", templatePath, err)
+	}
+	return content.Bytes(), nil
+}
+
+// the cluster is created in the unit namespace, unless the builder sets another one
+func (u *Unit) CreateInnoDBCluster(builder *k8s.InnoDBClusterBuilder) error {
+	ic := builder.Build()
+	if len(ic.GetNamespace()) == 0 {
+		ic.SetNamespace(u.Namespace)
+	}
+	_, err := u.Client.CreateInnoDBCluster(ic)
+	return err
+}
+
 func (u *Unit) LoadScript(podName string, containerId k8s.ContainerId, script string) error {
-	return mysql.LoadScript(u.Namespace, podName, containerId, script)
+	return mysql.LoadScript(u.Client, u.Namespace, podName, containerId, script)
 }
 
 func (u *Unit) AssertGotClusterEvent(
@@ -124,49 +179,57 @@ func (u *Unit) AssertGotClusterEvent(
 		return err
 	}
 
-	var events *corev1.EventList
-	checker := func(args ...interface{}) (bool, error) {
-		events, err = u.Client.ListInnoDBClusterEvents(u.Namespace, cluster, sinceResourceVersion)
-		if err != nil {
-			return false, err
-		}
-		for _, event := range events.Items {
+	// events are matched against all existing ones, not only the ones after sinceResourceVersion
+	ctx, cancel := u.WithTimeout(60 * time.Second)
+	defer cancel()
+	params := k8s.WatchParams{Namespace: u.Namespace, Name: cluster}
+	err = u.Client.WaitOnEvents(ctx, params, "InnoDBCluster", func(events []*corev1.Event) (bool, string) {
+		for _, event := range events {
 			if event.Type == evType && event.Reason == reason && rx.MatchString(event.Message) {
-				return true, nil
+				return true, ""
 			}
 		}
-		return false, nil
-	}
-	ok, err := u.Wait(checker, 60, 3)
+		return false, fmt.Sprintf("%d events, none matches", len(events))
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("event (%s, %s, %s) not found for '%s': %s", cluster, evType, reason, msg, err)
 	}
+	return nil
+}
 
-	// if events != nil {
-	// 	t.Logf("events for cluster %s", cluster)
-	// 	for i, event := range events.Items {
-	// 		t.Logf("event %d: %s, %s, %s", i, event.Type, event.Reason, event.Message)
-	// 	}
-	// }
+// the unit context is cancelled at teardown
+func (u *Unit) Context() context.Context {
+	return u.ctx
+}
 
-	if !ok {
-		return fmt.Errorf("event (%s, %s, %s) not found for '%s'", cluster, evType, reason, msg)
-	}
-	return nil
+// the timeout is scaled with the configured factor (testSuite.timeoutScale)
+func (u *Unit) WithTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(u.ctx, u.Cfg.ScaleTimeout(timeout))
 }
 
 type ConditionChecker func(args ...interface{}) (bool, error)
 
-func (u *Unit) Wait(checker ConditionChecker, timeout time.Duration, interval time.Duration, args ...interface{}) (bool, error) {
-	timeout *= time.Second
-	interval *= time.Second
-	for elapsed := 0 * time.Second; elapsed < timeout; elapsed += interval {
+// polls the checker until it is satisfied, it fails or ctx is done - meant for conditions
+// which can't be watched, e.g. queries to MySQL, for k8s objects prefer the watch-based waits
+func (u *Unit) WaitContext(ctx context.Context, checker ConditionChecker, interval time.Duration, args ...interface{}) (bool, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
 		if result, err := checker(args...); result || err != nil {
 			return result, err
 		}
-		time.Sleep(interval)
+		select {
+		case <-ctx.Done():
+			return false, errors.New("timeout waiting for condition")
+		case <-ticker.C:
+		}
 	}
-	return false, errors.New("timeout waiting for condition")
+}
+
+func (u *Unit) Wait(checker ConditionChecker, timeout time.Duration, interval time.Duration, args ...interface{}) (bool, error) {
+	ctx, cancel := u.WithTimeout(timeout)
+	defer cancel()
+	return u.WaitContext(ctx, checker, interval, args...)
 }
 
 func (u *Unit) GetInnoDBClusterResourceVersion(name string) (string, error) {
@@ -185,19 +248,23 @@ func (u *Unit) WaitOnInnoDBCluster(params k8s.WaitOnInnoDBClusterParams) error {
 		params.Namespace = u.Namespace
 	}
 
-	const DefaultICTimeout = 300
+	const DefaultICTimeout = 300 * time.Second
 	if params.Timeout == 0 {
 		params.Timeout = DefaultICTimeout
 	}
 
-	return u.Client.WaitOnInnoDBCluster(params)
+	ctx, cancel := u.WithTimeout(params.Timeout)
+	defer cancel()
+	_, err := u.Client.WaitOnInnoDBCluster(ctx, params)
+	return err
 }
 
 func (u *Unit) WaitOnPodInNamespaceSince(namespace string, name string, sinceResourceVersion string, status corev1.PodPhase) error {
 	// Wait for given pod object to reach one of the states in the list.
 	// Aborts on timeout or when an unexpected error is detected in the operator.
-	const Timeout = 120
-	return u.Client.WaitOnPod(namespace, name, sinceResourceVersion, status, Timeout)
+	ctx, cancel := u.WithTimeout(120 * time.Second)
+	defer cancel()
+	return u.Client.WaitOnPod(ctx, namespace, name, sinceResourceVersion, status)
 }
 
 func (u *Unit) WaitOnPodInNamespace(namespace string, name string, status corev1.PodPhase) error {
@@ -212,30 +279,55 @@ func (u *Unit) WaitOnPod(name string, status corev1.PodPhase) error {
 	return u.WaitOnPodInNamespace(u.Namespace, name, status)
 }
 
-func (u *Unit) WaitOnRoutersInNamespace(namespace string, clusterName string, expectedNumOnline int) error {
-	log.Info.Printf("Waiting for %d routers of the cluster %s/%s to become running", expectedNumOnline, namespace, clusterName)
+type PodStateChecker func(pod *corev1.Pod) (bool, error)
 
-	routerChecker := func(args ...interface{}) (bool, error) {
-		routers, err := u.Client.ListPodsWithFilter(namespace, clusterName+"-router-.*")
+// waits until the pod reaches the state recognized by the checker, e.g. k8s.IsPodInitImagePullIssueError
+func (u *Unit) WaitOnPodState(name string, timeout time.Duration, checker PodStateChecker) error {
+	ctx, cancel := u.WithTimeout(timeout)
+	defer cancel()
+	params := k8s.WatchParams{Namespace: u.Namespace, Name: name}
+	return u.Client.WaitOnPods(ctx, params, func(pods []*corev1.Pod) (bool, string) {
+		if len(pods) == 0 {
+			return false, "not found"
+		}
+		ok, err := checker(pods[0])
 		if err != nil {
-			return false, err
+			return false, fmt.Sprintf("%s: %s", k8s.DescribePods(pods), err)
 		}
+		return ok, k8s.DescribePods(pods)
+	})
+}
 
-		if len(routers.Items) != expectedNumOnline {
-			return false, err
+// waits until any event is posted for the cluster
+func (u *Unit) WaitOnClusterEvents(cluster string, timeout time.Duration) error {
+	ctx, cancel := u.WithTimeout(timeout)
+	defer cancel()
+	params := k8s.WatchParams{Namespace: u.Namespace, Name: cluster}
+	return u.Client.WaitOnEvents(ctx, params, "InnoDBCluster", func(events []*corev1.Event) (bool, string) {
+		return len(events) > 0, "no events"
+	})
+}
+
+func (u *Unit) WaitOnRoutersInNamespace(namespace string, clusterName string, expectedNumOnline int) error {
+	log.Info.Printf("Waiting for %d routers of the cluster %s/%s to become running", expectedNumOnline, namespace, clusterName)
+
+	ctx, cancel := u.WithTimeout(120 * time.Second)
+	defer cancel()
+	params := k8s.WatchParams{Namespace: namespace}
+	return u.Client.WaitOnPods(ctx, params, func(pods []*corev1.Pod) (bool, string) {
+		routers := filterRouterPods(pods, clusterName)
+		if len(routers) != expectedNumOnline {
+			return false, k8s.DescribePods(routers)
 		}
 
-		for _, router := range routers.Items {
+		for _, router := range routers {
 			if router.Status.Phase != corev1.PodRunning {
-				return false, nil
+				return false, k8s.DescribePods(routers)
 			}
 		}
 
-		return true, nil
-	}
-
-	_, err := u.Wait(routerChecker, 120, 3)
-	return err
+		return true, ""
+	})
 }
 
 func (u *Unit) WaitOnRouters(clusterName string, expectedNumOnline int) error {
@@ -243,8 +335,9 @@ func (u *Unit) WaitOnRouters(clusterName string, expectedNumOnline int) error {
 }
 
 func (u *Unit) WaitOnInnoDBClusterGoneInNamespaceSince(namespace string, name string, sinceResourceVersion string) error {
-	const Timeout = 120
-	return u.Client.WaitOnInnoDBClusterGone(namespace, name, sinceResourceVersion, Timeout)
+	ctx, cancel := u.WithTimeout(120 * time.Second)
+	defer cancel()
+	return u.Client.WaitOnInnoDBClusterGone(ctx, namespace, name, sinceResourceVersion)
 }
 
 func (u *Unit) WaitOnInnoDBClusterGoneInNamespace(namespace string, name string) error {
@@ -256,8 +349,9 @@ func (u *Unit) WaitOnInnoDBClusterGone(name string) error {
 }
 
 func (u *Unit) WaitOnPodGoneInNamespaceSince(namespace string, name string, sinceResourceVersion string) error {
-	const Timeout = 120
-	return u.Client.WaitOnPodGone(namespace, name, sinceResourceVersion, Timeout)
+	ctx, cancel := u.WithTimeout(120 * time.Second)
+	defer cancel()
+	return u.Client.WaitOnPodGone(ctx, namespace, name, sinceResourceVersion)
 }
 
 func (u *Unit) WaitOnPodGoneInNamespace(namespace string, name string) error {
@@ -271,21 +365,57 @@ func (u *Unit) WaitOnPodGone(name string) error {
 func (u *Unit) WaitOnRoutersGone(clusterName string) error {
 	log.Info.Printf("Waiting for routers of the cluster %s/%s to gone", u.Namespace, clusterName)
 
-	routerChecker := func(args ...interface{}) (bool, error) {
-		routers, err := u.Client.ListPodsWithFilter(u.Namespace, clusterName+"-router-.*")
-		if err != nil {
-			return false, err
+	ctx, cancel := u.WithTimeout(120 * time.Second)
+	defer cancel()
+	params := k8s.WatchParams{Namespace: u.Namespace}
+	return u.Client.WaitOnPods(ctx, params, func(pods []*corev1.Pod) (bool, string) {
+		routers := filterRouterPods(pods, clusterName)
+		return len(routers) == 0, k8s.DescribePods(routers)
+	})
+}
+
+func filterRouterPods(pods []*corev1.Pod, clusterName string) []*corev1.Pod {
+	var routers []*corev1.Pod
+	for _, pod := range pods {
+		if strings.HasPrefix(pod.GetName(), clusterName+"-router-") {
+			routers = append(routers, pod)
 		}
+	}
+	return routers
+}
 
-		if len(routers.Items) > 0 {
-			return false, err
+func (u *Unit) WaitOnStatefulSetReadyInNamespace(namespace string, name string, expectedReady int32) error {
+	ctx, cancel := u.WithTimeout(300 * time.Second)
+	defer cancel()
+	params := k8s.WatchParams{Namespace: namespace, Name: name}
+	return u.Client.WaitOnStatefulSets(ctx, params, func(stss []*appsv1.StatefulSet) (bool, string) {
+		if len(stss) == 0 {
+			return false, "sts doesn't exist"
 		}
+		sts := stss[0]
+		return sts.Status.ReadyReplicas == expectedReady, k8s.DescribeStatefulSet(sts)
+	})
+}
 
-		return true, nil
-	}
+func (u *Unit) WaitOnStatefulSetReady(name string, expectedReady int32) error {
+	return u.WaitOnStatefulSetReadyInNamespace(u.Namespace, name, expectedReady)
+}
 
-	_, err := u.Wait(routerChecker, 120, 3)
-	return err
+func (u *Unit) WaitOnDeploymentRolledOutInNamespace(namespace string, name string) error {
+	ctx, cancel := u.WithTimeout(300 * time.Second)
+	defer cancel()
+	params := k8s.WatchParams{Namespace: namespace, Name: name}
+	return u.Client.WaitOnDeployments(ctx, params, func(deployments []*appsv1.Deployment) (bool, string) {
+		if len(deployments) == 0 {
+			return false, "deployment doesn't exist"
+		}
+		deployment := deployments[0]
+		return k8s.IsDeploymentRolledOut(deployment), k8s.DescribeDeployment(deployment)
+	})
+}
+
+func (u *Unit) WaitOnDeploymentRolledOut(name string) error {
+	return u.WaitOnDeploymentRolledOutInNamespace(u.Namespace, name)
 }
 
 func (u *Unit) DeleteAllPersistentVolumeClaims() error {
@@ -329,7 +459,7 @@ func (u *Unit) waitNamespaceIsEmpty(namespace string) error {
 		pendingItems, err = u.verifyNamespaceIsEmpty(namespace)
 		return len(pendingItems) == 0, err
 	}
-	_, err := u.Wait(checker, 300, 10)
+	_, err := u.Wait(checker, 300*time.Second, 10*time.Second)
 	if err != nil {
 		if len(pendingItems) > 0 {
 			return fmt.Errorf("%s: namespace %s is not empty: %s", err, namespace, pendingItems)
@@ -340,6 +470,11 @@ func (u *Unit) waitNamespaceIsEmpty(namespace string) error {
 }
 
 func (u *Unit) WipeNamespace(namespace string) error {
+	// they are cluster-wide, so they may outlive the namespace
+	if err := u.deleteFakeOciWebhooks(namespace); err != nil {
+		return err
+	}
+
 	if hasNamespace, _, err := u.Client.HasNamespace(namespace); !hasNamespace || err != nil {
 		return err
 	}
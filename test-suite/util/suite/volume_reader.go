@@ -0,0 +1,177 @@
+// Darek Slusarczyk alias marines marinesovitch 2021, 2022
+
+package suite
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marinesovitch/ote/test-suite/util/k8s"
+	"github.com/marinesovitch/ote/test-suite/util/objstore"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// A pod mounting a volume claim read-only, e.g. the one backups are written to, so the test
+// suite can see its files. It is an objstore.ObjectStore with names relative to the root of
+// the volume, e.g. dump-test-volume1-20221208-102030/@.json. The server image is used as
+// it is already there, only sh and coreutils are needed.
+type VolumeReader struct {
+	PodName   string
+	ClaimName string
+
+	unit      *Unit
+	namespace string
+}
+
+const volumeReaderMountPath = "/volume"
+
+type generateVolumeReaderData struct {
+	Name       string
+	Image      string
+	PullPolicy string
+	NodeName   string
+	MountPath  string
+	ClaimName  string
+}
+
+// the node matters for volumes tied to one, e.g. hostPath ones, it may be empty otherwise
+func (u *Unit) StartVolumeReader(claimName string, nodeName string) (*VolumeReader, error) {
+	reader := &VolumeReader{
+		PodName:   "volume-reader-" + claimName,
+		ClaimName: claimName,
+		unit:      u,
+		namespace: u.Namespace,
+	}
+
+	data := generateVolumeReaderData{
+		Name:       reader.PodName,
+		Image:      u.GetDefaultServerImage(),
+		PullPolicy: u.Cfg.Images.PullPolicy,
+		NodeName:   nodeName,
+		MountPath:  volumeReaderMountPath,
+		ClaimName:  claimName,
+	}
+	const VolumeReaderTemplate = "volume-reader.yaml"
+	content, err := u.renderTemplate(VolumeReaderTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := u.Client.ApplyYaml(u.Context(), reader.namespace, content); err != nil {
+		return nil, fmt.Errorf("cannot start the volume reader: %w", err)
+	}
+
+	if err := u.WaitOnPodInNamespace(reader.namespace, reader.PodName, corev1.PodRunning); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// the node a pod with the given prefix of name ran on, e.g. the one of the job of a backup
+func (u *Unit) GetNodeOfPod(podNamePrefix string) (string, error) {
+	pods, err := u.Client.ListPods(u.Namespace)
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if strings.HasPrefix(pod.GetName(), podNamePrefix) && len(pod.Spec.NodeName) > 0 {
+			return pod.Spec.NodeName, nil
+		}
+	}
+	return "", fmt.Errorf("no pod %s* scheduled on a node found in %s", podNamePrefix, u.Namespace)
+}
+
+// the claim can't be deleted as long as the reader uses it
+func (r *VolumeReader) Stop() error {
+	if err := r.unit.Client.DeletePod(r.namespace, r.PodName); err != nil {
+		return err
+	}
+	return r.unit.WaitOnPodGone(r.PodName)
+}
+
+func (r *VolumeReader) String() string {
+	return "pvc://" + r.ClaimName
+}
+
+func (r *VolumeReader) getPath(name string) string {
+	return path.Join(volumeReaderMountPath, name)
+}
+
+func (r *VolumeReader) shell(script string) (string, error) {
+	output, err := r.unit.Client.ExecuteGetOutput(r.namespace, r.PodName, k8s.VolumeReader, "sh", "-c", script)
+	if err != nil {
+		return output, fmt.Errorf("%s: %w: %s", r, err, strings.TrimSpace(output))
+	}
+	return output, nil
+}
+
+// only sizes are listed, there is no portable way to get modification times without find or stat
+func (r *VolumeReader) List(ctx context.Context, prefix string) ([]objstore.ObjectInfo, error) {
+	const ListScript = `list() {
+	for f in "$1"/* "$1"/.[!.]*; do
+		if [ -d "$f" ]; then list "$f"; elif [ -f "$f" ]; then echo "$(wc -c < "$f") $f"; fi
+	done
+}
+list ` + volumeReaderMountPath
+
+	output, err := r.shell(ListScript)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []objstore.ObjectInfo
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		rawSize, filePath, found := strings.Cut(strings.TrimSpace(line), " ")
+		if !found {
+			continue
+		}
+		size, err := strconv.ParseInt(rawSize, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: unexpected line of the list '%s'", r, line)
+		}
+		name := strings.TrimPrefix(filePath, volumeReaderMountPath+"/")
+		if strings.HasPrefix(name, strings.TrimLeft(prefix, "/")) {
+			objects = append(objects, objstore.ObjectInfo{Name: name, Size: size})
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+func (r *VolumeReader) Get(ctx context.Context, name string) ([]byte, error) {
+	if _, err := r.Stat(ctx, name); err != nil {
+		return nil, err
+	}
+	content, err := r.unit.Client.Cat(r.namespace, r.PodName, k8s.VolumeReader, r.getPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+func (r *VolumeReader) Stat(ctx context.Context, name string) (objstore.ObjectInfo, error) {
+	filePath := r.getPath(name)
+	output, err := r.shell(fmt.Sprintf(`if [ -f '%s' ]; then wc -c < '%s'; fi`, filePath, filePath))
+	if err != nil {
+		return objstore.ObjectInfo{}, err
+	}
+	output = strings.TrimSpace(output)
+	if len(output) == 0 {
+		return objstore.ObjectInfo{}, objstore.NewObjectNotFoundError(r, name)
+	}
+	size, err := strconv.ParseInt(output, 10, 64)
+	if err != nil {
+		return objstore.ObjectInfo{}, fmt.Errorf("%s: unexpected size of %s '%s'", r, name, output)
+	}
+	return objstore.ObjectInfo{Name: name, Size: size}, nil
+}
+
+// the volume is mounted read-only, it goes away with its claim anyway
+func (r *VolumeReader) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	return 0, fmt.Errorf("%s is read-only", r)
+}